@@ -0,0 +1,191 @@
+package backplane
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LocalBackplane in-process backplane used when the server runs as a single node
+type LocalBackplane struct {
+	mux         sync.RWMutex
+	closed      bool
+	nextID      int
+	subscribers map[string]map[int]MessageHandler
+	presence    map[string]presenceEntry
+}
+
+type presenceEntry struct {
+	nodeID    string
+	expiresAt time.Time
+}
+
+type localSubscription struct {
+	bp      *LocalBackplane
+	channel string
+	id      int
+}
+
+// NewLocalBackplane starts a new in-process backplane
+func NewLocalBackplane() *LocalBackplane {
+	return &LocalBackplane{
+		subscribers: make(map[string]map[int]MessageHandler),
+		presence:    make(map[string]presenceEntry),
+	}
+}
+
+/*
+Publish
+delivers the payload to every handler subscribed to the channel
+*/
+func (l *LocalBackplane) Publish(ctx context.Context, channel string, payload []byte) error {
+
+	l.mux.RLock()
+	if l.closed {
+		l.mux.RUnlock()
+		return ErrClosed
+	}
+
+	handlers := make([]MessageHandler, 0, len(l.subscribers[channel]))
+	for _, h := range l.subscribers[channel] {
+		handlers = append(handlers, h)
+	}
+	l.mux.RUnlock()
+
+	for _, h := range handlers {
+		h(payload)
+	}
+
+	return nil
+}
+
+/*
+Subscribe
+registers a handler for the given channel
+*/
+func (l *LocalBackplane) Subscribe(ctx context.Context, channel string, handler MessageHandler) (Subscription, error) {
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.closed {
+		return nil, ErrClosed
+	}
+
+	if _, ok := l.subscribers[channel]; !ok {
+		l.subscribers[channel] = make(map[int]MessageHandler)
+	}
+
+	l.nextID++
+	l.subscribers[channel][l.nextID] = handler
+
+	return &localSubscription{bp: l, channel: channel, id: l.nextID}, nil
+}
+
+func (s *localSubscription) Unsubscribe() error {
+	s.bp.mux.Lock()
+	defer s.bp.mux.Unlock()
+
+	delete(s.bp.subscribers[s.channel], s.id)
+	if len(s.bp.subscribers[s.channel]) == 0 {
+		delete(s.bp.subscribers, s.channel)
+	}
+
+	return nil
+}
+
+/*
+SetPresence
+registers the node of a connection, the entry expires after ttl
+*/
+func (l *LocalBackplane) SetPresence(ctx context.Context, key, nodeID string, ttl time.Duration) error {
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	l.presence[key] = presenceEntry{nodeID: nodeID, expiresAt: time.Now().Add(ttl)}
+
+	return nil
+}
+
+/*
+ClaimPresence
+registers the node of a connection only if the key holds no live entry, returns
+false when the key is already taken
+*/
+func (l *LocalBackplane) ClaimPresence(ctx context.Context, key, nodeID string, ttl time.Duration) (bool, error) {
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.closed {
+		return false, ErrClosed
+	}
+
+	if entry, ok := l.presence[key]; ok && time.Now().Before(entry.expiresAt) {
+		return false, nil
+	}
+
+	l.presence[key] = presenceEntry{nodeID: nodeID, expiresAt: time.Now().Add(ttl)}
+
+	return true, nil
+}
+
+/*
+GetPresence
+returns the node that holds the connection
+*/
+func (l *LocalBackplane) GetPresence(ctx context.Context, key string) (string, error) {
+
+	l.mux.RLock()
+	entry, ok := l.presence[key]
+	l.mux.RUnlock()
+
+	if !ok {
+		return "", ErrNoPresence
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		l.mux.Lock()
+		if current, ok := l.presence[key]; ok && current == entry {
+			delete(l.presence, key)
+		}
+		l.mux.Unlock()
+		return "", ErrNoPresence
+	}
+
+	return entry.nodeID, nil
+}
+
+/*
+RemovePresence
+removes the presence entry only if it still belongs to the given node
+*/
+func (l *LocalBackplane) RemovePresence(ctx context.Context, key, nodeID string) error {
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if entry, ok := l.presence[key]; ok && entry.nodeID == nodeID {
+		delete(l.presence, key)
+	}
+
+	return nil
+}
+
+// Close drops every subscription and presence entry
+func (l *LocalBackplane) Close() error {
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.closed = true
+	l.subscribers = make(map[string]map[int]MessageHandler)
+	l.presence = make(map[string]presenceEntry)
+
+	return nil
+}
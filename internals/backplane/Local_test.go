@@ -0,0 +1,162 @@
+package backplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLocalPublish test the method Publish of the local backplane
+func TestLocalPublish(t *testing.T) {
+
+	t.Run("Publish - Delivered to subscribers", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		defer bp.Close()
+
+		received := make(chan []byte, 2)
+
+		_, err := bp.Subscribe(context.Background(), NodeChannel("node-a"), func(payload []byte) {
+			received <- payload
+		})
+		assert.Nil(t, err)
+
+		err = bp.Publish(context.Background(), NodeChannel("node-a"), []byte("hello"))
+		assert.Nil(t, err)
+
+		assert.Equal(t, []byte("hello"), <-received)
+	})
+
+	t.Run("Publish - Not delivered after unsubscribe", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		defer bp.Close()
+
+		received := make(chan []byte, 1)
+
+		sub, err := bp.Subscribe(context.Background(), NodeChannel("node-a"), func(payload []byte) {
+			received <- payload
+		})
+		assert.Nil(t, err)
+
+		err = sub.Unsubscribe()
+		assert.Nil(t, err)
+
+		err = bp.Publish(context.Background(), NodeChannel("node-a"), []byte("hello"))
+		assert.Nil(t, err)
+
+		assert.Len(t, received, 0)
+	})
+
+	t.Run("Publish - Closed backplane", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		bp.Close()
+
+		err := bp.Publish(context.Background(), NodeChannel("node-a"), []byte("hello"))
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+}
+
+// TestLocalPresence test the presence methods of the local backplane
+func TestLocalPresence(t *testing.T) {
+
+	t.Run("Presence - Registered", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		defer bp.Close()
+
+		key := PresenceKey("p2p", "66d6561e43416dd7f7eb6aa4")
+
+		err := bp.SetPresence(context.Background(), key, "node-a", time.Minute)
+		assert.Nil(t, err)
+
+		node, err := bp.GetPresence(context.Background(), key)
+		assert.Nil(t, err)
+		assert.Equal(t, "node-a", node)
+	})
+
+	t.Run("Presence - Expired", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		defer bp.Close()
+
+		key := PresenceKey("p2p", "66d6561e43416dd7f7eb6aa4")
+
+		err := bp.SetPresence(context.Background(), key, "node-a", time.Millisecond)
+		assert.Nil(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = bp.GetPresence(context.Background(), key)
+		assert.ErrorIs(t, err, ErrNoPresence)
+	})
+
+	t.Run("Presence - Not removed by another node", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		defer bp.Close()
+
+		key := PresenceKey("p2p", "66d6561e43416dd7f7eb6aa4")
+
+		err := bp.SetPresence(context.Background(), key, "node-b", time.Minute)
+		assert.Nil(t, err)
+
+		err = bp.RemovePresence(context.Background(), key, "node-a")
+		assert.Nil(t, err)
+
+		node, err := bp.GetPresence(context.Background(), key)
+		assert.Nil(t, err)
+		assert.Equal(t, "node-b", node)
+
+		err = bp.RemovePresence(context.Background(), key, "node-b")
+		assert.Nil(t, err)
+
+		_, err = bp.GetPresence(context.Background(), key)
+		assert.ErrorIs(t, err, ErrNoPresence)
+	})
+}
+
+// TestLocalClaimPresence test the method ClaimPresence of the local backplane
+func TestLocalClaimPresence(t *testing.T) {
+
+	t.Run("ClaimPresence - Only the first claim wins", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		defer bp.Close()
+
+		key := PresenceKey("call", "66d6561e43416dd7f7eb6aa4")
+
+		claimed, err := bp.ClaimPresence(context.Background(), key, "node-a", time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, claimed)
+
+		claimed, err = bp.ClaimPresence(context.Background(), key, "node-b", time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, claimed)
+
+		node, err := bp.GetPresence(context.Background(), key)
+		assert.Nil(t, err)
+		assert.Equal(t, "node-a", node)
+	})
+
+	t.Run("ClaimPresence - Expired entries are claimed again", func(t *testing.T) {
+
+		bp := NewLocalBackplane()
+		defer bp.Close()
+
+		key := PresenceKey("call", "66d6561e43416dd7f7eb6aa4")
+
+		claimed, err := bp.ClaimPresence(context.Background(), key, "node-a", time.Millisecond)
+		assert.Nil(t, err)
+		assert.True(t, claimed)
+
+		time.Sleep(5 * time.Millisecond)
+
+		claimed, err = bp.ClaimPresence(context.Background(), key, "node-b", time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, claimed)
+	})
+}
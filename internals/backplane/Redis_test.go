@@ -0,0 +1,160 @@
+package backplane
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// startMockRedis starts a local redis server for the test
+func startMockRedis(t *testing.T) (*miniredis.Miniredis, *RedisBackplane) {
+
+	srv := miniredis.RunT(t)
+
+	bp, err := NewRedisBackplane(fmt.Sprintf("redis://%s", srv.Addr()))
+	assert.Nil(t, err)
+
+	return srv, bp
+}
+
+// TestRedisPublish test the method Publish of the redis backplane
+func TestRedisPublish(t *testing.T) {
+
+	t.Run("Publish - Delivered between nodes", func(t *testing.T) {
+
+		srv, nodeA := startMockRedis(t)
+		defer nodeA.Close()
+
+		nodeB, err := NewRedisBackplane(fmt.Sprintf("redis://%s", srv.Addr()))
+		assert.Nil(t, err)
+		defer nodeB.Close()
+
+		received := make(chan []byte, 1)
+
+		_, err = nodeB.Subscribe(context.Background(), NodeChannel("node-b"), func(payload []byte) {
+			received <- payload
+		})
+		assert.Nil(t, err)
+
+		err = nodeA.Publish(context.Background(), NodeChannel("node-b"), []byte("hello"))
+		assert.Nil(t, err)
+
+		select {
+		case payload := <-received:
+			assert.Equal(t, []byte("hello"), payload)
+		case <-time.After(2 * time.Second):
+			t.Fatal("message not delivered")
+		}
+	})
+
+	t.Run("Publish - Connection error", func(t *testing.T) {
+
+		_, err := NewRedisBackplane("redis://127.0.0.1:1")
+		assert.Error(t, err)
+	})
+}
+
+// TestRedisPresence test the presence methods of the redis backplane
+func TestRedisPresence(t *testing.T) {
+
+	t.Run("Presence - Registered", func(t *testing.T) {
+
+		_, bp := startMockRedis(t)
+		defer bp.Close()
+
+		key := PresenceKey("group", "66d6561e43416dd7f7eb6aa4")
+
+		err := bp.SetPresence(context.Background(), key, "node-a", time.Minute)
+		assert.Nil(t, err)
+
+		node, err := bp.GetPresence(context.Background(), key)
+		assert.Nil(t, err)
+		assert.Equal(t, "node-a", node)
+	})
+
+	t.Run("Presence - Expired when node stops refreshing", func(t *testing.T) {
+
+		srv, bp := startMockRedis(t)
+		defer bp.Close()
+
+		key := PresenceKey("group", "66d6561e43416dd7f7eb6aa4")
+
+		err := bp.SetPresence(context.Background(), key, "node-a", DEFAULT_PRESENCE_TTL)
+		assert.Nil(t, err)
+
+		srv.FastForward(DEFAULT_PRESENCE_TTL + time.Second)
+
+		_, err = bp.GetPresence(context.Background(), key)
+		assert.ErrorIs(t, err, ErrNoPresence)
+	})
+
+	t.Run("Presence - Not removed by another node", func(t *testing.T) {
+
+		_, bp := startMockRedis(t)
+		defer bp.Close()
+
+		key := PresenceKey("group", "66d6561e43416dd7f7eb6aa4")
+
+		err := bp.SetPresence(context.Background(), key, "node-b", time.Minute)
+		assert.Nil(t, err)
+
+		err = bp.RemovePresence(context.Background(), key, "node-a")
+		assert.Nil(t, err)
+
+		node, err := bp.GetPresence(context.Background(), key)
+		assert.Nil(t, err)
+		assert.Equal(t, "node-b", node)
+
+		err = bp.RemovePresence(context.Background(), key, "node-b")
+		assert.Nil(t, err)
+
+		_, err = bp.GetPresence(context.Background(), key)
+		assert.ErrorIs(t, err, ErrNoPresence)
+	})
+}
+
+// TestRedisClaimPresence test the method ClaimPresence of the redis backplane
+func TestRedisClaimPresence(t *testing.T) {
+
+	t.Run("ClaimPresence - Only the first claim wins", func(t *testing.T) {
+
+		_, bp := startMockRedis(t)
+		defer bp.Close()
+
+		key := PresenceKey("call", "66d6561e43416dd7f7eb6aa4")
+
+		claimed, err := bp.ClaimPresence(context.Background(), key, "node-a", time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, claimed)
+
+		claimed, err = bp.ClaimPresence(context.Background(), key, "node-b", time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, claimed)
+
+		node, err := bp.GetPresence(context.Background(), key)
+		assert.Nil(t, err)
+		assert.Equal(t, "node-a", node)
+	})
+
+	t.Run("ClaimPresence - Expired entries are claimed again", func(t *testing.T) {
+
+		srv, bp := startMockRedis(t)
+		defer bp.Close()
+
+		key := PresenceKey("call", "66d6561e43416dd7f7eb6aa4")
+
+		claimed, err := bp.ClaimPresence(context.Background(), key, "node-a", time.Millisecond)
+		assert.Nil(t, err)
+		assert.True(t, claimed)
+
+		srv.FastForward(5 * time.Millisecond)
+
+		claimed, err = bp.ClaimPresence(context.Background(), key, "node-b", time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, claimed)
+	})
+}
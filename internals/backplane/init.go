@@ -0,0 +1,82 @@
+package backplane
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// KEY_PREFIX prefix used on every key and channel stored on the backplane
+	KEY_PREFIX = "wechat:"
+
+	// DEFAULT_PRESENCE_TTL time a presence entry lives if the node does not refresh it
+	DEFAULT_PRESENCE_TTL = 30 * time.Second
+)
+
+// ERRORS
+var (
+	ErrNoPresence = errors.New("no presence registered")
+	ErrClosed     = errors.New("backplane closed")
+)
+
+// MessageHandler is called for every payload published on a subscribed channel
+type MessageHandler func(payload []byte)
+
+// Subscription represents an active subscription to a channel
+type Subscription interface {
+	Unsubscribe() error
+}
+
+/*
+Backplane
+shares messages and presence between server nodes so every replica can
+reach the clients connected to the others
+*/
+type Backplane interface {
+	// fan-out
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string, handler MessageHandler) (Subscription, error)
+
+	// presence
+	SetPresence(ctx context.Context, key, nodeID string, ttl time.Duration) error
+	ClaimPresence(ctx context.Context, key, nodeID string, ttl time.Duration) (bool, error)
+	GetPresence(ctx context.Context, key string) (string, error)
+	RemovePresence(ctx context.Context, key, nodeID string) error
+
+	Close() error
+}
+
+/*
+NewBackplane
+returns the redis backplane when BACKPLANE_URL is set, otherwise returns
+the in-process backplane for single node deployments
+*/
+func NewBackplane() (Backplane, error) {
+
+	url := os.Getenv("BACKPLANE_URL")
+	if url == "" {
+		return NewLocalBackplane(), nil
+	}
+
+	return NewRedisBackplane(url)
+}
+
+// NodeChannel formats the channel a node listens to for directed messages
+func NodeChannel(nodeID string) string {
+	return KEY_PREFIX + "node:" + nodeID
+}
+
+// PresenceKey formats the key that holds the node a connection lives on
+func PresenceKey(kind, userID string) string {
+	return KEY_PREFIX + "presence:" + kind + ":" + userID
+}
+
+// BroadcastChannel formats the channel every node listens to
+func BroadcastChannel() string {
+	return KEY_PREFIX + "broadcast"
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+InsertAuditEntryDB
+Inserts an administrative action to the audit log of the groups
+*/
+func (db *DB) InsertAuditEntryDB(e models.AuditEntry) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	info, err := db.FormatGroupAudit().InsertOne(ctx, e, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return info.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+/*
+GetAuditEntriesDB
+Gets the audit log of the group, newest first
+*/
+func (db *DB) GetAuditEntriesDB(page int, groupID string) ([]*models.AuditEntry, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"group_id": bson.M{"$eq": groupID},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetSkip(int64((page - 1) * 20))
+	opts.SetLimit(20)
+
+	res := []*models.AuditEntry{}
+
+	cursor, err := db.FormatGroupAudit().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var entry models.AuditEntry
+
+		err := cursor.Decode(&entry)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, &entry)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestInsertAuditEntryDB test database method InsertAuditEntryDB
+func TestInsertAuditEntryDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("InsertAuditEntryDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		e := models.FormatAuditEntry("123456789", ObjectIDMock, models.AUDIT_INFO_EDITED, map[string]any{"name": "old"}, map[string]any{"name": "new"})
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		id, err := db.InsertAuditEntryDB(e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, e.ID.Hex(), id)
+	})
+
+	mt.Run("InsertAuditEntryDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		e := models.FormatAuditEntry("123456789", ObjectIDMock, models.AUDIT_INFO_EDITED, nil, nil)
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    12345,
+			Message: "Error inserting audit entry",
+		}))
+		id, err := db.InsertAuditEntryDB(e)
+
+		assert.Error(t, err)
+		assert.Empty(t, id)
+	})
+}
+
+// TestGetAuditEntriesDB test database method GetAuditEntriesDB
+func TestGetAuditEntriesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetAuditEntriesDB - Success with results", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		entries := []bson.D{
+			{
+				{Key: "group_id", Value: "123456789"},
+				{Key: "action", Value: models.AUDIT_INFO_EDITED},
+				{Key: "before", Value: bson.D{{Key: "name", Value: "old"}}},
+				{Key: "after", Value: bson.D{{Key: "name", Value: "new"}}},
+			},
+			{
+				{Key: "group_id", Value: "123456789"},
+				{Key: "action", Value: models.AUDIT_MEMBERS_ADDED},
+				{Key: "targets", Value: bson.A{ObjectIDMock}},
+			},
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.AUDIT", mtest.FirstBatch, entries...),
+		)
+
+		res, err := db.GetAuditEntriesDB(1, "123456789")
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "new", res[0].After["name"])
+		assert.Equal(t, ObjectIDMock, res[1].Targets[0])
+	})
+
+	mt.Run("GetAuditEntriesDB - encounter error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    123456,
+			Message: "Mongo db has encounter and error",
+		}))
+
+		res, err := db.GetAuditEntriesDB(1, "123456789")
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
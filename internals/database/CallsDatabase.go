@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+InsertCallLogDB
+Inserts the log of a finished call to the call logs collection
+*/
+func (db *DB) InsertCallLogDB(c models.CallLog) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	info, err := db.FormatCallLogs().InsertOne(ctx, c, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return info.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+/*
+GetCallHistoryDB
+Gets the calls of the user, newest first
+*/
+func (db *DB) GetCallHistoryDB(page int, user primitive.ObjectID) ([]*models.CallLog, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"participants": bson.M{"$eq": user},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "started_at", Value: -1}})
+	opts.SetSkip(int64((page - 1) * 20))
+	opts.SetLimit(20)
+
+	var res []*models.CallLog
+
+	cursor, err := db.FormatCallLogs().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var log models.CallLog
+
+		err := cursor.Decode(&log)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, &log)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
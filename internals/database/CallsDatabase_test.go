@@ -0,0 +1,103 @@
+package database
+
+import (
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestInsertCallLogDB test database method InsertCallLogDB
+func TestInsertCallLogDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("InsertCallLogDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		var c models.CallLog
+		c.FormatP2PCallLog(primitive.NewObjectID(), ObjectIDMock, models.CallRecord{State: models.CALL_STATE_MISSED})
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		id, err := db.InsertCallLogDB(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, c.ID.Hex(), id)
+	})
+
+	mt.Run("InsertCallLogDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		var c models.CallLog
+		c.FormatP2PCallLog(primitive.NewObjectID(), ObjectIDMock, models.CallRecord{State: models.CALL_STATE_MISSED})
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    12345,
+			Message: "Error inserting call log",
+		}))
+		id, err := db.InsertCallLogDB(c)
+
+		assert.Error(t, err)
+		assert.Empty(t, id)
+	})
+}
+
+// TestGetCallHistoryDB test database method GetCallHistoryDB
+func TestGetCallHistoryDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetCallHistoryDB - Success with results", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		logs := []bson.D{
+			{{Key: "call_id", Value: "node.1"}, {Key: "state", Value: models.CALL_STATE_ENDED}, {Key: "duration", Value: int64(65)}},
+			{{Key: "call_id", Value: "node.2"}, {Key: "state", Value: models.CALL_STATE_MISSED}},
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.CALLS", mtest.FirstBatch, logs...),
+		)
+
+		res, err := db.GetCallHistoryDB(1, ObjectIDMock)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "node.1", res[0].CallID)
+		assert.Equal(t, int64(65), res[0].Duration)
+		assert.Equal(t, models.CALL_STATE_MISSED, res[1].State)
+	})
+
+	mt.Run("GetCallHistoryDB - encounter error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    123456,
+			Message: "Mongo db has encounter and error",
+		}))
+
+		res, err := db.GetCallHistoryDB(1, ObjectIDMock)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
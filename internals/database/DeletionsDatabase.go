@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+ScheduleGroupDeletionDB
+Marks the group to be deleted at the given time, fails with ErrNoDocuments when
+the group does not exist or its deletion is already scheduled
+*/
+func (db *DB) ScheduleGroupDeletionDB(id primitive.ObjectID, at time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":       bson.M{"$eq": id},
+		"delete_at": bson.M{"$exists": false},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"delete_at": at},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+CancelGroupDeletionDB
+Undoes the scheduled deletion of the group, fails with ErrNoDocuments when there
+is nothing to undo
+*/
+func (db *DB) CancelGroupDeletionDB(id primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":       bson.M{"$eq": id},
+		"delete_at": bson.M{"$exists": true},
+	}
+
+	update := bson.M{
+		"$unset": bson.M{"delete_at": ""},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+ClaimGroupDeletionDB
+Takes one group whose grace period is over, the deletion is pushed back by the
+lease so no other node runs it meanwhile and it is retried if this node dies
+*/
+func (db *DB) ClaimGroupDeletionDB(now time.Time, lease time.Duration) (*models.Group, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"delete_at": bson.M{"$lte": now},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"delete_at": now.Add(lease)},
+	}
+
+	var res models.Group
+
+	err := db.FormatGroupCollection().FindOneAndUpdate(ctx, filter, update).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+GetGroupMediaDB
+Gets the urls of every media sent to the group with its placeholders
+*/
+func (db *DB) GetGroupMediaDB(id primitive.ObjectID) ([]string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	media := []string{}
+
+	for _, field := range []string{"media", "placeholders"} {
+
+		filter := bson.M{
+			"target_id": bson.M{"$eq": id},
+			field:       bson.M{"$exists": true},
+		}
+
+		values, err := db.FormatGroupChatlogs().Distinct(ctx, field, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range values {
+			if url, ok := v.(string); ok && url != "" {
+				media = append(media, url)
+			}
+		}
+	}
+
+	return media, nil
+}
+
+/*
+GetGroupVideosDB
+Gets the content ids of every video sent to the group, the videos are deleted
+by their content id
+*/
+func (db *DB) GetGroupVideosDB(id primitive.ObjectID) ([]string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"target_id": bson.M{"$eq": id},
+		"body_type": bson.M{"$eq": models.MESSAGE_TYPE_MEDIA_VIDEOS},
+	}
+
+	values, err := db.FormatGroupChatlogs().Distinct(ctx, "content_id", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	videos := []string{}
+	for _, v := range values {
+		if contentID, ok := v.(string); ok && contentID != "" {
+			videos = append(videos, contentID)
+		}
+	}
+
+	return videos, nil
+}
+
+/*
+DeleteGroupCascadeDB
+Deletes the chat logs, invites, join requests, audit log, topic reads, message
+reactions, mentions, pins, stars and scheduled messages of the group and the
+group itself in a single transaction
+*/
+func (db *DB) DeleteGroupCascadeDB(id primitive.ObjectID, groupID string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		_, err := db.FormatGroupChatlogs().DeleteMany(sctx, bson.M{"target_id": bson.M{"$eq": id}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatGroupInvites().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatJoinRequests().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatGroupAudit().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatTopicReads().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatMessageReactions().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatGroupMentions().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatMessagePins().DeleteMany(sctx, bson.M{"conversation_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatPinCounters().DeleteOne(sctx, bson.M{"_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatMessageStars().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.FormatScheduledMessages().DeleteMany(sctx, bson.M{"group_id": bson.M{"$eq": groupID}})
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := db.FormatGroupCollection().DeleteOne(sctx, bson.M{"_id": bson.M{"$eq": id}})
+		if err != nil {
+			return nil, err
+		}
+
+		if res.DeletedCount < 1 {
+			return nil, ErrNoDeleted
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
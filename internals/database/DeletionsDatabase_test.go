@@ -0,0 +1,196 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestScheduleGroupDeletionDB test database methods ScheduleGroupDeletionDB and CancelGroupDeletionDB
+func TestScheduleGroupDeletionDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ScheduleGroupDeletionDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.ScheduleGroupDeletionDB(ObjectIDMock, time.Now().Add(time.Hour)))
+	})
+
+	mt.Run("ScheduleGroupDeletionDB - Error already scheduled", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.ScheduleGroupDeletionDB(ObjectIDMock, time.Now()), mongo.ErrNoDocuments.Error())
+	})
+
+	mt.Run("CancelGroupDeletionDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.CancelGroupDeletionDB(ObjectIDMock))
+	})
+
+	mt.Run("CancelGroupDeletionDB - Error nothing to undo", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.CancelGroupDeletionDB(ObjectIDMock), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestClaimGroupDeletionDB test database method ClaimGroupDeletionDB
+func TestClaimGroupDeletionDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ClaimGroupDeletionDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: ObjectIDMock},
+			{Key: "group_id", Value: "123456789"},
+			{Key: "delete_at", Value: time.Now()},
+		}}))
+
+		group, err := db.ClaimGroupDeletionDB(time.Now(), time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "123456789", group.GroupID)
+		assert.True(t, group.PendingDeletion())
+	})
+
+	mt.Run("ClaimGroupDeletionDB - Error nothing expired", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		_, err := db.ClaimGroupDeletionDB(time.Now(), time.Minute)
+
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestGetGroupMediaDB test database method GetGroupMediaDB
+func TestGetGroupMediaDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetGroupMediaDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"https://cdn/content/a.jpg", "", "https://cdn/files/b.pdf"}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"https://cdn/thumbs/a.jpg", ""}}),
+		)
+
+		media, err := db.GetGroupMediaDB(ObjectIDMock)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://cdn/content/a.jpg", "https://cdn/files/b.pdf", "https://cdn/thumbs/a.jpg"}, media)
+	})
+
+	mt.Run("GetGroupMediaDB - Error database", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: "error"}))
+
+		_, err := db.GetGroupMediaDB(ObjectIDMock)
+
+		assert.Error(t, err)
+	})
+}
+
+// TestGetGroupVideosDB test database method GetGroupVideosDB
+func TestGetGroupVideosDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetGroupVideosDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"101$video-a", ""}}))
+
+		videos, err := db.GetGroupVideosDB(ObjectIDMock)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"101$video-a"}, videos)
+	})
+}
+
+// TestDeleteGroupCascadeDB test database method DeleteGroupCascadeDB
+func TestDeleteGroupCascadeDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DeleteGroupCascadeDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+		mt.AddMockResponses(deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.DeleteGroupCascadeDB(ObjectIDMock, "123456789"))
+	})
+
+	mt.Run("DeleteGroupCascadeDB - Error group already deleted", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0})
+		mt.AddMockResponses(deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, deleted, mtest.CreateSuccessResponse())
+
+		assert.EqualError(t, db.DeleteGroupCascadeDB(ObjectIDMock, "123456789"), ErrNoDeleted.Error())
+	})
+}
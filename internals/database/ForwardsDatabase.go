@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+InsertForwardedMessagesDB
+Inserts the copies of a forwarded message on the group and the private chat
+logs in a single transaction, either every destination gets its copy or none does
+*/
+func (db *DB) InsertForwardedMessagesDB(groups []models.GroupChatContentLog, users []models.P2PContentChatLog) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		if len(groups) > 0 {
+			docs := make([]any, 0, len(groups))
+			for _, m := range groups {
+				docs = append(docs, m)
+			}
+
+			_, err := db.FormatGroupChatlogs().InsertMany(sctx, docs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(users) > 0 {
+			docs := make([]any, 0, len(users))
+			for _, m := range users {
+				docs = append(docs, m)
+			}
+
+			_, err := db.FormatUserChatlogs().InsertMany(sctx, docs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestInsertForwardedMessagesDB test database method InsertForwardedMessagesDB
+func TestInsertForwardedMessagesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	groups := []models.GroupChatContentLog{{ID: primitive.NewObjectID(), TargetID: primitive.NewObjectID()}}
+	users := []models.P2PContentChatLog{{ID: primitive.NewObjectID(), TargetID: primitive.NewObjectID()}}
+
+	mt.Run("InsertForwardedMessagesDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.InsertForwardedMessagesDB(groups, users))
+	})
+
+	mt.Run("InsertForwardedMessagesDB - Success groups only", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.InsertForwardedMessagesDB(groups, nil))
+	})
+
+	mt.Run("InsertForwardedMessagesDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key error"}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		assert.Error(t, db.InsertForwardedMessagesDB(groups, users))
+	})
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+CreateGalleryIndexesDB
+Creates the indexes the galleries of the conversations are read with, the
+private index serves both directions of the conversation
+*/
+func (db *DB) CreateGalleryIndexesDB() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	_, err := db.FormatGroupChatlogs().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "target_id", Value: 1},
+			{Key: "body_type", Value: 1},
+			{Key: "created_at", Value: -1},
+			{Key: "_id", Value: -1},
+		},
+		Options: options.Index().SetName("gallery"),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.FormatUserChatlogs().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "author_id", Value: 1},
+			{Key: "target_id", Value: 1},
+			{Key: "body_type", Value: 1},
+			{Key: "created_at", Value: -1},
+			{Key: "_id", Value: -1},
+		},
+		Options: options.Index().SetName("gallery"),
+	})
+
+	return err
+}
+
+/*
+GetGroupMediaGalleryDB
+Gets the content messages of the given types of the group sent before the
+cursor, newest first, a zero cursor starts from the newest message
+*/
+func (db *DB) GetGroupMediaGalleryDB(id primitive.ObjectID, types []int, cursor models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+
+	filter := bson.M{
+		"target_id": bson.M{"$eq": id},
+	}
+
+	return db.getMediaGallery(db.FormatGroupChatlogs(), filter, types, cursor)
+}
+
+/*
+GetP2PMediaGalleryDB
+Gets the content messages of the given types of the private conversation of
+the users sent before the cursor, newest first, a zero cursor starts from the
+newest message
+*/
+func (db *DB) GetP2PMediaGalleryDB(user, peer primitive.ObjectID, types []int, cursor models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"author_id": bson.M{"$eq": user}, "target_id": bson.M{"$eq": peer}},
+			bson.M{"author_id": bson.M{"$eq": peer}, "target_id": bson.M{"$eq": user}},
+		},
+	}
+
+	return db.getMediaGallery(db.FormatUserChatlogs(), filter, types, cursor)
+}
+
+/*
+getMediaGallery
+reads a page of the gallery of the conversation of the filter, the messages sent
+on the same date are ordered by their id so none is skipped between pages
+*/
+func (db *DB) getMediaGallery(chatlogs *mongo.Collection, filter bson.M, types []int, from models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter["body_type"] = bson.M{"$in": types}
+
+	if !from.Before.IsZero() {
+		page := bson.M{"created_at": bson.M{"$lt": from.Before}}
+		if !from.ID.IsZero() {
+			page = bson.M{"$or": bson.A{
+				bson.M{"created_at": bson.M{"$lt": from.Before}},
+				bson.M{"created_at": bson.M{"$eq": from.Before}, "_id": bson.M{"$lt": from.ID}},
+			}}
+		}
+		// the private filter already holds an $or
+		filter = bson.M{"$and": bson.A{filter, page}}
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetLimit(models.MEDIA_GALLERY_PAGE)
+
+	res := []*models.MediaGalleryItem{}
+
+	cursor, err := chatlogs.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var item models.MediaGalleryItem
+
+		err := cursor.Decode(&item)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &item)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestCreateGalleryIndexesDB test database method CreateGalleryIndexesDB
+func TestCreateGalleryIndexesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CreateGalleryIndexesDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.CreateGalleryIndexesDB())
+	})
+
+	mt.Run("CreateGalleryIndexesDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 85, Message: "index options conflict"}))
+
+		assert.Error(t, db.CreateGalleryIndexesDB())
+	})
+}
+
+// TestGetMediaGalleryDB test database methods GetGroupMediaGalleryDB and GetP2PMediaGalleryDB
+func TestGetMediaGalleryDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	user := primitive.NewObjectID()
+	peer := primitive.NewObjectID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	item := func(bodyType int, media, thumbnail string, at time.Time) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "author_id", Value: peer},
+			{Key: "body_type", Value: bodyType},
+			{Key: "media", Value: bson.A{media}},
+			{Key: "placeholders", Value: bson.A{thumbnail}},
+			{Key: "created_at", Value: at},
+		}
+	}
+
+	mt.Run("GetGroupMediaGalleryDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.FirstBatch,
+			item(models.MESSAGE_TYPE_MEDIA_IMAGES, "cat.jpg", "cat_thumb.jpg", now),
+			item(models.MESSAGE_TYPE_MEDIA_VIDEOS, "dog.mp4", "dog_thumb.jpg", now.Add(-time.Minute)),
+		))
+
+		types, _ := models.GalleryTypes(0)
+		res, err := db.GetGroupMediaGalleryDB(ObjectIDMock, types, models.GalleryCursor{})
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, []string{"cat.jpg"}, res[0].Media)
+		assert.Equal(t, []string{"dog_thumb.jpg"}, res[1].Placeholders)
+		assert.Equal(t, models.MESSAGE_TYPE_MEDIA_VIDEOS, res[1].BodyType)
+	})
+
+	mt.Run("GetP2PMediaGalleryDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.USRCHLOGS", mtest.FirstBatch,
+			item(models.MESSAGE_TYPE_FILE, "report.pdf", "", now),
+		))
+
+		res, err := db.GetP2PMediaGalleryDB(user, peer, []int{models.MESSAGE_TYPE_FILE}, models.GalleryCursor{Before: now.Add(time.Hour), ID: primitive.NewObjectID()})
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, []string{"report.pdf"}, res[0].Media)
+		assert.Equal(t, now, res[0].CreatedAt.Local())
+
+		// the messages sent on the same date are paged by their id
+		sort := mt.GetStartedEvent().Command.Lookup("sort").Document()
+		assert.Equal(t, int32(-1), sort.Lookup("_id").Int32())
+	})
+
+	mt.Run("GetP2PMediaGalleryDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "find failed"}))
+
+		_, err := db.GetP2PMediaGalleryDB(user, peer, []int{models.MESSAGE_TYPE_FILE}, models.GalleryCursor{})
+		assert.Error(t, err)
+	})
+}
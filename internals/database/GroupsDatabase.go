@@ -83,6 +83,323 @@ func (db *DB) UpdateGroupDB(update map[string]any, id primitive.ObjectID) error
 	return nil
 }
 
+/*
+UpdateGroupAvatarDB
+Replaces the avatar of the group and keeps the last changes on its history
+*/
+func (db *DB) UpdateGroupAvatarDB(id primitive.ObjectID, avatar models.GroupAvatar) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": id},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"profile_image": avatar.URL},
+		"$push": bson.M{
+			"avatar_history": bson.M{
+				"$each":  []models.GroupAvatar{avatar},
+				"$slice": -models.MAX_AVATAR_HISTORY,
+			},
+		},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+UpdateGroupMuteDB
+Mutes the user on the group until the given time, a zero time removes the mute
+*/
+func (db *DB) UpdateGroupMuteDB(id, user primitive.ObjectID, until time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": id},
+	}
+
+	key := "mutes." + user.Hex()
+
+	update := bson.M{
+		"$set": bson.M{key: until},
+	}
+	if until.IsZero() {
+		update = bson.M{
+			"$unset": bson.M{key: ""},
+		}
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+ClaimGroupPostDB
+records the post of the user on the group when the slow mode lets it post, the
+last post is checked and moved by a single update so the members can not get
+past the slow mode by reconnecting or from several nodes. Returns the last post
+of the user when it still has to wait, zero when the post was recorded
+*/
+func (db *DB) ClaimGroupPostDB(id, user primitive.ObjectID, now time.Time, wait time.Duration) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	key := "last_posts." + user.Hex()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": id},
+		"$or": bson.A{
+			bson.M{key: bson.M{"$exists": false}},
+			bson.M{key: bson.M{"$lte": now.Add(-wait)}},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{key: now},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if res.MatchedCount > 0 {
+		return time.Time{}, nil
+	}
+
+	var group models.Group
+
+	opts := options.FindOne().SetProjection(bson.M{key: 1})
+
+	err = db.FormatGroupCollection().FindOne(ctx, bson.M{"_id": bson.M{"$eq": id}}, opts).Decode(&group)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	last, ok := group.LastPosts[user.Hex()]
+	if !ok {
+		// the post was cleared after the update, the user waits the whole slow mode
+		return now, nil
+	}
+
+	return last, nil
+}
+
+/*
+LeaveGroupDB
+removes the user from the group and hands its roles in a single transaction,
+the roles are given only if the group is still as it was when the leave was
+decided, mongo.ErrNoDocuments is returned when the group changed meanwhile
+*/
+func (db *DB) LeaveGroupDB(id primitive.ObjectID, leave models.GroupLeave) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	expected := bson.A{
+		bson.M{"participants": bson.M{"$eq": leave.UserID}},
+	}
+
+	set := bson.M{}
+
+	switch {
+	case leave.Last:
+		expected = append(expected, bson.M{"participants": bson.M{"$size": 1}})
+		set["owner_id"] = primitive.NilObjectID
+	case !leave.Promoted.IsZero():
+		// the user is the last admin and the promoted participant is still on the group
+		expected = append(expected,
+			bson.M{"admins": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$ne": leave.UserID}}}},
+			bson.M{"participants": bson.M{"$eq": leave.Promoted}},
+		)
+	default:
+		// another admin stays on the group
+		expected = append(expected, bson.M{"admins": bson.M{"$elemMatch": bson.M{"$ne": leave.UserID}}})
+	}
+
+	if !leave.OwnerID.IsZero() {
+		expected = append(expected, bson.M{"owner_id": bson.M{"$eq": leave.UserID}})
+		if leave.OwnerID != leave.Promoted {
+			expected = append(expected, bson.M{"admins": bson.M{"$eq": leave.OwnerID}})
+		}
+		set["owner_id"] = leave.OwnerID
+	} else if !leave.Last {
+		expected = append(expected, bson.M{"owner_id": bson.M{"$ne": leave.UserID}})
+	}
+
+	filter := bson.M{
+		"_id":  bson.M{"$eq": id},
+		"$and": expected,
+	}
+
+	update := bson.M{
+		"$pull": bson.M{"participants": leave.UserID, "admins": leave.UserID},
+	}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		res, err := db.FormatGroupCollection().UpdateOne(sctx, filter, update)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.MatchedCount < 1 {
+			return nil, mongo.ErrNoDocuments
+		}
+
+		if leave.Promoted.IsZero() {
+			return nil, nil
+		}
+
+		_, err = db.FormatGroupCollection().UpdateOne(sctx, bson.M{"_id": bson.M{"$eq": id}}, bson.M{
+			"$addToSet": bson.M{"admins": leave.Promoted},
+		})
+
+		return nil, err
+	})
+
+	return err
+}
+
+/*
+TransferGroupOwnershipDB
+hands the group to the target and keeps both as admins, the group is handed only
+while the owner still owns it and the target is still a participant,
+mongo.ErrNoDocuments is returned when the group changed meanwhile
+*/
+func (db *DB) TransferGroupOwnershipDB(id, owner, target primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":          bson.M{"$eq": id},
+		"owner_id":     bson.M{"$eq": owner},
+		"participants": bson.M{"$eq": target},
+	}
+
+	update := bson.M{
+		"$set":      bson.M{"owner_id": target},
+		"$addToSet": bson.M{"admins": bson.M{"$each": bson.A{target, owner}}},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+GetUserGroupIDsDB
+Gets the ids of the groups the user participates in
+*/
+func (db *DB) GetUserGroupIDsDB(user primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"participants": bson.M{"$eq": user},
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := db.FormatGroupCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	res := []primitive.ObjectID{}
+
+	for cursor.Next(ctx) {
+
+		var group struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+
+		err := cursor.Decode(&group)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, group.ID)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+/*
+UpdateNotificationMuteDB
+Mutes the notifications of the group for the user until the given time, a zero time removes the mute
+*/
+func (db *DB) UpdateNotificationMuteDB(id, user primitive.ObjectID, until time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": id},
+	}
+
+	key := "notification_mutes." + user.Hex()
+
+	update := bson.M{
+		"$set": bson.M{key: until},
+	}
+	if until.IsZero() {
+		update = bson.M{
+			"$unset": bson.M{key: ""},
+		}
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
 /*
 	DeleteGroupDB
 	Deletes a group document on the collection selected
@@ -115,26 +432,46 @@ func (db *DB) DeleteGroupDB(i string) error {
 }
 
 /*
-	SearchGroups
-	Gets a list of groups matching the query or deliberately
+SearchGroups
+Searches the public groups by name, category and tags, returns only the public
+projection of every group
 */
-
-func (db *DB) SearchGroups(page int, query string) ([]*models.Group, error) {
+func (db *DB) SearchGroups(page int, f models.GroupDirectoryFilter) ([]*models.PublicGroup, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
 
 	filter := bson.M{
+		"visibility": bson.M{"$eq": models.GROUP_VISIBILITY_PUBLIC},
 		"name": bson.M{
-			"$regex": primitive.Regex{Pattern: query, Options: "i"},
+			"$regex": primitive.Regex{Pattern: f.Query, Options: "i"},
 		},
+		"delete_at": bson.M{"$exists": false},
+	}
+
+	if f.Category != "" {
+		filter["category"] = bson.M{"$eq": f.Category}
+	}
+
+	if len(f.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": f.Tags}
 	}
 
 	opts := options.Find()
+	opts.SetProjection(bson.M{
+		"_id":           0,
+		"group_id":      1,
+		"name":          1,
+		"description":   1,
+		"profile_image": 1,
+		"category":      1,
+		"tags":          1,
+		"member_count":  bson.M{"$size": bson.M{"$ifNull": bson.A{"$participants", bson.A{}}}},
+	})
 	opts.SetSkip(int64((page - 1) * 8))
 	opts.SetLimit(8)
 
-	var res []*models.Group
+	var res []*models.PublicGroup
 
 	cursor, err := db.FormatGroupCollection().Find(ctx, filter, opts)
 	if err != nil {
@@ -144,7 +481,7 @@ func (db *DB) SearchGroups(page int, query string) ([]*models.Group, error) {
 
 	for cursor.Next(ctx) {
 
-		var group models.Group
+		var group models.PublicGroup
 
 		err := cursor.Decode(&group)
 		if err != nil {
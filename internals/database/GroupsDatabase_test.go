@@ -2,6 +2,7 @@ package database
 
 import (
 	"testing"
+	"time"
 	"wechat-back/internals/models"
 
 	"github.com/stretchr/testify/assert"
@@ -183,7 +184,257 @@ func TestUpdateGroupDB(t *testing.T) {
 
 }
 
+// TestUpdateGroupAvatarDB test database method UpdateGroupAvatarDB
+func TestUpdateGroupAvatarDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateGroupAvatarDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		avatar := models.FormatGroupAvatar(ObjectIDMock)
+		avatar.URL = "https://cdn/profiles/123_1.jpg"
+
+		assert.NoError(t, db.UpdateGroupAvatarDB(ObjectIDMock, avatar))
+	})
+
+	mt.Run("UpdateGroupAvatarDB - Error no group", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.UpdateGroupAvatarDB(ObjectIDMock, models.FormatGroupAvatar(ObjectIDMock)), mongo.ErrNoDocuments.Error())
+	})
+}
+
 // TestDeleteGroupDB test database method DeleteGroupDB
+// TestUpdateGroupMuteDB test database method UpdateGroupMuteDB
+func TestUpdateGroupMuteDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateGroupMuteDB - Success mute", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.UpdateGroupMuteDB(ObjectIDMock, primitive.NewObjectID(), time.Now().Add(time.Hour)))
+	})
+
+	mt.Run("UpdateGroupMuteDB - Success unmute", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.UpdateGroupMuteDB(ObjectIDMock, primitive.NewObjectID(), time.Time{}))
+	})
+
+	mt.Run("UpdateGroupMuteDB - Error no group", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.UpdateGroupMuteDB(ObjectIDMock, primitive.NewObjectID(), time.Time{}), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestClaimGroupPostDB test database method ClaimGroupPostDB
+func TestClaimGroupPostDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	user := primitive.NewObjectID()
+
+	mt.Run("ClaimGroupPostDB - Success post recorded", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		last, err := db.ClaimGroupPostDB(ObjectIDMock, user, time.Now(), time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, last.IsZero())
+	})
+
+	mt.Run("ClaimGroupPostDB - Success user has to wait", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		posted := time.Now().Add(-10 * time.Second).Truncate(time.Millisecond)
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateCursorResponse(0, "test_db.groups", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: ObjectIDMock},
+				{Key: "last_posts", Value: bson.D{{Key: user.Hex(), Value: posted}}},
+			}),
+		)
+
+		last, err := db.ClaimGroupPostDB(ObjectIDMock, user, time.Now(), time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, posted.Equal(last))
+	})
+
+	mt.Run("ClaimGroupPostDB - Error no group", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateCursorResponse(0, "test_db.groups", mtest.FirstBatch),
+		)
+
+		_, err := db.ClaimGroupPostDB(ObjectIDMock, user, time.Now(), time.Minute)
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestLeaveGroupDB test database method LeaveGroupDB
+func TestLeaveGroupDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	user := primitive.NewObjectID()
+
+	mt.Run("LeaveGroupDB - Success member leaves", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}), mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.LeaveGroupDB(ObjectIDMock, models.GroupLeave{UserID: user}))
+	})
+
+	mt.Run("LeaveGroupDB - Success last admin promotes a member", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		promoted := primitive.NewObjectID()
+		updated := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+		mt.AddMockResponses(updated, updated, mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.LeaveGroupDB(ObjectIDMock, models.GroupLeave{UserID: user, Promoted: promoted, OwnerID: promoted}))
+	})
+
+	mt.Run("LeaveGroupDB - Error group changed", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}), mtest.CreateSuccessResponse())
+
+		err := db.LeaveGroupDB(ObjectIDMock, models.GroupLeave{UserID: user, Last: true})
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestTransferGroupOwnershipDB test database method TransferGroupOwnershipDB
+func TestTransferGroupOwnershipDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	owner := primitive.NewObjectID()
+	target := primitive.NewObjectID()
+
+	mt.Run("TransferGroupOwnershipDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.TransferGroupOwnershipDB(ObjectIDMock, owner, target))
+
+		// the group is handed only by its owner to one of its participants
+		filter := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q").Document()
+		assert.Equal(t, owner, filter.Lookup("owner_id", "$eq").ObjectID())
+		assert.Equal(t, target, filter.Lookup("participants", "$eq").ObjectID())
+	})
+
+	mt.Run("TransferGroupOwnershipDB - Error group changed", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.TransferGroupOwnershipDB(ObjectIDMock, owner, target), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestUpdateNotificationMuteDB test database method UpdateNotificationMuteDB
+func TestUpdateNotificationMuteDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateNotificationMuteDB - Success mute", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.UpdateNotificationMuteDB(ObjectIDMock, primitive.NewObjectID(), time.Now().Add(time.Hour)))
+	})
+
+	mt.Run("UpdateNotificationMuteDB - Error no group", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.UpdateNotificationMuteDB(ObjectIDMock, primitive.NewObjectID(), time.Time{}), mongo.ErrNoDocuments.Error())
+	})
+}
+
 func TestDeleteGroupDB(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
@@ -260,8 +511,8 @@ func TestSearchGroups(t *testing.T) {
 		query := "group"
 
 		groups := []bson.D{
-			{{Key: "name", Value: "Group One"}, {Key: "description", Value: "First Group"}},
-			{{Key: "name", Value: "Group Two"}, {Key: "description", Value: "Second Group"}},
+			{{Key: "name", Value: "Group One"}, {Key: "description", Value: "First Group"}, {Key: "member_count", Value: 3}},
+			{{Key: "name", Value: "Group Two"}, {Key: "description", Value: "Second Group"}, {Key: "member_count", Value: 1}},
 		}
 
 		// Mock a cursor response with two batches of documents.
@@ -269,12 +520,30 @@ func TestSearchGroups(t *testing.T) {
 			mtest.CreateCursorResponse(0, "test_db.GROUPS", mtest.FirstBatch, groups...),
 		)
 
-		res, err := db.SearchGroups(page, query)
+		res, err := db.SearchGroups(page, models.GroupDirectoryFilter{Query: query, Category: "music", Tags: []string{"rock"}})
 
 		assert.NoError(t, err)
 		assert.Len(t, res, 2)
 		assert.Equal(t, "Group One", res[0].Name)
 		assert.Equal(t, "Group Two", res[1].Name)
+		assert.Equal(t, 3, res[0].MemberCount)
+	})
+
+	mt.Run("SearchGroups - Groups scheduled for deletion left out", func(mt *mtest.T) {
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.GROUPS", mtest.FirstBatch, bson.D{{Key: "name", Value: "Group One"}}))
+
+		_, err := db.SearchGroups(1, models.GroupDirectoryFilter{Query: "group"})
+		assert.NoError(t, err)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		exists, ok := filter.Lookup("delete_at", "$exists").BooleanOK()
+		assert.True(t, ok)
+		assert.False(t, exists)
 	})
 
 	mt.Run("SearchGroups - No groups", func(mt *mtest.T) {
@@ -288,7 +557,7 @@ func TestSearchGroups(t *testing.T) {
 
 		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test_db.GROPS", mtest.FirstBatch, nil))
 
-		res, err := db.SearchGroups(pg, "")
+		res, err := db.SearchGroups(pg, models.GroupDirectoryFilter{})
 
 		assert.Error(t, err)
 		assert.Empty(t, res)
@@ -308,7 +577,7 @@ func TestSearchGroups(t *testing.T) {
 			Message: "Mongo db has encounter and error",
 		}))
 
-		res, err := db.SearchGroups(pg, "")
+		res, err := db.SearchGroups(pg, models.GroupDirectoryFilter{})
 
 		assert.Error(t, err)
 		assert.Nil(t, res)
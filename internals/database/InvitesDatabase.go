@@ -0,0 +1,342 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+InsertGroupInviteDB
+Inserts a new invite link of a group
+*/
+func (db *DB) InsertGroupInviteDB(i models.GroupInvite) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	info, err := db.FormatGroupInvites().InsertOne(ctx, i, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return info.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+/*
+GetGroupInviteDB
+Gets the invite link by its code
+*/
+func (db *DB) GetGroupInviteDB(code string) (*models.GroupInvite, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"code": bson.M{"$eq": code},
+	}
+
+	var res models.GroupInvite
+
+	err := db.FormatGroupInvites().FindOne(ctx, filter, nil).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+GetGroupInvitesDB
+Gets the invite links of the group that have not been revoked, newest first
+*/
+func (db *DB) GetGroupInvitesDB(groupID string) ([]*models.GroupInvite, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"group_id": bson.M{"$eq": groupID},
+		"revoked":  bson.M{"$eq": false},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var res []*models.GroupInvite
+
+	cursor, err := db.FormatGroupInvites().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var invite models.GroupInvite
+
+		err := cursor.Decode(&invite)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, &invite)
+	}
+
+	return res, cursor.Err()
+}
+
+/*
+RevokeGroupInviteDB
+Revokes the invite link, it can not be used anymore
+*/
+func (db *DB) RevokeGroupInviteDB(code string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"code": bson.M{"$eq": code},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"revoked": true},
+	}
+
+	res, err := db.FormatGroupInvites().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+UseGroupInviteDB
+Counts a use of the invite link, the update only matches while the link is not
+revoked and has uses left so concurrent joins can not go over the limit
+*/
+func (db *DB) UseGroupInviteDB(code string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"code":    bson.M{"$eq": code},
+		"revoked": bson.M{"$eq": false},
+		"$or": bson.A{
+			bson.M{"max_uses": bson.M{"$eq": 0}},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+		},
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"uses": 1},
+	}
+
+	res, err := db.FormatGroupInvites().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+AddGroupParticipantDB
+Adds the user to the participants of the group
+*/
+func (db *DB) AddGroupParticipantDB(id primitive.ObjectID, user primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": id},
+	}
+
+	update := bson.M{
+		"$addToSet": bson.M{"participants": user},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+InsertJoinRequestDB
+Queues the join request of the user, if the user already has a pending request
+on the group the existing one is kept and an empty id is returned
+*/
+func (db *DB) InsertJoinRequestDB(j models.JoinRequest) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"group_id": bson.M{"$eq": j.GroupID},
+		"user_id":  bson.M{"$eq": j.UserID},
+		"state":    bson.M{"$eq": models.JOIN_REQUEST_PENDING},
+	}
+
+	update := bson.M{
+		"$setOnInsert": j,
+	}
+
+	res, err := db.FormatJoinRequests().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return "", err
+	}
+
+	if res.UpsertedID == nil {
+		return "", nil
+	}
+
+	return res.UpsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+/*
+GetPendingJoinRequestDB
+Gets the pending join request of the user on the group
+*/
+func (db *DB) GetPendingJoinRequestDB(groupID string, user primitive.ObjectID) (*models.JoinRequest, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"group_id": bson.M{"$eq": groupID},
+		"user_id":  bson.M{"$eq": user},
+		"state":    bson.M{"$eq": models.JOIN_REQUEST_PENDING},
+	}
+
+	var res models.JoinRequest
+
+	err := db.FormatJoinRequests().FindOne(ctx, filter, nil).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+GetJoinRequestDB
+Gets the join request by the ID
+*/
+func (db *DB) GetJoinRequestDB(id primitive.ObjectID) (*models.JoinRequest, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": id},
+	}
+
+	var res models.JoinRequest
+
+	err := db.FormatJoinRequests().FindOne(ctx, filter, nil).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+GetJoinRequestsDB
+Gets the pending join requests of the group, oldest first
+*/
+func (db *DB) GetJoinRequestsDB(page int, groupID string) ([]*models.JoinRequest, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"group_id": bson.M{"$eq": groupID},
+		"state":    bson.M{"$eq": models.JOIN_REQUEST_PENDING},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: 1}})
+	opts.SetSkip(int64((page - 1) * 20))
+	opts.SetLimit(20)
+
+	var res []*models.JoinRequest
+
+	cursor, err := db.FormatJoinRequests().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var request models.JoinRequest
+
+		err := cursor.Decode(&request)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, &request)
+	}
+
+	return res, cursor.Err()
+}
+
+/*
+ReviewJoinRequestDB
+Sets the final state of a pending join request, fails with mongo.ErrNoDocuments
+when the request was already reviewed
+*/
+func (db *DB) ReviewJoinRequestDB(id primitive.ObjectID, state string, reviewer primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":   bson.M{"$eq": id},
+		"state": bson.M{"$eq": models.JOIN_REQUEST_PENDING},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"state":       state,
+			"reviewed_by": reviewer,
+			"reviewed_at": time.Now(),
+		},
+	}
+
+	res, err := db.FormatJoinRequests().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
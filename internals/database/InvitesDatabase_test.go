@@ -0,0 +1,369 @@
+package database
+
+import (
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestInsertGroupInviteDB test database method InsertGroupInviteDB
+func TestInsertGroupInviteDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("InsertGroupInviteDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		invite, err := models.FormatGroupInvite("123456789", ObjectIDMock, models.InviteOptions{MaxUses: 5})
+		assert.NoError(t, err)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		id, err := db.InsertGroupInviteDB(*invite)
+
+		assert.NoError(t, err)
+		assert.Equal(t, invite.ID.Hex(), id)
+	})
+
+	mt.Run("InsertGroupInviteDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		invite, err := models.FormatGroupInvite("123456789", ObjectIDMock, models.InviteOptions{})
+		assert.NoError(t, err)
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    12345,
+			Message: "Error inserting invite",
+		}))
+		id, err := db.InsertGroupInviteDB(*invite)
+
+		assert.Error(t, err)
+		assert.Empty(t, id)
+	})
+}
+
+// TestGetGroupInviteDB test database method GetGroupInviteDB
+func TestGetGroupInviteDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetGroupInviteDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test_db.INVITES", mtest.FirstBatch, bson.D{
+			{Key: "code", Value: "abc123"},
+			{Key: "group_id", Value: "123456789"},
+			{Key: "max_uses", Value: 3},
+			{Key: "uses", Value: 1},
+		}))
+
+		res, err := db.GetGroupInviteDB("abc123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", res.Code)
+		assert.Equal(t, "123456789", res.GroupID)
+		assert.Equal(t, 3, res.MaxUses)
+		assert.Equal(t, 1, res.Uses)
+	})
+
+	mt.Run("GetGroupInviteDB - Error no documents", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.INVITES", mtest.FirstBatch))
+
+		res, err := db.GetGroupInviteDB("abc123")
+
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+		assert.Nil(t, res)
+	})
+}
+
+// TestGetGroupInvitesDB test database method GetGroupInvitesDB
+func TestGetGroupInvitesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetGroupInvitesDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.INVITES", mtest.FirstBatch,
+				bson.D{{Key: "code", Value: "abc123"}},
+				bson.D{{Key: "code", Value: "def456"}},
+			),
+		)
+
+		res, err := db.GetGroupInvitesDB("123456789")
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "def456", res[1].Code)
+	})
+
+	mt.Run("GetGroupInvitesDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    12345,
+			Message: "Error finding invites",
+		}))
+
+		res, err := db.GetGroupInvitesDB("123456789")
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+// TestUseGroupInviteDB test database methods UseGroupInviteDB and RevokeGroupInviteDB
+func TestUseGroupInviteDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UseGroupInviteDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.UseGroupInviteDB("abc123"))
+	})
+
+	mt.Run("UseGroupInviteDB - Error exhausted", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.UseGroupInviteDB("abc123"), mongo.ErrNoDocuments.Error())
+	})
+
+	mt.Run("RevokeGroupInviteDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.RevokeGroupInviteDB("abc123"))
+	})
+
+	mt.Run("RevokeGroupInviteDB - Error no documents", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.RevokeGroupInviteDB("abc123"), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestAddGroupParticipantDB test database method AddGroupParticipantDB
+func TestAddGroupParticipantDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("AddGroupParticipantDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.AddGroupParticipantDB(ObjectIDMock, primitive.NewObjectID()))
+	})
+
+	mt.Run("AddGroupParticipantDB - Error no group", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.AddGroupParticipantDB(ObjectIDMock, primitive.NewObjectID()), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestInsertJoinRequestDB test database method InsertJoinRequestDB
+func TestInsertJoinRequestDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("InsertJoinRequestDB - New request", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		request := models.FormatJoinRequest("123456789", models.User{ID: primitive.NewObjectID()}, "abc123")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 0},
+			bson.E{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: request.ID}}}},
+		))
+
+		id, err := db.InsertJoinRequestDB(request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, request.ID.Hex(), id)
+	})
+
+	mt.Run("InsertJoinRequestDB - Already pending", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		request := models.FormatJoinRequest("123456789", models.User{ID: primitive.NewObjectID()}, "abc123")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 0}))
+
+		id, err := db.InsertJoinRequestDB(request)
+
+		assert.NoError(t, err)
+		assert.Empty(t, id)
+	})
+}
+
+// TestGetPendingJoinRequestDB test database method GetPendingJoinRequestDB
+func TestGetPendingJoinRequestDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetPendingJoinRequestDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.JOIN_REQUESTS", mtest.FirstBatch,
+				bson.D{{Key: "name", Value: "ana"}, {Key: "state", Value: models.JOIN_REQUEST_PENDING}},
+			),
+		)
+
+		request, err := db.GetPendingJoinRequestDB("123456789", primitive.NewObjectID())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ana", request.Name)
+	})
+
+	mt.Run("GetPendingJoinRequestDB - Error no request", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.JOIN_REQUESTS", mtest.FirstBatch))
+
+		_, err := db.GetPendingJoinRequestDB("123456789", primitive.NewObjectID())
+
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestGetJoinRequestsDB test database method GetJoinRequestsDB
+func TestGetJoinRequestsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetJoinRequestsDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.JOIN_REQUESTS", mtest.FirstBatch,
+				bson.D{{Key: "name", Value: "ana"}, {Key: "state", Value: models.JOIN_REQUEST_PENDING}},
+			),
+		)
+
+		res, err := db.GetJoinRequestsDB(1, "123456789")
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, "ana", res[0].Name)
+	})
+}
+
+// TestReviewJoinRequestDB test database method ReviewJoinRequestDB
+func TestReviewJoinRequestDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ReviewJoinRequestDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.ReviewJoinRequestDB(ObjectIDMock, models.JOIN_REQUEST_APPROVED, primitive.NewObjectID()))
+	})
+
+	mt.Run("ReviewJoinRequestDB - Error already reviewed", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		err := db.ReviewJoinRequestDB(ObjectIDMock, models.JOIN_REQUEST_REJECTED, primitive.NewObjectID())
+
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
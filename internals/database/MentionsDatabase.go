@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+InsertMentionsDB
+Inserts the mentions of a message, a message that mentions nobody inserts nothing
+*/
+func (db *DB) InsertMentionsDB(mentions []models.Mention) error {
+
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	docs := make([]any, 0, len(mentions))
+	for _, m := range mentions {
+		docs = append(docs, m)
+	}
+
+	// the ids are set by the server so a retried insertion only adds the missing mentions
+	_, err := db.FormatGroupMentions().InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
+
+/*
+GetUnreadMentionsDB
+Gets the unread mentions of the user, newest first, an empty group id gets the
+mentions of every group of the user
+*/
+func (db *DB) GetUnreadMentionsDB(pg int, user primitive.ObjectID, groupID string) ([]*models.Mention, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id": bson.M{"$eq": user},
+		"read":    bson.M{"$eq": false},
+	}
+	if groupID != "" {
+		filter["group_id"] = bson.M{"$eq": groupID}
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	opts.SetSkip(int64((pg - 1) * 20))
+	opts.SetLimit(20)
+
+	res := []*models.Mention{}
+
+	cursor, err := db.FormatGroupMentions().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var mention models.Mention
+
+		err := cursor.Decode(&mention)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &mention)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+/*
+MarkMentionsReadDB
+Marks as read the mentions of the user on the group sent up to the given time
+*/
+func (db *DB) MarkMentionsReadDB(user primitive.ObjectID, groupID string, at time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id":    bson.M{"$eq": user},
+		"group_id":   bson.M{"$eq": groupID},
+		"read":       bson.M{"$eq": false},
+		"created_at": bson.M{"$lte": at},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"read": true},
+	}
+
+	_, err := db.FormatGroupMentions().UpdateMany(ctx, filter, update)
+	return err
+}
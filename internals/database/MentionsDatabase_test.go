@@ -0,0 +1,176 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestInsertMentionsDB test database method InsertMentionsDB
+func TestInsertMentionsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mentions := []models.Mention{
+		{ID: primitive.NewObjectID(), GroupID: "123456789", UserID: primitive.NewObjectID()},
+		{ID: primitive.NewObjectID(), GroupID: "123456789", UserID: primitive.NewObjectID()},
+	}
+
+	mt.Run("InsertMentionsDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.InsertMentionsDB(mentions))
+	})
+
+	mt.Run("InsertMentionsDB - Success nobody mentioned", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		assert.NoError(t, db.InsertMentionsDB(nil))
+	})
+
+	mt.Run("InsertMentionsDB - Success retried insertion", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key error"}))
+
+		assert.NoError(t, db.InsertMentionsDB(mentions))
+	})
+
+	mt.Run("InsertMentionsDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "insert failed"}))
+
+		assert.Error(t, db.InsertMentionsDB(mentions))
+	})
+}
+
+// TestGetUnreadMentionsDB test database method GetUnreadMentionsDB
+func TestGetUnreadMentionsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetUnreadMentionsDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mentions := []bson.D{}
+		for _, body := range []string{"@you second", "@you first"} {
+			mentions = append(mentions, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "group_id", Value: "123456789"},
+				{Key: "message_id", Value: primitive.NewObjectID()},
+				{Key: "user_id", Value: ObjectIDMock},
+				{Key: "body", Value: body},
+				{Key: "read", Value: false},
+			})
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "test_db.MENTIONS", mtest.FirstBatch, mentions...),
+			mtest.CreateCursorResponse(0, "test_db.MENTIONS", mtest.NextBatch),
+		)
+
+		res, err := db.GetUnreadMentionsDB(1, ObjectIDMock, "")
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "@you second", res[0].Body)
+		assert.Equal(t, "123456789", res[1].GroupID)
+	})
+
+	mt.Run("GetUnreadMentionsDB - Success no mentions", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.MENTIONS", mtest.FirstBatch))
+
+		res, err := db.GetUnreadMentionsDB(1, ObjectIDMock, "123456789")
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+
+	mt.Run("GetUnreadMentionsDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "find failed"}))
+
+		_, err := db.GetUnreadMentionsDB(1, ObjectIDMock, "")
+		assert.Error(t, err)
+	})
+}
+
+// TestMarkMentionsReadDB test database method MarkMentionsReadDB
+func TestMarkMentionsReadDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("MarkMentionsReadDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}, bson.E{Key: "nModified", Value: 3}))
+
+		assert.NoError(t, db.MarkMentionsReadDB(ObjectIDMock, "123456789", time.Now()))
+	})
+
+	mt.Run("MarkMentionsReadDB - Success nothing unread", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.NoError(t, db.MarkMentionsReadDB(ObjectIDMock, "123456789", time.Now()))
+	})
+
+	mt.Run("MarkMentionsReadDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "update failed"}))
+
+		assert.EqualError(t, db.MarkMentionsReadDB(ObjectIDMock, "123456789", time.Now()), mongo.CommandError{Code: 1, Message: "update failed"}.Error())
+	})
+}
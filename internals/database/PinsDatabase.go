@@ -0,0 +1,343 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+PinMessageDB
+Pins the message on its conversation in a single transaction, returns
+ErrNoModified when the message is already pinned and models.ErrPinLimit when
+the conversation already holds the most pins it can. The pins are counted on a
+document per conversation so concurrent pins can not go over the limit
+*/
+func (db *DB) PinMessageDB(pin models.MessagePin) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		filter := bson.M{
+			"conversation_id": bson.M{"$eq": pin.ConversationID},
+			"message_id":      bson.M{"$eq": pin.MessageID},
+		}
+
+		info, err := db.FormatMessagePins().UpdateOne(sctx, filter, bson.M{"$setOnInsert": pin}, options.Update().SetUpsert(true))
+		if err != nil {
+			return nil, err
+		}
+
+		if info.UpsertedCount < 1 {
+			return nil, ErrNoModified
+		}
+
+		// a full counter does not match, its upsert collides with it and rolls the pin back
+		_, err = db.FormatPinCounters().UpdateOne(sctx, bson.M{
+			"_id":   bson.M{"$eq": pin.ConversationID},
+			"count": bson.M{"$lt": models.MAX_PINNED_MESSAGES},
+		}, bson.M{"$inc": bson.M{"count": 1}}, options.Update().SetUpsert(true))
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, models.ErrPinLimit
+		}
+
+		return nil, err
+	})
+
+	return err
+}
+
+/*
+UnpinMessageDB
+Unpins the message from its conversation in a single transaction, returns
+ErrNoDeleted when the message was not pinned
+*/
+func (db *DB) UnpinMessageDB(conversationID string, message primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		filter := bson.M{
+			"conversation_id": bson.M{"$eq": conversationID},
+			"message_id":      bson.M{"$eq": message},
+		}
+
+		res, err := db.FormatMessagePins().DeleteOne(sctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.DeletedCount < 1 {
+			return nil, ErrNoDeleted
+		}
+
+		return nil, db.discountPin(sctx, conversationID)
+	})
+
+	return err
+}
+
+// discountPin takes a removed pin off the counter of its conversation
+func (db *DB) discountPin(ctx context.Context, conversationID string) error {
+
+	_, err := db.FormatPinCounters().UpdateOne(ctx, bson.M{
+		"_id":   bson.M{"$eq": conversationID},
+		"count": bson.M{"$gt": 0},
+	}, bson.M{"$inc": bson.M{"count": -1}})
+
+	return err
+}
+
+/*
+GetPinnedMessagesDB
+Gets the pins of the conversation with their messages, newest pin first. The
+messages are read from the group chat logs when group is true and from the
+private ones otherwise
+*/
+func (db *DB) GetPinnedMessagesDB(conversationID string, group bool) ([]*models.MessagePin, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"conversation_id": bson.M{"$eq": conversationID},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "pinned_at", Value: -1}})
+	opts.SetLimit(models.MAX_PINNED_MESSAGES)
+
+	res := []*models.MessagePin{}
+
+	cursor, err := db.FormatMessagePins().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var pin models.MessagePin
+
+		err := cursor.Decode(&pin)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &pin)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []primitive.ObjectID{}
+	for _, pin := range res {
+		ids = append(ids, pin.MessageID)
+	}
+
+	messages, err := db.messagesByID(ctx, ids, group)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pin := range res {
+		if msg, ok := messages[pin.MessageID]; ok {
+			pin.Message = msg
+		}
+	}
+
+	return res, nil
+}
+
+/*
+StarMessageDB
+Stars the message for the user, returns ErrNoModified when the user already starred it
+*/
+func (db *DB) StarMessageDB(star models.MessageStar) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id":    bson.M{"$eq": star.UserID},
+		"message_id": bson.M{"$eq": star.MessageID},
+	}
+
+	res, err := db.FormatMessageStars().UpdateOne(ctx, filter, bson.M{"$setOnInsert": star}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	if res.UpsertedCount < 1 {
+		return ErrNoModified
+	}
+
+	return nil
+}
+
+/*
+UnstarMessageDB
+Removes the star of the user from the message, returns ErrNoDeleted when the user had not starred it
+*/
+func (db *DB) UnstarMessageDB(user, message primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id":    bson.M{"$eq": user},
+		"message_id": bson.M{"$eq": message},
+	}
+
+	res, err := db.FormatMessageStars().DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount < 1 {
+		return ErrNoDeleted
+	}
+
+	return nil
+}
+
+/*
+GetStarredMessagesDB
+Gets the stars of the user with their messages, newest star first
+*/
+func (db *DB) GetStarredMessagesDB(pg int, user primitive.ObjectID) ([]*models.MessageStar, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id": bson.M{"$eq": user},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "starred_at", Value: -1}})
+	opts.SetSkip(int64((pg - 1) * 20))
+	opts.SetLimit(20)
+
+	res := []*models.MessageStar{}
+
+	cursor, err := db.FormatMessageStars().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var star models.MessageStar
+
+		err := cursor.Decode(&star)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &star)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	var groupIDs, p2pIDs []primitive.ObjectID
+	for _, star := range res {
+		if star.GroupID != "" {
+			groupIDs = append(groupIDs, star.MessageID)
+		} else {
+			p2pIDs = append(p2pIDs, star.MessageID)
+		}
+	}
+
+	groupMessages, err := db.messagesByID(ctx, groupIDs, true)
+	if err != nil {
+		return nil, err
+	}
+
+	p2pMessages, err := db.messagesByID(ctx, p2pIDs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, star := range res {
+		messages := p2pMessages
+		if star.GroupID != "" {
+			messages = groupMessages
+		}
+		if msg, ok := messages[star.MessageID]; ok {
+			star.Message = msg
+		}
+	}
+
+	return res, nil
+}
+
+// messagesByID reads the messages of the group chat logs, or of the private ones, by id
+func (db *DB) messagesByID(ctx context.Context, ids []primitive.ObjectID, group bool) (map[primitive.ObjectID]any, error) {
+
+	res := make(map[primitive.ObjectID]any)
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	chatlogs := db.FormatUserChatlogs()
+	if group {
+		chatlogs = db.FormatGroupChatlogs()
+	}
+
+	cursor, err := chatlogs.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		if group {
+			var msg models.GroupChatContentLog
+			err = cursor.Decode(&msg)
+			res[msg.ID] = &msg
+		} else {
+			var msg models.P2PContentChatLog
+			err = cursor.Decode(&msg)
+			res[msg.ID] = &msg
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestPinMessageDB test database method PinMessageDB
+func TestPinMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	pin := models.FormatMessagePin("123456789", ObjectIDMock, primitive.NewObjectID())
+
+	upserted := func() bson.D {
+		return mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: pin.ID}}}},
+		)
+	}
+
+	counted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	mt.Run("PinMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(upserted(), counted, mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.PinMessageDB(pin))
+	})
+
+	mt.Run("PinMessageDB - Error already pinned", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 0}), mtest.CreateSuccessResponse())
+
+		assert.EqualError(t, db.PinMessageDB(pin), ErrNoModified.Error())
+	})
+
+	mt.Run("PinMessageDB - Error pin limit", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		// the full counter is not matched and its upsert collides with it
+		full := mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"})
+		mt.AddMockResponses(upserted(), full, mtest.CreateSuccessResponse())
+
+		assert.EqualError(t, db.PinMessageDB(pin), models.ErrPinLimit.Error())
+	})
+}
+
+// TestUnpinMessageDB test database method UnpinMessageDB
+func TestUnpinMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UnpinMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		assert.NoError(t, db.UnpinMessageDB("123456789", ObjectIDMock))
+	})
+
+	mt.Run("UnpinMessageDB - Error not pinned", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}), mtest.CreateSuccessResponse())
+
+		assert.EqualError(t, db.UnpinMessageDB("123456789", ObjectIDMock), ErrNoDeleted.Error())
+	})
+}
+
+// TestGetPinnedMessagesDB test database method GetPinnedMessagesDB
+func TestGetPinnedMessagesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetPinnedMessagesDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		pinned := primitive.NewObjectID()
+		deleted := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.PINS", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "conversation_id", Value: "123456789"}, {Key: "message_id", Value: pinned}},
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "conversation_id", Value: "123456789"}, {Key: "message_id", Value: deleted}},
+			),
+			mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: pinned}, {Key: "body", Value: "hello"}},
+			),
+		)
+
+		pins, err := db.GetPinnedMessagesDB("123456789", true)
+		assert.NoError(t, err)
+		assert.Len(t, pins, 2)
+		assert.Equal(t, "hello", pins[0].Message.(*models.GroupChatContentLog).Body)
+		assert.Nil(t, pins[1].Message)
+	})
+
+	mt.Run("GetPinnedMessagesDB - Success no pins", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.PINS", mtest.FirstBatch))
+
+		pins, err := db.GetPinnedMessagesDB("123456789", false)
+		assert.NoError(t, err)
+		assert.Empty(t, pins)
+	})
+}
+
+// TestStarMessageDB test database method StarMessageDB
+func TestStarMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	star := models.FormatMessageStar(ObjectIDMock, "", primitive.NewObjectID())
+
+	mt.Run("StarMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: star.ID}}}},
+		))
+
+		assert.NoError(t, db.StarMessageDB(star))
+	})
+
+	mt.Run("StarMessageDB - Error already starred", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.StarMessageDB(star), ErrNoModified.Error())
+	})
+}
+
+// TestUnstarMessageDB test database method UnstarMessageDB
+func TestUnstarMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UnstarMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		assert.NoError(t, db.UnstarMessageDB(primitive.NewObjectID(), ObjectIDMock))
+	})
+
+	mt.Run("UnstarMessageDB - Error not starred", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		assert.EqualError(t, db.UnstarMessageDB(primitive.NewObjectID(), ObjectIDMock), ErrNoDeleted.Error())
+	})
+}
+
+// TestGetStarredMessagesDB test database method GetStarredMessagesDB
+func TestGetStarredMessagesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetStarredMessagesDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		user := primitive.NewObjectID()
+		groupMessage := primitive.NewObjectID()
+		p2pMessage := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.STARS", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "user_id", Value: user}, {Key: "message_id", Value: groupMessage}, {Key: "group_id", Value: "123456789"}},
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "user_id", Value: user}, {Key: "message_id", Value: p2pMessage}},
+			),
+			mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: groupMessage}, {Key: "body", Value: "group"}},
+			),
+			mtest.CreateCursorResponse(0, "test_db.USCHLOGS", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: p2pMessage}, {Key: "body", Value: "private"}},
+			),
+		)
+
+		stars, err := db.GetStarredMessagesDB(1, user)
+		assert.NoError(t, err)
+		assert.Len(t, stars, 2)
+		assert.Equal(t, "group", stars[0].Message.(*models.GroupChatContentLog).Body)
+		assert.Equal(t, "private", stars[1].Message.(*models.P2PContentChatLog).Body)
+	})
+}
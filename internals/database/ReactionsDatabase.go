@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+GetGroupMessageDB
+Gets the message of a group chat log
+*/
+func (db *DB) GetGroupMessageDB(id primitive.ObjectID) (*models.GroupChatContentLog, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var res models.GroupChatContentLog
+
+	err := db.FormatGroupChatlogs().FindOne(ctx, bson.M{"_id": bson.M{"$eq": id}}).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+GetP2PMessageDB
+Gets the message of a private conversation
+*/
+func (db *DB) GetP2PMessageDB(id primitive.ObjectID) (*models.P2PContentChatLog, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var res models.P2PContentChatLog
+
+	err := db.FormatUserChatlogs().FindOne(ctx, bson.M{"_id": bson.M{"$eq": id}}).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// reactionChatlogs chat log collection holding the message of the reaction
+func (db *DB) reactionChatlogs(r models.MessageReaction) *mongo.Collection {
+	if r.GroupID != "" {
+		return db.FormatGroupChatlogs()
+	}
+	return db.FormatUserChatlogs()
+}
+
+/*
+AddMessageReactionDB
+Saves the reaction and counts it on the message in a single transaction, returns
+the new counts of the message and ErrNoModified when the user already reacted
+with the emoji
+*/
+func (db *DB) AddMessageReactionDB(r models.MessageReaction) (map[string]int, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	res, err := session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		filter := bson.M{
+			"message_id": bson.M{"$eq": r.MessageID},
+			"user_id":    bson.M{"$eq": r.UserID},
+			"emoji":      bson.M{"$eq": r.Emoji},
+		}
+
+		info, err := db.FormatMessageReactions().UpdateOne(sctx, filter, bson.M{"$setOnInsert": r}, options.Update().SetUpsert(true))
+		if err != nil {
+			return nil, err
+		}
+
+		if info.UpsertedCount < 1 {
+			return nil, ErrNoModified
+		}
+
+		return db.countReaction(sctx, r, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(map[string]int), nil
+}
+
+/*
+RemoveMessageReactionDB
+Deletes the reaction and discounts it from the message in a single transaction,
+returns the new counts of the message and ErrNoDeleted when the user had not
+reacted with the emoji
+*/
+func (db *DB) RemoveMessageReactionDB(r models.MessageReaction) (map[string]int, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	res, err := session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		filter := bson.M{
+			"message_id": bson.M{"$eq": r.MessageID},
+			"user_id":    bson.M{"$eq": r.UserID},
+			"emoji":      bson.M{"$eq": r.Emoji},
+		}
+
+		info, err := db.FormatMessageReactions().DeleteOne(sctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.DeletedCount < 1 {
+			return nil, ErrNoDeleted
+		}
+
+		counts, err := db.countReaction(sctx, r, -1)
+		if err != nil {
+			return nil, err
+		}
+
+		if counts[r.Emoji] > 0 {
+			return counts, nil
+		}
+
+		// the emojis nobody reacts with are not kept on the message
+		filter = bson.M{
+			"_id":                  bson.M{"$eq": r.MessageID},
+			"reactions." + r.Emoji: bson.M{"$lte": 0},
+		}
+
+		_, err = db.reactionChatlogs(r).UpdateOne(sctx, filter, bson.M{"$unset": bson.M{"reactions." + r.Emoji: ""}})
+		if err != nil {
+			return nil, err
+		}
+
+		delete(counts, r.Emoji)
+
+		return counts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(map[string]int), nil
+}
+
+// countReaction adds the delta to the count of the emoji on the message and returns the new counts
+func (db *DB) countReaction(ctx context.Context, r models.MessageReaction, delta int) (map[string]int, error) {
+
+	update := bson.M{
+		"$inc": bson.M{"reactions." + r.Emoji: delta},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"reactions": 1})
+
+	var message struct {
+		Reactions map[string]int `bson:"reactions"`
+	}
+
+	err := db.reactionChatlogs(r).FindOneAndUpdate(ctx, bson.M{"_id": bson.M{"$eq": r.MessageID}}, update, opts).Decode(&message)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Reactions == nil {
+		message.Reactions = map[string]int{}
+	}
+
+	return message.Reactions, nil
+}
+
+/*
+GetMessageReactionsDB
+Gets the users that reacted to the message and their emojis, oldest first
+*/
+func (db *DB) GetMessageReactionsDB(pg int, message primitive.ObjectID) ([]*models.MessageReaction, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"message_id": bson.M{"$eq": message},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: 1}})
+	opts.SetSkip(int64((pg - 1) * 20))
+	opts.SetLimit(20)
+
+	res := []*models.MessageReaction{}
+
+	cursor, err := db.FormatMessageReactions().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var reaction models.MessageReaction
+
+		err := cursor.Decode(&reaction)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &reaction)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
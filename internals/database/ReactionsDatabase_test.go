@@ -0,0 +1,252 @@
+package database
+
+import (
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestGetGroupMessageDB test database method GetGroupMessageDB
+func TestGetGroupMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetGroupMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test_db.GRCHLOGS", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: ObjectIDMock},
+			{Key: "target_id", Value: ObjectIDMock},
+			{Key: "body", Value: "hello"},
+			{Key: "reactions", Value: bson.D{{Key: "👍", Value: 2}}},
+		}))
+
+		msg, err := db.GetGroupMessageDB(ObjectIDMock)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", msg.Body)
+		assert.Equal(t, 2, msg.Reactions["👍"])
+	})
+
+	mt.Run("GetGroupMessageDB - Error not found", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.FirstBatch))
+
+		_, err := db.GetGroupMessageDB(ObjectIDMock)
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestGetP2PMessageDB test database method GetP2PMessageDB
+func TestGetP2PMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetP2PMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test_db.USCHLOGS", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: ObjectIDMock},
+			{Key: "author_id", Value: ObjectIDMock},
+			{Key: "body", Value: "hello"},
+		}))
+
+		msg, err := db.GetP2PMessageDB(ObjectIDMock)
+		assert.NoError(t, err)
+		assert.Equal(t, ObjectIDMock, msg.AuthorID)
+	})
+}
+
+// TestAddMessageReactionDB test database method AddMessageReactionDB
+func TestAddMessageReactionDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	reaction := models.FormatMessageReaction(ObjectIDMock, "123456789", models.User{ID: primitive.NewObjectID(), Name: "alice"}, "👍")
+
+	mt.Run("AddMessageReactionDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 1},
+				bson.E{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: reaction.ID}}}},
+			),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: ObjectIDMock},
+				{Key: "reactions", Value: bson.D{{Key: "👍", Value: 3}, {Key: "🎉", Value: 1}}},
+			}}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		counts, err := db.AddMessageReactionDB(reaction)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"👍": 3, "🎉": 1}, counts)
+	})
+
+	mt.Run("AddMessageReactionDB - Error already reacted", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 0}), mtest.CreateSuccessResponse())
+
+		_, err := db.AddMessageReactionDB(reaction)
+		assert.EqualError(t, err, ErrNoModified.Error())
+	})
+
+	mt.Run("AddMessageReactionDB - Error message not found", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 1},
+				bson.E{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: reaction.ID}}}},
+			),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		_, err := db.AddMessageReactionDB(reaction)
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestRemoveMessageReactionDB test database method RemoveMessageReactionDB
+func TestRemoveMessageReactionDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	reaction := models.FormatMessageReaction(ObjectIDMock, "", models.User{ID: primitive.NewObjectID(), Name: "alice"}, "👍")
+
+	mt.Run("RemoveMessageReactionDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: ObjectIDMock},
+				{Key: "reactions", Value: bson.D{{Key: "👍", Value: 1}}},
+			}}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		counts, err := db.RemoveMessageReactionDB(reaction)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"👍": 1}, counts)
+	})
+
+	mt.Run("RemoveMessageReactionDB - Success last reaction of the emoji", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: ObjectIDMock},
+				{Key: "reactions", Value: bson.D{{Key: "👍", Value: 0}, {Key: "🎉", Value: 2}}},
+			}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		counts, err := db.RemoveMessageReactionDB(reaction)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"🎉": 2}, counts)
+	})
+
+	mt.Run("RemoveMessageReactionDB - Error not reacted", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}), mtest.CreateSuccessResponse())
+
+		_, err := db.RemoveMessageReactionDB(reaction)
+		assert.EqualError(t, err, ErrNoDeleted.Error())
+	})
+}
+
+// TestGetMessageReactionsDB test database method GetMessageReactionsDB
+func TestGetMessageReactionsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetMessageReactionsDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		reactions := []bson.D{}
+		for _, emoji := range []string{"👍", "🎉"} {
+			reactions = append(reactions, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "message_id", Value: ObjectIDMock},
+				{Key: "user_id", Value: primitive.NewObjectID()},
+				{Key: "emoji", Value: emoji},
+			})
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "test_db.REACTIONS", mtest.FirstBatch, reactions...),
+			mtest.CreateCursorResponse(0, "test_db.REACTIONS", mtest.NextBatch),
+		)
+
+		res, err := db.GetMessageReactionsDB(1, ObjectIDMock)
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "🎉", res[1].Emoji)
+	})
+
+	mt.Run("GetMessageReactionsDB - Success no reactions", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.REACTIONS", mtest.FirstBatch))
+
+		res, err := db.GetMessageReactionsDB(1, ObjectIDMock)
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+}
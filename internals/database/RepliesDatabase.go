@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+AddThreadReplyDB
+Counts a new reply on the root message of the thread
+*/
+func (db *DB) AddThreadReplyDB(root primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": root},
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"reply_count": 1},
+	}
+
+	res, err := db.FormatGroupChatlogs().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+GetThreadChatLogsDB
+Gets the replies of the thread of the group, newest first
+*/
+func (db *DB) GetThreadChatLogsDB(pg int, id, root primitive.ObjectID) ([]*models.GroupChatContentLog, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"target_id": bson.M{"$eq": id},
+		"thread_id": bson.M{"$eq": root},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	opts.SetSkip(int64((pg - 1) * 60))
+	opts.SetLimit(60)
+
+	res := []*models.GroupChatContentLog{}
+
+	cursor, err := db.FormatGroupChatlogs().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var chat models.GroupChatContentLog
+
+		err := cursor.Decode(&chat)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &chat)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestAddThreadReplyDB test database method AddThreadReplyDB
+func TestAddThreadReplyDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("AddThreadReplyDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.AddThreadReplyDB(ObjectIDMock))
+	})
+
+	mt.Run("AddThreadReplyDB - Error root not found", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.AddThreadReplyDB(ObjectIDMock), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestGetThreadChatLogsDB test database method GetThreadChatLogsDB
+func TestGetThreadChatLogsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetThreadChatLogsDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		root := primitive.NewObjectID()
+		replies := []bson.D{}
+		for _, body := range []string{"second", "first"} {
+			replies = append(replies, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "target_id", Value: ObjectIDMock},
+				{Key: "thread_id", Value: root},
+				{Key: "quote", Value: bson.D{{Key: "message_id", Value: root}, {Key: "body", Value: "root"}}},
+				{Key: "body", Value: body},
+			})
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "test_db.GRCHLOGS", mtest.FirstBatch, replies...),
+			mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.NextBatch),
+		)
+
+		res, err := db.GetThreadChatLogsDB(1, ObjectIDMock, root)
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, root, res[0].ThreadID)
+		assert.Equal(t, "root", res[0].Quote.Body)
+	})
+
+	mt.Run("GetThreadChatLogsDB - Success empty thread", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.FirstBatch))
+
+		res, err := db.GetThreadChatLogsDB(1, ObjectIDMock, primitive.NewObjectID())
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+}
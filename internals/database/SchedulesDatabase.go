@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+InsertScheduledMessageDB
+Inserts a message that waits for its send time
+*/
+func (db *DB) InsertScheduledMessageDB(m models.ScheduledMessage) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	res, err := db.FormatScheduledMessages().InsertOne(ctx, m)
+	if err != nil {
+		return "", err
+	}
+
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+/*
+GetScheduledMessagesDB
+Gets the scheduled messages of the author that were not sent yet, the next to be sent first
+*/
+func (db *DB) GetScheduledMessagesDB(pg int, author primitive.ObjectID) ([]*models.ScheduledMessage, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"author_id": bson.M{"$eq": author},
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "send_at", Value: 1}})
+	opts.SetSkip(int64((pg - 1) * 20))
+	opts.SetLimit(20)
+
+	res := []*models.ScheduledMessage{}
+
+	cursor, err := db.FormatScheduledMessages().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var msg models.ScheduledMessage
+
+		err := cursor.Decode(&msg)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &msg)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// editableScheduledMessage filter of the scheduled message of the author while it is not due, failed messages stay editable
+func editableScheduledMessage(id, author primitive.ObjectID, now time.Time) bson.M {
+	return bson.M{
+		"_id":       bson.M{"$eq": id},
+		"author_id": bson.M{"$eq": author},
+		"$or": bson.A{
+			bson.M{"status": bson.M{"$eq": models.SCHEDULE_FAILED}},
+			bson.M{"status": bson.M{"$eq": models.SCHEDULE_PENDING}, "send_at": bson.M{"$gt": now}},
+		},
+	}
+}
+
+/*
+UpdateScheduledMessageDB
+Changes the body and the send time of the scheduled message of the author while
+it is not due, a failed message goes back to pending. Returns mongo.ErrNoDocuments
+when there is no such message or it is already being sent
+*/
+func (db *DB) UpdateScheduledMessageDB(id, author primitive.ObjectID, body string, sendAt, now time.Time) (*models.ScheduledMessage, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"body":       body,
+			"send_at":    sendAt.UTC(),
+			"status":     models.SCHEDULE_PENDING,
+			"updated_at": now,
+		},
+		"$unset": bson.M{"error": ""},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var res models.ScheduledMessage
+
+	err := db.FormatScheduledMessages().FindOneAndUpdate(ctx, editableScheduledMessage(id, author, now), update, opts).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+CancelScheduledMessageDB
+Deletes the scheduled message of the author while it is not due, returns
+ErrNoDeleted when there is no such message or it is already being sent
+*/
+func (db *DB) CancelScheduledMessageDB(id, author primitive.ObjectID, now time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	res, err := db.FormatScheduledMessages().DeleteOne(ctx, editableScheduledMessage(id, author, now))
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount < 1 {
+		return ErrNoDeleted
+	}
+
+	return nil
+}
+
+/*
+ClaimScheduledMessageDB
+Takes the pending message that is due the longest, it is leased so no other
+node sends it meanwhile and it is retried if this node dies
+*/
+func (db *DB) ClaimScheduledMessageDB(now time.Time, lease time.Duration) (*models.ScheduledMessage, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"status":      bson.M{"$eq": models.SCHEDULE_PENDING},
+		"send_at":     bson.M{"$lte": now},
+		"lease_until": bson.M{"$lte": now},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"lease_until": now.Add(lease)},
+	}
+
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "send_at", Value: 1}})
+
+	var res models.ScheduledMessage
+
+	err := db.FormatScheduledMessages().FindOneAndUpdate(ctx, filter, update, opts).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+CompleteScheduledMessageDB
+Removes the scheduled message once it is sent, returns ErrNoDeleted when it was already removed
+*/
+func (db *DB) CompleteScheduledMessageDB(id primitive.ObjectID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	res, err := db.FormatScheduledMessages().DeleteOne(ctx, bson.M{"_id": bson.M{"$eq": id}})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount < 1 {
+		return ErrNoDeleted
+	}
+
+	return nil
+}
+
+/*
+FailScheduledMessageDB
+Keeps the scheduled message that could not be sent with the reason, the author
+reschedules or cancels it
+*/
+func (db *DB) FailScheduledMessageDB(id primitive.ObjectID, reason string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"status": models.SCHEDULE_FAILED,
+			"error":  reason,
+		},
+	}
+
+	res, err := db.FormatScheduledMessages().UpdateOne(ctx, bson.M{"_id": bson.M{"$eq": id}}, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestInsertScheduledMessageDB test database method InsertScheduledMessageDB
+func TestInsertScheduledMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("InsertScheduledMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		msg := models.ScheduledMessage{ID: ObjectIDMock, Status: models.SCHEDULE_PENDING}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		id, err := db.InsertScheduledMessageDB(msg)
+		assert.NoError(t, err)
+		assert.Equal(t, ObjectIDMock.Hex(), id)
+	})
+}
+
+// TestGetScheduledMessagesDB test database method GetScheduledMessagesDB
+func TestGetScheduledMessagesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetScheduledMessagesDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		author := primitive.NewObjectID()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.SCHEDULED", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "author_id", Value: author}, {Key: "status", Value: models.SCHEDULE_PENDING}},
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "author_id", Value: author}, {Key: "status", Value: models.SCHEDULE_FAILED}, {Key: "error", Value: "muted"}},
+		))
+
+		res, err := db.GetScheduledMessagesDB(1, author)
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "muted", res[1].Error)
+	})
+}
+
+// TestUpdateScheduledMessageDB test database method UpdateScheduledMessageDB
+func TestUpdateScheduledMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	author := primitive.NewObjectID()
+	sendAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+
+	mt.Run("UpdateScheduledMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: ObjectIDMock},
+			{Key: "author_id", Value: author},
+			{Key: "body", Value: "later"},
+			{Key: "status", Value: models.SCHEDULE_PENDING},
+			{Key: "send_at", Value: sendAt},
+		}}))
+
+		msg, err := db.UpdateScheduledMessageDB(ObjectIDMock, author, "later", sendAt, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, "later", msg.Body)
+		assert.Equal(t, sendAt, msg.SendAt.UTC())
+	})
+
+	mt.Run("UpdateScheduledMessageDB - Error already due", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		_, err := db.UpdateScheduledMessageDB(ObjectIDMock, author, "later", sendAt, time.Now())
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestCancelScheduledMessageDB test database method CancelScheduledMessageDB
+func TestCancelScheduledMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CancelScheduledMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		assert.NoError(t, db.CancelScheduledMessageDB(ObjectIDMock, primitive.NewObjectID(), time.Now()))
+	})
+
+	mt.Run("CancelScheduledMessageDB - Error already due", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		assert.EqualError(t, db.CancelScheduledMessageDB(ObjectIDMock, primitive.NewObjectID(), time.Now()), ErrNoDeleted.Error())
+	})
+}
+
+// TestClaimScheduledMessageDB test database method ClaimScheduledMessageDB
+func TestClaimScheduledMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ClaimScheduledMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: ObjectIDMock},
+			{Key: "conversation", Value: models.CONVERSATION_P2P},
+			{Key: "status", Value: models.SCHEDULE_PENDING},
+		}}))
+
+		msg, err := db.ClaimScheduledMessageDB(time.Now(), time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, ObjectIDMock, msg.ID)
+	})
+
+	mt.Run("ClaimScheduledMessageDB - Nothing due", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		_, err := db.ClaimScheduledMessageDB(time.Now(), time.Minute)
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestCompleteScheduledMessageDB test database method CompleteScheduledMessageDB
+func TestCompleteScheduledMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CompleteScheduledMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		assert.NoError(t, db.CompleteScheduledMessageDB(ObjectIDMock))
+	})
+}
+
+// TestFailScheduledMessageDB test database method FailScheduledMessageDB
+func TestFailScheduledMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("FailScheduledMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.FailScheduledMessageDB(ObjectIDMock, "muted"))
+	})
+
+	mt.Run("FailScheduledMessageDB - Error not found", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.FailScheduledMessageDB(ObjectIDMock, "muted"), mongo.ErrNoDocuments.Error())
+	})
+}
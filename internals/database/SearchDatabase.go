@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+CreateSearchIndexesDB
+Creates the text indexes over the bodies of the chat logs the message search
+runs on, creating an index that already exists does nothing
+*/
+func (db *DB) CreateSearchIndexesDB() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// chats mix languages, words are matched as written instead of stemmed
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "body", Value: "text"}},
+		Options: options.Index().SetName("body_text").SetDefaultLanguage("none"),
+	}
+
+	for _, chatlogs := range []*mongo.Collection{db.FormatUserChatlogs(), db.FormatGroupChatlogs()} {
+		_, err := chatlogs.Indexes().CreateOne(ctx, index)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+SearchMessagesDB
+Searches the messages of the groups of the filter and, when asked, of the
+private conversations of the user. The results of both chat logs are ranked
+together by relevance, the newest first on equal relevance
+*/
+func (db *DB) SearchMessagesDB(pg int, user primitive.ObjectID, f models.MessageSearchFilter) ([]*models.MessageSearchResult, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	// every chat log returns the results of the pages before the one asked so they can be ranked together
+	limit := int64(pg * 20)
+
+	res := []*models.MessageSearchResult{}
+
+	if len(f.Groups) > 0 {
+
+		filter := searchFilter(f)
+		filter["target_id"] = bson.M{"$in": f.Groups}
+
+		found, err := searchChatlogs(ctx, db.FormatGroupChatlogs(), filter, limit, models.CONVERSATION_GROUP)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, found...)
+	}
+
+	if f.Private {
+
+		filter := searchFilter(f)
+		filter["$or"] = bson.A{
+			bson.M{"author_id": bson.M{"$eq": user}},
+			bson.M{"target_id": bson.M{"$eq": user}},
+		}
+		if !f.Peer.IsZero() {
+			filter["$or"] = bson.A{
+				bson.M{"author_id": bson.M{"$eq": user}, "target_id": bson.M{"$eq": f.Peer}},
+				bson.M{"author_id": bson.M{"$eq": f.Peer}, "target_id": bson.M{"$eq": user}},
+			}
+		}
+
+		found, err := searchChatlogs(ctx, db.FormatUserChatlogs(), filter, limit, models.CONVERSATION_P2P)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range found {
+			// the private results point to the other user of the conversation
+			if r.TargetID == user {
+				r.TargetID = r.AuthorID
+			}
+		}
+
+		res = append(res, found...)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].CreatedAt.After(res[j].CreatedAt)
+	})
+
+	from := min(len(res), (pg-1)*20)
+	to := min(len(res), pg*20)
+
+	res = res[from:to]
+	for _, r := range res {
+		r.Highlight(f.Query)
+	}
+
+	return res, nil
+}
+
+// searchFilter text query and filters shared by both chat logs
+func searchFilter(f models.MessageSearchFilter) bson.M {
+
+	filter := bson.M{
+		"$text": bson.M{"$search": f.Query},
+	}
+
+	if !f.Author.IsZero() {
+		filter["author_id"] = bson.M{"$eq": f.Author}
+	}
+
+	if f.BodyType != 0 {
+		filter["body_type"] = bson.M{"$eq": f.BodyType}
+	}
+
+	created := bson.M{}
+	if !f.From.IsZero() {
+		created["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		created["$lt"] = f.To
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	return filter
+}
+
+// searchChatlogs runs the text query on the chat log, best matches first
+func searchChatlogs(ctx context.Context, chatlogs *mongo.Collection, filter bson.M, limit int64, conversation string) ([]*models.MessageSearchResult, error) {
+
+	score := bson.M{"$meta": "textScore"}
+
+	opts := options.Find()
+	opts.SetProjection(bson.M{
+		"target_id":   1,
+		"author_id":   1,
+		"author_name": 1,
+		"body_type":   1,
+		"body":        1,
+		"topic_id":    1,
+		"thread_id":   1,
+		"created_at":  1,
+		"score":       score,
+	})
+	opts.SetSort(bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}})
+	opts.SetLimit(limit)
+
+	res := []*models.MessageSearchResult{}
+
+	cursor, err := chatlogs.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var result models.MessageSearchResult
+
+		err := cursor.Decode(&result)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Conversation = conversation
+		res = append(res, &result)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestCreateSearchIndexesDB test database method CreateSearchIndexesDB
+func TestCreateSearchIndexesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CreateSearchIndexesDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.CreateSearchIndexesDB())
+	})
+
+	mt.Run("CreateSearchIndexesDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 85, Message: "index options conflict"}))
+
+		assert.Error(t, db.CreateSearchIndexesDB())
+	})
+}
+
+// TestSearchMessagesDB test database method SearchMessagesDB
+func TestSearchMessagesDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	user := primitive.NewObjectID()
+	peer := primitive.NewObjectID()
+	group := primitive.NewObjectID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	message := func(target, author primitive.ObjectID, body string, score float64, at time.Time) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "target_id", Value: target},
+			{Key: "author_id", Value: author},
+			{Key: "body_type", Value: models.MESSAGE_TYPE_TEXT},
+			{Key: "body", Value: body},
+			{Key: "score", Value: score},
+			{Key: "created_at", Value: at},
+		}
+	}
+
+	mt.Run("SearchMessagesDB - Success ranks both chat logs together", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.FirstBatch,
+				message(group, peer, "Release notes for the launch", 1.5, now),
+				message(group, peer, "launch party", 0.75, now),
+			),
+			mtest.CreateCursorResponse(0, "test_db.USRCHLOGS", mtest.FirstBatch,
+				message(user, peer, "the launch is tomorrow", 1.1, now),
+				message(peer, user, "launch?", 0.75, now.Add(time.Minute)),
+			),
+		)
+
+		res, err := db.SearchMessagesDB(1, user, models.MessageSearchFilter{Query: "launch", Groups: []primitive.ObjectID{group}, Private: true})
+		assert.NoError(t, err)
+		assert.Len(t, res, 4)
+
+		assert.Equal(t, models.CONVERSATION_GROUP, res[0].Conversation)
+		assert.Equal(t, models.CONVERSATION_P2P, res[1].Conversation)
+		// the private results point to the other user
+		assert.Equal(t, peer, res[1].TargetID)
+		// equal relevance, newest first
+		assert.Equal(t, "launch?", res[2].Snippet)
+		assert.Equal(t, "launch party", res[3].Snippet)
+
+		assert.Equal(t, []models.SearchHighlight{{Start: 22, End: 28}}, res[0].Highlights)
+	})
+
+	mt.Run("SearchMessagesDB - Success long bodies are cut around the match", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		body := ""
+		for range 20 {
+			body += "lorem ipsum "
+		}
+		body += "Launch day"
+		for range 20 {
+			body += " dolor sit"
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.USRCHLOGS", mtest.FirstBatch, message(user, peer, body, 1, now)))
+
+		res, err := db.SearchMessagesDB(1, user, models.MessageSearchFilter{Query: "launch -lorem", Private: true, Peer: peer})
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+
+		snippet := []rune(res[0].Snippet)
+		assert.Len(t, snippet, models.SEARCH_SNIPPET_RUNES+2)
+		assert.Equal(t, "…", string(snippet[0]))
+		assert.Len(t, res[0].Highlights, 1)
+		h := res[0].Highlights[0]
+		assert.Equal(t, "Launch", string(snippet[h.Start:h.End]))
+	})
+
+	mt.Run("SearchMessagesDB - Success later pages", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		docs := []bson.D{}
+		for i := range 25 {
+			docs = append(docs, message(group, peer, "launch", float64(25-i), now))
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.GRCHLOGS", mtest.FirstBatch, docs...))
+
+		res, err := db.SearchMessagesDB(2, user, models.MessageSearchFilter{Query: "launch", Groups: []primitive.ObjectID{group}})
+		assert.NoError(t, err)
+		assert.Len(t, res, 5)
+		assert.Equal(t, float64(5), res[0].Score)
+	})
+
+	mt.Run("SearchMessagesDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 27, Message: "text index required for $text query"}))
+
+		_, err := db.SearchMessagesDB(1, user, models.MessageSearchFilter{Query: "launch", Private: true})
+		assert.Error(t, err)
+	})
+}
+
+// TestGetUserGroupIDsDB test database method GetUserGroupIDsDB
+func TestGetUserGroupIDsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetUserGroupIDsDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		groups := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.GROUPS", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: groups[0]}},
+			bson.D{{Key: "_id", Value: groups[1]}},
+		))
+
+		res, err := db.GetUserGroupIDsDB(ObjectIDMock)
+		assert.NoError(t, err)
+		assert.Equal(t, groups, res)
+	})
+
+	mt.Run("GetUserGroupIDsDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "find failed"}))
+
+		_, err := db.GetUserGroupIDsDB(ObjectIDMock)
+		assert.Error(t, err)
+	})
+}
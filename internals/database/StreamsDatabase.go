@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// STREAM_USER_CHATLOGS change stream over the user chat logs collection
+	STREAM_USER_CHATLOGS = "user_chatlogs"
+
+	// STREAM_GROUP_CHATLOGS change stream over the group chat logs collection
+	STREAM_GROUP_CHATLOGS = "group_chatlogs"
+
+	// CHANGE_STREAM_HISTORY_LOST server error when the resume token is no longer on the oplog
+	CHANGE_STREAM_HISTORY_LOST = 286
+)
+
+// ERRORS
+var (
+	ErrUnknownStream = errors.New("unknown stream")
+)
+
+/*
+WatchChatlogsDB
+opens a change stream over the inserts and updates of the chat logs collection,
+if a resume token is given the stream continues after it
+*/
+func (db *DB) WatchChatlogsDB(ctx context.Context, stream string, token bson.Raw) (ChatlogStream, error) {
+
+	var coll *mongo.Collection
+
+	switch stream {
+	case STREAM_USER_CHATLOGS:
+		coll = db.FormatUserChatlogs()
+	case STREAM_GROUP_CHATLOGS:
+		coll = db.FormatGroupChatlogs()
+	default:
+		return nil, ErrUnknownStream
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}},
+		}}},
+	}
+
+	opts := options.ChangeStream()
+	opts.SetFullDocument(options.UpdateLookup)
+	if len(token) > 0 {
+		opts.SetResumeAfter(token)
+	}
+
+	cs, err := coll.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// IsHistoryLost reports whether the stream can not resume because its token fell off the oplog
+func IsHistoryLost(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCode(CHANGE_STREAM_HISTORY_LOST)
+}
+
+/*
+GetResumeTokenDB
+gets the last resume token saved for the stream, nil if the stream never saved one
+*/
+func (db *DB) GetResumeTokenDB(stream string) (bson.Raw, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": stream},
+	}
+
+	var res struct {
+		Token bson.Raw `bson:"token"`
+	}
+
+	err := db.FormatResumeTokens().FindOne(ctx, filter, nil).Decode(&res)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return res.Token, nil
+}
+
+/*
+SaveResumeTokenDB
+saves the resume token of the stream so a restarted node continues where it left off,
+a nil token clears it so the stream is opened from now
+*/
+func (db *DB) SaveResumeTokenDB(stream string, token bson.Raw) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": stream},
+	}
+
+	updateDoc := bson.M{
+		"$set": bson.M{
+			"token":      token,
+			"updated_at": time.Now(),
+		},
+	}
+	if len(token) == 0 {
+		updateDoc = bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"token": ""},
+		}
+	}
+
+	_, err := db.FormatResumeTokens().UpdateOne(ctx, filter, updateDoc, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
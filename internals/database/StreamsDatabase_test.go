@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestGetResumeTokenDB test database method GetResumeTokenDB
+func TestGetResumeTokenDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetResumeTokenDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		token, err := bson.Marshal(bson.D{{Key: "_data", Value: "8266F1"}})
+		assert.NoError(t, err)
+
+		doc := bson.D{
+			{Key: "_id", Value: "node-a:user_chatlogs"},
+			{Key: "token", Value: bson.Raw(token)},
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.RESUME", mtest.FirstBatch, doc))
+
+		res, err := db.GetResumeTokenDB("node-a:user_chatlogs")
+
+		assert.NoError(t, err)
+		assert.Equal(t, bson.Raw(token), res)
+	})
+
+	mt.Run("GetResumeTokenDB - No token saved", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.RESUME", mtest.FirstBatch))
+
+		res, err := db.GetResumeTokenDB("node-a:user_chatlogs")
+
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+// TestSaveResumeTokenDB test database method SaveResumeTokenDB
+func TestSaveResumeTokenDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SaveResumeTokenDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		token, err := bson.Marshal(bson.D{{Key: "_data", Value: "8266F1"}})
+		assert.NoError(t, err)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "upserted", Value: bson.A{}}))
+
+		err = db.SaveResumeTokenDB("node-a:user_chatlogs", token)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("SaveResumeTokenDB - Success token cleared", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		err := db.SaveResumeTokenDB("node-a:user_chatlogs", nil)
+
+		assert.NoError(t, err)
+	})
+
+	mt.Run("SaveResumeTokenDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    123456,
+			Message: "could not save token",
+		}))
+
+		err := db.SaveResumeTokenDB("node-a:user_chatlogs", nil)
+
+		assert.Error(t, err)
+	})
+}
+
+// TestWatchChatlogsDB test database method WatchChatlogsDB
+func TestWatchChatlogsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("WatchChatlogsDB - Unknown stream", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		res, err := db.WatchChatlogsDB(context.TODO(), "unknown", nil)
+
+		assert.EqualError(t, err, ErrUnknownStream.Error())
+		assert.Nil(t, res)
+	})
+
+	mt.Run("WatchChatlogsDB - Error history lost", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		token, err := bson.Marshal(bson.D{{Key: "_data", Value: "8266F1"}})
+		assert.NoError(t, err)
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    CHANGE_STREAM_HISTORY_LOST,
+			Name:    "ChangeStreamHistoryLost",
+			Message: "resume of change stream was not possible",
+		}))
+
+		res, err := db.WatchChatlogsDB(context.TODO(), STREAM_USER_CHATLOGS, token)
+
+		assert.True(t, IsHistoryLost(err))
+		assert.Nil(t, res)
+	})
+}
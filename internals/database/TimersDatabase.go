@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+CreateTimerIndexesDB
+Creates the indexes the expired messages are claimed with, only the messages
+sent with a timer are indexed
+*/
+func (db *DB) CreateTimerIndexesDB() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_at", Value: 1}},
+		Options: options.Index().SetName("expiry").SetSparse(true),
+	}
+
+	_, err := db.FormatGroupChatlogs().Indexes().CreateOne(ctx, index)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.FormatUserChatlogs().Indexes().CreateOne(ctx, index)
+
+	return err
+}
+
+/*
+SetP2PMessageTimerDB
+Creates or replaces the timer of the disappearing messages of the private conversation
+*/
+func (db *DB) SetP2PMessageTimerDB(timer models.ConversationTimer) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": timer.ConversationID},
+	}
+
+	_, err := db.FormatConversationTimers().ReplaceOne(ctx, filter, timer, options.Replace().SetUpsert(true))
+
+	return err
+}
+
+/*
+GetP2PMessageTimerDB
+Gets the timer of the private conversation in seconds, zero when it was never set
+*/
+func (db *DB) GetP2PMessageTimerDB(conversationID string) (int, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": conversationID},
+	}
+
+	var res models.ConversationTimer
+
+	err := db.FormatConversationTimers().FindOne(ctx, filter).Decode(&res)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return res.Duration, nil
+}
+
+/*
+ClaimExpiredGroupMessageDB
+Takes one expired group message, its expiry is pushed back by the lease so no
+other node removes it meanwhile and it is retried if this node dies
+*/
+func (db *DB) ClaimExpiredGroupMessageDB(now time.Time, lease time.Duration) (*models.GroupChatContentLog, error) {
+
+	var res models.GroupChatContentLog
+
+	err := db.claimExpiredMessage(db.FormatGroupChatlogs(), now, lease, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+ClaimExpiredP2PMessageDB
+Takes one expired private message, its expiry is pushed back by the lease so no
+other node removes it meanwhile and it is retried if this node dies
+*/
+func (db *DB) ClaimExpiredP2PMessageDB(now time.Time, lease time.Duration) (*models.P2PContentChatLog, error) {
+
+	var res models.P2PContentChatLog
+
+	err := db.claimExpiredMessage(db.FormatUserChatlogs(), now, lease, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (db *DB) claimExpiredMessage(coll *mongo.Collection, now time.Time, lease time.Duration, res any) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"expire_at": bson.M{"$lte": now},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"expire_at": now.Add(lease)},
+	}
+
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "expire_at", Value: 1}})
+
+	return coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(res)
+}
+
+/*
+GetSharedMediaDB
+Gets the urls of the given media and placeholders and the content ids of the
+given videos that other messages still use, forwarded copies share the media
+of the original message. The id is a message or a group, the message itself or
+every message of the group is not counted
+*/
+func (db *DB) GetSharedMediaDB(id primitive.ObjectID, media []string) ([]string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	shared := []string{}
+
+	for _, coll := range []*mongo.Collection{db.FormatGroupChatlogs(), db.FormatUserChatlogs()} {
+		for _, field := range []string{"media", "placeholders", "content_id"} {
+
+			filter := bson.M{
+				"$nor": bson.A{bson.M{"_id": id}, bson.M{"target_id": id}},
+				field:  bson.M{"$in": media},
+			}
+
+			values, err := coll.Distinct(ctx, field, filter)
+			if err != nil {
+				return nil, err
+			}
+
+			// the copies may hold other media beside the given one
+			for _, v := range values {
+				if url, ok := v.(string); ok && slices.Contains(media, url) && !slices.Contains(shared, url) {
+					shared = append(shared, url)
+				}
+			}
+		}
+	}
+
+	return shared, nil
+}
+
+/*
+DeleteExpiredGroupMessageDB
+Deletes the expired group message with its reactions, mentions, pins and stars
+in a single transaction, a reply is discounted from the root of its thread
+*/
+func (db *DB) DeleteExpiredGroupMessageDB(id, thread primitive.ObjectID) error {
+	return db.deleteExpiredMessage(db.FormatGroupChatlogs(), id, thread, true)
+}
+
+/*
+DeleteExpiredP2PMessageDB
+Deletes the expired private message with its reactions, pins and stars in a
+single transaction
+*/
+func (db *DB) DeleteExpiredP2PMessageDB(id primitive.ObjectID) error {
+	return db.deleteExpiredMessage(db.FormatUserChatlogs(), id, primitive.NilObjectID, false)
+}
+
+func (db *DB) deleteExpiredMessage(chatlogs *mongo.Collection, id, thread primitive.ObjectID, group bool) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sctx mongo.SessionContext) (any, error) {
+
+		related := []*mongo.Collection{db.FormatMessageReactions(), db.FormatMessageStars()}
+		if group {
+			related = append(related, db.FormatGroupMentions())
+		}
+
+		for _, coll := range related {
+			_, err := coll.DeleteMany(sctx, bson.M{"message_id": bson.M{"$eq": id}})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// a message is pinned at most once, on its own conversation
+		var pin models.MessagePin
+
+		err := db.FormatMessagePins().FindOneAndDelete(sctx, bson.M{"message_id": bson.M{"$eq": id}}).Decode(&pin)
+		if err == nil {
+			err = db.discountPin(sctx, pin.ConversationID)
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		res, err := chatlogs.DeleteOne(sctx, bson.M{"_id": bson.M{"$eq": id}})
+		if err != nil {
+			return nil, err
+		}
+
+		if res.DeletedCount < 1 {
+			return nil, ErrNoDeleted
+		}
+
+		if !thread.IsZero() {
+			_, err = chatlogs.UpdateOne(sctx, bson.M{
+				"_id":         bson.M{"$eq": thread},
+				"reply_count": bson.M{"$gt": 0},
+			}, bson.M{"$inc": bson.M{"reply_count": -1}})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+
+	return err
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestSetP2PMessageTimerDB test database method SetP2PMessageTimerDB
+func TestSetP2PMessageTimerDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SetP2PMessageTimerDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		err := db.SetP2PMessageTimerDB(models.ConversationTimer{
+			ConversationID: models.P2PConversationID(ObjectIDMock, primitive.NewObjectID()),
+			Duration:       3600,
+			UpdatedBy:      ObjectIDMock,
+			UpdatedAt:      time.Now(),
+		})
+		assert.NoError(t, err)
+	})
+}
+
+// TestGetP2PMessageTimerDB test database method GetP2PMessageTimerDB
+func TestGetP2PMessageTimerDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetP2PMessageTimerDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "test_db.TIMERS", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "a:b"},
+			{Key: "duration", Value: 86400},
+		}))
+
+		timer, err := db.GetP2PMessageTimerDB("a:b")
+		assert.NoError(t, err)
+		assert.Equal(t, 86400, timer)
+	})
+
+	mt.Run("GetP2PMessageTimerDB - Success never set", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.TIMERS", mtest.FirstBatch))
+
+		timer, err := db.GetP2PMessageTimerDB("a:b")
+		assert.NoError(t, err)
+		assert.Zero(t, timer)
+	})
+}
+
+// TestClaimExpiredGroupMessageDB test database method ClaimExpiredGroupMessageDB
+func TestClaimExpiredGroupMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ClaimExpiredGroupMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: ObjectIDMock},
+			{Key: "target_id", Value: ObjectIDMock},
+			{Key: "media", Value: bson.A{"https://cdn/content/a.jpg"}},
+		}}))
+
+		msg, err := db.ClaimExpiredGroupMessageDB(time.Now(), time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, ObjectIDMock, msg.ID)
+		assert.Equal(t, []string{"https://cdn/content/a.jpg"}, msg.Media)
+	})
+
+	mt.Run("ClaimExpiredGroupMessageDB - Error nothing expired", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		_, err := db.ClaimExpiredGroupMessageDB(time.Now(), time.Minute)
+		assert.EqualError(t, err, mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestClaimExpiredP2PMessageDB test database method ClaimExpiredP2PMessageDB
+func TestClaimExpiredP2PMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ClaimExpiredP2PMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: ObjectIDMock},
+			{Key: "author_id", Value: ObjectIDMock},
+			{Key: "body", Value: "see you"},
+		}}))
+
+		msg, err := db.ClaimExpiredP2PMessageDB(time.Now(), time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, "see you", msg.Body)
+	})
+}
+
+// TestGetSharedMediaDB test database method GetSharedMediaDB
+func TestGetSharedMediaDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetSharedMediaDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		none := mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{}})
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"https://cdn/content/a.jpg", "https://cdn/content/z.jpg"}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"https://cdn/thumbs/a.jpg"}}),
+			none,
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"https://cdn/files/b.pdf", "https://cdn/content/a.jpg"}}),
+			none,
+			mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{"101$video-a"}}),
+		)
+
+		shared, err := db.GetSharedMediaDB(ObjectIDMock, []string{"https://cdn/content/a.jpg", "https://cdn/thumbs/a.jpg", "https://cdn/files/b.pdf", "https://cdn/files/c.pdf", "101$video-a"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://cdn/content/a.jpg", "https://cdn/thumbs/a.jpg", "https://cdn/files/b.pdf", "101$video-a"}, shared)
+	})
+}
+
+// TestDeleteExpiredGroupMessageDB test database method DeleteExpiredGroupMessageDB
+func TestDeleteExpiredGroupMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DeleteExpiredGroupMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+		mt.AddMockResponses(deleted, deleted, deleted, deleted, deleted, mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.DeleteExpiredGroupMessageDB(ObjectIDMock, primitive.NilObjectID))
+	})
+
+	mt.Run("DeleteExpiredGroupMessageDB - Success reply discounted", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+		discounted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+		mt.AddMockResponses(deleted, deleted, deleted, deleted, deleted, discounted, mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.DeleteExpiredGroupMessageDB(ObjectIDMock, primitive.NewObjectID()))
+	})
+
+	mt.Run("DeleteExpiredGroupMessageDB - Success pin discounted", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+		unpinned := mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "conversation_id", Value: "123456789"},
+			{Key: "message_id", Value: ObjectIDMock},
+		}})
+		discounted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+		mt.AddMockResponses(deleted, deleted, deleted, unpinned, discounted, deleted, mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.DeleteExpiredGroupMessageDB(ObjectIDMock, primitive.NilObjectID))
+
+		// the counter of the conversation of the pin is the one discounted
+		discountedID := ""
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			if e.CommandName == "update" {
+				discountedID = e.Command.Lookup("updates", "0", "q", "_id", "$eq").StringValue()
+			}
+		}
+		assert.Equal(t, "123456789", discountedID)
+	})
+
+	mt.Run("DeleteExpiredGroupMessageDB - Error already deleted", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0})
+		mt.AddMockResponses(deleted, deleted, deleted, deleted, deleted, mtest.CreateSuccessResponse())
+
+		assert.EqualError(t, db.DeleteExpiredGroupMessageDB(ObjectIDMock, primitive.NewObjectID()), ErrNoDeleted.Error())
+	})
+}
+
+// TestDeleteExpiredP2PMessageDB test database method DeleteExpiredP2PMessageDB
+func TestDeleteExpiredP2PMessageDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DeleteExpiredP2PMessageDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		deleted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+		mt.AddMockResponses(deleted, deleted, deleted, deleted, mtest.CreateSuccessResponse())
+
+		assert.NoError(t, db.DeleteExpiredP2PMessageDB(ObjectIDMock))
+	})
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+AddGroupTopicDB
+Adds the topic to the group, the group is not matched when it already holds
+the maximum number of topics
+*/
+func (db *DB) AddGroupTopicDB(id primitive.ObjectID, topic models.GroupTopic) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": bson.M{"$eq": id},
+		fmt.Sprintf("topics.%d", models.MAX_GROUP_TOPICS-1): bson.M{"$exists": false},
+	}
+
+	update := bson.M{
+		"$push": bson.M{"topics": topic},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+UpdateGroupTopicDB
+Updates the name and the archived state of the topic of the group
+*/
+func (db *DB) UpdateGroupTopicDB(id primitive.ObjectID, topic models.GroupTopic) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":        bson.M{"$eq": id},
+		"topics._id": bson.M{"$eq": topic.ID},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"topics.$.name":     topic.Name,
+			"topics.$.archived": topic.Archived,
+		},
+	}
+
+	res, err := db.FormatGroupCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+/*
+MarkTopicReadDB
+Saves the last time the user read the topic, an older time never replaces a newer one
+*/
+func (db *DB) MarkTopicReadDB(groupID string, topic, user primitive.ObjectID, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"topic_id": bson.M{"$eq": topic},
+		"user_id":  bson.M{"$eq": user},
+	}
+
+	update := bson.M{
+		"$max":         bson.M{"read_at": at},
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "group_id": groupID},
+	}
+
+	_, err := db.FormatTopicReads().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+/*
+GetTopicReadsDB
+Gets the last time the user read every topic of the group
+*/
+func (db *DB) GetTopicReadsDB(groupID string, user primitive.ObjectID) (map[primitive.ObjectID]time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"group_id": bson.M{"$eq": groupID},
+		"user_id":  bson.M{"$eq": user},
+	}
+
+	cursor, err := db.FormatTopicReads().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	res := make(map[primitive.ObjectID]time.Time)
+
+	for cursor.Next(ctx) {
+
+		var read models.TopicRead
+
+		err := cursor.Decode(&read)
+		if err != nil {
+			return nil, err
+		}
+
+		res[read.TopicID] = read.ReadAt
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+/*
+CountTopicUnreadDB
+Counts the messages of the given topics sent by other users after the user read
+them, a zero read time counts every message of the topic. The replies of the
+threads are left out, they live on their threads
+*/
+func (db *DB) CountTopicUnreadDB(id, user primitive.ObjectID, reads map[primitive.ObjectID]time.Time) (map[primitive.ObjectID]int, error) {
+
+	res := make(map[primitive.ObjectID]int)
+	if len(reads) == 0 {
+		return res, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	topics := bson.A{}
+	for topic, readAt := range reads {
+		topics = append(topics, bson.M{
+			"topic_id":   bson.M{"$eq": topic},
+			"created_at": bson.M{"$gt": readAt},
+		})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"target_id": bson.M{"$eq": id},
+			"author_id": bson.M{"$ne": user},
+			"thread_id": bson.M{"$exists": false},
+			"$or":       topics,
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$topic_id",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := db.FormatGroupChatlogs().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var count struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Count int                `bson:"count"`
+		}
+
+		err := cursor.Decode(&count)
+		if err != nil {
+			return nil, err
+		}
+
+		res[count.ID] = count.Count
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+/*
+GetTopicChatLogsDB
+Gets the messages of the topic of the group, newest first, a zero topic gets
+the main conversation of the group. The replies of the threads are left out
+*/
+func (db *DB) GetTopicChatLogsDB(pg int, id, topic primitive.ObjectID) ([]*models.GroupChatContentLog, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"target_id": bson.M{"$eq": id},
+		"topic_id":  bson.M{"$eq": topic},
+		"thread_id": bson.M{"$exists": false},
+	}
+	if topic.IsZero() {
+		filter["topic_id"] = bson.M{"$exists": false}
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	opts.SetSkip(int64((pg - 1) * 60))
+	opts.SetLimit(60)
+
+	res := []*models.GroupChatContentLog{}
+
+	cursor, err := db.FormatGroupChatlogs().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var chat models.GroupChatContentLog
+
+		err := cursor.Decode(&chat)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &chat)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestAddGroupTopicDB test database method AddGroupTopicDB
+func TestAddGroupTopicDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("AddGroupTopicDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.AddGroupTopicDB(ObjectIDMock, models.FormatGroupTopic("releases", ObjectIDMock)))
+	})
+
+	mt.Run("AddGroupTopicDB - Error group full", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.AddGroupTopicDB(ObjectIDMock, models.FormatGroupTopic("releases", ObjectIDMock)), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestUpdateGroupTopicDB test database method UpdateGroupTopicDB
+func TestUpdateGroupTopicDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateGroupTopicDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.UpdateGroupTopicDB(ObjectIDMock, models.GroupTopic{ID: primitive.NewObjectID(), Name: "releases", Archived: true}))
+	})
+
+	mt.Run("UpdateGroupTopicDB - Error no topic", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.EqualError(t, db.UpdateGroupTopicDB(ObjectIDMock, models.GroupTopic{ID: primitive.NewObjectID()}), mongo.ErrNoDocuments.Error())
+	})
+}
+
+// TestMarkTopicReadDB test database method MarkTopicReadDB
+func TestMarkTopicReadDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("MarkTopicReadDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, db.MarkTopicReadDB("123456789", primitive.NewObjectID(), ObjectIDMock, time.Now()))
+	})
+
+	mt.Run("MarkTopicReadDB - Error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    12345,
+			Message: "Error saving topic read",
+		}))
+
+		assert.Error(t, db.MarkTopicReadDB("123456789", primitive.NewObjectID(), ObjectIDMock, time.Now()))
+	})
+}
+
+// TestGetTopicReadsDB test database method GetTopicReadsDB
+func TestGetTopicReadsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetTopicReadsDB - Success with results", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		topic := primitive.NewObjectID()
+		readAt := time.Now().Truncate(time.Millisecond).UTC()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.TOPIC_READS", mtest.FirstBatch, bson.D{
+				{Key: "group_id", Value: "123456789"},
+				{Key: "topic_id", Value: topic},
+				{Key: "user_id", Value: ObjectIDMock},
+				{Key: "read_at", Value: readAt},
+			}),
+		)
+
+		res, err := db.GetTopicReadsDB("123456789", ObjectIDMock)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.True(t, readAt.Equal(res[topic]))
+	})
+
+	mt.Run("GetTopicReadsDB - encounter error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    123456,
+			Message: "Mongo db has encounter and error",
+		}))
+
+		res, err := db.GetTopicReadsDB("123456789", ObjectIDMock)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+// TestCountTopicUnreadDB test database method CountTopicUnreadDB
+func TestCountTopicUnreadDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CountTopicUnreadDB - Success with results", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		read, unread := primitive.NewObjectID(), primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.GR_CHLOGS", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: unread},
+				{Key: "count", Value: 4},
+			}),
+		)
+
+		res, err := db.CountTopicUnreadDB(ObjectIDMock, ObjectIDMock, map[primitive.ObjectID]time.Time{read: time.Now(), unread: {}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, res[unread])
+		assert.Equal(t, 0, res[read])
+	})
+
+	mt.Run("CountTopicUnreadDB - Success without topics", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		res, err := db.CountTopicUnreadDB(ObjectIDMock, ObjectIDMock, nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+
+	mt.Run("CountTopicUnreadDB - encounter error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    123456,
+			Message: "Mongo db has encounter and error",
+		}))
+
+		res, err := db.CountTopicUnreadDB(ObjectIDMock, ObjectIDMock, map[primitive.ObjectID]time.Time{primitive.NewObjectID(): {}})
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+// TestGetTopicChatLogsDB test database method GetTopicChatLogsDB
+func TestGetTopicChatLogsDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetTopicChatLogsDB - Success with results", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		topic := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test_db.GR_CHLOGS", mtest.FirstBatch,
+				bson.D{{Key: "target_id", Value: ObjectIDMock}, {Key: "topic_id", Value: topic}, {Key: "body", Value: "second"}},
+				bson.D{{Key: "target_id", Value: ObjectIDMock}, {Key: "topic_id", Value: topic}, {Key: "body", Value: "first"}},
+			),
+		)
+
+		res, err := db.GetTopicChatLogsDB(1, ObjectIDMock, topic)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "second", res[0].Body)
+		assert.Equal(t, topic, res[1].TopicID)
+	})
+
+	mt.Run("GetTopicChatLogsDB - encounter error", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    123456,
+			Message: "Mongo db has encounter and error",
+		}))
+
+		res, err := db.GetTopicChatLogsDB(1, ObjectIDMock, primitive.NilObjectID)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
@@ -139,3 +139,47 @@ func (db *DB) GetUsers(pg int, query string) ([]*models.User, error) {
 
 	return results, nil
 }
+
+/*
+GetUsersByIDDB
+Gets the users with the given ids, the ids without a user are left out
+*/
+func (db *DB) GetUsersByIDDB(ids []primitive.ObjectID) ([]*models.User, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res := []*models.User{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	filter := bson.M{
+		"_id": bson.M{"$in": ids},
+	}
+
+	cursor, err := db.FormatUserCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+
+		var user models.User
+
+		err := cursor.Decode(&user)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &user)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
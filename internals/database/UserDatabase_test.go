@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
@@ -239,3 +240,40 @@ func TestGetUsers(t *testing.T) {
 	})
 
 }
+
+// TestGetUsersByIDDB test database method GetUsersByIDDB
+func TestGetUsersByIDDB(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetUsersByIDDB - Success", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test_db.users", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: ObjectIDMock}, {Key: "name", Value: "alice"}},
+		))
+
+		res, err := db.GetUsersByIDDB([]primitive.ObjectID{ObjectIDMock, primitive.NewObjectID()})
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, "alice", res[0].Name)
+	})
+
+	mt.Run("GetUsersByIDDB - Success no ids", func(mt *mtest.T) {
+
+		db := &DB{
+			Client:   mt.Client,
+			Database: MockDBName,
+		}
+
+		res, err := db.GetUsersByIDDB(nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+}
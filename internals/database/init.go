@@ -5,8 +5,10 @@ import (
 	"errors"
 	"log"
 	"os"
+	"time"
 	"wechat-back/internals/models"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -18,17 +20,133 @@ type DBHUB interface {
 	InsertUserDB(models.User) (string, error)
 	UpdateUserAccountDB(map[string]any, string) error
 	GetUsers(int, string) ([]*models.User, error)
+	GetUsersByIDDB([]primitive.ObjectID) ([]*models.User, error)
 
 	// groups
 	GetGroupDB(string) (*models.Group, error)
 	InsertGroupDB(models.Group) (string, error)
 	UpdateGroupDB(map[string]any, primitive.ObjectID) error
 	DeleteGroupDB(string) error
-	SearchGroups(int, string) ([]*models.Group, error)
+	SearchGroups(int, models.GroupDirectoryFilter) ([]*models.PublicGroup, error)
+	AddGroupParticipantDB(primitive.ObjectID, primitive.ObjectID) error
+	UpdateGroupAvatarDB(primitive.ObjectID, models.GroupAvatar) error
+	UpdateGroupMuteDB(primitive.ObjectID, primitive.ObjectID, time.Time) error
+	ClaimGroupPostDB(primitive.ObjectID, primitive.ObjectID, time.Time, time.Duration) (time.Time, error)
+	LeaveGroupDB(primitive.ObjectID, models.GroupLeave) error
+	TransferGroupOwnershipDB(primitive.ObjectID, primitive.ObjectID, primitive.ObjectID) error
+	UpdateNotificationMuteDB(primitive.ObjectID, primitive.ObjectID, time.Time) error
+	GetUserGroupIDsDB(primitive.ObjectID) ([]primitive.ObjectID, error)
+
+	// group deletions
+	ScheduleGroupDeletionDB(primitive.ObjectID, time.Time) error
+	CancelGroupDeletionDB(primitive.ObjectID) error
+	ClaimGroupDeletionDB(time.Time, time.Duration) (*models.Group, error)
+	GetGroupMediaDB(primitive.ObjectID) ([]string, error)
+	GetGroupVideosDB(primitive.ObjectID) ([]string, error)
+	DeleteGroupCascadeDB(primitive.ObjectID, string) error
+
+	// invites
+	InsertGroupInviteDB(models.GroupInvite) (string, error)
+	GetGroupInviteDB(string) (*models.GroupInvite, error)
+	GetGroupInvitesDB(string) ([]*models.GroupInvite, error)
+	RevokeGroupInviteDB(string) error
+	UseGroupInviteDB(string) error
+	InsertJoinRequestDB(models.JoinRequest) (string, error)
+	GetPendingJoinRequestDB(string, primitive.ObjectID) (*models.JoinRequest, error)
+	GetJoinRequestDB(primitive.ObjectID) (*models.JoinRequest, error)
+	GetJoinRequestsDB(int, string) ([]*models.JoinRequest, error)
+	ReviewJoinRequestDB(primitive.ObjectID, string, primitive.ObjectID) error
+
+	// topics
+	AddGroupTopicDB(primitive.ObjectID, models.GroupTopic) error
+	UpdateGroupTopicDB(primitive.ObjectID, models.GroupTopic) error
+	MarkTopicReadDB(string, primitive.ObjectID, primitive.ObjectID, time.Time) error
+	GetTopicReadsDB(string, primitive.ObjectID) (map[primitive.ObjectID]time.Time, error)
+	CountTopicUnreadDB(primitive.ObjectID, primitive.ObjectID, map[primitive.ObjectID]time.Time) (map[primitive.ObjectID]int, error)
+	GetTopicChatLogsDB(int, primitive.ObjectID, primitive.ObjectID) ([]*models.GroupChatContentLog, error)
+
+	// reactions
+	GetGroupMessageDB(primitive.ObjectID) (*models.GroupChatContentLog, error)
+	GetP2PMessageDB(primitive.ObjectID) (*models.P2PContentChatLog, error)
+	AddMessageReactionDB(models.MessageReaction) (map[string]int, error)
+	RemoveMessageReactionDB(models.MessageReaction) (map[string]int, error)
+	GetMessageReactionsDB(int, primitive.ObjectID) ([]*models.MessageReaction, error)
+
+	// replies
+	AddThreadReplyDB(primitive.ObjectID) error
+	GetThreadChatLogsDB(int, primitive.ObjectID, primitive.ObjectID) ([]*models.GroupChatContentLog, error)
+
+	// mentions
+	InsertMentionsDB([]models.Mention) error
+	GetUnreadMentionsDB(int, primitive.ObjectID, string) ([]*models.Mention, error)
+	MarkMentionsReadDB(primitive.ObjectID, string, time.Time) error
+
+	// search
+	SearchMessagesDB(int, primitive.ObjectID, models.MessageSearchFilter) ([]*models.MessageSearchResult, error)
+
+	// galleries
+	GetGroupMediaGalleryDB(primitive.ObjectID, []int, models.GalleryCursor) ([]*models.MediaGalleryItem, error)
+	GetP2PMediaGalleryDB(primitive.ObjectID, primitive.ObjectID, []int, models.GalleryCursor) ([]*models.MediaGalleryItem, error)
+
+	// pins
+	PinMessageDB(models.MessagePin) error
+	UnpinMessageDB(string, primitive.ObjectID) error
+	GetPinnedMessagesDB(string, bool) ([]*models.MessagePin, error)
+	StarMessageDB(models.MessageStar) error
+	UnstarMessageDB(primitive.ObjectID, primitive.ObjectID) error
+	GetStarredMessagesDB(int, primitive.ObjectID) ([]*models.MessageStar, error)
+
+	// forwards
+	InsertForwardedMessagesDB([]models.GroupChatContentLog, []models.P2PContentChatLog) error
+
+	// scheduled messages
+	InsertScheduledMessageDB(models.ScheduledMessage) (string, error)
+	GetScheduledMessagesDB(int, primitive.ObjectID) ([]*models.ScheduledMessage, error)
+	UpdateScheduledMessageDB(primitive.ObjectID, primitive.ObjectID, string, time.Time, time.Time) (*models.ScheduledMessage, error)
+	CancelScheduledMessageDB(primitive.ObjectID, primitive.ObjectID, time.Time) error
+	ClaimScheduledMessageDB(time.Time, time.Duration) (*models.ScheduledMessage, error)
+	CompleteScheduledMessageDB(primitive.ObjectID) error
+	FailScheduledMessageDB(primitive.ObjectID, string) error
+
+	// message timers
+	SetP2PMessageTimerDB(models.ConversationTimer) error
+	GetP2PMessageTimerDB(string) (int, error)
+	ClaimExpiredGroupMessageDB(time.Time, time.Duration) (*models.GroupChatContentLog, error)
+	ClaimExpiredP2PMessageDB(time.Time, time.Duration) (*models.P2PContentChatLog, error)
+	GetSharedMediaDB(primitive.ObjectID, []string) ([]string, error)
+	DeleteExpiredGroupMessageDB(primitive.ObjectID, primitive.ObjectID) error
+	DeleteExpiredP2PMessageDB(primitive.ObjectID) error
+
+	// audit
+	InsertAuditEntryDB(models.AuditEntry) (string, error)
+	GetAuditEntriesDB(int, string) ([]*models.AuditEntry, error)
 
 	// chats
 	InsertP2PMessageDB(any) (string, error)
 	InsertGroupMessageDB(any) (string, error)
+
+	// calls
+	InsertCallLogDB(models.CallLog) (string, error)
+	GetCallHistoryDB(int, primitive.ObjectID) ([]*models.CallLog, error)
+}
+
+/*
+StreamHUB
+access to the change streams of the chat logs collections
+*/
+type StreamHUB interface {
+	WatchChatlogsDB(context.Context, string, bson.Raw) (ChatlogStream, error)
+	GetResumeTokenDB(string) (bson.Raw, error)
+	SaveResumeTokenDB(string, bson.Raw) error
+}
+
+// ChatlogStream iterates over the changes of a chat log collection
+type ChatlogStream interface {
+	Next(context.Context) bool
+	Decode(any) error
+	ResumeToken() bson.Raw
+	Err() error
+	Close(context.Context) error
 }
 
 // ERRORS
@@ -77,3 +195,68 @@ func (db *DB) FormatUserChatlogs() *mongo.Collection {
 func (db *DB) FormatGroupChatlogs() *mongo.Collection {
 	return db.Client.Database(db.Database).Collection(os.Getenv("DB_GR_CHLOGS"))
 }
+
+// FormatCallLogs Formats the collection for call logs
+func (db *DB) FormatCallLogs() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_CALL_LOGS"))
+}
+
+// FormatGroupInvites Formats the collection for group invite links
+func (db *DB) FormatGroupInvites() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_GROUP_INVITES"))
+}
+
+// FormatJoinRequests Formats the collection for group join requests
+func (db *DB) FormatJoinRequests() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_JOIN_REQUESTS"))
+}
+
+// FormatResumeTokens Formats the collection for change streams resume tokens
+func (db *DB) FormatResumeTokens() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_RESUME_TKNS"))
+}
+
+// FormatGroupAudit Formats the collection for the audit log of the groups
+func (db *DB) FormatGroupAudit() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_GROUP_AUDIT"))
+}
+
+// FormatTopicReads Formats the collection for the last time the users read the topics
+func (db *DB) FormatTopicReads() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_TOPIC_READS"))
+}
+
+// FormatMessageReactions Formats the collection for the reactions to the messages
+func (db *DB) FormatMessageReactions() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_MSG_REACTIONS"))
+}
+
+// FormatGroupMentions Formats the collection for the mentions of the participants of the groups
+func (db *DB) FormatGroupMentions() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_GROUP_MENTIONS"))
+}
+
+// FormatMessagePins Formats the collection for the messages pinned on the conversations
+func (db *DB) FormatMessagePins() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_MSG_PINS"))
+}
+
+// FormatPinCounters Formats the collection for the number of messages pinned on every conversation
+func (db *DB) FormatPinCounters() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_PIN_COUNTERS"))
+}
+
+// FormatMessageStars Formats the collection for the messages starred by the users
+func (db *DB) FormatMessageStars() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_MSG_STARS"))
+}
+
+// FormatScheduledMessages Formats the collection for the messages waiting for their send time
+func (db *DB) FormatScheduledMessages() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_SCHEDULED_MSGS"))
+}
+
+// FormatConversationTimers Formats the collection for the timers of the disappearing messages of the private conversations
+func (db *DB) FormatConversationTimers() *mongo.Collection {
+	return db.Client.Database(db.Database).Collection(os.Getenv("DB_CONV_TIMERS"))
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+GetGroupAuditEP
+Returns the audit log of the group, newest first, only visible to the admins
+*/
+func GetGroupAuditEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), r.URL.Query().Get("ai"), models.PERMISSION_VIEW_AUDIT)
+	if !ok {
+		return
+	}
+
+	entries, err := db.GetAuditEntriesDB(pg, group.GroupID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(entries, server.OK, "ok"))
+}
+
+/*
+recordAudit
+stores the administrative action on the audit log of the group, the action is
+already applied so a failure is only logged
+*/
+func recordAudit(db database.DBHUB, group *models.Group, actor, action string, before, after map[string]any, targets ...primitive.ObjectID) {
+
+	alog := logger.StartLogger()
+
+	actorID, _ := primitive.ObjectIDFromHex(actor)
+
+	_, err := db.InsertAuditEntryDB(models.FormatAuditEntry(group.GroupID, actorID, action, before, after, targets...))
+	if err != nil {
+		alog.ErrorLog(fmt.Sprintf("audit entry %s of group %s not stored: %v", action, group.GroupID, err))
+		return
+	}
+
+	alog.InfoLogger(fmt.Sprintf("user %s: %s on group %s", actor, action, group.GroupID))
+}
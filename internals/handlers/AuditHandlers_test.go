@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestGetGroupAuditEP tests the handler GetGroupAuditEP
+func TestGetGroupAuditEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("GetGroupAuditEP - Success", func(mt *mtest.T) {
+
+		var page int
+		var groupID string
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			GetAuditEntriesDBMockFunc: func(pg int, gi string) ([]*models.AuditEntry, error) {
+				page, groupID = pg, gi
+				entry := models.FormatAuditEntry(gi, MockObjectID, models.AUDIT_INFO_EDITED, map[string]any{"name": "old"}, map[string]any{"name": "new"})
+				return []*models.AuditEntry{&entry}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupAuditEP, db, http.MethodGet, "/gau?gi=123456789&pg=2&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 2, page)
+		assert.Equal(t, "123456789", groupID)
+
+		entries := res.DATA.([]any)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.AUDIT_INFO_EDITED, entries[0].(map[string]any)["action"])
+		assert.Equal(t, "new", entries[0].(map[string]any)["after"].(map[string]any)["name"])
+	})
+
+	mt.Run("GetGroupAuditEP - Error member denied", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupAuditEP, db, http.MethodGet, "/gau?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("GetGroupAuditEP - Error invalid page", func(mt *mtest.T) {
+
+		db := &DBMock{Client: mt.Client, DatabaseName: MockDBName}
+
+		rr, res := serveGroupRequest(t, GetGroupAuditEP, db, http.MethodGet, "/gau?gi=123456789&pg=0&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("GetGroupAuditEP - Error database", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			GetAuditEntriesDBMockFunc: func(pg int, gi string) ([]*models.AuditEntry, error) {
+				return nil, errors.New("database down")
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupAuditEP, db, http.MethodGet, "/gau?gi=123456789&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.DB_ERROR, res.Code)
+	})
+}
+
+// TestGroupAuditEntries tests that the administrative handlers record their actions
+func TestGroupAuditEntries(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	auditDB := func(mt *mtest.T, entries *[]models.AuditEntry) *DBMock {
+		return &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			InsertAuditEntryDBMockFunc: func(e models.AuditEntry) (string, error) {
+				*entries = append(*entries, e)
+				return e.ID.Hex(), nil
+			},
+		}
+	}
+
+	mt.Run("UpdateGroupAdminsEP - records the promotion", func(mt *mtest.T) {
+
+		var entries []models.AuditEntry
+		db := auditDB(mt, &entries)
+
+		rr, _ := serveGroupRequest(t, UpdateGroupAdminsEP, db, http.MethodPut, fmt.Sprintf("/uga?gi=123456789&ot=%s&ai=%s&ads=%s", OPERATION_ADD, MockObjectID.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.AUDIT_ADMINS_ADDED, entries[0].Action)
+		assert.Equal(t, "123456789", entries[0].GroupID)
+		assert.Equal(t, MockObjectID, entries[0].ActorID)
+		assert.Equal(t, []primitive.ObjectID{member}, entries[0].Targets)
+		assert.Equal(t, []primitive.ObjectID{MockObjectID}, entries[0].Before["admins"])
+		assert.ElementsMatch(t, []primitive.ObjectID{MockObjectID, member}, entries[0].After["admins"])
+	})
+
+	mt.Run("UpdateGroupParticipantsEP - records the removal", func(mt *mtest.T) {
+
+		var entries []models.AuditEntry
+		db := auditDB(mt, &entries)
+
+		rr, _ := serveGroupRequest(t, UpdateGroupParticipantsEP, db, http.MethodPut, fmt.Sprintf("/igp?gi=123456789&ot=%s&ai=%s&usrs=%s", OPERATION_REMOVE, MockObjectID.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.AUDIT_MEMBERS_REMOVED, entries[0].Action)
+		assert.Equal(t, []primitive.ObjectID{MockObjectID, member}, entries[0].Before["participants"])
+		assert.Equal(t, []primitive.ObjectID{MockObjectID}, entries[0].After["participants"])
+	})
+
+	mt.Run("UpdateGroupInfoEP - records the old and new values", func(mt *mtest.T) {
+
+		var entries []models.AuditEntry
+		db := auditDB(mt, &entries)
+
+		rr, _ := serveGroupRequest(t, UpdateGroupInfoEP, db, http.MethodPut, "/ugi?ai="+MockObjectID.Hex(), models.Group{GroupID: "123456789", Name: "Wiser Wizards"})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.AUDIT_INFO_EDITED, entries[0].Action)
+		assert.Equal(t, "Wise Wizards", entries[0].Before["name"])
+		assert.Equal(t, "Wiser Wizards", entries[0].After["name"])
+	})
+
+	mt.Run("UpdateGroupPermissionsEP - records the permissions", func(mt *mtest.T) {
+
+		var entries []models.AuditEntry
+		db := auditDB(mt, &entries)
+
+		perms := models.DefaultGroupPermissions()
+		perms.SendMedia = models.GROUP_ROLE_ADMIN
+		rr, _ := serveGroupRequest(t, UpdateGroupPermissionsEP, db, http.MethodPut, "/ugpm?gi=123456789&ai="+MockObjectID.Hex(), perms)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.GROUP_ROLE_MEMBER, entries[0].Before[models.PERMISSION_SEND_MEDIA])
+		assert.Equal(t, models.GROUP_ROLE_ADMIN, entries[0].After[models.PERMISSION_SEND_MEDIA])
+	})
+
+	mt.Run("MuteGroupMemberEP - records the mute", func(mt *mtest.T) {
+
+		var entries []models.AuditEntry
+		db := auditDB(mt, &entries)
+
+		rr, _ := serveGroupRequest(t, MuteGroupMemberEP, db, http.MethodPut, fmt.Sprintf("/mgm?gi=123456789&ai=%s&ti=%s&du=60", MockObjectID.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.AUDIT_MEMBER_MUTED, entries[0].Action)
+		assert.Equal(t, []primitive.ObjectID{member}, entries[0].Targets)
+	})
+
+	mt.Run("UpdateGroupAdminsEP - denied actions are not recorded", func(mt *mtest.T) {
+
+		var entries []models.AuditEntry
+		db := auditDB(mt, &entries)
+
+		rr, _ := serveGroupRequest(t, UpdateGroupAdminsEP, db, http.MethodPut, fmt.Sprintf("/uga?gi=123456789&ot=%s&ai=%s&ads=%s", OPERATION_ADD, member.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Empty(t, entries)
+	})
+}
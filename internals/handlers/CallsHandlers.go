@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+	"wechat-back/internals/turn"
+)
+
+/*
+GetTURNCredentialsEP
+issues short lived TURN credentials so the user can relay its calls when it is behind NAT
+*/
+func GetTURNCredentialsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	creds, err := turn.NewCredentials(user.ID.Hex())
+	if err != nil {
+		alog.ErrorLog(fmt.Sprintf("turn credentials not issued: %v", err))
+		tools.WriteJSON(w, http.StatusServiceUnavailable, tools.FormatErrResponse(server.SERVICES_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(creds, server.OK, "ok"))
+}
+
+/*
+GetCallHistoryEP
+returns the calls of the user, newest first
+*/
+func GetCallHistoryEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	calls, err := db.GetCallHistoryDB(pg, user.ID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(calls, server.OK, "ok"))
+}
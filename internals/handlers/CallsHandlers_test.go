@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wechat-back/internals/decorators"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/turn"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestGetTURNCredentialsEP Tests the handler GetTURNCredentialsEP
+func TestGetTURNCredentialsEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetTURNCredentialsEP - Successful credentials", func(mt *mtest.T) {
+
+		t.Setenv("TURN_SECRET", "secret")
+		t.Setenv("TURN_URIS", "turn:turn.example.com:3478")
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: MockObjectID, Email: s}, true, nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/turn?ui=george@mail.com", nil)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+
+		handler := decorators.HandlerDecorator(GetTURNCredentialsEP, db)
+		handler.ServeHTTP(rr, req)
+
+		var res struct {
+			models.ServerResponse
+			DATA turn.Credentials `json:"data"`
+		}
+
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.False(t, res.Error)
+		assert.True(t, strings.HasSuffix(res.DATA.Username, ":"+MockObjectID.Hex()))
+		assert.Equal(t, []string{"turn:turn.example.com:3478"}, res.DATA.URIs)
+		assert.True(t, turn.ValidateCredentials("secret", res.DATA.Username, res.DATA.Password, time.Now()))
+	})
+
+	mt.Run("GetTURNCredentialsEP - Error user not found", func(mt *mtest.T) {
+
+		t.Setenv("TURN_SECRET", "secret")
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/turn?ui=ghost@mail.com", nil)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+
+		handler := decorators.HandlerDecorator(GetTURNCredentialsEP, db)
+		handler.ServeHTTP(rr, req)
+
+		var res models.ServerResponse
+
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.True(t, res.Error)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("GetTURNCredentialsEP - Error turn not configured", func(mt *mtest.T) {
+
+		t.Setenv("TURN_SECRET", "")
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: MockObjectID, Email: s}, true, nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/turn?ui=george@mail.com", nil)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+
+		handler := decorators.HandlerDecorator(GetTURNCredentialsEP, db)
+		handler.ServeHTTP(rr, req)
+
+		var res models.ServerResponse
+
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.True(t, res.Error)
+		assert.Equal(t, server.SERVICES_ERROR, res.Code)
+	})
+}
+
+// TestGetCallHistoryEP Tests the handler GetCallHistoryEP
+func TestGetCallHistoryEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetCallHistoryEP - Successful history", func(mt *mtest.T) {
+
+		var requested int
+		var owner primitive.ObjectID
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: MockObjectID, Email: s}, true, nil
+			},
+			GetCallHistoryDBMockFunc: func(pg int, user primitive.ObjectID) ([]*models.CallLog, error) {
+				requested = pg
+				owner = user
+				return []*models.CallLog{
+					{CallerID: MockObjectID, CallRecord: models.CallRecord{CallID: "node.1", State: models.CALL_STATE_MISSED}},
+				}, nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/clh?ui=george@mail.com&pg=2", nil)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+
+		handler := decorators.HandlerDecorator(GetCallHistoryEP, db)
+		handler.ServeHTTP(rr, req)
+
+		var res struct {
+			models.ServerResponse
+			DATA []models.CallLog `json:"data"`
+		}
+
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.False(t, res.Error)
+		assert.Equal(t, 2, requested)
+		assert.Equal(t, MockObjectID, owner)
+		assert.Len(t, res.DATA, 1)
+		assert.Equal(t, models.CALL_STATE_MISSED, res.DATA[0].State)
+	})
+
+	mt.Run("GetCallHistoryEP - Error invalid page", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/clh?ui=george@mail.com&pg=first", nil)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+
+		handler := decorators.HandlerDecorator(GetCallHistoryEP, db)
+		handler.ServeHTTP(rr, req)
+
+		var res models.ServerResponse
+
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("GetCallHistoryEP - Error database", func(mt *mtest.T) {
+
+		expectedError := errors.New("there was an error with the cursor")
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: MockObjectID, Email: s}, true, nil
+			},
+			GetCallHistoryDBMockFunc: func(pg int, user primitive.ObjectID) ([]*models.CallLog, error) {
+				return nil, expectedError
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/clh?ui=george@mail.com", nil)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+
+		handler := decorators.HandlerDecorator(GetCallHistoryEP, db)
+		handler.ServeHTTP(rr, req)
+
+		var res models.ServerResponse
+
+		err = json.NewDecoder(rr.Body).Decode(&res)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.True(t, res.Error)
+		assert.EqualError(t, expectedError, res.Message)
+		assert.Equal(t, server.DB_ERROR, res.Code)
+	})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+ForwardMessageEP
+Forwards the message (mi), of the group (gi) or of a private conversation, to the
+groups and the private conversations of the body. The user must read the message
+and post on every destination, the copies keep the media of the message and are
+stored on every destination or on none
+*/
+func ForwardMessageEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	messageID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("mi"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	var destinations models.ForwardRequest
+
+	err = tools.ReadJSON(w, r, &destinations)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	err = destinations.Validate(user.ID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	content, ok := forwardSource(w, db, r.URL.Query().Get("gi"), messageID, user.ID)
+	if !ok {
+		return
+	}
+
+	permission := models.PERMISSION_SEND_MEDIA
+	if content.BodyType == models.MESSAGE_TYPE_TEXT {
+		permission = models.PERMISSION_SEND_MESSAGES
+	}
+
+	now := time.Now()
+
+	groups := make([]*models.Group, 0, len(destinations.Groups))
+	for _, groupID := range destinations.Groups {
+
+		group, ok := authorizeGroup(w, db, groupID, user.ID.Hex(), permission)
+		if !ok {
+			return
+		}
+
+		err := group.CheckPost(user.ID, now)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusForbidden, tools.FormatErrResponse(server.ModerationCode(err), err))
+			return
+		}
+
+		groups = append(groups, group)
+	}
+
+	users, err := db.GetUsersByIDDB(destinations.Users)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+	if len(users) != len(destinations.Users) {
+		alog.ErrorLog(fmt.Sprintf("%d of the %d users to forward to do not exist", len(destinations.Users)-len(users), len(destinations.Users)))
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("user not found", server.NO_DOCUMENTS))
+		return
+	}
+
+	// the forward is a post on every group, it goes through their slow mode
+	for _, group := range groups {
+
+		err := server.ClaimSlowMode(db, group, user.ID, now)
+		if errors.Is(err, models.ErrSlowMode) {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusForbidden, tools.FormatErrResponse(server.SLOW_MODE, err))
+			return
+		} else if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+	}
+
+	// every copy follows the timer of its destination
+	groupLogs := make([]models.GroupChatContentLog, 0, len(groups))
+	for _, group := range groups {
+		log := content.GroupLog(group.ID, user, now)
+		log.ExpireAt = models.MessageExpiry(group.MessageTimer, now)
+		groupLogs = append(groupLogs, log)
+	}
+
+	p2pLogs := make([]models.P2PContentChatLog, 0, len(users))
+	for _, target := range users {
+
+		timer, err := db.GetP2PMessageTimerDB(models.P2PConversationID(user.ID, target.ID))
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+
+		log := content.P2PLog(target.ID, user, now)
+		log.ExpireAt = models.MessageExpiry(timer, now)
+		p2pLogs = append(p2pLogs, log)
+	}
+
+	err = db.InsertForwardedMessagesDB(groupLogs, p2pLogs)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	res := make([]models.ForwardedMessage, 0, len(groupLogs)+len(p2pLogs))
+
+	for i, log := range groupLogs {
+		if server.WebsocketHUB != nil {
+			server.WebsocketHUB.DeliverGroupMessage(groups[i].ID, groups[i].Participants, log)
+		}
+		res = append(res, models.ForwardedMessage{Conversation: models.CONVERSATION_GROUP, TargetID: log.TargetID, GroupID: groups[i].GroupID, MessageID: log.ID})
+	}
+
+	for _, log := range p2pLogs {
+		if server.WebsocketHUB != nil {
+			server.WebsocketHUB.DeliverP2PMessage(log.AuthorID, log.TargetID, log)
+		}
+		res = append(res, models.ForwardedMessage{Conversation: models.CONVERSATION_P2P, TargetID: log.TargetID, MessageID: log.ID})
+	}
+
+	alog.InfoLogger(fmt.Sprintf("user %s forwarded message %s to %d conversations", user.ID.Hex(), messageID.Hex(), len(res)))
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(res, server.OK, "ok"))
+}
+
+/*
+forwardSource
+reads the content of the message to forward, the user must be a member of the
+group (gi) of group messages and the author or the target of private ones.
+Writes the error response and returns false otherwise
+*/
+func forwardSource(w http.ResponseWriter, db database.DBHUB, groupID string, message, user primitive.ObjectID) (models.ForwardedContent, bool) {
+
+	alog := logger.StartLogger()
+
+	var content models.ForwardedContent
+
+	if groupID != "" {
+
+		// every member forwards the messages it reads
+		group, ok := authorizeGroup(w, db, groupID, user.Hex(), models.PERMISSION_SEND_MESSAGES)
+		if !ok {
+			return content, false
+		}
+
+		msg, err := db.GetGroupMessageDB(message)
+		if err == nil && msg.TargetID != group.ID {
+			err = mongo.ErrNoDocuments
+		}
+		if err == nil {
+			content, err = msg.ForwardedContent()
+		}
+		return content, writeForwardSourceErr(w, err)
+	}
+
+	msg, err := db.GetP2PMessageDB(message)
+	if err == nil && msg.AuthorID != user && msg.TargetID != user {
+		alog.WarningLogger(fmt.Sprintf("user %s is not part of the conversation of message %s", user.Hex(), message.Hex()))
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return content, false
+	}
+	if err == nil {
+		content, err = msg.ForwardedContent()
+	}
+	return content, writeForwardSourceErr(w, err)
+}
+
+// writeForwardSourceErr writes the response of the error reading the message to forward, returns true when there is none
+func writeForwardSourceErr(w http.ResponseWriter, err error) bool {
+
+	if err == nil {
+		return true
+	}
+
+	logger.StartLogger().ErrorLog(err.Error())
+
+	switch err {
+	case mongo.ErrNoDocuments:
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+	case models.ErrForwardType:
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+	default:
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+	}
+
+	return false
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestForwardMessageEP tests the handler ForwardMessageEP
+func TestForwardMessageEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	peer := primitive.NewObjectID()
+	message := primitive.NewObjectID()
+	source := inviteTestGroup(member)
+	destination := inviteTestGroup(member)
+	destination.GroupID = "987654321"
+
+	findMember := func(s string) (models.User, bool, error) {
+		return models.User{ID: member, Name: "alice"}, true, nil
+	}
+
+	getGroup := func(s string) (*models.Group, error) {
+		if s == destination.GroupID {
+			return destination, nil
+		}
+		return source, nil
+	}
+
+	getPeers := func(ids []primitive.ObjectID) ([]*models.User, error) {
+		res := []*models.User{}
+		for _, id := range ids {
+			if id == peer {
+				res = append(res, &models.User{ID: peer, Name: "bob"})
+			}
+		}
+		return res, nil
+	}
+
+	imageMessage := func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+		return &models.GroupChatContentLog{
+			ID:           oi,
+			TargetID:     source.ID,
+			AuthorID:     MockObjectID,
+			AuthorName:   "carol",
+			BodyType:     models.MESSAGE_TYPE_MEDIA_IMAGES,
+			Body:         "look",
+			ContentID:    "content",
+			Media:        []string{"https://cdn/image.png"},
+			Placeholders: []string{"https://cdn/thumb.png"},
+		}, nil
+	}
+
+	mt.Run("ForwardMessageEP - Success group message", func(mt *mtest.T) {
+
+		var groups []models.GroupChatContentLog
+		var users []models.P2PContentChatLog
+		db := &DBMock{
+			Client:                    mt.Client,
+			DatabaseName:              MockDBName,
+			FindUserMockFunc:          findMember,
+			GetGroupDBMockFunc:        getGroup,
+			GetGroupMessageDBMockFunc: imageMessage,
+			GetUsersByIDDBMockFunc:    getPeers,
+			InsertForwardedMessagesDBMockFunc: func(g []models.GroupChatContentLog, u []models.P2PContentChatLog) error {
+				groups, users = g, u
+				return nil
+			},
+		}
+
+		body := models.ForwardRequest{Groups: []string{destination.GroupID, destination.GroupID}, Users: []primitive.ObjectID{peer}}
+		rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&gi=123456789&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, res.DATA, 2)
+
+		assert.Len(t, groups, 1)
+		assert.Equal(t, destination.ID, groups[0].TargetID)
+		assert.Equal(t, member, groups[0].AuthorID)
+		assert.Equal(t, []string{"https://cdn/image.png"}, groups[0].Media)
+		assert.Equal(t, &models.MessageForward{MessageID: message, AuthorID: MockObjectID, AuthorName: "carol", Count: 1}, groups[0].Forward)
+
+		assert.Len(t, users, 1)
+		assert.Equal(t, peer, users[0].TargetID)
+		assert.Equal(t, "content", users[0].ContentID)
+		assert.Equal(t, []string{"https://cdn/thumb.png"}, users[0].Placeholders)
+		assert.NotEqual(t, groups[0].ID, users[0].ID)
+	})
+
+	mt.Run("ForwardMessageEP - Success forwarded private message", func(mt *mtest.T) {
+
+		origin := primitive.NewObjectID()
+
+		var users []models.P2PContentChatLog
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{
+					ID:       oi,
+					AuthorID: MockObjectID,
+					TargetID: member,
+					BodyType: models.MESSAGE_TYPE_TEXT,
+					Body:     "hello",
+					Forward:  &models.MessageForward{MessageID: origin, AuthorID: peer, AuthorName: "bob", Count: 2},
+				}, nil
+			},
+			GetUsersByIDDBMockFunc: getPeers,
+			InsertForwardedMessagesDBMockFunc: func(g []models.GroupChatContentLog, u []models.P2PContentChatLog) error {
+				users = u
+				return nil
+			},
+		}
+
+		body := models.ForwardRequest{Users: []primitive.ObjectID{peer}}
+		rr, _ := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, users, 1)
+		assert.Equal(t, "hello", users[0].Body)
+		assert.Equal(t, &models.MessageForward{MessageID: origin, AuthorID: peer, AuthorName: "bob", Count: 3}, users[0].Forward)
+	})
+
+	mt.Run("ForwardMessageEP - Error invalid destinations", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+		}
+
+		tooMany := models.ForwardRequest{}
+		for range models.MAX_FORWARD_DESTINATIONS + 1 {
+			tooMany.Users = append(tooMany.Users, primitive.NewObjectID())
+		}
+
+		for _, body := range []models.ForwardRequest{{}, tooMany, {Users: []primitive.ObjectID{member}}} {
+			rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&mi="+message.Hex(), body)
+
+			assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+			assert.Equal(t, server.BAD_FIELD, res.Code)
+		}
+	})
+
+	mt.Run("ForwardMessageEP - Error message that can not be forwarded", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:             mt.Client,
+			DatabaseName:       MockDBName,
+			FindUserMockFunc:   findMember,
+			GetGroupDBMockFunc: getGroup,
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: source.ID, BodyType: models.MESSAGE_TYPE_SYSTEM}, nil
+			},
+		}
+
+		body := models.ForwardRequest{Users: []primitive.ObjectID{peer}}
+		rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&gi=123456789&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("ForwardMessageEP - Error outsider of the private conversation", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: MockObjectID, TargetID: peer, BodyType: models.MESSAGE_TYPE_TEXT}, nil
+			},
+		}
+
+		body := models.ForwardRequest{Users: []primitive.ObjectID{peer}}
+		rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("ForwardMessageEP - Error destination group of another user", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				if s == destination.GroupID {
+					return inviteTestGroup(primitive.NewObjectID()), nil
+				}
+				return source, nil
+			},
+			GetGroupMessageDBMockFunc: imageMessage,
+			InsertForwardedMessagesDBMockFunc: func(g []models.GroupChatContentLog, u []models.P2PContentChatLog) error {
+				t.Fatal("message forwarded to a group of another user")
+				return nil
+			},
+		}
+
+		body := models.ForwardRequest{Groups: []string{destination.GroupID}}
+		rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&gi=123456789&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("ForwardMessageEP - Error muted on the destination group", func(mt *mtest.T) {
+
+		muted := inviteTestGroup(member)
+		muted.Mutes = map[string]time.Time{member.Hex(): time.Now().Add(time.Hour)}
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				if s == destination.GroupID {
+					return muted, nil
+				}
+				return source, nil
+			},
+			GetGroupMessageDBMockFunc: imageMessage,
+		}
+
+		body := models.ForwardRequest{Groups: []string{destination.GroupID}}
+		rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&gi=123456789&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.MEMBER_MUTED, res.Code)
+	})
+
+	mt.Run("ForwardMessageEP - Error slow mode of the destination group", func(mt *mtest.T) {
+
+		slow := inviteTestGroup(member)
+		slow.GroupID = destination.GroupID
+		slow.SlowMode = 60
+
+		var claimed time.Duration
+		inserted := false
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				if s == destination.GroupID {
+					return slow, nil
+				}
+				return source, nil
+			},
+			GetGroupMessageDBMockFunc: imageMessage,
+			GetUsersByIDDBMockFunc:    getPeers,
+			ClaimGroupPostDBMockFunc: func(i, u primitive.ObjectID, now time.Time, wait time.Duration) (time.Time, error) {
+				claimed = wait
+				return now.Add(-10 * time.Second), nil
+			},
+			InsertForwardedMessagesDBMockFunc: func(g []models.GroupChatContentLog, u []models.P2PContentChatLog) error {
+				inserted = true
+				return nil
+			},
+		}
+
+		body := models.ForwardRequest{Groups: []string{destination.GroupID}}
+		rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&gi=123456789&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.SLOW_MODE, res.Code)
+		assert.Equal(t, slow.SlowModeOf(member), claimed)
+		assert.False(t, inserted)
+	})
+
+	mt.Run("ForwardMessageEP - Error user not found", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:                    mt.Client,
+			DatabaseName:              MockDBName,
+			FindUserMockFunc:          findMember,
+			GetGroupDBMockFunc:        getGroup,
+			GetGroupMessageDBMockFunc: imageMessage,
+			GetUsersByIDDBMockFunc:    getPeers,
+		}
+
+		body := models.ForwardRequest{Users: []primitive.ObjectID{peer, primitive.NewObjectID()}}
+		rr, res := serveGroupRequest(t, ForwardMessageEP, db, http.MethodPost, "/mfw?ui=alice@mail.com&gi=123456789&mi="+message.Hex(), body)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+}
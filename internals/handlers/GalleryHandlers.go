@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+GetGroupGalleryEP
+Returns the images, videos and files shared on the group (gi), newest first,
+narrowed to a message type (ty). The next page is asked with the date (bf) and
+the id (bi) of the cursor the previous page returned
+*/
+func GetGroupGalleryEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	types, cursor, err := parseGalleryFilter(r)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	// every member reads the gallery
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), r.URL.Query().Get("ai"), models.PERMISSION_SEND_MESSAGES)
+	if !ok {
+		return
+	}
+
+	items, err := db.GetGroupMediaGalleryDB(group.ID, types, cursor)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(models.FormatMediaGallery(items), server.OK, "ok"))
+}
+
+/*
+GetP2PGalleryEP
+Returns the images, videos and files shared between the user (ai) and the
+target (ti), newest first, narrowed to a message type (ty). The next page is
+asked with the date (bf) and the id (bi) of the cursor the previous page returned
+*/
+func GetP2PGalleryEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	types, cursor, err := parseGalleryFilter(r)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ai"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	target, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ti"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	items, err := db.GetP2PMediaGalleryDB(user, target, types, cursor)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(models.FormatMediaGallery(items), server.OK, "ok"))
+}
+
+// parseGalleryFilter reads the message types and the page cursor of the gallery from the query params
+func parseGalleryFilter(r *http.Request) ([]int, models.GalleryCursor, error) {
+
+	var cursor models.GalleryCursor
+
+	bodyType := 0
+	if v := r.URL.Query().Get("ty"); v != "" {
+		t, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, cursor, models.ErrInvalidGalleryType
+		}
+		bodyType = t
+	}
+
+	types, err := models.GalleryTypes(bodyType)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	if v := r.URL.Query().Get("bf"); v != "" {
+		cursor.Before, err = time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, cursor, fmt.Errorf("invalid date %q", v)
+		}
+	}
+
+	if v := r.URL.Query().Get("bi"); v != "" {
+		cursor.ID, err = primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, cursor, fmt.Errorf("invalid id %q", v)
+		}
+	}
+
+	return types, cursor, nil
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// galleryItems full page of the gallery, one minute between the items
+func galleryItems(n int, newest time.Time) []*models.MediaGalleryItem {
+	items := []*models.MediaGalleryItem{}
+	for i := range n {
+		items = append(items, &models.MediaGalleryItem{
+			ID:           primitive.NewObjectID(),
+			BodyType:     models.MESSAGE_TYPE_MEDIA_IMAGES,
+			Media:        []string{"img.jpg"},
+			Placeholders: []string{"thumb.jpg"},
+			CreatedAt:    newest.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+	return items
+}
+
+// TestGetGroupGalleryEP tests the handler GetGroupGalleryEP
+func TestGetGroupGalleryEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+	newest := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mt.Run("GetGroupGalleryEP - Success full page", func(mt *mtest.T) {
+
+		var types []int
+		var cursor models.GalleryCursor
+		var items []*models.MediaGalleryItem
+		from := primitive.NewObjectID()
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMediaGalleryDBMockFunc: func(id primitive.ObjectID, ty []int, c models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+				types, cursor = ty, c
+				items = galleryItems(models.MEDIA_GALLERY_PAGE, newest)
+				return items, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupGalleryEP, db, http.MethodGet, "/ggl?gi=123456789&ai="+member.Hex()+"&ty=65&bf=2024-05-02T00:00:00Z&bi="+from.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []int{models.MESSAGE_TYPE_MEDIA_IMAGES}, types)
+		assert.Equal(t, 2, cursor.Before.Day())
+		assert.Equal(t, from, cursor.ID)
+
+		data := res.DATA.(map[string]any)
+		assert.Len(t, data["items"], models.MEDIA_GALLERY_PAGE)
+		next := data["next"].(map[string]any)
+		assert.Equal(t, "2024-05-01T11:31:00Z", next["before"])
+		assert.Equal(t, items[len(items)-1].ID.Hex(), next["id"])
+	})
+
+	mt.Run("GetGroupGalleryEP - Success last page", func(mt *mtest.T) {
+
+		var types []int
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMediaGalleryDBMockFunc: func(id primitive.ObjectID, ty []int, c models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+				types = ty
+				return galleryItems(3, newest), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupGalleryEP, db, http.MethodGet, "/ggl?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, types, 3)
+		data := res.DATA.(map[string]any)
+		assert.Len(t, data["items"], 3)
+		assert.NotContains(t, data, "next")
+	})
+
+	mt.Run("GetGroupGalleryEP - Error outsider", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetGroupGalleryEP, db, http.MethodGet, "/ggl?gi=123456789&ai="+primitive.NewObjectID().Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	mt.Run("GetGroupGalleryEP - Error invalid filters", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		for _, query := range []string{"&ty=8", "&ty=images", "&bf=yesterday", "&bi=nope"} {
+			rr, _ := serveGroupRequest(t, GetGroupGalleryEP, db, http.MethodGet, "/ggl?gi=123456789&ai="+member.Hex()+query, nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+}
+
+// TestGetP2PGalleryEP tests the handler GetP2PGalleryEP
+func TestGetP2PGalleryEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	user := primitive.NewObjectID()
+	peer := primitive.NewObjectID()
+
+	mt.Run("GetP2PGalleryEP - Success", func(mt *mtest.T) {
+
+		var asked [2]primitive.ObjectID
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetP2PMediaGalleryDBMockFunc: func(u, p primitive.ObjectID, ty []int, c models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+				asked = [2]primitive.ObjectID{u, p}
+				return galleryItems(1, time.Now()), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetP2PGalleryEP, db, http.MethodGet, "/pgl?ai="+user.Hex()+"&ti="+peer.Hex()+"&ty=22", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, [2]primitive.ObjectID{user, peer}, asked)
+		assert.Len(t, res.DATA.(map[string]any)["items"], 1)
+	})
+
+	mt.Run("GetP2PGalleryEP - Error invalid target", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		rr, _ := serveGroupRequest(t, GetP2PGalleryEP, db, http.MethodGet, "/pgl?ai="+user.Hex()+"&ti=nope", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	mt.Run("GetP2PGalleryEP - Error db", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetP2PMediaGalleryDBMockFunc: func(u, p primitive.ObjectID, ty []int, c models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+				return nil, errors.New("find failed")
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetP2PGalleryEP, db, http.MethodGet, "/pgl?ai="+user.Hex()+"&ti="+peer.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+	"wechat-back/internals/database"
 	"wechat-back/internals/decorators"
 	"wechat-back/internals/models"
 	"wechat-back/internals/server"
@@ -215,6 +217,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 			GroupID:     "123456789",
 			Name:        "Pirates fans",
 			Description: "Fan group of pirates. We share stories and more...",
+			Admins:      []primitive.ObjectID{MockObjectID},
 		}
 
 		db := &DBMock{
@@ -231,7 +234,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 		bod, err := json.Marshal(data)
 		assert.Nil(t, err)
 
-		req, err := http.NewRequest(http.MethodPost, "/cg", bytes.NewReader(bod))
+		req, err := http.NewRequest(http.MethodPost, "/cg?ai="+MockObjectID.Hex(), bytes.NewReader(bod))
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -271,7 +274,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 		bod, err := json.Marshal(data)
 		assert.Nil(t, err)
 
-		req, err := http.NewRequest(http.MethodPost, "/cg", bytes.NewReader(bod))
+		req, err := http.NewRequest(http.MethodPost, "/cg?ai="+MockObjectID.Hex(), bytes.NewReader(bod))
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -300,6 +303,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 			GroupID:     "123456789",
 			Name:        "Pirates fans",
 			Description: "Fan group of pirates. We share stories and more...",
+			Admins:      []primitive.ObjectID{MockObjectID},
 		}
 
 		db := &DBMock{
@@ -316,7 +320,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 		bod, err := json.Marshal(data)
 		assert.Nil(t, err)
 
-		req, err := http.NewRequest(http.MethodPost, "/cg", bytes.NewReader(bod))
+		req, err := http.NewRequest(http.MethodPost, "/cg?ai="+MockObjectID.Hex(), bytes.NewReader(bod))
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -347,6 +351,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 			GroupID:     "123456789",
 			Name:        "Pirates fans",
 			Description: "Fan group of pirates. We share stories and more...",
+			Admins:      []primitive.ObjectID{MockObjectID},
 		}
 
 		db := &DBMock{
@@ -363,7 +368,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 		bod, err := json.Marshal(data)
 		assert.Nil(t, err)
 
-		req, err := http.NewRequest(http.MethodPost, "/cg", bytes.NewReader(bod))
+		req, err := http.NewRequest(http.MethodPost, "/cg?ai="+MockObjectID.Hex(), bytes.NewReader(bod))
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -394,6 +399,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 			GroupID:     "123456789",
 			Name:        "Pirates fans",
 			Description: "Fan group of pirates. We share stories and more...",
+			Admins:      []primitive.ObjectID{MockObjectID},
 		}
 
 		db := &DBMock{
@@ -410,7 +416,7 @@ func TestUpdateGroupInfoEP(t *testing.T) {
 		bod, err := json.Marshal(data)
 		assert.Nil(t, err)
 
-		req, err := http.NewRequest(http.MethodPost, "/cg", bytes.NewReader(bod))
+		req, err := http.NewRequest(http.MethodPost, "/cg?ai="+MockObjectID.Hex(), bytes.NewReader(bod))
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -446,7 +452,7 @@ func TestUpdateGroupAdminsEP(t *testing.T) {
 		operationType := OPERATION_ADD
 		groupID := "123456789"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -498,7 +504,7 @@ func TestUpdateGroupAdminsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -551,7 +557,7 @@ func TestUpdateGroupAdminsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "not a group id"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -605,7 +611,7 @@ func TestUpdateGroupAdminsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "not a group id"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -659,7 +665,7 @@ func TestUpdateGroupAdminsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := ""
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -713,7 +719,7 @@ func TestUpdateGroupAdminsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := "not a valid primitive id"
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -767,7 +773,7 @@ func TestUpdateGroupAdminsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -828,7 +834,7 @@ func TestUpdateGroupParticipantsEP(t *testing.T) {
 		operationType := OPERATION_ADD
 		groupID := "123456789"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -880,7 +886,7 @@ func TestUpdateGroupParticipantsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -933,7 +939,7 @@ func TestUpdateGroupParticipantsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "not a group id"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -987,7 +993,7 @@ func TestUpdateGroupParticipantsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "not a group id"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -1041,7 +1047,7 @@ func TestUpdateGroupParticipantsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := ""
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -1095,7 +1101,7 @@ func TestUpdateGroupParticipantsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := "not a valid primitive id"
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -1149,7 +1155,7 @@ func TestUpdateGroupParticipantsEP(t *testing.T) {
 		operationType := OPERATION_REMOVE
 		groupID := "123456789"
 		targets := secondUser.Hex()
-		adminName := "jorge"
+		adminName := MockObjectID.Hex()
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -1206,6 +1212,7 @@ func TestDeleteGroupEP(t *testing.T) {
 	mt.Run("DeleteGroupEP - Success deletition", func(mt *mtest.T) {
 
 		groupID := "123456"
+		var deleteAt time.Time
 
 		DBDoc := &models.Group{
 			ID:           MockObjectID,
@@ -1222,12 +1229,13 @@ func TestDeleteGroupEP(t *testing.T) {
 			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
 				return DBDoc, nil
 			},
-			DeleteGroupDBMockFunc: func(s string) error {
+			ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
+				deleteAt = at
 				return nil
 			},
 		}
 
-		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?gi=%s", groupID), nil)
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?ai=%s&gi=%s", MockObjectID.Hex(), groupID), nil)
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -1242,10 +1250,11 @@ func TestDeleteGroupEP(t *testing.T) {
 		err = json.NewDecoder(rr.Body).Decode(&res)
 		assert.Nil(t, err)
 
-		assert.Equal(t, http.StatusContinue, rr.Code)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
 		assert.False(t, res.Error)
 		assert.Equal(t, server.COMPLETED, res.Code)
 		assert.NotNil(t, res.DATA)
+		assert.WithinDuration(t, time.Now().Add(models.DEFAULT_GROUP_DELETION_GRACE), deleteAt, time.Minute)
 
 	})
 
@@ -1268,12 +1277,12 @@ func TestDeleteGroupEP(t *testing.T) {
 			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
 				return DBDoc, nil
 			},
-			DeleteGroupDBMockFunc: func(s string) error {
+			ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
 				return nil
 			},
 		}
 
-		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?gi=%s", groupID), nil)
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?ai=%s&gi=%s", MockObjectID.Hex(), groupID), nil)
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -1306,12 +1315,12 @@ func TestDeleteGroupEP(t *testing.T) {
 			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
 				return nil, expectedErr
 			},
-			DeleteGroupDBMockFunc: func(s string) error {
+			ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
 				return nil
 			},
 		}
 
-		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?gi=%s", groupID), nil)
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?ai=%s&gi=%s", MockObjectID.Hex(), groupID), nil)
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -1355,12 +1364,12 @@ func TestDeleteGroupEP(t *testing.T) {
 			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
 				return DBDoc, nil
 			},
-			DeleteGroupDBMockFunc: func(s string) error {
+			ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
 				return expectedError
 			},
 		}
 
-		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?gi=%s", groupID), nil)
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/dgp?ai=%s&gi=%s", MockObjectID.Hex(), groupID), nil)
 		assert.Nil(t, err)
 
 		req.Header.Set("Content-Type", "application/json")
@@ -1394,37 +1403,31 @@ func TestSearchGroupsEP(t *testing.T) {
 
 		groupID := "1245678"
 
-		results := []*models.Group{
+		results := []*models.PublicGroup{
 			{
-				ID:           MockObjectID,
-				GroupID:      groupID,
-				Name:         "Wise Wizards",
-				Description:  "Group about wizards",
-				Participants: []primitive.ObjectID{MockObjectID},
-				Admins:       []primitive.ObjectID{MockObjectID},
+				GroupID:     groupID,
+				Name:        "Wise Wizards",
+				Description: "Group about wizards",
+				MemberCount: 1,
 			},
 			{
-				ID:           MockObjectID,
-				GroupID:      groupID,
-				Name:         "Pets mexico",
-				Description:  "Group about wizards",
-				Participants: []primitive.ObjectID{MockObjectID},
-				Admins:       []primitive.ObjectID{MockObjectID},
+				GroupID:     groupID,
+				Name:        "Pets mexico",
+				Description: "Group about wizards",
+				MemberCount: 1,
 			},
 			{
-				ID:           MockObjectID,
-				GroupID:      groupID,
-				Name:         "Cooking recipies",
-				Description:  "Group about wizards",
-				Participants: []primitive.ObjectID{MockObjectID},
-				Admins:       []primitive.ObjectID{MockObjectID},
+				GroupID:     groupID,
+				Name:        "Cooking recipies",
+				Description: "Group about wizards",
+				MemberCount: 1,
 			},
 		}
 
 		db := &DBMock{
 			Client:       mt.Client,
 			DatabaseName: MockDBName,
-			SearchGroupsMockFunc: func(i int, s string) ([]*models.Group, error) {
+			SearchGroupsMockFunc: func(i int, f models.GroupDirectoryFilter) ([]*models.PublicGroup, error) {
 
 				return results, nil
 			},
@@ -1457,12 +1460,12 @@ func TestSearchGroupsEP(t *testing.T) {
 	mt.Run("SearchGroupsEP - Error no documents", func(mt *mtest.T) {
 		expectedError := mongo.ErrNilCursor
 
-		results := []*models.Group{}
+		results := []*models.PublicGroup{}
 
 		db := &DBMock{
 			Client:       mt.Client,
 			DatabaseName: MockDBName,
-			SearchGroupsMockFunc: func(i int, s string) ([]*models.Group, error) {
+			SearchGroupsMockFunc: func(i int, f models.GroupDirectoryFilter) ([]*models.PublicGroup, error) {
 
 				return results, expectedError
 			},
@@ -1497,37 +1500,31 @@ func TestSearchGroupsEP(t *testing.T) {
 		groupID := "1245678"
 		expectedError := errors.New("there was an error with the cursor")
 
-		results := []*models.Group{
+		results := []*models.PublicGroup{
 			{
-				ID:           MockObjectID,
-				GroupID:      groupID,
-				Name:         "Wise Wizards",
-				Description:  "Group about wizards",
-				Participants: []primitive.ObjectID{MockObjectID},
-				Admins:       []primitive.ObjectID{MockObjectID},
+				GroupID:     groupID,
+				Name:        "Wise Wizards",
+				Description: "Group about wizards",
+				MemberCount: 1,
 			},
 			{
-				ID:           MockObjectID,
-				GroupID:      groupID,
-				Name:         "Pets mexico",
-				Description:  "Group about wizards",
-				Participants: []primitive.ObjectID{MockObjectID},
-				Admins:       []primitive.ObjectID{MockObjectID},
+				GroupID:     groupID,
+				Name:        "Pets mexico",
+				Description: "Group about wizards",
+				MemberCount: 1,
 			},
 			{
-				ID:           MockObjectID,
-				GroupID:      groupID,
-				Name:         "Cooking recipies",
-				Description:  "Group about wizards",
-				Participants: []primitive.ObjectID{MockObjectID},
-				Admins:       []primitive.ObjectID{MockObjectID},
+				GroupID:     groupID,
+				Name:        "Cooking recipies",
+				Description: "Group about wizards",
+				MemberCount: 1,
 			},
 		}
 
 		db := &DBMock{
 			Client:       mt.Client,
 			DatabaseName: MockDBName,
-			SearchGroupsMockFunc: func(i int, s string) ([]*models.Group, error) {
+			SearchGroupsMockFunc: func(i int, f models.GroupDirectoryFilter) ([]*models.PublicGroup, error) {
 
 				return results, expectedError
 			},
@@ -1559,3 +1556,849 @@ func TestSearchGroupsEP(t *testing.T) {
 	})
 
 }
+
+// TestGroupPermissions tests that the group handlers enforce the role of the caller
+func TestGroupPermissions(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	owner := MockObjectID
+	admin := primitive.NewObjectID()
+	member := primitive.NewObjectID()
+	outsider := primitive.NewObjectID()
+
+	newGroup := func(perms models.GroupPermissions) *models.Group {
+		return &models.Group{
+			ID:           primitive.NewObjectID(),
+			GroupID:      "123456789",
+			Name:         "Wise Wizards",
+			OwnerID:      owner,
+			Admins:       []primitive.ObjectID{owner, admin},
+			Participants: []primitive.ObjectID{owner, admin, member},
+			Permissions:  perms,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		body     any
+		handler  func(http.ResponseWriter, *http.Request, *DBMock)
+		perms    models.GroupPermissions
+		expected int
+	}{
+		{"edit info - admin allowed", http.MethodPost, "/cg?ai=" + admin.Hex(), models.Group{Name: "new"}, wrap(UpdateGroupInfoEP), models.GroupPermissions{}, http.StatusOK},
+		{"edit info - member denied", http.MethodPost, "/cg?ai=" + member.Hex(), models.Group{Name: "new"}, wrap(UpdateGroupInfoEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"edit info - member allowed by permissions", http.MethodPost, "/cg?ai=" + member.Hex(), models.Group{Name: "new"}, wrap(UpdateGroupInfoEP), models.GroupPermissions{EditInfo: models.GROUP_ROLE_MEMBER}, http.StatusOK},
+		{"edit info - outsider denied", http.MethodPost, "/cg?ai=" + outsider.Hex(), models.Group{Name: "new"}, wrap(UpdateGroupInfoEP), models.GroupPermissions{EditInfo: models.GROUP_ROLE_MEMBER}, http.StatusForbidden},
+		{"edit info - bad actor id", http.MethodPost, "/cg?ai=jorge", models.Group{Name: "new"}, wrap(UpdateGroupInfoEP), models.GroupPermissions{}, http.StatusBadRequest},
+		{"add members - admin allowed", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", admin.Hex(), OPERATION_ADD, outsider.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{}, http.StatusOK},
+		{"add members - member denied", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", member.Hex(), OPERATION_ADD, outsider.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"add members - member allowed by permissions", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", member.Hex(), OPERATION_ADD, outsider.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{AddMembers: models.GROUP_ROLE_MEMBER}, http.StatusOK},
+		{"add members - admin denied by permissions", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", admin.Hex(), OPERATION_ADD, outsider.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{AddMembers: models.GROUP_ROLE_OWNER}, http.StatusForbidden},
+		{"remove members - admin allowed", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", admin.Hex(), OPERATION_REMOVE, member.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{}, http.StatusOK},
+		{"remove members - member denied", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", member.Hex(), OPERATION_REMOVE, admin.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"remove members - admin can not remove admins", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", admin.Hex(), OPERATION_REMOVE, admin.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"remove members - owner can not be removed", http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", owner.Hex(), OPERATION_REMOVE, owner.Hex()), nil, wrap(UpdateGroupParticipantsEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"manage admins - owner allowed", http.MethodPut, fmt.Sprintf("/uchta?ai=%s&ot=%s&gi=1&ads=%s", owner.Hex(), OPERATION_ADD, member.Hex()), nil, wrap(UpdateGroupAdminsEP), models.GroupPermissions{}, http.StatusOK},
+		{"manage admins - admin denied", http.MethodPut, fmt.Sprintf("/uchta?ai=%s&ot=%s&gi=1&ads=%s", admin.Hex(), OPERATION_ADD, member.Hex()), nil, wrap(UpdateGroupAdminsEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"manage admins - target not a participant", http.MethodPut, fmt.Sprintf("/uchta?ai=%s&ot=%s&gi=1&ads=%s", owner.Hex(), OPERATION_ADD, outsider.Hex()), nil, wrap(UpdateGroupAdminsEP), models.GroupPermissions{}, http.StatusBadRequest},
+		{"manage admins - owner can not be demoted", http.MethodPut, fmt.Sprintf("/uchta?ai=%s&ot=%s&gi=1&ads=%s", owner.Hex(), OPERATION_REMOVE, owner.Hex()), nil, wrap(UpdateGroupAdminsEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"edit permissions - owner allowed", http.MethodPut, "/ugpm?gi=1&ai=" + owner.Hex(), models.GroupPermissions{SendMedia: models.GROUP_ROLE_ADMIN}, wrap(UpdateGroupPermissionsEP), models.GroupPermissions{}, http.StatusOK},
+		{"edit permissions - admin denied", http.MethodPut, "/ugpm?gi=1&ai=" + admin.Hex(), models.GroupPermissions{SendMedia: models.GROUP_ROLE_ADMIN}, wrap(UpdateGroupPermissionsEP), models.GroupPermissions{}, http.StatusForbidden},
+		{"edit permissions - invalid role", http.MethodPut, "/ugpm?gi=1&ai=" + owner.Hex(), models.GroupPermissions{SendMedia: "wizard"}, wrap(UpdateGroupPermissionsEP), models.GroupPermissions{}, http.StatusNotAcceptable},
+		{"delete group - owner allowed", http.MethodDelete, "/dgp?gi=1&ai=" + owner.Hex(), nil, wrap(DeleteGroupEP), models.GroupPermissions{}, http.StatusAccepted},
+		{"delete group - admin denied", http.MethodDelete, "/dgp?gi=1&ai=" + admin.Hex(), nil, wrap(DeleteGroupEP), models.GroupPermissions{}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		mt.Run("GroupPermissions - "+tt.name, func(mt *mtest.T) {
+
+			group := newGroup(tt.perms)
+
+			db := &DBMock{
+				Client:       mt.Client,
+				DatabaseName: MockDBName,
+				GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+					return group, nil
+				},
+				UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+					return nil
+				},
+				ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
+					return nil
+				},
+			}
+
+			var body io.Reader = http.NoBody
+			if tt.body != nil {
+				bod, err := json.Marshal(tt.body)
+				assert.Nil(t, err)
+				body = bytes.NewReader(bod)
+			}
+
+			req, err := http.NewRequest(tt.method, tt.url, body)
+			assert.Nil(t, err)
+
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req, db)
+
+			var res models.ServerResponse
+
+			err = json.NewDecoder(rr.Body).Decode(&res)
+			assert.Nil(t, err)
+
+			assert.Equal(t, tt.expected, rr.Code)
+			if tt.expected == http.StatusForbidden {
+				assert.Equal(t, server.NOT_ALLOWED, res.Code)
+			}
+		})
+	}
+
+	mt.Run("GroupPermissions - Removed members lose the admin role", func(mt *mtest.T) {
+
+		group := newGroup(models.GroupPermissions{})
+
+		var update map[string]any
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+				update = m
+				return nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", owner.Hex(), OPERATION_REMOVE, admin.Hex()), nil)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		UpdateGroupParticipantsEP(rr, req, db)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []primitive.ObjectID{owner, member}, update["participants"])
+		assert.Equal(t, []primitive.ObjectID{owner}, update["admins"])
+	})
+}
+
+// wrap adapts the handler to the mock database
+func wrap(handler func(http.ResponseWriter, *http.Request, database.DBHUB)) func(http.ResponseWriter, *http.Request, *DBMock) {
+	return func(w http.ResponseWriter, r *http.Request, db *DBMock) {
+		handler(w, r, db)
+	}
+}
+
+// TestGroupDirectory tests the handlers SearchGroupsEP, UpdateGroupDirectoryEP and GetGroupProfileEP
+func TestGroupDirectory(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	admin := primitive.NewObjectID()
+	member := primitive.NewObjectID()
+
+	directoryGroup := func(visibility string) *models.Group {
+		return &models.Group{
+			ID:           primitive.NewObjectID(),
+			GroupID:      "123456789",
+			Name:         "Wise Wizards",
+			OwnerID:      MockObjectID,
+			Admins:       []primitive.ObjectID{MockObjectID, admin},
+			Participants: []primitive.ObjectID{MockObjectID, admin, member},
+			Visibility:   visibility,
+		}
+	}
+
+	mt.Run("SearchGroupsEP - Category and tags filters", func(mt *mtest.T) {
+
+		var filter models.GroupDirectoryFilter
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			SearchGroupsMockFunc: func(i int, f models.GroupDirectoryFilter) ([]*models.PublicGroup, error) {
+				filter = f
+				return []*models.PublicGroup{}, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, SearchGroupsEP, db, http.MethodGet, "/sgp?q=wiz&ct=games&tg=Magic,+rpg+,magic", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "wiz", filter.Query)
+		assert.Equal(t, "games", filter.Category)
+		assert.Equal(t, []string{"magic", "rpg"}, filter.Tags)
+	})
+
+	mt.Run("SearchGroupsEP - Error too many tags", func(mt *mtest.T) {
+
+		db := &DBMock{Client: mt.Client, DatabaseName: MockDBName}
+
+		rr, res := serveGroupRequest(t, SearchGroupsEP, db, http.MethodGet, "/sgp?tg=a,b,c,d,e,f,g,h,i,j,k", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("UpdateGroupDirectoryEP - Owner changes the visibility", func(mt *mtest.T) {
+
+		var update map[string]any
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return directoryGroup(models.GROUP_VISIBILITY_PRIVATE), nil
+			},
+			UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+				update = m
+				return nil
+			},
+		}
+
+		settings := models.GroupDirectorySettings{Visibility: models.GROUP_VISIBILITY_PUBLIC, Category: "games", Tags: []string{"RPG"}}
+		rr, _ := serveGroupRequest(t, UpdateGroupDirectoryEP, db, http.MethodPut, "/ugd?gi=123456789&ai="+MockObjectID.Hex(), settings)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.GROUP_VISIBILITY_PUBLIC, update["visibility"])
+		assert.Equal(t, "games", update["category"])
+		assert.Equal(t, []string{"rpg"}, update["tags"])
+	})
+
+	mt.Run("UpdateGroupDirectoryEP - Admin changes the tags", func(mt *mtest.T) {
+
+		var update map[string]any
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return directoryGroup(models.GROUP_VISIBILITY_PUBLIC), nil
+			},
+			UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+				update = m
+				return nil
+			},
+		}
+
+		settings := models.GroupDirectorySettings{Visibility: models.GROUP_VISIBILITY_PUBLIC, Tags: []string{"magic"}}
+		rr, _ := serveGroupRequest(t, UpdateGroupDirectoryEP, db, http.MethodPut, "/ugd?gi=123456789&ai="+admin.Hex(), settings)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, update, "visibility")
+		assert.Equal(t, []string{"magic"}, update["tags"])
+	})
+
+	mt.Run("UpdateGroupDirectoryEP - Error admin can not change the visibility", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return directoryGroup(models.GROUP_VISIBILITY_HIDDEN), nil
+			},
+			UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+				t.Fatal("visibility updated by an admin")
+				return nil
+			},
+		}
+
+		settings := models.GroupDirectorySettings{Visibility: models.GROUP_VISIBILITY_PUBLIC}
+		rr, res := serveGroupRequest(t, UpdateGroupDirectoryEP, db, http.MethodPut, "/ugd?gi=123456789&ai="+admin.Hex(), settings)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("UpdateGroupDirectoryEP - Error invalid visibility", func(mt *mtest.T) {
+
+		db := &DBMock{Client: mt.Client, DatabaseName: MockDBName}
+
+		settings := models.GroupDirectorySettings{Visibility: "secret"}
+		rr, res := serveGroupRequest(t, UpdateGroupDirectoryEP, db, http.MethodPut, "/ugd?gi=123456789&ai="+MockObjectID.Hex(), settings)
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	profiles := []struct {
+		name       string
+		visibility string
+		user       primitive.ObjectID
+		expected   int
+	}{
+		{"public group", models.GROUP_VISIBILITY_PUBLIC, primitive.NewObjectID(), http.StatusOK},
+		{"private group", models.GROUP_VISIBILITY_PRIVATE, primitive.NewObjectID(), http.StatusOK},
+		{"hidden group to a participant", models.GROUP_VISIBILITY_HIDDEN, member, http.StatusOK},
+		{"hidden group to a stranger", models.GROUP_VISIBILITY_HIDDEN, primitive.NewObjectID(), http.StatusNotFound},
+	}
+
+	for _, tt := range profiles {
+		mt.Run("GetGroupProfileEP - "+tt.name, func(mt *mtest.T) {
+
+			db := &DBMock{
+				Client:       mt.Client,
+				DatabaseName: MockDBName,
+				FindUserMockFunc: func(s string) (models.User, bool, error) {
+					return models.User{ID: tt.user}, true, nil
+				},
+				GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+					return directoryGroup(tt.visibility), nil
+				},
+			}
+
+			rr, res := serveGroupRequest(t, GetGroupProfileEP, db, http.MethodGet, "/gpf?gi=123456789&ui=ana@mail.com", nil)
+
+			assert.Equal(t, tt.expected, rr.Code)
+			if tt.expected == http.StatusOK {
+				profile := res.DATA.(map[string]any)
+				assert.Equal(t, float64(3), profile["member_count"])
+				assert.NotContains(t, profile, "participants")
+				assert.NotContains(t, profile, "admins")
+			}
+		})
+	}
+}
+
+// TestLeaveGroupEP tests the handler LeaveGroupEP
+func TestLeaveGroupEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	owner := MockObjectID
+	admin := primitive.NewObjectID()
+	member := primitive.NewObjectID()
+	newcomer := primitive.NewObjectID()
+
+	tests := []struct {
+		name         string
+		user         primitive.ObjectID
+		admins       []primitive.ObjectID
+		participants []primitive.ObjectID
+		expPromoted  primitive.ObjectID
+		expOwner     primitive.ObjectID
+		expEvents    []string
+	}{
+		{"member leaves", member, []primitive.ObjectID{owner, admin}, []primitive.ObjectID{owner, admin, member}, primitive.NilObjectID, primitive.NilObjectID, []string{models.SYSTEM_EVENT_MEMBER_LEFT}},
+		{"owner leaves to the oldest admin", owner, []primitive.ObjectID{owner, admin}, []primitive.ObjectID{owner, member, admin}, primitive.NilObjectID, admin, []string{models.SYSTEM_EVENT_MEMBER_LEFT, models.SYSTEM_EVENT_OWNERSHIP_TRANSFERRED}},
+		{"last admin promotes the longest-standing member", owner, []primitive.ObjectID{owner}, []primitive.ObjectID{owner, member, newcomer}, member, member, []string{models.SYSTEM_EVENT_MEMBER_LEFT, models.SYSTEM_EVENT_ADMIN_PROMOTED, models.SYSTEM_EVENT_OWNERSHIP_TRANSFERRED}},
+	}
+
+	for _, tt := range tests {
+		mt.Run("LeaveGroupEP - "+tt.name, func(mt *mtest.T) {
+
+			var leave models.GroupLeave
+			var events []string
+			db := &DBMock{
+				Client:       mt.Client,
+				DatabaseName: MockDBName,
+				FindUserMockFunc: func(s string) (models.User, bool, error) {
+					return models.User{ID: tt.user, Name: "ana"}, true, nil
+				},
+				GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+					return &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", OwnerID: owner, Admins: tt.admins, Participants: tt.participants}, nil
+				},
+				LeaveGroupDBMockFunc: func(oi primitive.ObjectID, l models.GroupLeave) error {
+					leave = l
+					return nil
+				},
+				ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
+					t.Fatal("group with participants deleted")
+					return nil
+				},
+				InsertGroupMessageDBMockFun: func(m any) (string, error) {
+					log := m.(models.GroupSystemLog)
+					assert.Equal(t, models.MESSAGE_TYPE_SYSTEM, log.BodyType)
+					events = append(events, log.Event)
+					return "", nil
+				},
+			}
+
+			rr, _ := serveGroupRequest(t, LeaveGroupEP, db, http.MethodPut, "/lgp?gi=123456789&ui=ana@mail.com", nil)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.user, leave.UserID)
+			assert.False(t, leave.Last)
+			assert.Equal(t, tt.expPromoted, leave.Promoted)
+			assert.Equal(t, tt.expOwner, leave.OwnerID)
+			assert.Equal(t, tt.expEvents, events)
+		})
+	}
+
+	mt.Run("LeaveGroupEP - Last participant deletes the group", func(mt *mtest.T) {
+
+		var leave models.GroupLeave
+		deleted := false
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: owner}, true, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", OwnerID: owner, Admins: []primitive.ObjectID{owner}, Participants: []primitive.ObjectID{owner}}, nil
+			},
+			LeaveGroupDBMockFunc: func(oi primitive.ObjectID, l models.GroupLeave) error {
+				leave = l
+				return nil
+			},
+			ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
+				deleted = leave.Last && !at.After(time.Now())
+				return nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, LeaveGroupEP, db, http.MethodPut, "/lgp?gi=123456789&ui=ana@mail.com", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, server.COMPLETED, res.Code)
+		assert.Equal(t, owner, leave.UserID)
+		assert.True(t, deleted)
+	})
+
+	mt.Run("LeaveGroupEP - Error group changed meanwhile", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: owner}, true, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", OwnerID: owner, Admins: []primitive.ObjectID{owner}, Participants: []primitive.ObjectID{owner, member}}, nil
+			},
+			LeaveGroupDBMockFunc: func(oi primitive.ObjectID, l models.GroupLeave) error {
+				return mongo.ErrNoDocuments
+			},
+			InsertGroupMessageDBMockFun: func(m any) (string, error) {
+				t.Fatal("system message written for a failed leave")
+				return "", nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, LeaveGroupEP, db, http.MethodPut, "/lgp?gi=123456789&ui=ana@mail.com", nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("LeaveGroupEP - Error not a participant", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: newcomer}, true, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, LeaveGroupEP, db, http.MethodPut, "/lgp?gi=123456789&ui=ana@mail.com", nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+}
+
+// TestTransferGroupOwnershipEP tests the handler TransferGroupOwnershipEP and the last admin safeguards
+func TestTransferGroupOwnershipEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	owner := MockObjectID
+	admin := primitive.NewObjectID()
+	member := primitive.NewObjectID()
+
+	newGroup := func() *models.Group {
+		return &models.Group{
+			ID:           primitive.NewObjectID(),
+			GroupID:      "123456789",
+			OwnerID:      owner,
+			Admins:       []primitive.ObjectID{owner, admin},
+			Participants: []primitive.ObjectID{owner, admin, member},
+		}
+	}
+
+	mt.Run("TransferGroupOwnershipEP - Success", func(mt *mtest.T) {
+
+		var from, to primitive.ObjectID
+		var event string
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return newGroup(), nil
+			},
+			TransferGroupOwnershipDBMockFunc: func(i, o, t primitive.ObjectID) error {
+				from, to = o, t
+				return nil
+			},
+			InsertGroupMessageDBMockFun: func(m any) (string, error) {
+				event = m.(models.GroupSystemLog).Event
+				return "", nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, TransferGroupOwnershipEP, db, http.MethodPut, fmt.Sprintf("/tgo?gi=1&ai=%s&ti=%s", owner.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, owner, from)
+		assert.Equal(t, member, to)
+		assert.Equal(t, member.Hex(), res.DATA.(map[string]any)["owner_id"])
+		assert.ElementsMatch(t, []any{owner.Hex(), admin.Hex(), member.Hex()}, res.DATA.(map[string]any)["admins"])
+		assert.Equal(t, models.SYSTEM_EVENT_OWNERSHIP_TRANSFERRED, event)
+	})
+
+	mt.Run("TransferGroupOwnershipEP - Error group changed", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return newGroup(), nil
+			},
+			TransferGroupOwnershipDBMockFunc: func(i, o, t primitive.ObjectID) error {
+				return mongo.ErrNoDocuments
+			},
+			InsertGroupMessageDBMockFun: func(m any) (string, error) {
+				t.Fatal("ownership announced")
+				return "", nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, TransferGroupOwnershipEP, db, http.MethodPut, fmt.Sprintf("/tgo?gi=1&ai=%s&ti=%s", owner.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	transfers := []struct {
+		name     string
+		actor    primitive.ObjectID
+		target   string
+		expected int
+	}{
+		{"admin denied", admin, member.Hex(), http.StatusForbidden},
+		{"target not a participant", owner, primitive.NewObjectID().Hex(), http.StatusBadRequest},
+		{"invalid target", owner, "jorge", http.StatusBadRequest},
+	}
+
+	for _, tt := range transfers {
+		mt.Run("TransferGroupOwnershipEP - Error "+tt.name, func(mt *mtest.T) {
+
+			db := &DBMock{
+				Client:       mt.Client,
+				DatabaseName: MockDBName,
+				GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+					return newGroup(), nil
+				},
+				TransferGroupOwnershipDBMockFunc: func(i, o, t2 primitive.ObjectID) error {
+					t.Fatal("ownership transferred")
+					return nil
+				},
+			}
+
+			rr, _ := serveGroupRequest(t, TransferGroupOwnershipEP, db, http.MethodPut, fmt.Sprintf("/tgo?gi=1&ai=%s&ti=%s", tt.actor.Hex(), tt.target), nil)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+
+	safeguards := []struct {
+		name    string
+		url     string
+		handler func(http.ResponseWriter, *http.Request, database.DBHUB)
+	}{
+		{"admins can not be emptied", fmt.Sprintf("/uchta?ai=%s&ot=%s&gi=1&ads=%s", admin.Hex(), OPERATION_REMOVE, admin.Hex()), UpdateGroupAdminsEP},
+		{"last admin can not be removed", fmt.Sprintf("/igp?ai=%s&ot=%s&gi=1&usrs=%s", admin.Hex(), OPERATION_REMOVE, admin.Hex()), UpdateGroupParticipantsEP},
+	}
+
+	for _, tt := range safeguards {
+		mt.Run("LastAdmin - "+tt.name, func(mt *mtest.T) {
+
+			// groups without owner are managed by their admins
+			db := &DBMock{
+				Client:       mt.Client,
+				DatabaseName: MockDBName,
+				GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+					return &models.Group{
+						ID:           primitive.NewObjectID(),
+						GroupID:      "123456789",
+						Admins:       []primitive.ObjectID{admin},
+						Participants: []primitive.ObjectID{admin, member},
+					}, nil
+				},
+				UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+					t.Fatal("group left without admins")
+					return nil
+				},
+			}
+
+			rr, res := serveGroupRequest(t, tt.handler, db, http.MethodPut, tt.url, nil)
+
+			assert.Equal(t, http.StatusConflict, rr.Code)
+			assert.Equal(t, server.NOT_ALLOWED, res.Code)
+		})
+	}
+}
+
+// avatarRequest builds the multipart request that uploads a new avatar
+func avatarRequest(t *testing.T, url string) *http.Request {
+
+	var body bytes.Buffer
+	writter := multipart.NewWriter(&body)
+
+	part, err := writter.CreateFormFile("avatar", "test.jpg")
+	assert.Nil(t, err)
+
+	_, err = part.Write([]byte("avatar"))
+	assert.Nil(t, err)
+	assert.Nil(t, writter.Close())
+
+	req, err := http.NewRequest(http.MethodPut, url, &body)
+	assert.Nil(t, err)
+
+	req.Header.Set("Content-Type", writter.FormDataContentType())
+	return req
+}
+
+// TestUpdateGroupAvatarEP tests the handler UpdateGroupAvatarEP
+func TestUpdateGroupAvatarEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	avatarGroup := func() *models.Group {
+		group := inviteTestGroup(member)
+		group.ProfileImage = "https://cdn/profiles/123456789.jpg"
+		return group
+	}
+
+	mt.Run("UpdateGroupAvatarEP - Success", func(mt *mtest.T) {
+
+		var stored models.GroupAvatar
+		var event string
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return avatarGroup(), nil
+			},
+			UpdateGroupAvatarDBMockFunc: func(oi primitive.ObjectID, a models.GroupAvatar) error {
+				stored = a
+				return nil
+			},
+			InsertGroupMessageDBMockFun: func(m any) (string, error) {
+				event = m.(models.GroupSystemLog).Event
+				return "", nil
+			},
+		}
+
+		var inserted string
+		var deleted []string
+		m := &media.MediaMock{
+			InsertGroupAvatarMockFunc: func(b []byte, s string) (string, error) {
+				inserted = s
+				return "https://cdn/profiles/" + s, nil
+			},
+			DeleteGroupAvatarMockFunc: func(s string) error {
+				deleted = append(deleted, s)
+				return nil
+			},
+		}
+
+		rr := httptest.NewRecorder()
+		decorators.HandlerWProvidersDecorator(UpdateGroupAvatarEP, db, m).ServeHTTP(rr, avatarRequest(t, "/ugav?gi=123456789&ai="+MockObjectID.Hex()))
+
+		var res models.ServerResponse
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(&res))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, fmt.Sprintf("123456789_%d.jpg", stored.Version), inserted)
+		assert.Equal(t, "https://cdn/profiles/"+inserted, stored.URL)
+		assert.Equal(t, MockObjectID, stored.AuthorID)
+		assert.Equal(t, []string{"123456789.jpg"}, deleted)
+		assert.Equal(t, models.SYSTEM_EVENT_AVATAR_CHANGED, event)
+		assert.Equal(t, stored.URL, res.DATA.(map[string]any)["profile_image"])
+	})
+
+	mt.Run("UpdateGroupAvatarEP - Error database keeps the previous avatar", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return avatarGroup(), nil
+			},
+			UpdateGroupAvatarDBMockFunc: func(oi primitive.ObjectID, a models.GroupAvatar) error {
+				return mongo.ErrClientDisconnected
+			},
+		}
+
+		var inserted string
+		var deleted []string
+		m := &media.MediaMock{
+			InsertGroupAvatarMockFunc: func(b []byte, s string) (string, error) {
+				inserted = s
+				return "https://cdn/profiles/" + s, nil
+			},
+			DeleteGroupAvatarMockFunc: func(s string) error {
+				deleted = append(deleted, s)
+				return nil
+			},
+		}
+
+		rr := httptest.NewRecorder()
+		decorators.HandlerWProvidersDecorator(UpdateGroupAvatarEP, db, m).ServeHTTP(rr, avatarRequest(t, "/ugav?gi=123456789&ai="+MockObjectID.Hex()))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, []string{inserted}, deleted)
+	})
+
+	mt.Run("UpdateGroupAvatarEP - Error member denied", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return avatarGroup(), nil
+			},
+		}
+
+		m := &media.MediaMock{
+			InsertGroupAvatarMockFunc: func(b []byte, s string) (string, error) {
+				t.Fatal("avatar stored for a member")
+				return "", nil
+			},
+		}
+
+		rr := httptest.NewRecorder()
+		decorators.HandlerWProvidersDecorator(UpdateGroupAvatarEP, db, m).ServeHTTP(rr, avatarRequest(t, "/ugav?gi=123456789&ai="+member.Hex()))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	mt.Run("UpdateGroupAvatarEP - Error no avatar", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return avatarGroup(), nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPut, "/ugav?gi=123456789&ai="+MockObjectID.Hex(), http.NoBody)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		decorators.HandlerWProvidersDecorator(UpdateGroupAvatarEP, db, &media.MediaMock{}).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+// TestRestoreGroupEP tests the handler RestoreGroupEP and the deletion grace period
+func TestRestoreGroupEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	admin := primitive.NewObjectID()
+
+	pendingGroup := func() *models.Group {
+		deleteAt := time.Now().Add(time.Hour)
+		return &models.Group{
+			ID:           primitive.NewObjectID(),
+			GroupID:      "123456789",
+			OwnerID:      MockObjectID,
+			Admins:       []primitive.ObjectID{MockObjectID, admin},
+			Participants: []primitive.ObjectID{MockObjectID, admin},
+			DeleteAt:     &deleteAt,
+		}
+	}
+
+	mt.Run("DeleteGroupEP - Error already scheduled", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return pendingGroup(), nil
+			},
+			ScheduleGroupDeletionDBMockFunc: func(oi primitive.ObjectID, at time.Time) error {
+				t.Fatal("deletion scheduled twice")
+				return nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, DeleteGroupEP, db, http.MethodDelete, "/dgp?gi=123456789&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("RestoreGroupEP - Success", func(mt *mtest.T) {
+
+		cancelled := false
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return pendingGroup(), nil
+			},
+			CancelGroupDeletionDBMockFunc: func(oi primitive.ObjectID) error {
+				cancelled = true
+				return nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, RestoreGroupEP, db, http.MethodPut, "/rgp?gi=123456789&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.True(t, cancelled)
+		assert.NotContains(t, res.DATA, "delete_at")
+	})
+
+	mt.Run("RestoreGroupEP - Error not scheduled", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return pendingGroup(), nil
+			},
+			CancelGroupDeletionDBMockFunc: func(oi primitive.ObjectID) error {
+				return mongo.ErrNoDocuments
+			},
+		}
+
+		rr, res := serveGroupRequest(t, RestoreGroupEP, db, http.MethodPut, "/rgp?gi=123456789&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("RestoreGroupEP - Error admin denied", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return pendingGroup(), nil
+			},
+			CancelGroupDeletionDBMockFunc: func(oi primitive.ObjectID) error {
+				t.Fatal("deletion undone by an admin")
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, RestoreGroupEP, db, http.MethodPut, "/rgp?gi=123456789&ai="+admin.Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
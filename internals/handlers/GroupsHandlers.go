@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"wechat-back/internals/database"
 	"wechat-back/internals/logger"
 	"wechat-back/internals/models"
@@ -51,16 +53,32 @@ func CreateNewGroupEP(w http.ResponseWriter, r *http.Request, db database.DBHUB,
 		return
 	}
 
+	if group.Visibility != "" && !models.ValidVisibility(group.Visibility) {
+		err = fmt.Errorf("invalid visibility %q", group.Visibility)
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	group.Tags, err = models.NormalizeTags(group.Tags)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
 	group = *models.FormatGroup(&group)
 
-	groupAvatarID, err := provider.InsertGroupAvatar(content, fmt.Sprintf("%s.jpg", group.GroupID))
+	avatar := models.FormatGroupAvatar(group.OwnerID)
+	avatar.URL, err = provider.InsertGroupAvatar(content, avatar.Filename(group.GroupID))
 	if err != nil {
 		alog.ErrorLog(err.Error())
 		tools.WriteJSON(w, http.StatusInternalServerError, tools.FormatErrResponse(server.SERVICES_ERROR, err))
 		return
 	}
 
-	group.ProfileImage = groupAvatarID
+	group.ProfileImage = avatar.URL
+	group.AvatarHistory = []models.GroupAvatar{avatar}
 	_, err = db.InsertGroupDB(group)
 	if err != nil {
 		alog.ErrorLog(err.Error())
@@ -107,6 +125,10 @@ func UpdateGroupInfoEP(w http.ResponseWriter, r *http.Request, db database.DBHUB
 		return
 	}
 
+	if !authorizeGroupAction(w, DBgroup, r.URL.Query().Get("ai"), models.PERMISSION_EDIT_INFO) {
+		return
+	}
+
 	update := make(map[string]any)
 	update["name"] = group.Name
 	update["description"] = group.Description
@@ -119,9 +141,16 @@ func UpdateGroupInfoEP(w http.ResponseWriter, r *http.Request, db database.DBHUB
 		return
 	}
 
-	DBgroup.ID = primitive.NilObjectID
+	recordAudit(db, DBgroup, r.URL.Query().Get("ai"), models.AUDIT_INFO_EDITED,
+		map[string]any{"name": DBgroup.Name, "description": DBgroup.Description},
+		map[string]any{"name": group.Name, "description": group.Description},
+	)
+
 	DBgroup.Name = group.Name
 	DBgroup.Description = group.Description
+	publishGroupUpdate(DBgroup)
+
+	DBgroup.ID = primitive.NilObjectID
 
 	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(DBgroup, server.OK, "ok"))
 
@@ -136,7 +165,7 @@ func UpdateGroupAdminsEP(w http.ResponseWriter, r *http.Request, db database.DBH
 
 	w.Header().Set("Content-Type", "application/json")
 
-	admin := r.URL.Query().Get("ai") // name
+	admin := r.URL.Query().Get("ai")
 	operationType := r.URL.Query().Get("ot")
 	gID := r.URL.Query().Get("gi")
 	targets := strings.Split(r.URL.Query().Get("ads"), ",")
@@ -158,6 +187,10 @@ func UpdateGroupAdminsEP(w http.ResponseWriter, r *http.Request, db database.DBH
 		return
 	}
 
+	if !authorizeGroupAction(w, DBgroup, admin, models.PERMISSION_MANAGE_ADMINS) {
+		return
+	}
+
 	// convert targets to primitive
 	tars := []primitive.ObjectID{}
 	for _, t := range targets {
@@ -171,6 +204,19 @@ func UpdateGroupAdminsEP(w http.ResponseWriter, r *http.Request, db database.DBH
 		tars = append(tars, tar)
 	}
 
+	for _, tar := range tars {
+		if operationType == OPERATION_ADD && DBgroup.RoleOf(tar) == "" {
+			alog.ErrorLog(fmt.Sprintf("user %s is not a participant", tar.Hex()))
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse(fmt.Sprintf("user %s is not a participant", tar.Hex()), server.BAD_FIELD))
+			return
+		}
+		if operationType != OPERATION_ADD && DBgroup.RoleOf(tar) == models.GROUP_ROLE_OWNER {
+			alog.ErrorLog("the owner can not be removed from the admins")
+			tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("the owner can not be removed from the admins", server.NOT_ALLOWED))
+			return
+		}
+	}
+
 	newAdmins := []primitive.ObjectID{}
 	update := make(map[string]any)
 	if operationType == OPERATION_ADD {
@@ -181,6 +227,12 @@ func UpdateGroupAdminsEP(w http.ResponseWriter, r *http.Request, db database.DBH
 		update["admins"] = newAdmins
 	}
 
+	if len(newAdmins) == 0 {
+		alog.ErrorLog(fmt.Sprintf("group %s can not be left without admins", DBgroup.GroupID))
+		tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("the group needs at least one admin", server.NOT_ALLOWED))
+		return
+	}
+
 	err = db.UpdateGroupDB(update, DBgroup.ID)
 	if err != nil {
 		alog.ErrorLog(err.Error())
@@ -188,11 +240,16 @@ func UpdateGroupAdminsEP(w http.ResponseWriter, r *http.Request, db database.DBH
 		return
 	}
 
-	// notify all users that they have been added or remove as admins
-	alog.InfoLogger(fmt.Sprintf("admin %s has {operation} as admin", admin))
+	action := models.AUDIT_ADMINS_ADDED
+	if operationType != OPERATION_ADD {
+		action = models.AUDIT_ADMINS_REMOVED
+	}
+	recordAudit(db, DBgroup, admin, action, map[string]any{"admins": DBgroup.Admins}, map[string]any{"admins": newAdmins}, tars...)
 
-	DBgroup.ID = primitive.NilObjectID
 	DBgroup.Admins = newAdmins
+	publishGroupUpdate(DBgroup)
+
+	DBgroup.ID = primitive.NilObjectID
 
 	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(DBgroup, server.OK, "ok"))
 
@@ -208,7 +265,7 @@ func UpdateGroupParticipantsEP(w http.ResponseWriter, r *http.Request, db databa
 
 	w.Header().Set("Content-Type", "application/json")
 
-	admin := r.URL.Query().Get("ai") // name
+	admin := r.URL.Query().Get("ai")
 	operationType := r.URL.Query().Get("ot")
 	gID := r.URL.Query().Get("gi")
 	targets := strings.Split(r.URL.Query().Get("usrs"), ",")
@@ -230,6 +287,14 @@ func UpdateGroupParticipantsEP(w http.ResponseWriter, r *http.Request, db databa
 		return
 	}
 
+	permission := models.PERMISSION_ADD_MEMBERS
+	if operationType != OPERATION_ADD {
+		permission = models.PERMISSION_REMOVE_MEMBERS
+	}
+	if !authorizeGroupAction(w, DBgroup, admin, permission) {
+		return
+	}
+
 	// convert targets to primitive
 	tars := []primitive.ObjectID{}
 	for _, t := range targets {
@@ -243,14 +308,36 @@ func UpdateGroupParticipantsEP(w http.ResponseWriter, r *http.Request, db databa
 		tars = append(tars, tar)
 	}
 
+	before := map[string]any{"participants": DBgroup.Participants, "admins": DBgroup.Admins}
+
 	newParticipants := []primitive.ObjectID{}
 	update := make(map[string]any)
 	if operationType == OPERATION_ADD {
-		newParticipants = append(newParticipants, tools.AddSliceValues(DBgroup.Admins, tars...)...)
+		newParticipants = append(newParticipants, tools.AddSliceValues(DBgroup.Participants, tars...)...)
 		update["participants"] = newParticipants
 	} else {
-		newParticipants = append(newParticipants, tools.FilterSliceValues(DBgroup.Admins, tars)...)
+		actor, _ := primitive.ObjectIDFromHex(admin)
+		for _, tar := range tars {
+			// removing an admin is an admin management action
+			role := DBgroup.RoleOf(tar)
+			if role == models.GROUP_ROLE_OWNER || (role == models.GROUP_ROLE_ADMIN && !DBgroup.Can(actor, models.PERMISSION_MANAGE_ADMINS)) {
+				alog.ErrorLog(fmt.Sprintf("user %s can not remove %s %s", admin, role, tar.Hex()))
+				tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+				return
+			}
+		}
+
+		newParticipants = append(newParticipants, tools.FilterSliceValues(DBgroup.Participants, tars)...)
 		update["participants"] = newParticipants
+
+		admins := tools.FilterSliceValues(DBgroup.Admins, tars)
+		if len(admins) == 0 && len(newParticipants) > 0 {
+			alog.ErrorLog(fmt.Sprintf("group %s can not be left without admins", DBgroup.GroupID))
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("the group needs at least one admin", server.NOT_ALLOWED))
+			return
+		}
+		DBgroup.Admins = admins
+		update["admins"] = DBgroup.Admins
 	}
 
 	err = db.UpdateGroupDB(update, DBgroup.ID)
@@ -260,25 +347,169 @@ func UpdateGroupParticipantsEP(w http.ResponseWriter, r *http.Request, db databa
 		return
 	}
 
-	// notify all users that they have been added or remove as admins
-	alog.InfoLogger(fmt.Sprintf("admin %s has added {operation} as participants", admin))
+	action := models.AUDIT_MEMBERS_ADDED
+	if operationType != OPERATION_ADD {
+		action = models.AUDIT_MEMBERS_REMOVED
+	}
+	recordAudit(db, DBgroup, admin, action, before, map[string]any{"participants": newParticipants, "admins": DBgroup.Admins}, tars...)
 
-	DBgroup.ID = primitive.NilObjectID
 	DBgroup.Participants = newParticipants
+	publishGroupUpdate(DBgroup)
+
+	DBgroup.ID = primitive.NilObjectID
 
 	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(DBgroup, server.OK, "ok"))
 
 }
 
-/* FUNCTION TO BE IMPLEMENTED
-func UpdateGroupImage() {
+/*
+UpdateGroupPermissionsEP
+Updates the minimum role required for the configurable actions of the group
+*/
+func UpdateGroupPermissionsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var permissions models.GroupPermissions
+
+	err := tools.ReadJSON(w, r, &permissions)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	permissions = permissions.WithDefaults()
+	err = permissions.Validate()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	DBgroup, err := db.GetGroupDB(r.URL.Query().Get("gi"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	if !authorizeGroupAction(w, DBgroup, r.URL.Query().Get("ai"), models.PERMISSION_EDIT_PERMISSIONS) {
+		return
+	}
+
+	update := make(map[string]any)
+	update["permissions"] = permissions
+
+	err = db.UpdateGroupDB(update, DBgroup.ID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, DBgroup, r.URL.Query().Get("ai"), models.AUDIT_PERMISSIONS_CHANGED,
+		DBgroup.Permissions.WithDefaults().AuditValues(),
+		permissions.AuditValues(),
+	)
 
+	DBgroup.Permissions = permissions
+	publishGroupUpdate(DBgroup)
+
+	DBgroup.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(DBgroup, server.OK, "ok"))
 }
+
+/*
+UpdateGroupAvatarEP
+Replaces the avatar of the group, the new avatar gets a versioned url and the
+previous one is removed from the storage
 */
+func UpdateGroupAvatarEP(w http.ResponseWriter, r *http.Request, db database.DBHUB, provider media.MediaHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_EDIT_INFO)
+	if !ok {
+		return
+	}
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusInternalServerError, tools.FormatErrResponse(server.SERVICES_ERROR, err))
+		return
+	}
+
+	author, _ := primitive.ObjectIDFromHex(admin)
+	avatar := models.FormatGroupAvatar(author)
+
+	avatar.URL, err = provider.InsertGroupAvatar(content, avatar.Filename(group.GroupID))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusInternalServerError, tools.FormatErrResponse(server.SERVICES_ERROR, err))
+		return
+	}
+
+	err = db.UpdateGroupAvatarDB(group.ID, avatar)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		// the group keeps the previous avatar, the new object is not referenced
+		if err := provider.DeleteGroupAvatar(avatar.Filename(group.GroupID)); err != nil {
+			alog.ErrorLog(err.Error())
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	// a failed removal only leaves an orphan object, the group already points to the new avatar
+	previous := models.AvatarFilename(group.ProfileImage)
+	if previous != "" && previous != avatar.Filename(group.GroupID) {
+		if err := provider.DeleteGroupAvatar(previous); err != nil {
+			alog.ErrorLog(fmt.Sprintf("avatar %s of group %s not deleted: %v", previous, group.GroupID, err))
+		}
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_AVATAR_CHANGED, map[string]any{"profile_image": group.ProfileImage}, map[string]any{"profile_image": avatar.URL})
+
+	group.ProfileImage = avatar.URL
+	group.AvatarHistory = append(group.AvatarHistory, avatar)
+	if len(group.AvatarHistory) > models.MAX_AVATAR_HISTORY {
+		group.AvatarHistory = group.AvatarHistory[len(group.AvatarHistory)-models.MAX_AVATAR_HISTORY:]
+	}
+
+	publishGroupUpdate(group)
+	writeSystemMessage(db, group, author, author, "", models.SYSTEM_EVENT_AVATAR_CHANGED, "the group avatar was changed")
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
 
 /*
 DeleteGroupEP
-deletes all the chats logs and deletes the group
+schedules the deletion of the group, once the grace period is over the chat logs,
+media and connections of the group are removed with it
 */
 func DeleteGroupEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
 
@@ -305,17 +536,81 @@ func DeleteGroupEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
 		return
 	}
 
-	// delete chatlogs TO BE IMPLEMENTED
-	// CHATLOG CODE HERE
-	err = db.DeleteGroupDB(DBgroup.ID.Hex())
+	if !authorizeGroupAction(w, DBgroup, r.URL.Query().Get("ai"), models.PERMISSION_DELETE_GROUP) {
+		return
+	}
+
+	if DBgroup.PendingDeletion() {
+		alog.ErrorLog(fmt.Sprintf("group %s is already scheduled for deletion", DBgroup.GroupID))
+		tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("the group is already scheduled for deletion", server.NOT_ALLOWED))
+		return
+	}
+
+	deleteAt := time.Now().Add(models.GroupDeletionGrace())
+
+	err = db.ScheduleGroupDeletionDB(DBgroup.ID, deleteAt)
 	if err != nil {
 		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("the group is already scheduled for deletion", server.NOT_ALLOWED))
+			return
+		}
 		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
 		return
 	}
-	// return redirection
 
-	tools.WriteJSON(w, http.StatusContinue, tools.FormatSuccessResponse(DBgroup.GroupID, server.COMPLETED, "done"))
+	recordAudit(db, DBgroup, r.URL.Query().Get("ai"), models.AUDIT_DELETION_SCHEDULED, nil, map[string]any{"delete_at": deleteAt})
+
+	DBgroup.DeleteAt = &deleteAt
+	publishGroupUpdate(DBgroup)
+
+	event := models.GroupDeletionEvent{GroupID: DBgroup.GroupID, DeleteAt: &deleteAt}
+	notifyGroupMembers(DBgroup, DBgroup.Participants, models.EVENT_GROUP_DELETION_SCHEDULED, event)
+
+	tools.WriteJSON(w, http.StatusAccepted, tools.FormatSuccessResponse(event, server.COMPLETED, "deletion scheduled"))
+}
+
+/*
+RestoreGroupEP
+undoes the deletion of the group while its grace period is not over
+*/
+func RestoreGroupEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_DELETE_GROUP)
+	if !ok {
+		return
+	}
+
+	err := db.CancelGroupDeletionDB(group.ID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("the group is not scheduled for deletion", server.NOT_ALLOWED))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	var before map[string]any
+	if group.DeleteAt != nil {
+		before = map[string]any{"delete_at": *group.DeleteAt}
+	}
+	recordAudit(db, group, admin, models.AUDIT_DELETION_CANCELLED, before, nil)
+
+	group.DeleteAt = nil
+	publishGroupUpdate(group)
+	notifyGroupMembers(group, group.Participants, models.EVENT_GROUP_DELETION_CANCELLED, models.GroupDeletionEvent{GroupID: group.GroupID})
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
 }
 
 /*
@@ -340,9 +635,21 @@ func SearchGroupsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
 		return
 	}
 
-	query := r.URL.Query().Get("q")
+	filter := models.GroupDirectoryFilter{
+		Query:    r.URL.Query().Get("q"),
+		Category: r.URL.Query().Get("ct"),
+	}
+
+	if tags := r.URL.Query().Get("tg"); tags != "" {
+		filter.Tags, err = models.NormalizeTags(strings.Split(tags, ","))
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+			return
+		}
+	}
 
-	groups, err := db.SearchGroups(pg, query)
+	groups, err := db.SearchGroups(pg, filter)
 	if err != nil {
 		alog.ErrorLog(err.Error())
 		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
@@ -352,3 +659,274 @@ func SearchGroupsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
 	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(groups, server.OK, "ok"))
 
 }
+
+/*
+UpdateGroupDirectoryEP
+Updates the visibility, category and tags of the group, changing the visibility
+requires the permission to edit the permissions of the group
+*/
+func UpdateGroupDirectoryEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var settings models.GroupDirectorySettings
+
+	err := tools.ReadJSON(w, r, &settings)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	if settings.Visibility != "" && !models.ValidVisibility(settings.Visibility) {
+		err = fmt.Errorf("invalid visibility %q", settings.Visibility)
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	settings.Tags, err = models.NormalizeTags(settings.Tags)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_EDIT_INFO)
+	if !ok {
+		return
+	}
+
+	before := map[string]any{"visibility": group.Visibility, "category": group.Category, "tags": group.Tags}
+
+	update := make(map[string]any)
+	update["category"] = settings.Category
+	update["tags"] = settings.Tags
+
+	if settings.Visibility != "" && settings.Visibility != group.Visibility {
+		if !authorizeGroupAction(w, group, admin, models.PERMISSION_EDIT_PERMISSIONS) {
+			return
+		}
+		update["visibility"] = settings.Visibility
+		group.Visibility = settings.Visibility
+	}
+
+	err = db.UpdateGroupDB(update, group.ID)
+	if err != nil && err != database.ErrNoModified {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	group.Category = settings.Category
+	group.Tags = settings.Tags
+	recordAudit(db, group, admin, models.AUDIT_DIRECTORY_CHANGED, before, map[string]any{"visibility": group.Visibility, "category": group.Category, "tags": group.Tags})
+
+	publishGroupUpdate(group)
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
+
+/*
+GetGroupProfileEP
+Returns the public profile of the group, hidden groups are only visible to their participants
+*/
+func GetGroupProfileEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	group, err := db.GetGroupDB(r.URL.Query().Get("gi"))
+	if err != nil && err != mongo.ErrNoDocuments {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	// hidden groups answer as if they did not exist
+	if err == mongo.ErrNoDocuments || (group.Visibility == models.GROUP_VISIBILITY_HIDDEN && group.RoleOf(user.ID) == "") {
+		alog.ErrorLog(fmt.Sprintf("group %s not found", r.URL.Query().Get("gi")))
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, mongo.ErrNoDocuments))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group.Public(), server.OK, "ok"))
+}
+
+/*
+LeaveGroupEP
+Removes the user from the group, if the last admin leaves the longest-standing
+participant is promoted and if the owner leaves the ownership goes to the
+longest-standing admin, the group is deleted when its last participant leaves
+*/
+func LeaveGroupEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	group, err := db.GetGroupDB(r.URL.Query().Get("gi"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	if group.RoleOf(user.ID) == "" {
+		alog.ErrorLog(fmt.Sprintf("user %s is not a participant of group %s", user.ID.Hex(), group.GroupID))
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	leave := group.Leave(user.ID)
+
+	err = db.LeaveGroupDB(group.ID, leave)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("the group changed, try again", server.NOT_ALLOWED))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	// nobody is left to read the group, it is deleted without grace period
+	if leave.Last {
+		err = db.ScheduleGroupDeletionDB(group.ID, time.Now())
+		if err != nil && err != mongo.ErrNoDocuments {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+
+		tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group.GroupID, server.COMPLETED, "group deleted"))
+		return
+	}
+
+	publishGroupUpdate(group)
+
+	writeSystemMessage(db, group, user.ID, user.ID, user.Name, models.SYSTEM_EVENT_MEMBER_LEFT, fmt.Sprintf("%s left the group", user.Name))
+	if !leave.Promoted.IsZero() {
+		writeSystemMessage(db, group, user.ID, leave.Promoted, user.Name, models.SYSTEM_EVENT_ADMIN_PROMOTED, "the longest-standing participant is now an admin")
+	}
+	if !leave.OwnerID.IsZero() {
+		writeSystemMessage(db, group, user.ID, leave.OwnerID, user.Name, models.SYSTEM_EVENT_OWNERSHIP_TRANSFERRED, "the group has a new owner")
+	}
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
+
+/*
+TransferGroupOwnershipEP
+Hands the group to another participant, the new owner becomes admin and the
+previous owner stays as admin
+*/
+func TransferGroupOwnershipEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	target, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ti"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_TRANSFER_OWNERSHIP)
+	if !ok {
+		return
+	}
+
+	if group.RoleOf(target) == "" {
+		alog.ErrorLog(fmt.Sprintf("user %s is not a participant", target.Hex()))
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse(fmt.Sprintf("user %s is not a participant", target.Hex()), server.BAD_FIELD))
+		return
+	}
+
+	author, _ := primitive.ObjectIDFromHex(admin)
+
+	before := map[string]any{"owner_id": group.OwnerID, "admins": group.Admins}
+
+	group.OwnerID = target
+	group.Admins = tools.AddSliceValues(tools.FilterSliceValues(group.Admins, []primitive.ObjectID{target}), target)
+	if !slices.Contains(group.Admins, author) {
+		group.Admins = tools.AddSliceValues(group.Admins, author)
+	}
+
+	err = db.TransferGroupOwnershipDB(group.ID, author, target)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("the group changed, try again", server.NOT_ALLOWED))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_OWNERSHIP_TRANSFERRED, before, map[string]any{"owner_id": group.OwnerID, "admins": group.Admins}, target)
+
+	publishGroupUpdate(group)
+	writeSystemMessage(db, group, author, target, "", models.SYSTEM_EVENT_OWNERSHIP_TRANSFERRED, "the group has a new owner")
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
+
+// writeSystemMessage stores the system message on the group chat log and delivers it to the participants
+func writeSystemMessage(db database.DBHUB, group *models.Group, author, subject primitive.ObjectID, authorName, event, body string) {
+
+	var payload models.GroupSystemLog
+	payload.FormatSystemLog(group.ID, author, subject, authorName, event, body)
+
+	_, err := db.InsertGroupMessageDB(payload)
+	if err != nil {
+		logger.StartLogger().ErrorLog(fmt.Sprintf("system message %s of group %s not stored: %v", event, group.GroupID, err))
+		return
+	}
+
+	if server.WebsocketHUB != nil {
+		server.WebsocketHUB.DeliverGroupMessage(group.ID, group.Participants, payload)
+	}
+}
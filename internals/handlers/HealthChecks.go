@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"wechat-back/internals/logger"
+	"wechat-back/internals/server"
 	"wechat-back/internals/tools"
 )
 
@@ -15,3 +16,17 @@ func ServerHealthCheckEP(w http.ResponseWriter, r *http.Request) {
 	tools.WriteJSON(w, http.StatusOK, nil)
 
 }
+
+/*
+WorkerPoolMetricsEP
+returns the queue depth and latency of the websocket worker pool
+*/
+func WorkerPoolMetricsEP(w http.ResponseWriter, r *http.Request) {
+
+	if server.WebsocketHUB == nil {
+		tools.WriteJSON(w, http.StatusServiceUnavailable, tools.FormatCustomErrResponse("websocket service not started", server.SERVER_ERROR))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(server.WebsocketHUB.WorkerPool.Metrics(), server.OK, "ok"))
+}
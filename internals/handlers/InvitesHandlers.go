@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+CreateGroupInviteEP
+Creates an invite link of the group, the body sets the optional expiry and max uses
+*/
+func CreateGroupInviteEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var opts models.InviteOptions
+
+	err := tools.ReadJSON(w, r, &opts)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_ADD_MEMBERS)
+	if !ok {
+		return
+	}
+
+	author, _ := primitive.ObjectIDFromHex(admin)
+
+	invite, err := models.FormatGroupInvite(group.GroupID, author, opts)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	_, err = db.InsertGroupInviteDB(*invite)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_INVITE_CREATED, nil, map[string]any{"code": invite.Code, "max_uses": invite.MaxUses, "expires_at": invite.ExpiresAt})
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(invite, server.OK, "ok"))
+}
+
+/*
+GetGroupInvitesEP
+Returns the invite links of the group that have not been revoked
+*/
+func GetGroupInvitesEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), r.URL.Query().Get("ai"), models.PERMISSION_ADD_MEMBERS)
+	if !ok {
+		return
+	}
+
+	invites, err := db.GetGroupInvitesDB(group.GroupID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(invites, server.OK, "ok"))
+}
+
+/*
+RevokeGroupInviteEP
+Revokes an invite link of the group
+*/
+func RevokeGroupInviteEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_ADD_MEMBERS)
+	if !ok {
+		return
+	}
+
+	code := r.URL.Query().Get("ic")
+
+	invite, err := db.GetGroupInviteDB(code)
+	if err != nil && err != mongo.ErrNoDocuments {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+	if err == mongo.ErrNoDocuments || invite.GroupID != group.GroupID {
+		alog.ErrorLog(fmt.Sprintf("invite %s not found on group %s", code, group.GroupID))
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, mongo.ErrNoDocuments))
+		return
+	}
+
+	err = db.RevokeGroupInviteDB(code)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	invite.Revoked = true
+	recordAudit(db, group, admin, models.AUDIT_INVITE_REVOKED, map[string]any{"code": code, "revoked": false}, map[string]any{"code": code, "revoked": true})
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(invite, server.OK, "ok"))
+}
+
+/*
+JoinGroupEP
+Joins the user to the group of the invite link, when the group requires approval
+the join request is queued for the admins and 202 is returned
+*/
+func JoinGroupEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	code := r.URL.Query().Get("ic")
+
+	invite, err := db.GetGroupInviteDB(code)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	err = invite.Check(time.Now())
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusGone, tools.FormatErrResponse(server.NOT_ALLOWED, err))
+		return
+	}
+
+	group, err := db.GetGroupDB(invite.GroupID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	if group.RoleOf(user.ID) != "" {
+		group.ID = primitive.NilObjectID
+		tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "already a participant"))
+		return
+	}
+
+	if group.PendingDeletion() {
+		alog.ErrorLog(fmt.Sprintf("group %s is scheduled for deletion", group.GroupID))
+		tools.WriteJSON(w, http.StatusGone, tools.FormatCustomErrResponse("the group is scheduled for deletion", server.NOT_ALLOWED))
+		return
+	}
+
+	// a pending request was already counted on an invite, asking again does not use the link
+	if group.ApprovalRequired {
+
+		pending, err := db.GetPendingJoinRequestDB(group.GroupID, user.ID)
+		if err == nil {
+			tools.WriteJSON(w, http.StatusAccepted, tools.FormatSuccessResponse(pending, server.OK, "join request pending approval"))
+			return
+		} else if err != mongo.ErrNoDocuments {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+	}
+
+	// the use is counted atomically, the link could be exhausted or revoked since it was read
+	err = db.UseGroupInviteDB(code)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusGone, tools.FormatErrResponse(server.NOT_ALLOWED, models.ErrInviteExhausted))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	if group.ApprovalRequired {
+
+		request := models.FormatJoinRequest(group.GroupID, user, code)
+
+		id, err := db.InsertJoinRequestDB(request)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+
+		// a request of the user queued since the check, the admins were notified then
+		if id != "" {
+			notifyGroupMembers(group, group.Admins, models.EVENT_GROUP_JOIN_REQUESTED, models.GroupMemberEvent{
+				GroupID:   group.GroupID,
+				UserID:    user.ID,
+				Name:      user.Name,
+				RequestID: request.ID,
+			})
+		}
+
+		tools.WriteJSON(w, http.StatusAccepted, tools.FormatSuccessResponse(request, server.OK, "join request pending approval"))
+		return
+	}
+
+	err = addGroupMember(db, group, user.ID, user.Name)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
+
+/*
+GetJoinRequestsEP
+Returns the pending join requests of the group, oldest first
+*/
+func GetJoinRequestsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), r.URL.Query().Get("ai"), models.PERMISSION_ADD_MEMBERS)
+	if !ok {
+		return
+	}
+
+	requests, err := db.GetJoinRequestsDB(pg, group.GroupID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(requests, server.OK, "ok"))
+}
+
+/*
+ReviewJoinRequestEP
+Approves (ot=1) or rejects (ot=0) a pending join request of the group
+*/
+func ReviewJoinRequestEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	admin := r.URL.Query().Get("ai")
+	operationType := r.URL.Query().Get("ot")
+
+	if operationType != OPERATION_ADD && operationType != OPERATION_REMOVE {
+		alog.ErrorLog(fmt.Sprintf("invalid operation %q", operationType))
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse(fmt.Sprintf("invalid operation %q", operationType), server.BAD_FIELD))
+		return
+	}
+
+	requestID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ri"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_ADD_MEMBERS)
+	if !ok {
+		return
+	}
+
+	if group.PendingDeletion() {
+		alog.ErrorLog(fmt.Sprintf("group %s is scheduled for deletion", group.GroupID))
+		tools.WriteJSON(w, http.StatusGone, tools.FormatCustomErrResponse("the group is scheduled for deletion", server.NOT_ALLOWED))
+		return
+	}
+
+	request, err := db.GetJoinRequestDB(requestID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+	if err == mongo.ErrNoDocuments || request.GroupID != group.GroupID {
+		alog.ErrorLog(fmt.Sprintf("join request %s not found on group %s", requestID.Hex(), group.GroupID))
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, mongo.ErrNoDocuments))
+		return
+	}
+
+	state := models.JOIN_REQUEST_REJECTED
+	if operationType == OPERATION_ADD {
+		state = models.JOIN_REQUEST_APPROVED
+	}
+
+	reviewer, _ := primitive.ObjectIDFromHex(admin)
+
+	err = db.ReviewJoinRequestDB(requestID, state, reviewer)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("join request already reviewed", server.BAD_REQUEST))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_JOIN_REQUEST_REVIEWED, map[string]any{"state": request.State}, map[string]any{"state": state}, request.UserID)
+
+	request.State = state
+	request.ReviewedBy = reviewer
+	request.ReviewedAt = time.Now()
+
+	if state == models.JOIN_REQUEST_APPROVED {
+		err = addGroupMember(db, group, request.UserID, request.Name)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(request, server.OK, "ok"))
+}
+
+/*
+UpdateGroupApprovalEP
+Turns on (ar=true) or off (ar=false) the approval of the users that join through invite links
+*/
+func UpdateGroupApprovalEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	required, err := strconv.ParseBool(r.URL.Query().Get("ar"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_EDIT_PERMISSIONS)
+	if !ok {
+		return
+	}
+
+	update := make(map[string]any)
+	update["approval_required"] = required
+
+	err = db.UpdateGroupDB(update, group.ID)
+	if err != nil && err != database.ErrNoModified {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_APPROVAL_CHANGED, map[string]any{"approval_required": group.ApprovalRequired}, map[string]any{"approval_required": required})
+
+	group.ApprovalRequired = required
+	publishGroupUpdate(group)
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
+
+// addGroupMember adds the user to the group and tells the participants that the user joined
+func addGroupMember(db database.DBHUB, group *models.Group, user primitive.ObjectID, name string) error {
+
+	err := db.AddGroupParticipantDB(group.ID, user)
+	if err != nil {
+		return err
+	}
+
+	group.Participants = tools.AddSliceValues(group.Participants, user)
+	publishGroupUpdate(group)
+
+	notifyGroupMembers(group, group.Participants, models.EVENT_GROUP_MEMBER_JOINED, models.GroupMemberEvent{
+		GroupID: group.GroupID,
+		UserID:  user,
+		Name:    name,
+	})
+
+	return nil
+}
@@ -0,0 +1,601 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// serveGroupRequest runs the handler and decodes the server response
+func serveGroupRequest(t *testing.T, handler func(http.ResponseWriter, *http.Request, database.DBHUB), db database.DBHUB, method, url string, body any) (*httptest.ResponseRecorder, models.ServerResponse) {
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		bod, err := json.Marshal(body)
+		assert.Nil(t, err)
+		reader = bytes.NewReader(bod)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	assert.Nil(t, err)
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler(rr, req, db)
+
+	var res models.ServerResponse
+
+	err = json.NewDecoder(rr.Body).Decode(&res)
+	assert.Nil(t, err)
+
+	return rr, res
+}
+
+func inviteTestGroup(member primitive.ObjectID) *models.Group {
+	return &models.Group{
+		ID:           primitive.NewObjectID(),
+		GroupID:      "123456789",
+		Name:         "Wise Wizards",
+		OwnerID:      MockObjectID,
+		Admins:       []primitive.ObjectID{MockObjectID},
+		Participants: []primitive.ObjectID{MockObjectID, member},
+	}
+}
+
+// TestCreateGroupInviteEP tests the handler CreateGroupInviteEP
+func TestCreateGroupInviteEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("CreateGroupInviteEP - Success", func(mt *mtest.T) {
+
+		var stored models.GroupInvite
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			InsertGroupInviteDBMockFunc: func(i models.GroupInvite) (string, error) {
+				stored = i
+				return i.ID.Hex(), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CreateGroupInviteEP, db, http.MethodPost, "/gil?gi=123456789&ai="+MockObjectID.Hex(), models.InviteOptions{TTL: 3600, MaxUses: 10})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, server.OK, res.Code)
+		assert.Len(t, stored.Code, models.INVITE_CODE_LENGTH)
+		assert.Equal(t, "123456789", stored.GroupID)
+		assert.Equal(t, MockObjectID, stored.CreatedBy)
+		assert.Equal(t, 10, stored.MaxUses)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+	})
+
+	mt.Run("CreateGroupInviteEP - Error member not allowed", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CreateGroupInviteEP, db, http.MethodPost, "/gil?gi=123456789&ai="+member.Hex(), models.InviteOptions{})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("CreateGroupInviteEP - Error negative max uses", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CreateGroupInviteEP, db, http.MethodPost, "/gil?gi=123456789&ai="+MockObjectID.Hex(), models.InviteOptions{MaxUses: -1})
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("CreateGroupInviteEP - Error group not found", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return nil, mongo.ErrNoDocuments
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, CreateGroupInviteEP, db, http.MethodPost, "/gil?gi=123456789&ai="+MockObjectID.Hex(), models.InviteOptions{})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+// TestRevokeGroupInviteEP tests the handlers GetGroupInvitesEP and RevokeGroupInviteEP
+func TestRevokeGroupInviteEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("GetGroupInvitesEP - Success", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			GetGroupInvitesDBMockFunc: func(s string) ([]*models.GroupInvite, error) {
+				return []*models.GroupInvite{{Code: "abc123", GroupID: s}}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupInvitesEP, db, http.MethodGet, "/gil?gi=123456789&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, res.DATA, 1)
+	})
+
+	mt.Run("RevokeGroupInviteEP - Success", func(mt *mtest.T) {
+
+		revoked := ""
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			GetGroupInviteDBMockFunc: func(s string) (*models.GroupInvite, error) {
+				return &models.GroupInvite{Code: s, GroupID: "123456789"}, nil
+			},
+			RevokeGroupInviteDBMockFunc: func(s string) error {
+				revoked = s
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, RevokeGroupInviteEP, db, http.MethodDelete, "/gil?gi=123456789&ic=abc123&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "abc123", revoked)
+	})
+
+	mt.Run("RevokeGroupInviteEP - Error invite of another group", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			GetGroupInviteDBMockFunc: func(s string) (*models.GroupInvite, error) {
+				return &models.GroupInvite{Code: s, GroupID: "987654321"}, nil
+			},
+			RevokeGroupInviteDBMockFunc: func(s string) error {
+				t.Fatal("invite of another group revoked")
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, RevokeGroupInviteEP, db, http.MethodDelete, "/gil?gi=123456789&ic=abc123&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+// TestJoinGroupEP tests the handler JoinGroupEP
+func TestJoinGroupEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	newUser := models.User{ID: primitive.NewObjectID(), Name: "ana", Email: "ana@mail.com"}
+
+	joinDB := func(mt *mtest.T, group *models.Group, invite *models.GroupInvite) *DBMock {
+		return &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return newUser, true, nil
+			},
+			GetGroupInviteDBMockFunc: func(s string) (*models.GroupInvite, error) {
+				return invite, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+		}
+	}
+
+	mt.Run("JoinGroupEP - Success join", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		db := joinDB(mt, group, &models.GroupInvite{Code: "abc123", GroupID: group.GroupID})
+
+		used, added := false, primitive.NilObjectID
+		db.UseGroupInviteDBMockFunc = func(s string) error {
+			used = true
+			return nil
+		}
+		db.AddGroupParticipantDBMockFunc = func(g, u primitive.ObjectID) error {
+			added = u
+			return nil
+		}
+
+		rr, res := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, server.OK, res.Code)
+		assert.True(t, used)
+		assert.Equal(t, newUser.ID, added)
+		assert.Contains(t, group.Participants, newUser.ID)
+	})
+
+	mt.Run("JoinGroupEP - Approval required", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		group.ApprovalRequired = true
+		db := joinDB(mt, group, &models.GroupInvite{Code: "abc123", GroupID: group.GroupID})
+
+		var queued models.JoinRequest
+		db.InsertJoinRequestDBMockFunc = func(j models.JoinRequest) (string, error) {
+			queued = j
+			return j.ID.Hex(), nil
+		}
+		db.AddGroupParticipantDBMockFunc = func(g, u primitive.ObjectID) error {
+			t.Fatal("user added before approval")
+			return nil
+		}
+
+		rr, res := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, server.OK, res.Code)
+		assert.Equal(t, newUser.ID, queued.UserID)
+		assert.Equal(t, models.JOIN_REQUEST_PENDING, queued.State)
+		assert.Equal(t, "abc123", queued.InviteCode)
+		assert.NotContains(t, group.Participants, newUser.ID)
+	})
+
+	mt.Run("JoinGroupEP - Approval already pending", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		group.ApprovalRequired = true
+		db := joinDB(mt, group, &models.GroupInvite{Code: "abc123", GroupID: group.GroupID})
+
+		pending := models.FormatJoinRequest(group.GroupID, newUser, "abc123")
+		db.GetPendingJoinRequestDBMockFunc = func(g string, u primitive.ObjectID) (*models.JoinRequest, error) {
+			return &pending, nil
+		}
+		db.UseGroupInviteDBMockFunc = func(s string) error {
+			t.Fatal("invite used by a pending request")
+			return nil
+		}
+		db.InsertJoinRequestDBMockFunc = func(j models.JoinRequest) (string, error) {
+			t.Fatal("join request queued twice")
+			return "", nil
+		}
+
+		rr, res := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, pending.ID.Hex(), res.DATA.(map[string]any)["_id"])
+	})
+
+	mt.Run("JoinGroupEP - Error group scheduled for deletion", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		deleteAt := time.Now().Add(time.Hour)
+		group.DeleteAt = &deleteAt
+		db := joinDB(mt, group, &models.GroupInvite{Code: "abc123", GroupID: group.GroupID})
+		db.UseGroupInviteDBMockFunc = func(s string) error {
+			t.Fatal("invite used on a group scheduled for deletion")
+			return nil
+		}
+
+		rr, res := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+		assert.Equal(t, http.StatusGone, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+		assert.NotContains(t, group.Participants, newUser.ID)
+	})
+
+	mt.Run("JoinGroupEP - Already a participant", func(mt *mtest.T) {
+
+		group := inviteTestGroup(newUser.ID)
+		db := joinDB(mt, group, &models.GroupInvite{Code: "abc123", GroupID: group.GroupID})
+		db.UseGroupInviteDBMockFunc = func(s string) error {
+			t.Fatal("invite used by a participant")
+			return nil
+		}
+
+		rr, _ := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	tests := []struct {
+		name   string
+		invite *models.GroupInvite
+		useErr error
+	}{
+		{"Error revoked", &models.GroupInvite{Code: "abc123", GroupID: "123456789", Revoked: true}, nil},
+		{"Error expired", &models.GroupInvite{Code: "abc123", GroupID: "123456789", ExpiresAt: time.Now().Add(-time.Minute)}, nil},
+		{"Error max uses", &models.GroupInvite{Code: "abc123", GroupID: "123456789", MaxUses: 2, Uses: 2}, nil},
+		{"Error max uses reached concurrently", &models.GroupInvite{Code: "abc123", GroupID: "123456789", MaxUses: 2, Uses: 1}, mongo.ErrNoDocuments},
+	}
+
+	for _, tt := range tests {
+		mt.Run("JoinGroupEP - "+tt.name, func(mt *mtest.T) {
+
+			group := inviteTestGroup(primitive.NewObjectID())
+			db := joinDB(mt, group, tt.invite)
+			db.UseGroupInviteDBMockFunc = func(s string) error {
+				return tt.useErr
+			}
+
+			rr, res := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+			assert.Equal(t, http.StatusGone, rr.Code)
+			assert.Equal(t, server.NOT_ALLOWED, res.Code)
+			assert.NotContains(t, group.Participants, newUser.ID)
+		})
+	}
+
+	mt.Run("JoinGroupEP - Error invite not found", func(mt *mtest.T) {
+
+		db := joinDB(mt, nil, nil)
+		db.GetGroupInviteDBMockFunc = func(s string) (*models.GroupInvite, error) {
+			return nil, mongo.ErrNoDocuments
+		}
+
+		rr, _ := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	mt.Run("JoinGroupEP - Error unknown user", func(mt *mtest.T) {
+
+		db := joinDB(mt, nil, nil)
+		db.FindUserMockFunc = func(s string) (models.User, bool, error) {
+			return models.User{}, false, nil
+		}
+
+		rr, res := serveGroupRequest(t, JoinGroupEP, db, http.MethodPost, "/jgp?ui=ana@mail.com&ic=abc123", nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+}
+
+// TestReviewJoinRequestEP tests the handlers GetJoinRequestsEP and ReviewJoinRequestEP
+func TestReviewJoinRequestEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	requester := primitive.NewObjectID()
+
+	reviewDB := func(mt *mtest.T, group *models.Group, request *models.JoinRequest) *DBMock {
+		return &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetJoinRequestDBMockFunc: func(i primitive.ObjectID) (*models.JoinRequest, error) {
+				return request, nil
+			},
+		}
+	}
+
+	mt.Run("GetJoinRequestsEP - Success", func(mt *mtest.T) {
+
+		db := reviewDB(mt, inviteTestGroup(primitive.NewObjectID()), nil)
+		db.GetJoinRequestsDBMockFunc = func(pg int, g string) ([]*models.JoinRequest, error) {
+			assert.Equal(t, 2, pg)
+			return []*models.JoinRequest{{UserID: requester}}, nil
+		}
+
+		rr, res := serveGroupRequest(t, GetJoinRequestsEP, db, http.MethodGet, "/gjr?gi=123456789&pg=2&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, res.DATA, 1)
+	})
+
+	mt.Run("GetJoinRequestsEP - Error bad page", func(mt *mtest.T) {
+
+		db := reviewDB(mt, inviteTestGroup(primitive.NewObjectID()), nil)
+
+		rr, res := serveGroupRequest(t, GetJoinRequestsEP, db, http.MethodGet, "/gjr?gi=123456789&pg=x&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("ReviewJoinRequestEP - Approve", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		request := models.FormatJoinRequest(group.GroupID, models.User{ID: requester, Name: "ana"}, "abc123")
+		db := reviewDB(mt, group, &request)
+
+		var state string
+		db.ReviewJoinRequestDBMockFunc = func(i primitive.ObjectID, s string, reviewer primitive.ObjectID) error {
+			state = s
+			assert.Equal(t, MockObjectID, reviewer)
+			return nil
+		}
+		added := primitive.NilObjectID
+		db.AddGroupParticipantDBMockFunc = func(g, u primitive.ObjectID) error {
+			added = u
+			return nil
+		}
+
+		rr, _ := serveGroupRequest(t, ReviewJoinRequestEP, db, http.MethodPut, fmt.Sprintf("/gjr?gi=123456789&ri=%s&ot=%s&ai=%s", request.ID.Hex(), OPERATION_ADD, MockObjectID.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.JOIN_REQUEST_APPROVED, state)
+		assert.Equal(t, requester, added)
+		assert.Contains(t, group.Participants, requester)
+	})
+
+	mt.Run("ReviewJoinRequestEP - Reject", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		request := models.FormatJoinRequest(group.GroupID, models.User{ID: requester, Name: "ana"}, "abc123")
+		db := reviewDB(mt, group, &request)
+
+		var state string
+		db.ReviewJoinRequestDBMockFunc = func(i primitive.ObjectID, s string, reviewer primitive.ObjectID) error {
+			state = s
+			return nil
+		}
+		db.AddGroupParticipantDBMockFunc = func(g, u primitive.ObjectID) error {
+			t.Fatal("rejected user added")
+			return nil
+		}
+
+		rr, _ := serveGroupRequest(t, ReviewJoinRequestEP, db, http.MethodPut, fmt.Sprintf("/gjr?gi=123456789&ri=%s&ot=%s&ai=%s", request.ID.Hex(), OPERATION_REMOVE, MockObjectID.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.JOIN_REQUEST_REJECTED, state)
+	})
+
+	mt.Run("ReviewJoinRequestEP - Error already reviewed", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		request := models.FormatJoinRequest(group.GroupID, models.User{ID: requester}, "abc123")
+		db := reviewDB(mt, group, &request)
+		db.ReviewJoinRequestDBMockFunc = func(i primitive.ObjectID, s string, reviewer primitive.ObjectID) error {
+			return mongo.ErrNoDocuments
+		}
+
+		rr, _ := serveGroupRequest(t, ReviewJoinRequestEP, db, http.MethodPut, fmt.Sprintf("/gjr?gi=123456789&ri=%s&ot=%s&ai=%s", request.ID.Hex(), OPERATION_ADD, MockObjectID.Hex()), nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	mt.Run("ReviewJoinRequestEP - Error group scheduled for deletion", func(mt *mtest.T) {
+
+		group := inviteTestGroup(primitive.NewObjectID())
+		deleteAt := time.Now().Add(time.Hour)
+		group.DeleteAt = &deleteAt
+		request := models.FormatJoinRequest(group.GroupID, models.User{ID: requester}, "abc123")
+		db := reviewDB(mt, group, &request)
+		db.ReviewJoinRequestDBMockFunc = func(i primitive.ObjectID, s string, reviewer primitive.ObjectID) error {
+			t.Fatal("join request reviewed on a group scheduled for deletion")
+			return nil
+		}
+		db.AddGroupParticipantDBMockFunc = func(g, u primitive.ObjectID) error {
+			t.Fatal("user added to a group scheduled for deletion")
+			return nil
+		}
+
+		rr, res := serveGroupRequest(t, ReviewJoinRequestEP, db, http.MethodPut, fmt.Sprintf("/gjr?gi=123456789&ri=%s&ot=%s&ai=%s", request.ID.Hex(), OPERATION_ADD, MockObjectID.Hex()), nil)
+
+		assert.Equal(t, http.StatusGone, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+		assert.NotContains(t, group.Participants, requester)
+	})
+
+	mt.Run("ReviewJoinRequestEP - Error member not allowed", func(mt *mtest.T) {
+
+		member := primitive.NewObjectID()
+		group := inviteTestGroup(member)
+		request := models.FormatJoinRequest(group.GroupID, models.User{ID: requester}, "abc123")
+		db := reviewDB(mt, group, &request)
+
+		rr, res := serveGroupRequest(t, ReviewJoinRequestEP, db, http.MethodPut, fmt.Sprintf("/gjr?gi=123456789&ri=%s&ot=%s&ai=%s", request.ID.Hex(), OPERATION_ADD, member.Hex()), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("ReviewJoinRequestEP - Error invalid operation", func(mt *mtest.T) {
+
+		db := reviewDB(mt, inviteTestGroup(primitive.NewObjectID()), nil)
+
+		rr, res := serveGroupRequest(t, ReviewJoinRequestEP, db, http.MethodPut, fmt.Sprintf("/gjr?gi=123456789&ri=%s&ot=approve&ai=%s", primitive.NewObjectID().Hex(), MockObjectID.Hex()), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+}
+
+// TestUpdateGroupApprovalEP tests the handler UpdateGroupApprovalEP
+func TestUpdateGroupApprovalEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateGroupApprovalEP - Success", func(mt *mtest.T) {
+
+		var update map[string]any
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(primitive.NewObjectID()), nil
+			},
+			UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+				update = m
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, UpdateGroupApprovalEP, db, http.MethodPut, "/ugap?gi=123456789&ar=true&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, true, update["approval_required"])
+	})
+
+	mt.Run("UpdateGroupApprovalEP - Error bad flag", func(mt *mtest.T) {
+
+		db := &DBMock{Client: mt.Client, DatabaseName: MockDBName}
+
+		rr, res := serveGroupRequest(t, UpdateGroupApprovalEP, db, http.MethodPut, "/ugap?gi=123456789&ar=maybe&ai="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("UpdateGroupApprovalEP - Error member not allowed", func(mt *mtest.T) {
+
+		member := primitive.NewObjectID()
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, UpdateGroupApprovalEP, db, http.MethodPut, "/ugap?gi=123456789&ar=true&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
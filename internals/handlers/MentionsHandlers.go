@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+GetUnreadMentionsEP
+Returns the unread mentions of the user (ai), newest first, on the group (gi) or
+on every group of the user when no group is given
+*/
+func GetUnreadMentionsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ai"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	groupID := r.URL.Query().Get("gi")
+	if groupID != "" {
+		// every member reads its mentions
+		group, ok := authorizeGroup(w, db, groupID, user.Hex(), models.PERMISSION_SEND_MESSAGES)
+		if !ok {
+			return
+		}
+		groupID = group.GroupID
+	}
+
+	mentions, err := db.GetUnreadMentionsDB(pg, user, groupID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(mentions, server.OK, "ok"))
+}
+
+/*
+MarkMentionsReadEP
+Marks every mention of the user (ai) on the group (gi) as read
+*/
+func MarkMentionsReadEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	actor := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), actor, models.PERMISSION_SEND_MESSAGES)
+	if !ok {
+		return
+	}
+
+	user, _ := primitive.ObjectIDFromHex(actor)
+	readAt := time.Now()
+
+	err := db.MarkMentionsReadDB(user, group.GroupID, readAt)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(models.MentionsRead{
+		GroupID: group.GroupID,
+		UserID:  user,
+		ReadAt:  readAt,
+	}, server.OK, "ok"))
+}
+
+/*
+MuteGroupNotificationsEP
+Mutes the push notifications of the group (gi) for the member (ai) during the
+given seconds (du), zero unmutes them. Mentions are still notified
+*/
+func MuteGroupNotificationsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	duration, err := strconv.Atoi(r.URL.Query().Get("du"))
+	if err != nil || duration < 0 || duration > models.MAX_NOTIFICATION_MUTE {
+		err = fmt.Errorf("the mute must be between 0 and %d seconds", models.MAX_NOTIFICATION_MUTE)
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	actor := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), actor, models.PERMISSION_SEND_MESSAGES)
+	if !ok {
+		return
+	}
+
+	user, _ := primitive.ObjectIDFromHex(actor)
+
+	var until time.Time
+	if duration > 0 {
+		until = time.Now().Add(time.Duration(duration) * time.Second)
+	}
+
+	err = db.UpdateNotificationMuteDB(group.ID, user, until)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	if group.NotificationMutes == nil {
+		group.NotificationMutes = make(map[string]time.Time)
+	}
+	if until.IsZero() {
+		delete(group.NotificationMutes, user.Hex())
+	} else {
+		group.NotificationMutes[user.Hex()] = until
+	}
+
+	// the connections of the group decide the pushes with their copy of the group
+	publishGroupUpdate(group)
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(models.NotificationMute{
+		GroupID: group.GroupID,
+		UserID:  user,
+		Until:   until,
+	}, server.OK, "ok"))
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestGetUnreadMentionsEP tests the handler GetUnreadMentionsEP
+func TestGetUnreadMentionsEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mentions := func(groupID *string) func(int, primitive.ObjectID, string) ([]*models.Mention, error) {
+		return func(pg int, user primitive.ObjectID, gi string) ([]*models.Mention, error) {
+			*groupID = gi
+			return []*models.Mention{{ID: primitive.NewObjectID(), GroupID: "123456789", UserID: user, Body: "hi @you"}}, nil
+		}
+	}
+
+	mt.Run("GetUnreadMentionsEP - Success every group", func(mt *mtest.T) {
+
+		groupID := "unset"
+		db := &DBMock{
+			Client:                      mt.Client,
+			DatabaseName:                MockDBName,
+			GetUnreadMentionsDBMockFunc: mentions(&groupID),
+		}
+
+		rr, res := serveGroupRequest(t, GetUnreadMentionsEP, db, http.MethodGet, "/gmn?ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "", groupID)
+		assert.Len(t, res.DATA, 1)
+	})
+
+	mt.Run("GetUnreadMentionsEP - Success one group", func(mt *mtest.T) {
+
+		groupID := ""
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			GetUnreadMentionsDBMockFunc: mentions(&groupID),
+		}
+
+		rr, _ := serveGroupRequest(t, GetUnreadMentionsEP, db, http.MethodGet, "/gmn?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "123456789", groupID)
+	})
+
+	mt.Run("GetUnreadMentionsEP - Error outsider", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetUnreadMentionsEP, db, http.MethodGet, "/gmn?gi=123456789&ai="+primitive.NewObjectID().Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	mt.Run("GetUnreadMentionsEP - Error invalid user", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		rr, _ := serveGroupRequest(t, GetUnreadMentionsEP, db, http.MethodGet, "/gmn?ai=nope", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	mt.Run("GetUnreadMentionsEP - Error invalid page", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		rr, _ := serveGroupRequest(t, GetUnreadMentionsEP, db, http.MethodGet, "/gmn?pg=0&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+// TestMarkMentionsReadEP tests the handler MarkMentionsReadEP
+func TestMarkMentionsReadEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("MarkMentionsReadEP - Success", func(mt *mtest.T) {
+
+		var marked primitive.ObjectID
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			MarkMentionsReadDBMockFunc: func(user primitive.ObjectID, groupID string, at time.Time) error {
+				marked = user
+				return nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, MarkMentionsReadEP, db, http.MethodPut, "/gmn?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, member, marked)
+		assert.Equal(t, "123456789", res.DATA.(map[string]any)["group_id"])
+	})
+
+	mt.Run("MarkMentionsReadEP - Error db", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			MarkMentionsReadDBMockFunc: func(user primitive.ObjectID, groupID string, at time.Time) error {
+				return errors.New("update failed")
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, MarkMentionsReadEP, db, http.MethodPut, "/gmn?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+// TestMuteGroupNotificationsEP tests the handler MuteGroupNotificationsEP
+func TestMuteGroupNotificationsEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("MuteGroupNotificationsEP - Success mute", func(mt *mtest.T) {
+
+		var until time.Time
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			UpdateNotificationMuteDBMockFunc: func(id, user primitive.ObjectID, t time.Time) error {
+				until = t
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, MuteGroupNotificationsEP, db, http.MethodPut, "/mgn?gi=123456789&du=3600&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Minute)
+	})
+
+	mt.Run("MuteGroupNotificationsEP - Success unmute", func(mt *mtest.T) {
+
+		until := time.Now()
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				group := inviteTestGroup(member)
+				group.NotificationMutes = map[string]time.Time{member.Hex(): time.Now().Add(time.Hour)}
+				return group, nil
+			},
+			UpdateNotificationMuteDBMockFunc: func(id, user primitive.ObjectID, t time.Time) error {
+				until = t
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, MuteGroupNotificationsEP, db, http.MethodPut, "/mgn?gi=123456789&du=0&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.True(t, until.IsZero())
+	})
+
+	mt.Run("MuteGroupNotificationsEP - Error duration", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		rr, _ := serveGroupRequest(t, MuteGroupNotificationsEP, db, http.MethodPut, "/mgn?gi=123456789&du="+strconv.Itoa(models.MAX_NOTIFICATION_MUTE+1)+"&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	mt.Run("MuteGroupNotificationsEP - Error outsider", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, MuteGroupNotificationsEP, db, http.MethodPut, "/mgn?gi=123456789&du=60&ai="+primitive.NewObjectID().Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+UpdateGroupModerationEP
+turns the announcement only mode and the slow mode of the group on and off
+*/
+func UpdateGroupModerationEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var settings models.GroupModeration
+
+	err := tools.ReadJSON(w, r, &settings)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	err = settings.Validate()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_MODERATE)
+	if !ok {
+		return
+	}
+
+	update := make(map[string]any)
+	update["announcement_only"] = settings.AnnouncementOnly
+	update["slow_mode"] = settings.SlowMode
+
+	err = db.UpdateGroupDB(update, group.ID)
+	if err != nil && err != database.ErrNoModified {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_MODERATION_CHANGED,
+		map[string]any{"announcement_only": group.AnnouncementOnly, "slow_mode": group.SlowMode},
+		map[string]any{"announcement_only": settings.AnnouncementOnly, "slow_mode": settings.SlowMode},
+	)
+
+	group.AnnouncementOnly = settings.AnnouncementOnly
+	group.SlowMode = settings.SlowMode
+	publishGroupUpdate(group)
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
+
+/*
+MuteGroupMemberEP
+mutes the member for the given seconds, zero seconds unmutes it. Admins can not
+be muted
+*/
+func MuteGroupMemberEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	target, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ti"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	duration, err := strconv.Atoi(r.URL.Query().Get("du"))
+	if err != nil || duration < 0 || duration > models.MAX_MUTE_DURATION {
+		err = fmt.Errorf("the mute must be between 0 and %d seconds", models.MAX_MUTE_DURATION)
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_MODERATE)
+	if !ok {
+		return
+	}
+
+	switch group.RoleOf(target) {
+	case "":
+		alog.ErrorLog(fmt.Sprintf("user %s is not a participant", target.Hex()))
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse(fmt.Sprintf("user %s is not a participant", target.Hex()), server.BAD_FIELD))
+		return
+	case models.GROUP_ROLE_ADMIN, models.GROUP_ROLE_OWNER:
+		alog.ErrorLog(fmt.Sprintf("admin %s can not be muted", target.Hex()))
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("admins can not be muted", server.NOT_ALLOWED))
+		return
+	}
+
+	var until time.Time
+	if duration > 0 {
+		until = time.Now().Add(time.Duration(duration) * time.Second)
+	}
+
+	err = db.UpdateGroupMuteDB(group.ID, target, until)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_MEMBER_MUTED, map[string]any{"muted_until": group.MutedUntil(target, time.Now())}, map[string]any{"muted_until": until}, target)
+
+	if group.Mutes == nil {
+		group.Mutes = make(map[string]time.Time)
+	}
+	if until.IsZero() {
+		delete(group.Mutes, target.Hex())
+	} else {
+		group.Mutes[target.Hex()] = until
+	}
+
+	publishGroupUpdate(group)
+	notifyGroupMembers(group, []primitive.ObjectID{target}, models.EVENT_GROUP_MEMBER_MUTED, models.GroupMuteEvent{
+		GroupID: group.GroupID,
+		UserID:  target.Hex(),
+		Until:   until,
+	})
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
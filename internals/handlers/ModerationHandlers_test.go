@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestUpdateGroupModerationEP tests the handler UpdateGroupModerationEP
+func TestUpdateGroupModerationEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("UpdateGroupModerationEP - Success", func(mt *mtest.T) {
+
+		var update map[string]any
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			UpdateGroupDBMockFunc: func(m map[string]any, oi primitive.ObjectID) error {
+				update = m
+				return nil
+			},
+		}
+
+		settings := models.GroupModeration{AnnouncementOnly: true, SlowMode: 30}
+		rr, res := serveGroupRequest(t, UpdateGroupModerationEP, db, http.MethodPut, "/ugmd?gi=123456789&ai="+MockObjectID.Hex(), settings)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, true, update["announcement_only"])
+		assert.Equal(t, 30, update["slow_mode"])
+		assert.Equal(t, float64(30), res.DATA.(map[string]any)["slow_mode"])
+	})
+
+	mt.Run("UpdateGroupModerationEP - Error slow mode too long", func(mt *mtest.T) {
+
+		db := &DBMock{Client: mt.Client, DatabaseName: MockDBName}
+
+		settings := models.GroupModeration{SlowMode: models.MAX_SLOW_MODE + 1}
+		rr, res := serveGroupRequest(t, UpdateGroupModerationEP, db, http.MethodPut, "/ugmd?gi=123456789&ai="+MockObjectID.Hex(), settings)
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("UpdateGroupModerationEP - Error member denied", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, UpdateGroupModerationEP, db, http.MethodPut, "/ugmd?gi=123456789&ai="+member.Hex(), models.GroupModeration{AnnouncementOnly: true})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+}
+
+// TestMuteGroupMemberEP tests the handler MuteGroupMemberEP
+func TestMuteGroupMemberEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("MuteGroupMemberEP - Success mute", func(mt *mtest.T) {
+
+		var until time.Time
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			UpdateGroupMuteDBMockFunc: func(oi, u primitive.ObjectID, at time.Time) error {
+				until = at
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, MuteGroupMemberEP, db, http.MethodPut, fmt.Sprintf("/mgm?gi=123456789&ai=%s&ti=%s&du=3600", MockObjectID.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), until, time.Minute)
+	})
+
+	mt.Run("MuteGroupMemberEP - Success unmute", func(mt *mtest.T) {
+
+		until := time.Now()
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				group := inviteTestGroup(member)
+				group.Mutes = map[string]time.Time{member.Hex(): time.Now().Add(time.Hour)}
+				return group, nil
+			},
+			UpdateGroupMuteDBMockFunc: func(oi, u primitive.ObjectID, at time.Time) error {
+				until = at
+				return nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, MuteGroupMemberEP, db, http.MethodPut, fmt.Sprintf("/mgm?gi=123456789&ai=%s&ti=%s&du=0", MockObjectID.Hex(), member.Hex()), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.True(t, until.IsZero())
+		assert.NotContains(t, res.DATA, "mutes")
+	})
+
+	tests := []struct {
+		name     string
+		actor    primitive.ObjectID
+		target   string
+		duration string
+		expected int
+	}{
+		{"admins can not be muted", MockObjectID, MockObjectID.Hex(), "60", http.StatusForbidden},
+		{"member denied", member, member.Hex(), "60", http.StatusForbidden},
+		{"target not a participant", MockObjectID, primitive.NewObjectID().Hex(), "60", http.StatusBadRequest},
+		{"invalid duration", MockObjectID, member.Hex(), "-1", http.StatusBadRequest},
+		{"duration too long", MockObjectID, member.Hex(), fmt.Sprint(models.MAX_MUTE_DURATION + 1), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		mt.Run("MuteGroupMemberEP - Error "+tt.name, func(mt *mtest.T) {
+
+			db := &DBMock{
+				Client:       mt.Client,
+				DatabaseName: MockDBName,
+				GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+					return inviteTestGroup(member), nil
+				},
+				UpdateGroupMuteDBMockFunc: func(oi, u primitive.ObjectID, at time.Time) error {
+					t.Fatal("mute stored")
+					return nil
+				},
+			}
+
+			rr, _ := serveGroupRequest(t, MuteGroupMemberEP, db, http.MethodPut, fmt.Sprintf("/mgm?gi=123456789&ai=%s&ti=%s&du=%s", tt.actor.Hex(), tt.target, tt.duration), nil)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+}
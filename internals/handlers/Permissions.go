@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+authorizeGroupAction
+central check of the group permissions for the REST handlers, writes the error
+response and returns false when the actor does not hold the permission
+*/
+func authorizeGroupAction(w http.ResponseWriter, group *models.Group, actor, permission string) bool {
+
+	alog := logger.StartLogger()
+
+	actorID, err := primitive.ObjectIDFromHex(actor)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_CREDENTIALS, err))
+		return false
+	}
+
+	if !group.Can(actorID, permission) {
+		alog.WarningLogger(fmt.Sprintf("user %s has no %s permission on group %s", actor, permission, group.GroupID))
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return false
+	}
+
+	return true
+}
+
+// publishGroupUpdate shares the new state of the group with the live connections
+func publishGroupUpdate(group *models.Group) {
+	if server.WebsocketHUB != nil {
+		server.WebsocketHUB.PublishGroupUpdate(group)
+	}
+}
+
+/*
+authorizeGroup
+gets the group and checks the permission of the actor on it, writes the error
+response and returns false when the group does not exist or the action is denied
+*/
+func authorizeGroup(w http.ResponseWriter, db database.DBHUB, groupID, actor, permission string) (*models.Group, bool) {
+
+	alog := logger.StartLogger()
+
+	group, err := db.GetGroupDB(groupID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return nil, false
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return nil, false
+	}
+
+	if !authorizeGroupAction(w, group, actor, permission) {
+		return nil, false
+	}
+
+	return group, true
+}
+
+// notifyGroupMembers sends the event to the members connected to the group
+func notifyGroupMembers(group *models.Group, members []primitive.ObjectID, event string, data any) {
+	if server.WebsocketHUB != nil {
+		server.WebsocketHUB.NotifyGroupMembers(group.ID, members, models.WebsocketEvent{Event: event, Data: data})
+	}
+}
+
+// notifyUsers sends the event to the private conversations of the users
+func notifyUsers(users []primitive.ObjectID, event string, data any) {
+	if server.WebsocketHUB != nil {
+		server.WebsocketHUB.NotifyUsers(users, models.WebsocketEvent{Event: event, Data: data})
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+PinMessageEP
+Pins (ot=1) or unpins (ot=0) the message (mi) on its conversation, group
+messages carry the group (gi) and need the pin permission, on private
+conversations both users pin. The pin is shared with the conversation
+*/
+func PinMessageEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	operationType := r.URL.Query().Get("ot")
+
+	if operationType != OPERATION_ADD && operationType != OPERATION_REMOVE {
+		alog.ErrorLog(fmt.Sprintf("invalid operation %q", operationType))
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse(fmt.Sprintf("invalid operation %q", operationType), server.BAD_FIELD))
+		return
+	}
+
+	messageID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("mi"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	group, recipients, ok := authorizeMessage(w, db, r.URL.Query().Get("gi"), messageID, user.ID)
+	if !ok {
+		return
+	}
+
+	var groupID, conversationID string
+	if group != nil {
+		if !authorizeGroupAction(w, group, user.ID.Hex(), models.PERMISSION_PIN_MESSAGES) {
+			return
+		}
+		groupID = group.GroupID
+		conversationID = group.GroupID
+	} else {
+		conversationID = models.P2PConversationID(recipients[0], recipients[1])
+	}
+
+	if operationType == OPERATION_ADD {
+		err = db.PinMessageDB(models.FormatMessagePin(conversationID, messageID, user.ID))
+	} else {
+		err = db.UnpinMessageDB(conversationID, messageID)
+	}
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		switch err {
+		case database.ErrNoModified:
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("message already pinned", server.BAD_REQUEST))
+		case database.ErrNoDeleted:
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("message not pinned", server.NO_DOCUMENTS))
+		case models.ErrPinLimit:
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatErrResponse(server.BAD_REQUEST, err))
+		default:
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		}
+		return
+	}
+
+	event := models.MessagePinEvent{
+		MessageID: messageID,
+		GroupID:   groupID,
+		UserID:    user.ID,
+		Unpinned:  operationType == OPERATION_REMOVE,
+	}
+
+	if group != nil {
+		recordAudit(db, group, user.ID.Hex(), models.AUDIT_MESSAGE_PINNED, map[string]any{"pinned": event.Unpinned}, map[string]any{"pinned": !event.Unpinned}, messageID)
+		notifyGroupMembers(group, recipients, models.EVENT_MESSAGE_PINNED, event)
+	} else {
+		notifyUsers(recipients, models.EVENT_MESSAGE_PINNED, event)
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(event, server.OK, "ok"))
+}
+
+/*
+GetPinnedMessagesEP
+Returns the pinned messages of the group (gi) or of the private conversation
+of the user with the target (ti), newest pin first
+*/
+func GetPinnedMessagesEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	var conversationID string
+
+	groupID := r.URL.Query().Get("gi")
+	if groupID != "" {
+
+		// every member reads the pins
+		group, ok := authorizeGroup(w, db, groupID, user.ID.Hex(), models.PERMISSION_SEND_MESSAGES)
+		if !ok {
+			return
+		}
+		conversationID = group.GroupID
+
+	} else {
+
+		target, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ti"))
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+			return
+		}
+		conversationID = models.P2PConversationID(user.ID, target)
+	}
+
+	pins, err := db.GetPinnedMessagesDB(conversationID, groupID != "")
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(pins, server.OK, "ok"))
+}
+
+/*
+StarMessageEP
+Stars (ot=1) or unstars (ot=0) the message (mi) for the user, group messages
+carry the group (gi). Only the user sees its stars
+*/
+func StarMessageEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	operationType := r.URL.Query().Get("ot")
+
+	if operationType != OPERATION_ADD && operationType != OPERATION_REMOVE {
+		alog.ErrorLog(fmt.Sprintf("invalid operation %q", operationType))
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse(fmt.Sprintf("invalid operation %q", operationType), server.BAD_FIELD))
+		return
+	}
+
+	messageID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("mi"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	group, _, ok := authorizeMessage(w, db, r.URL.Query().Get("gi"), messageID, user.ID)
+	if !ok {
+		return
+	}
+
+	groupID := ""
+	if group != nil {
+		groupID = group.GroupID
+	}
+
+	star := models.FormatMessageStar(messageID, groupID, user.ID)
+
+	if operationType == OPERATION_ADD {
+		err = db.StarMessageDB(star)
+	} else {
+		err = db.UnstarMessageDB(user.ID, messageID)
+	}
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		switch err {
+		case database.ErrNoModified:
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("message already starred", server.BAD_REQUEST))
+		case database.ErrNoDeleted:
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("message not starred", server.NO_DOCUMENTS))
+		default:
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		}
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(star, server.OK, "ok"))
+}
+
+/*
+GetStarredMessagesEP
+Returns the messages starred by the user, newest star first
+*/
+func GetStarredMessagesEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	stars, err := db.GetStarredMessagesDB(pg, user.ID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(stars, server.OK, "ok"))
+}
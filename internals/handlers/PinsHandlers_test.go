@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// pinURL url of the pin, or the star, of the message by the user
+func pinURL(path string, message primitive.ObjectID, groupID, operation string) string {
+	q := url.Values{}
+	q.Set("ui", "alice@mail.com")
+	q.Set("mi", message.Hex())
+	q.Set("ot", operation)
+	if groupID != "" {
+		q.Set("gi", groupID)
+	}
+	return path + "?" + q.Encode()
+}
+
+// TestPinMessageEP tests the handler PinMessageEP
+func TestPinMessageEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	message := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+
+	findUser := func(id primitive.ObjectID) func(string) (models.User, bool, error) {
+		return func(s string) (models.User, bool, error) {
+			return models.User{ID: id, Name: "alice"}, true, nil
+		}
+	}
+
+	mt.Run("PinMessageEP - Success group message", func(mt *mtest.T) {
+
+		var stored models.MessagePin
+		var audited models.AuditEntry
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findUser(MockObjectID),
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: group.ID}, nil
+			},
+			PinMessageDBMockFunc: func(p models.MessagePin) error {
+				stored = p
+				return nil
+			},
+			InsertAuditEntryDBMockFunc: func(e models.AuditEntry) (string, error) {
+				audited = e
+				return e.ID.Hex(), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, PinMessageEP, db, http.MethodPut, pinURL("/mpn", message, "123456789", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "123456789", stored.ConversationID)
+		assert.Equal(t, message, stored.MessageID)
+		assert.Equal(t, MockObjectID, stored.PinnedBy)
+		assert.Equal(t, models.AUDIT_MESSAGE_PINNED, audited.Action)
+		assert.Equal(t, false, res.DATA.(map[string]any)["unpinned"])
+	})
+
+	mt.Run("PinMessageEP - Success private message", func(mt *mtest.T) {
+
+		var conversation string
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findUser(member),
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: MockObjectID, TargetID: member}, nil
+			},
+			UnpinMessageDBMockFunc: func(s string, oi primitive.ObjectID) error {
+				conversation = s
+				return nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, PinMessageEP, db, http.MethodPut, pinURL("/mpn", message, "", OPERATION_REMOVE), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.P2PConversationID(member, MockObjectID), conversation)
+		assert.Equal(t, true, res.DATA.(map[string]any)["unpinned"])
+	})
+
+	mt.Run("PinMessageEP - Error member without the permission", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findUser(member),
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: group.ID}, nil
+			},
+			PinMessageDBMockFunc: func(p models.MessagePin) error {
+				t.Fatal("member without the permission pinned a message")
+				return nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, PinMessageEP, db, http.MethodPut, pinURL("/mpn", message, "123456789", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("PinMessageEP - Error pin limit", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findUser(member),
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: MockObjectID, TargetID: member}, nil
+			},
+			PinMessageDBMockFunc: func(p models.MessagePin) error {
+				return models.ErrPinLimit
+			},
+		}
+
+		rr, res := serveGroupRequest(t, PinMessageEP, db, http.MethodPut, pinURL("/mpn", message, "", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, server.BAD_REQUEST, res.Code)
+	})
+
+	mt.Run("PinMessageEP - Error not pinned", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findUser(member),
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: MockObjectID, TargetID: member}, nil
+			},
+			UnpinMessageDBMockFunc: func(s string, oi primitive.ObjectID) error {
+				return database.ErrNoDeleted
+			},
+		}
+
+		rr, res := serveGroupRequest(t, PinMessageEP, db, http.MethodPut, pinURL("/mpn", message, "", OPERATION_REMOVE), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+}
+
+// TestGetPinnedMessagesEP tests the handler GetPinnedMessagesEP
+func TestGetPinnedMessagesEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+
+	mt.Run("GetPinnedMessagesEP - Success group", func(mt *mtest.T) {
+
+		var conversation string
+		var fromGroup bool
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: member}, true, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetPinnedMessagesDBMockFunc: func(s string, g bool) ([]*models.MessagePin, error) {
+				conversation, fromGroup = s, g
+				return []*models.MessagePin{{ConversationID: s}}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetPinnedMessagesEP, db, http.MethodGet, "/mpn?ui=alice@mail.com&gi=123456789", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "123456789", conversation)
+		assert.True(t, fromGroup)
+		assert.Len(t, res.DATA, 1)
+	})
+
+	mt.Run("GetPinnedMessagesEP - Success private conversation", func(mt *mtest.T) {
+
+		var conversation string
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: member}, true, nil
+			},
+			GetPinnedMessagesDBMockFunc: func(s string, g bool) ([]*models.MessagePin, error) {
+				conversation = s
+				return []*models.MessagePin{}, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetPinnedMessagesEP, db, http.MethodGet, "/mpn?ui=alice@mail.com&ti="+MockObjectID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.P2PConversationID(MockObjectID, member), conversation)
+	})
+
+	mt.Run("GetPinnedMessagesEP - Error outsider of the group", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: primitive.NewObjectID()}, true, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetPinnedMessagesEP, db, http.MethodGet, "/mpn?ui=alice@mail.com&gi=123456789", nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+// TestStarMessageEP tests the handler StarMessageEP
+func TestStarMessageEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	message := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+
+	findMember := func(s string) (models.User, bool, error) {
+		return models.User{ID: member, Name: "alice"}, true, nil
+	}
+
+	mt.Run("StarMessageEP - Success group message", func(mt *mtest.T) {
+
+		var stored models.MessageStar
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: group.ID}, nil
+			},
+			StarMessageDBMockFunc: func(s models.MessageStar) error {
+				stored = s
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, StarMessageEP, db, http.MethodPut, pinURL("/mst", message, "123456789", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, member, stored.UserID)
+		assert.Equal(t, message, stored.MessageID)
+		assert.Equal(t, "123456789", stored.GroupID)
+	})
+
+	mt.Run("StarMessageEP - Error already starred", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: member, TargetID: MockObjectID}, nil
+			},
+			StarMessageDBMockFunc: func(s models.MessageStar) error {
+				return database.ErrNoModified
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, StarMessageEP, db, http.MethodPut, pinURL("/mst", message, "", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	mt.Run("StarMessageEP - Error outsider of the private conversation", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: MockObjectID, TargetID: primitive.NewObjectID()}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, StarMessageEP, db, http.MethodPut, pinURL("/mst", message, "", OPERATION_REMOVE), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("StarMessageEP - Error not starred", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: member, TargetID: MockObjectID}, nil
+			},
+			UnstarMessageDBMockFunc: func(u, m primitive.ObjectID) error {
+				return database.ErrNoDeleted
+			},
+		}
+
+		rr, res := serveGroupRequest(t, StarMessageEP, db, http.MethodPut, pinURL("/mst", message, "", OPERATION_REMOVE), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+}
+
+// TestGetStarredMessagesEP tests the handler GetStarredMessagesEP
+func TestGetStarredMessagesEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("GetStarredMessagesEP - Success", func(mt *mtest.T) {
+
+		var page int
+		var user primitive.ObjectID
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: member}, true, nil
+			},
+			GetStarredMessagesDBMockFunc: func(pg int, u primitive.ObjectID) ([]*models.MessageStar, error) {
+				page, user = pg, u
+				return []*models.MessageStar{{UserID: u}}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetStarredMessagesEP, db, http.MethodGet, "/mst?ui=alice@mail.com&pg=2", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 2, page)
+		assert.Equal(t, member, user)
+		assert.Len(t, res.DATA, 1)
+	})
+
+	mt.Run("GetStarredMessagesEP - Error invalid page", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		rr, res := serveGroupRequest(t, GetStarredMessagesEP, db, http.MethodGet, "/mst?ui=alice@mail.com&pg=0", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+}
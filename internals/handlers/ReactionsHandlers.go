@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+ReactToMessageEP
+Adds (ot=1) or removes (ot=0) the emoji (em) reaction of the user to the message
+(mi), group messages carry the group (gi) and private messages leave it empty.
+The new counts of the message are shared with the conversation
+*/
+func ReactToMessageEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	operationType := r.URL.Query().Get("ot")
+
+	if operationType != OPERATION_ADD && operationType != OPERATION_REMOVE {
+		alog.ErrorLog(fmt.Sprintf("invalid operation %q", operationType))
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse(fmt.Sprintf("invalid operation %q", operationType), server.BAD_FIELD))
+		return
+	}
+
+	emoji, err := models.NormalizeReaction(r.URL.Query().Get("em"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	messageID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("mi"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	group, recipients, ok := authorizeMessage(w, db, r.URL.Query().Get("gi"), messageID, user.ID)
+	if !ok {
+		return
+	}
+
+	groupID := ""
+	if group != nil {
+		groupID = group.GroupID
+	}
+
+	reaction := models.FormatMessageReaction(messageID, groupID, user, emoji)
+
+	var counts map[string]int
+	if operationType == OPERATION_ADD {
+		counts, err = db.AddMessageReactionDB(reaction)
+	} else {
+		counts, err = db.RemoveMessageReactionDB(reaction)
+	}
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		switch err {
+		case database.ErrNoModified:
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("already reacted with this emoji", server.BAD_REQUEST))
+		case database.ErrNoDeleted:
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("reaction not found", server.NO_DOCUMENTS))
+		case mongo.ErrNoDocuments:
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+		default:
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		}
+		return
+	}
+
+	event := models.MessageReactionEvent{
+		MessageID: messageID,
+		GroupID:   groupID,
+		UserID:    user.ID,
+		Emoji:     emoji,
+		Removed:   operationType == OPERATION_REMOVE,
+		Reactions: counts,
+	}
+
+	if group != nil {
+		notifyGroupMembers(group, recipients, models.EVENT_MESSAGE_REACTION, event)
+	} else {
+		notifyUsers(recipients, models.EVENT_MESSAGE_REACTION, event)
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(event, server.OK, "ok"))
+}
+
+/*
+GetMessageReactionsEP
+Returns who reacted to the message (mi) and with what, oldest first
+*/
+func GetMessageReactionsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	messageID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("mi"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	_, _, ok := authorizeMessage(w, db, r.URL.Query().Get("gi"), messageID, user.ID)
+	if !ok {
+		return
+	}
+
+	reactions, err := db.GetMessageReactionsDB(pg, messageID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(reactions, server.OK, "ok"))
+}
+
+/*
+authorizeMessage
+checks the user takes part in the conversation of the message, the group (gi) for
+group messages and the author or the target for private ones. Returns the group,
+nil for private messages, and the users of the conversation
+*/
+func authorizeMessage(w http.ResponseWriter, db database.DBHUB, groupID string, message, user primitive.ObjectID) (*models.Group, []primitive.ObjectID, bool) {
+
+	alog := logger.StartLogger()
+
+	if groupID != "" {
+
+		// every member reacts to the messages
+		group, ok := authorizeGroup(w, db, groupID, user.Hex(), models.PERMISSION_SEND_MESSAGES)
+		if !ok {
+			return nil, nil, false
+		}
+
+		msg, err := db.GetGroupMessageDB(message)
+		if err == nil && msg.TargetID != group.ID {
+			err = mongo.ErrNoDocuments
+		}
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			if err == mongo.ErrNoDocuments {
+				tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+				return nil, nil, false
+			}
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return nil, nil, false
+		}
+
+		return group, group.Participants, true
+	}
+
+	msg, err := db.GetP2PMessageDB(message)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return nil, nil, false
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return nil, nil, false
+	}
+
+	if msg.AuthorID != user && msg.TargetID != user {
+		alog.WarningLogger(fmt.Sprintf("user %s is not part of the conversation of message %s", user.Hex(), message.Hex()))
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return nil, nil, false
+	}
+
+	return nil, []primitive.ObjectID{msg.AuthorID, msg.TargetID}, true
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// reactionURL url of the reaction of the user to the message
+func reactionURL(message primitive.ObjectID, groupID, emoji, operation string) string {
+	q := url.Values{}
+	q.Set("ui", "alice@mail.com")
+	q.Set("mi", message.Hex())
+	q.Set("em", emoji)
+	q.Set("ot", operation)
+	if groupID != "" {
+		q.Set("gi", groupID)
+	}
+	return "/mrc?" + q.Encode()
+}
+
+// TestReactToMessageEP tests the handler ReactToMessageEP
+func TestReactToMessageEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	message := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+
+	findMember := func(s string) (models.User, bool, error) {
+		return models.User{ID: member, Name: "alice"}, true, nil
+	}
+
+	mt.Run("ReactToMessageEP - Success group message", func(mt *mtest.T) {
+
+		var stored models.MessageReaction
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: group.ID}, nil
+			},
+			AddMessageReactionDBMockFunc: func(r models.MessageReaction) (map[string]int, error) {
+				stored = r
+				return map[string]int{r.Emoji: 2}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, ReactToMessageEP, db, http.MethodPut, reactionURL(message, "123456789", " 👍 ", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "👍", stored.Emoji)
+		assert.Equal(t, "123456789", stored.GroupID)
+		assert.Equal(t, member, stored.UserID)
+		assert.Equal(t, "alice", stored.Name)
+		assert.Equal(t, float64(2), res.DATA.(map[string]any)["reactions"].(map[string]any)["👍"])
+		assert.Equal(t, false, res.DATA.(map[string]any)["removed"])
+	})
+
+	mt.Run("ReactToMessageEP - Success private message", func(mt *mtest.T) {
+
+		var removed models.MessageReaction
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: MockObjectID, TargetID: member}, nil
+			},
+			RemoveMessageReactionDBMockFunc: func(r models.MessageReaction) (map[string]int, error) {
+				removed = r
+				return map[string]int{}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, ReactToMessageEP, db, http.MethodPut, reactionURL(message, "", "❤️", OPERATION_REMOVE), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "❤️", removed.Emoji)
+		assert.Empty(t, removed.GroupID)
+		assert.Equal(t, true, res.DATA.(map[string]any)["removed"])
+	})
+
+	mt.Run("ReactToMessageEP - Error invalid emoji", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+		}
+
+		for _, emoji := range []string{"", "ok", "a.b", "$set", "👍👍👍👍👍👍👍👍👍👍👍"} {
+			rr, res := serveGroupRequest(t, ReactToMessageEP, db, http.MethodPut, reactionURL(message, "", emoji, OPERATION_ADD), nil)
+
+			assert.Equal(t, http.StatusNotAcceptable, rr.Code, emoji)
+			assert.Equal(t, server.BAD_FIELD, res.Code, emoji)
+		}
+	})
+
+	mt.Run("ReactToMessageEP - Error message of another group", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: primitive.NewObjectID()}, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, ReactToMessageEP, db, http.MethodPut, reactionURL(message, "123456789", "👍", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	mt.Run("ReactToMessageEP - Error outsider of the private conversation", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: MockObjectID, TargetID: primitive.NewObjectID()}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, ReactToMessageEP, db, http.MethodPut, reactionURL(message, "", "👍", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("ReactToMessageEP - Error already reacted", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: member, TargetID: MockObjectID}, nil
+			},
+			AddMessageReactionDBMockFunc: func(r models.MessageReaction) (map[string]int, error) {
+				return nil, database.ErrNoModified
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, ReactToMessageEP, db, http.MethodPut, reactionURL(message, "", "👍", OPERATION_ADD), nil)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	mt.Run("ReactToMessageEP - Error reaction not found", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return &models.P2PContentChatLog{ID: oi, AuthorID: member, TargetID: MockObjectID}, nil
+			},
+			RemoveMessageReactionDBMockFunc: func(r models.MessageReaction) (map[string]int, error) {
+				return nil, database.ErrNoDeleted
+			},
+		}
+
+		rr, res := serveGroupRequest(t, ReactToMessageEP, db, http.MethodPut, reactionURL(message, "", "👍", OPERATION_REMOVE), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+}
+
+// TestGetMessageReactionsEP tests the handler GetMessageReactionsEP
+func TestGetMessageReactionsEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	message := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+
+	mt.Run("GetMessageReactionsEP - Success", func(mt *mtest.T) {
+
+		var page int
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: member}, true, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: group.ID}, nil
+			},
+			GetMessageReactionsDBMockFunc: func(pg int, oi primitive.ObjectID) ([]*models.MessageReaction, error) {
+				page = pg
+				return []*models.MessageReaction{{MessageID: oi, UserID: MockObjectID, Emoji: "🎉"}}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetMessageReactionsEP, db, http.MethodGet, "/mrc?pg=2&gi=123456789&ui=alice@mail.com&mi="+message.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 2, page)
+		assert.Len(t, res.DATA, 1)
+	})
+
+	mt.Run("GetMessageReactionsEP - Error message not found", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: member}, true, nil
+			},
+			GetP2PMessageDBMockFunc: func(oi primitive.ObjectID) (*models.P2PContentChatLog, error) {
+				return nil, mongo.ErrNoDocuments
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetMessageReactionsEP, db, http.MethodGet, "/mrc?ui=alice@mail.com&mi="+message.Hex(), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
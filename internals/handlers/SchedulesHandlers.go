@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+ScheduleMessageEP
+Schedules a text message of the user to a group or to another user, the message
+is sent at its send time by the scheduler
+*/
+func ScheduleMessageEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.ScheduleRequest
+
+	err := tools.ReadJSON(w, r, &req)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	now := time.Now()
+
+	err = req.ValidateDestination(user.ID)
+	if err == nil {
+		err = req.Validate(now)
+	}
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	msg := models.FormatScheduledMessage(user, req, now)
+
+	if req.GroupID != "" {
+
+		group, ok := authorizeGroup(w, db, req.GroupID, user.ID.Hex(), models.PERMISSION_SEND_MESSAGES)
+		if !ok {
+			return
+		}
+
+		topic, err := group.CheckTopic(req.TopicID)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return
+		}
+
+		msg.TargetID = group.ID
+		msg.TopicID = topic
+
+	} else {
+
+		users, err := db.GetUsersByIDDB([]primitive.ObjectID{req.TargetID})
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+		if len(users) == 0 {
+			alog.ErrorLog(fmt.Sprintf("user %s not found", req.TargetID.Hex()))
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("user not found", server.NO_DOCUMENTS))
+			return
+		}
+	}
+
+	_, err = db.InsertScheduledMessageDB(msg)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(msg, server.OK, "ok"))
+}
+
+/*
+GetScheduledMessagesEP
+Returns the scheduled messages of the user that were not sent yet, the next to be
+sent first, the failed ones tell why they were not sent
+*/
+func GetScheduledMessagesEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	messages, err := db.GetScheduledMessagesDB(pg, user.ID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(messages, server.OK, "ok"))
+}
+
+/*
+UpdateScheduledMessageEP
+Changes the body and the send time of the scheduled message (si) of the user
+while it is not due, a failed message is scheduled again
+*/
+func UpdateScheduledMessageEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := primitive.ObjectIDFromHex(r.URL.Query().Get("si"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	var req models.ScheduleRequest
+
+	err = tools.ReadJSON(w, r, &req)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	now := time.Now()
+
+	err = req.Validate(now)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	msg, err := db.UpdateScheduledMessageDB(id, user.ID, req.Body, req.SendAt, now)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("scheduled message not found or already due", server.NO_DOCUMENTS))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(msg, server.OK, "ok"))
+}
+
+/*
+CancelScheduledMessageEP
+Cancels the scheduled message (si) of the user while it is not due
+*/
+func CancelScheduledMessageEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := primitive.ObjectIDFromHex(r.URL.Query().Get("si"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return
+	}
+
+	err = db.CancelScheduledMessageDB(id, user.ID, time.Now())
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == database.ErrNoDeleted {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("scheduled message not found or already due", server.NO_DOCUMENTS))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(id.Hex(), server.OK, "ok"))
+}
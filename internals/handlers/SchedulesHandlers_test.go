@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestScheduleMessageEP tests the handler ScheduleMessageEP
+func TestScheduleMessageEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	peer := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+
+	findMember := func(s string) (models.User, bool, error) {
+		return models.User{ID: member, Name: "alice"}, true, nil
+	}
+
+	sendAt := time.Now().Add(time.Hour)
+
+	mt.Run("ScheduleMessageEP - Success group", func(mt *mtest.T) {
+
+		var stored models.ScheduledMessage
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			InsertScheduledMessageDBMockFunc: func(m models.ScheduledMessage) (string, error) {
+				stored = m
+				return m.ID.Hex(), nil
+			},
+		}
+
+		body := models.ScheduleRequest{GroupID: "123456789", Body: "  meeting in 5  ", SendAt: sendAt}
+		rr, res := serveGroupRequest(t, ScheduleMessageEP, db, http.MethodPost, "/msc?ui=alice@mail.com", body)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.CONVERSATION_GROUP, stored.Conversation)
+		assert.Equal(t, group.ID, stored.TargetID)
+		assert.Equal(t, member, stored.AuthorID)
+		assert.Equal(t, "meeting in 5", stored.Body)
+		assert.Equal(t, models.SCHEDULE_PENDING, stored.Status)
+		assert.True(t, sendAt.Equal(stored.SendAt))
+		assert.Equal(t, stored.ID.Hex(), res.DATA.(map[string]any)["_id"])
+	})
+
+	mt.Run("ScheduleMessageEP - Success private conversation", func(mt *mtest.T) {
+
+		var stored models.ScheduledMessage
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			GetUsersByIDDBMockFunc: func(ids []primitive.ObjectID) ([]*models.User, error) {
+				return []*models.User{{ID: ids[0]}}, nil
+			},
+			InsertScheduledMessageDBMockFunc: func(m models.ScheduledMessage) (string, error) {
+				stored = m
+				return m.ID.Hex(), nil
+			},
+		}
+
+		body := models.ScheduleRequest{TargetID: peer, Body: "happy birthday", SendAt: sendAt}
+		rr, _ := serveGroupRequest(t, ScheduleMessageEP, db, http.MethodPost, "/msc?ui=alice@mail.com", body)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.CONVERSATION_P2P, stored.Conversation)
+		assert.Equal(t, peer, stored.TargetID)
+		assert.Empty(t, stored.GroupID)
+	})
+
+	mt.Run("ScheduleMessageEP - Error invalid requests", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+		}
+
+		for _, body := range []models.ScheduleRequest{
+			{TargetID: peer, Body: "   ", SendAt: sendAt},
+			{TargetID: peer, Body: "late", SendAt: time.Now().Add(-time.Minute)},
+			{TargetID: peer, Body: "too far", SendAt: time.Now().Add(models.MAX_SCHEDULE_AHEAD + time.Hour)},
+			{Body: "nowhere", SendAt: sendAt},
+			{GroupID: "123456789", TargetID: peer, Body: "both", SendAt: sendAt},
+			{TargetID: member, Body: "myself", SendAt: sendAt},
+		} {
+			rr, res := serveGroupRequest(t, ScheduleMessageEP, db, http.MethodPost, "/msc?ui=alice@mail.com", body)
+
+			assert.Equal(t, http.StatusNotAcceptable, rr.Code, body.Body)
+			assert.Equal(t, server.BAD_FIELD, res.Code, body.Body)
+		}
+	})
+
+	mt.Run("ScheduleMessageEP - Error outsider of the group", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: primitive.NewObjectID()}, true, nil
+			},
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+		}
+
+		body := models.ScheduleRequest{GroupID: "123456789", Body: "hello", SendAt: sendAt}
+		rr, res := serveGroupRequest(t, ScheduleMessageEP, db, http.MethodPost, "/msc?ui=alice@mail.com", body)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("ScheduleMessageEP - Error user not found", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+		}
+
+		body := models.ScheduleRequest{TargetID: peer, Body: "hello", SendAt: sendAt}
+		rr, res := serveGroupRequest(t, ScheduleMessageEP, db, http.MethodPost, "/msc?ui=alice@mail.com", body)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+}
+
+// TestGetScheduledMessagesEP tests the handler GetScheduledMessagesEP
+func TestGetScheduledMessagesEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("GetScheduledMessagesEP - Success", func(mt *mtest.T) {
+
+		var page int
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: member}, true, nil
+			},
+			GetScheduledMessagesDBMockFunc: func(pg int, author primitive.ObjectID) ([]*models.ScheduledMessage, error) {
+				page = pg
+				return []*models.ScheduledMessage{{AuthorID: author}}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetScheduledMessagesEP, db, http.MethodGet, "/msc?ui=alice@mail.com&pg=3", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 3, page)
+		assert.Len(t, res.DATA, 1)
+	})
+}
+
+// TestUpdateScheduledMessageEP tests the handler UpdateScheduledMessageEP
+func TestUpdateScheduledMessageEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	id := primitive.NewObjectID()
+
+	findMember := func(s string) (models.User, bool, error) {
+		return models.User{ID: member}, true, nil
+	}
+
+	mt.Run("UpdateScheduledMessageEP - Success", func(mt *mtest.T) {
+
+		var author primitive.ObjectID
+		var body string
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			UpdateScheduledMessageDBMockFunc: func(i, a primitive.ObjectID, b string, sendAt, now time.Time) (*models.ScheduledMessage, error) {
+				author, body = a, b
+				return &models.ScheduledMessage{ID: i, Body: b, SendAt: sendAt}, nil
+			},
+		}
+
+		req := models.ScheduleRequest{Body: "see you at 6", SendAt: time.Now().Add(2 * time.Hour)}
+		rr, _ := serveGroupRequest(t, UpdateScheduledMessageEP, db, http.MethodPut, "/msc?ui=alice@mail.com&si="+id.Hex(), req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, member, author)
+		assert.Equal(t, "see you at 6", body)
+	})
+
+	mt.Run("UpdateScheduledMessageEP - Error already due", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			UpdateScheduledMessageDBMockFunc: func(i, a primitive.ObjectID, b string, sendAt, now time.Time) (*models.ScheduledMessage, error) {
+				return nil, mongo.ErrNoDocuments
+			},
+		}
+
+		req := models.ScheduleRequest{Body: "see you at 6", SendAt: time.Now().Add(2 * time.Hour)}
+		rr, res := serveGroupRequest(t, UpdateScheduledMessageEP, db, http.MethodPut, "/msc?ui=alice@mail.com&si="+id.Hex(), req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+
+	mt.Run("UpdateScheduledMessageEP - Error send time in the past", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+		}
+
+		req := models.ScheduleRequest{Body: "see you at 6", SendAt: time.Now().Add(-time.Hour)}
+		rr, res := serveGroupRequest(t, UpdateScheduledMessageEP, db, http.MethodPut, "/msc?ui=alice@mail.com&si="+id.Hex(), req)
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+}
+
+// TestCancelScheduledMessageEP tests the handler CancelScheduledMessageEP
+func TestCancelScheduledMessageEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	id := primitive.NewObjectID()
+
+	findMember := func(s string) (models.User, bool, error) {
+		return models.User{ID: member}, true, nil
+	}
+
+	mt.Run("CancelScheduledMessageEP - Success", func(mt *mtest.T) {
+
+		var cancelled primitive.ObjectID
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			CancelScheduledMessageDBMockFunc: func(i, a primitive.ObjectID, now time.Time) error {
+				cancelled = i
+				return nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, CancelScheduledMessageEP, db, http.MethodDelete, "/msc?ui=alice@mail.com&si="+id.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, id, cancelled)
+	})
+
+	mt.Run("CancelScheduledMessageEP - Error already due", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findMember,
+			CancelScheduledMessageDBMockFunc: func(i, a primitive.ObjectID, now time.Time) error {
+				return database.ErrNoDeleted
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CancelScheduledMessageEP, db, http.MethodDelete, "/msc?ui=alice@mail.com&si="+id.Hex(), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+SearchMessagesEP
+Searches the query (q) on the messages of the conversations of the user (ai),
+best matches first. The search can be narrowed to a group (gi) or to the private
+conversation with a user (ti), to an author (au), to a date range (fr, to in
+RFC 3339) and to a message type (ty)
+*/
+func SearchMessagesEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 || pg > models.MAX_SEARCH_PAGE {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ai"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	filter, err := parseSearchFilter(r)
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	groupID := r.URL.Query().Get("gi")
+
+	switch {
+	case groupID != "" && !filter.Peer.IsZero():
+		alog.ErrorLog("group and private conversation given")
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse("search a group or a private conversation, not both", server.BAD_FIELD))
+		return
+
+	case groupID != "":
+		// every member searches the group
+		group, ok := authorizeGroup(w, db, groupID, user.Hex(), models.PERMISSION_SEND_MESSAGES)
+		if !ok {
+			return
+		}
+		filter.Groups = []primitive.ObjectID{group.ID}
+
+	case !filter.Peer.IsZero():
+		filter.Private = true
+
+	default:
+		filter.Private = true
+		filter.Groups, err = db.GetUserGroupIDsDB(user)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+	}
+
+	results, err := db.SearchMessagesDB(pg, user, filter)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(results, server.OK, "ok"))
+}
+
+// parseSearchFilter reads the filters of the search from the query params, the empty ones are left zero
+func parseSearchFilter(r *http.Request) (models.MessageSearchFilter, error) {
+
+	q := r.URL.Query()
+
+	f := models.MessageSearchFilter{
+		Query: q.Get("q"),
+	}
+
+	var err error
+
+	if v := q.Get("ti"); v != "" {
+		f.Peer, err = primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid user %q", v)
+		}
+	}
+
+	if v := q.Get("au"); v != "" {
+		f.Author, err = primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid author %q", v)
+		}
+	}
+
+	if v := q.Get("fr"); v != "" {
+		f.From, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("invalid date %q", v)
+		}
+	}
+
+	if v := q.Get("to"); v != "" {
+		f.To, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("invalid date %q", v)
+		}
+	}
+
+	if v := q.Get("ty"); v != "" {
+		f.BodyType, err = strconv.Atoi(v)
+		if err != nil {
+			return f, models.ErrInvalidSearchType
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestSearchMessagesEP tests the handler SearchMessagesEP
+func TestSearchMessagesEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+
+	searchURL := func(params url.Values) string {
+		params.Set("ai", member.Hex())
+		return "/sms?" + params.Encode()
+	}
+
+	mt.Run("SearchMessagesEP - Success every conversation", func(mt *mtest.T) {
+
+		var filter models.MessageSearchFilter
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetUserGroupIDsDBMockFunc: func(u primitive.ObjectID) ([]primitive.ObjectID, error) {
+				return []primitive.ObjectID{group.ID}, nil
+			},
+			SearchMessagesDBMockFunc: func(pg int, user primitive.ObjectID, f models.MessageSearchFilter) ([]*models.MessageSearchResult, error) {
+				filter = f
+				return []*models.MessageSearchResult{{ID: primitive.NewObjectID(), Conversation: models.CONVERSATION_GROUP, Snippet: "launch"}}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, SearchMessagesEP, db, http.MethodGet, searchURL(url.Values{
+			"q":  {"  launch "},
+			"fr": {"2024-01-01T00:00:00Z"},
+			"ty": {"8"},
+		}), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, res.DATA, 1)
+		assert.Equal(t, "launch", filter.Query)
+		assert.True(t, filter.Private)
+		assert.Equal(t, []primitive.ObjectID{group.ID}, filter.Groups)
+		assert.Equal(t, models.MESSAGE_TYPE_TEXT, filter.BodyType)
+		assert.Equal(t, 2024, filter.From.Year())
+	})
+
+	mt.Run("SearchMessagesEP - Success one group", func(mt *mtest.T) {
+
+		var filter models.MessageSearchFilter
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			SearchMessagesDBMockFunc: func(pg int, user primitive.ObjectID, f models.MessageSearchFilter) ([]*models.MessageSearchResult, error) {
+				filter = f
+				return []*models.MessageSearchResult{}, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, SearchMessagesEP, db, http.MethodGet, searchURL(url.Values{"q": {"launch"}, "gi": {"123456789"}}), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.False(t, filter.Private)
+		assert.Equal(t, []primitive.ObjectID{group.ID}, filter.Groups)
+	})
+
+	mt.Run("SearchMessagesEP - Success private conversation", func(mt *mtest.T) {
+
+		peer := primitive.NewObjectID()
+
+		var filter models.MessageSearchFilter
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			SearchMessagesDBMockFunc: func(pg int, user primitive.ObjectID, f models.MessageSearchFilter) ([]*models.MessageSearchResult, error) {
+				filter = f
+				return []*models.MessageSearchResult{}, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, SearchMessagesEP, db, http.MethodGet, searchURL(url.Values{"q": {"launch"}, "ti": {peer.Hex()}}), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.True(t, filter.Private)
+		assert.Equal(t, peer, filter.Peer)
+		assert.Empty(t, filter.Groups)
+	})
+
+	mt.Run("SearchMessagesEP - Error outsider", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(primitive.NewObjectID()), nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, SearchMessagesEP, db, http.MethodGet, searchURL(url.Values{"q": {"launch"}, "gi": {"123456789"}}), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	mt.Run("SearchMessagesEP - Error invalid filters", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		for _, params := range []url.Values{
+			{"q": {"   "}},
+			{"q": {"launch"}, "ty": {"77"}},
+			{"q": {"launch"}, "fr": {"yesterday"}},
+			{"q": {"launch"}, "fr": {"2024-02-01T00:00:00Z"}, "to": {"2024-01-01T00:00:00Z"}},
+			{"q": {"launch"}, "au": {"nope"}},
+			{"q": {"launch"}, "pg": {"11"}},
+			{"q": {"launch"}, "gi": {"123456789"}, "ti": {primitive.NewObjectID().Hex()}},
+		} {
+			rr, _ := serveGroupRequest(t, SearchMessagesEP, db, http.MethodGet, searchURL(params), nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, params.Encode())
+		}
+	})
+}
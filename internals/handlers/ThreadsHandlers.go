@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+GetThreadHistoryEP
+Returns the root message (mi) of a thread of the group with its replies, newest
+first, a reply of the thread returns the whole thread it belongs to
+*/
+func GetThreadHistoryEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	messageID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("mi"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	// every member reads the threads
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), r.URL.Query().Get("ai"), models.PERMISSION_SEND_MESSAGES)
+	if !ok {
+		return
+	}
+
+	root, err := db.GetGroupMessageDB(messageID)
+	if err == nil && root.TargetID != group.ID {
+		err = mongo.ErrNoDocuments
+	}
+	if err == nil && !root.ThreadID.IsZero() {
+		root, err = db.GetGroupMessageDB(root.ThreadID)
+	}
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, err))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	replies, err := db.GetThreadChatLogsDB(pg, group.ID, root.ID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(models.MessageThread{Root: root, Replies: replies}, server.OK, "ok"))
+}
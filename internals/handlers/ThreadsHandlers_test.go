@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestGetThreadHistoryEP tests the handler GetThreadHistoryEP
+func TestGetThreadHistoryEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	group := inviteTestGroup(member)
+	root := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group.ID, Body: "root", ReplyCount: 1}
+	reply := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group.ID, ThreadID: root.ID, Body: "reply"}
+
+	messages := func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+		for _, m := range []*models.GroupChatContentLog{root, reply} {
+			if m.ID == oi {
+				return m, nil
+			}
+		}
+		return nil, mongo.ErrNoDocuments
+	}
+
+	mt.Run("GetThreadHistoryEP - Success", func(mt *mtest.T) {
+
+		var thread primitive.ObjectID
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: messages,
+			GetThreadChatLogsDBMockFunc: func(pg int, id, oi primitive.ObjectID) ([]*models.GroupChatContentLog, error) {
+				thread = oi
+				return []*models.GroupChatContentLog{reply}, nil
+			},
+		}
+
+		// a reply of the thread returns the whole thread
+		rr, res := serveGroupRequest(t, GetThreadHistoryEP, db, http.MethodGet, "/gtr?gi=123456789&ai="+member.Hex()+"&mi="+reply.ID.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, root.ID, thread)
+		data := res.DATA.(map[string]any)
+		assert.Equal(t, "root", data["root"].(map[string]any)["body"])
+		assert.Equal(t, float64(1), data["root"].(map[string]any)["reply_count"])
+		assert.Len(t, data["replies"], 1)
+	})
+
+	mt.Run("GetThreadHistoryEP - Error message of another group", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+			GetGroupMessageDBMockFunc: func(oi primitive.ObjectID) (*models.GroupChatContentLog, error) {
+				return &models.GroupChatContentLog{ID: oi, TargetID: primitive.NewObjectID()}, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetThreadHistoryEP, db, http.MethodGet, "/gtr?gi=123456789&ai="+member.Hex()+"&mi="+root.ID.Hex(), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	mt.Run("GetThreadHistoryEP - Error outsider", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return group, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetThreadHistoryEP, db, http.MethodGet, "/gtr?gi=123456789&ai="+primitive.NewObjectID().Hex()+"&mi="+root.ID.Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+UpdateGroupMessageTimerEP
+sets the timer of the disappearing messages of the group, the messages sent from
+then on are removed once the timer runs out, zero turns the timer off
+*/
+func UpdateGroupMessageTimerEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var timer models.MessageTimer
+
+	err := tools.ReadJSON(w, r, &timer)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	err = timer.Validate()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_EDIT_INFO)
+	if !ok {
+		return
+	}
+
+	if group.MessageTimer != timer.Duration {
+
+		err = db.UpdateGroupDB(map[string]any{"message_timer": timer.Duration}, group.ID)
+		if err != nil && err != database.ErrNoModified {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+
+		recordAudit(db, group, admin, models.AUDIT_MESSAGE_TIMER_CHANGED, map[string]any{"message_timer": group.MessageTimer}, map[string]any{"message_timer": timer.Duration})
+
+		group.MessageTimer = timer.Duration
+		publishGroupUpdate(group)
+
+		author, _ := primitive.ObjectIDFromHex(admin)
+		writeSystemMessage(db, group, author, author, "", models.SYSTEM_EVENT_MESSAGE_TIMER_CHANGED, timer.SystemBody())
+	}
+
+	group.ID = primitive.NilObjectID
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(group, server.OK, "ok"))
+}
+
+/*
+UpdateP2PMessageTimerEP
+sets the timer of the disappearing messages of the private conversation of the
+user (ui) with the target (ti), either user can change it and the change is
+written on the conversation as a system message
+*/
+func UpdateP2PMessageTimerEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var timer models.MessageTimer
+
+	err := tools.ReadJSON(w, r, &timer)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	err = timer.Validate()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	user, target, ok := timerConversation(w, r, db)
+	if !ok {
+		return
+	}
+
+	conversation := models.P2PConversationID(user.ID, target)
+
+	current, err := db.GetP2PMessageTimerDB(conversation)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	if current != timer.Duration {
+
+		err = db.SetP2PMessageTimerDB(models.ConversationTimer{
+			ConversationID: conversation,
+			Duration:       timer.Duration,
+			UpdatedBy:      user.ID,
+			UpdatedAt:      time.Now(),
+		})
+		if err != nil {
+			alog.ErrorLog(err.Error())
+			tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+			return
+		}
+
+		var payload models.P2PSystemLog
+		payload.FormatSystemLog(target, user.ID, user.Name, models.SYSTEM_EVENT_MESSAGE_TIMER_CHANGED, timer.SystemBody())
+
+		_, err = db.InsertP2PMessageDB(payload)
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("system message %s of conversation %s not stored: %v", payload.Event, conversation, err))
+		} else if server.WebsocketHUB != nil {
+			server.WebsocketHUB.DeliverP2PMessage(user.ID, target, payload)
+		}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(timer, server.OK, "ok"))
+}
+
+/*
+GetP2PMessageTimerEP
+returns the timer of the disappearing messages of the private conversation of
+the user (ui) with the target (ti), zero when it is off
+*/
+func GetP2PMessageTimerEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	user, target, ok := timerConversation(w, r, db)
+	if !ok {
+		return
+	}
+
+	duration, err := db.GetP2PMessageTimerDB(models.P2PConversationID(user.ID, target))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(models.MessageTimer{Duration: duration}, server.OK, "ok"))
+}
+
+/*
+timerConversation
+resolves the user (ui) and the other user (ti) of the private conversation,
+writes the error response and returns false when either does not exist
+*/
+func timerConversation(w http.ResponseWriter, r *http.Request, db database.DBHUB) (models.User, primitive.ObjectID, bool) {
+
+	alog := logger.StartLogger()
+
+	target, err := primitive.ObjectIDFromHex(r.URL.Query().Get("ti"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return models.User{}, primitive.NilObjectID, false
+	}
+
+	user, exist, err := db.FindUserDB(r.URL.Query().Get("ui"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return models.User{}, primitive.NilObjectID, false
+	} else if !exist {
+		alog.ErrorLog("forbidden")
+		tools.WriteJSON(w, http.StatusForbidden, tools.FormatCustomErrResponse("forbidden", server.NOT_ALLOWED))
+		return models.User{}, primitive.NilObjectID, false
+	}
+
+	if target == user.ID {
+		alog.ErrorLog(fmt.Sprintf("user %s has no conversation with itself", user.ID.Hex()))
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatCustomErrResponse("no conversation with yourself", server.BAD_FIELD))
+		return models.User{}, primitive.NilObjectID, false
+	}
+
+	users, err := db.GetUsersByIDDB([]primitive.ObjectID{target})
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return models.User{}, primitive.NilObjectID, false
+	}
+	if len(users) == 0 {
+		alog.ErrorLog(fmt.Sprintf("user %s not found", target.Hex()))
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatCustomErrResponse("user not found", server.NO_DOCUMENTS))
+		return models.User{}, primitive.NilObjectID, false
+	}
+
+	return user, target, true
+}
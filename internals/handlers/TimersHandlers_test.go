@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestUpdateGroupMessageTimerEP tests the handler UpdateGroupMessageTimerEP
+func TestUpdateGroupMessageTimerEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+
+	mt.Run("UpdateGroupMessageTimerEP - Success", func(mt *mtest.T) {
+
+		var update map[string]any
+		var system models.GroupSystemLog
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			UpdateGroupDBMockFunc: func(u map[string]any, id primitive.ObjectID) error {
+				update = u
+				return nil
+			},
+			InsertGroupMessageDBMockFun: func(m any) (string, error) {
+				system = m.(models.GroupSystemLog)
+				return system.ID.Hex(), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, UpdateGroupMessageTimerEP, db, http.MethodPut, "/ugtm?gi=123456789&ai="+MockObjectID.Hex(), models.MessageTimer{Duration: 86400})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 86400, update["message_timer"])
+		assert.Equal(t, float64(86400), res.DATA.(map[string]any)["message_timer"])
+		assert.Equal(t, models.SYSTEM_EVENT_MESSAGE_TIMER_CHANGED, system.Event)
+		assert.Equal(t, "disappearing messages were set to 24h0m0s", system.Body)
+	})
+
+	mt.Run("UpdateGroupMessageTimerEP - Success timer unchanged", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				group := inviteTestGroup(member)
+				group.MessageTimer = 3600
+				return group, nil
+			},
+			UpdateGroupDBMockFunc: func(u map[string]any, id primitive.ObjectID) error {
+				t.Fatal("the group must not be updated")
+				return nil
+			},
+			InsertGroupMessageDBMockFun: func(m any) (string, error) {
+				t.Fatal("no system message expected")
+				return "", nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, UpdateGroupMessageTimerEP, db, http.MethodPut, "/ugtm?gi=123456789&ai="+MockObjectID.Hex(), models.MessageTimer{Duration: 3600})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	mt.Run("UpdateGroupMessageTimerEP - Error invalid timer", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+		}
+
+		for _, duration := range []int{-1, models.MIN_MESSAGE_TIMER - 1, models.MAX_MESSAGE_TIMER + 1} {
+			rr, res := serveGroupRequest(t, UpdateGroupMessageTimerEP, db, http.MethodPut, "/ugtm?gi=123456789&ai="+MockObjectID.Hex(), models.MessageTimer{Duration: duration})
+
+			assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+			assert.Equal(t, server.BAD_FIELD, res.Code)
+		}
+	})
+
+	mt.Run("UpdateGroupMessageTimerEP - Error member without permission", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, UpdateGroupMessageTimerEP, db, http.MethodPut, "/ugtm?gi=123456789&ai="+member.Hex(), models.MessageTimer{Duration: 3600})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+}
+
+// TestUpdateP2PMessageTimerEP tests the handler UpdateP2PMessageTimerEP
+func TestUpdateP2PMessageTimerEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+
+	findAlice := func(s string) (models.User, bool, error) {
+		return models.User{ID: alice, Name: "alice"}, true, nil
+	}
+
+	mt.Run("UpdateP2PMessageTimerEP - Success", func(mt *mtest.T) {
+
+		var stored models.ConversationTimer
+		var system models.P2PSystemLog
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findAlice,
+			GetUsersByIDDBMockFunc: func(ids []primitive.ObjectID) ([]*models.User, error) {
+				return []*models.User{{ID: ids[0]}}, nil
+			},
+			SetP2PMessageTimerDBMockFunc: func(timer models.ConversationTimer) error {
+				stored = timer
+				return nil
+			},
+			InsertP2PMessageDBMockFunc: func(m any) (string, error) {
+				system = m.(models.P2PSystemLog)
+				return system.ID.Hex(), nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, UpdateP2PMessageTimerEP, db, http.MethodPut, "/mtm?ui=alice@mail.com&ti="+bob.Hex(), models.MessageTimer{Duration: 604800})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.P2PConversationID(bob, alice), stored.ConversationID)
+		assert.Equal(t, 604800, stored.Duration)
+		assert.Equal(t, alice, stored.UpdatedBy)
+		assert.Equal(t, models.SYSTEM_EVENT_MESSAGE_TIMER_CHANGED, system.Event)
+		assert.Equal(t, models.MESSAGE_TYPE_SYSTEM, system.BodyType)
+		assert.Equal(t, bob, system.TargetID)
+		assert.Equal(t, "alice", system.AuthorName)
+	})
+
+	mt.Run("UpdateP2PMessageTimerEP - Success turned off", func(mt *mtest.T) {
+
+		var system models.P2PSystemLog
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findAlice,
+			GetUsersByIDDBMockFunc: func(ids []primitive.ObjectID) ([]*models.User, error) {
+				return []*models.User{{ID: ids[0]}}, nil
+			},
+			GetP2PMessageTimerDBMockFunc: func(s string) (int, error) {
+				return 3600, nil
+			},
+			InsertP2PMessageDBMockFunc: func(m any) (string, error) {
+				system = m.(models.P2PSystemLog)
+				return system.ID.Hex(), nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, UpdateP2PMessageTimerEP, db, http.MethodPut, "/mtm?ui=alice@mail.com&ti="+bob.Hex(), models.MessageTimer{Duration: 0})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "disappearing messages were turned off", system.Body)
+	})
+
+	mt.Run("UpdateP2PMessageTimerEP - Error conversation with itself", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findAlice,
+		}
+
+		rr, res := serveGroupRequest(t, UpdateP2PMessageTimerEP, db, http.MethodPut, "/mtm?ui=alice@mail.com&ti="+alice.Hex(), models.MessageTimer{Duration: 3600})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("UpdateP2PMessageTimerEP - Error user not found", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:           mt.Client,
+			DatabaseName:     MockDBName,
+			FindUserMockFunc: findAlice,
+		}
+
+		rr, res := serveGroupRequest(t, UpdateP2PMessageTimerEP, db, http.MethodPut, "/mtm?ui=alice@mail.com&ti="+bob.Hex(), models.MessageTimer{Duration: 3600})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.NO_DOCUMENTS, res.Code)
+	})
+}
+
+// TestGetP2PMessageTimerEP tests the handler GetP2PMessageTimerEP
+func TestGetP2PMessageTimerEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+
+	mt.Run("GetP2PMessageTimerEP - Success", func(mt *mtest.T) {
+
+		var conversation string
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			FindUserMockFunc: func(s string) (models.User, bool, error) {
+				return models.User{ID: alice}, true, nil
+			},
+			GetUsersByIDDBMockFunc: func(ids []primitive.ObjectID) ([]*models.User, error) {
+				return []*models.User{{ID: ids[0]}}, nil
+			},
+			GetP2PMessageTimerDBMockFunc: func(s string) (int, error) {
+				conversation = s
+				return 3600, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetP2PMessageTimerEP, db, http.MethodGet, "/mtm?ui=alice@mail.com&ti="+bob.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, models.P2PConversationID(alice, bob), conversation)
+		assert.Equal(t, float64(3600), res.DATA.(map[string]any)["duration"])
+	})
+}
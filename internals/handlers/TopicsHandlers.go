@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+	"wechat-back/internals/tools"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+CreateGroupTopicEP
+Adds a topic to the group, the messages of the topic follow the permissions and
+moderation settings of the group
+*/
+func CreateGroupTopicEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var settings models.TopicSettings
+
+	err := tools.ReadJSON(w, r, &settings)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	err = settings.Validate()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_MANAGE_TOPICS)
+	if !ok {
+		return
+	}
+
+	if group.HasTopicNamed(settings.Name, primitive.NilObjectID) {
+		alog.ErrorLog(fmt.Sprintf("topic %q already exists on group %s", settings.Name, group.GroupID))
+		tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("a topic with this name already exists", server.BAD_FIELD))
+		return
+	}
+
+	if len(group.Topics) >= models.MAX_GROUP_TOPICS {
+		alog.ErrorLog(fmt.Sprintf("group %s reached the topics limit", group.GroupID))
+		tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse(fmt.Sprintf("a group can hold up to %d topics", models.MAX_GROUP_TOPICS), server.NOT_ALLOWED))
+		return
+	}
+
+	author, _ := primitive.ObjectIDFromHex(admin)
+	topic := models.FormatGroupTopic(settings.Name, author)
+
+	err = db.AddGroupTopicDB(group.ID, topic)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse(fmt.Sprintf("a group can hold up to %d topics", models.MAX_GROUP_TOPICS), server.NOT_ALLOWED))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_TOPIC_CREATED, nil, map[string]any{"topic_id": topic.ID, "name": topic.Name})
+
+	group.Topics = append(group.Topics, topic)
+	publishGroupUpdate(group)
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(topic, server.OK, "ok"))
+}
+
+/*
+UpdateGroupTopicEP
+Renames the topic (tp) of the group, an archived topic keeps its history but
+takes no new messages
+*/
+func UpdateGroupTopicEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var settings models.TopicSettings
+
+	err := tools.ReadJSON(w, r, &settings)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	err = settings.Validate()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusNotAcceptable, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	topicID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("tp"))
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	admin := r.URL.Query().Get("ai")
+
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), admin, models.PERMISSION_MANAGE_TOPICS)
+	if !ok {
+		return
+	}
+
+	topic, err := group.Topic(topicID)
+	if err != nil || topic == nil {
+		alog.ErrorLog(fmt.Sprintf("topic %s not found on group %s", topicID.Hex(), group.GroupID))
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, models.ErrTopicNotFound))
+		return
+	}
+
+	if group.HasTopicNamed(settings.Name, topic.ID) {
+		alog.ErrorLog(fmt.Sprintf("topic %q already exists on group %s", settings.Name, group.GroupID))
+		tools.WriteJSON(w, http.StatusConflict, tools.FormatCustomErrResponse("a topic with this name already exists", server.BAD_FIELD))
+		return
+	}
+
+	before := map[string]any{"topic_id": topic.ID, "name": topic.Name, "archived": topic.Archived}
+
+	topic.Name = settings.Name
+	topic.Archived = settings.Archived
+
+	err = db.UpdateGroupTopicDB(group.ID, *topic)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		if err == mongo.ErrNoDocuments {
+			tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, models.ErrTopicNotFound))
+			return
+		}
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	recordAudit(db, group, admin, models.AUDIT_TOPIC_UPDATED, before, map[string]any{"topic_id": topic.ID, "name": topic.Name, "archived": topic.Archived})
+
+	publishGroupUpdate(group)
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(topic, server.OK, "ok"))
+}
+
+/*
+GetGroupTopicsEP
+Returns the topics of the group with the messages the user has not read on each
+*/
+func GetGroupTopicsEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	actor := r.URL.Query().Get("ai")
+
+	// every member reads the topics
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), actor, models.PERMISSION_SEND_MESSAGES)
+	if !ok {
+		return
+	}
+
+	user, _ := primitive.ObjectIDFromHex(actor)
+
+	reads, err := db.GetTopicReadsDB(group.GroupID, user)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	// topics never read count every message
+	since := make(map[primitive.ObjectID]time.Time, len(group.Topics))
+	for _, t := range group.Topics {
+		since[t.ID] = reads[t.ID]
+	}
+
+	unread, err := db.CountTopicUnreadDB(group.ID, user, since)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	topics := make([]models.TopicState, 0, len(group.Topics))
+	for _, t := range group.Topics {
+		topics = append(topics, models.TopicState{
+			GroupTopic: t,
+			ReadAt:     reads[t.ID],
+			Unread:     unread[t.ID],
+		})
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(topics, server.OK, "ok"))
+}
+
+/*
+GetTopicHistoryEP
+Returns the messages of the topic (tp) of the group, newest first, without a
+topic returns the main conversation of the group
+*/
+func GetTopicHistoryEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	p := r.URL.Query().Get("pg")
+	if p == "" {
+		p = "1"
+	}
+
+	pg, err := strconv.Atoi(p)
+	if err != nil || pg < 1 {
+		if err == nil {
+			err = fmt.Errorf("invalid page %d", pg)
+		}
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, err))
+		return
+	}
+
+	group, topicID, ok := authorizeTopic(w, db, r)
+	if !ok {
+		return
+	}
+
+	messages, err := db.GetTopicChatLogsDB(pg, group.ID, topicID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(messages, server.OK, "ok"))
+}
+
+/*
+MarkTopicReadEP
+Marks every message of the topic (tp) as read by the user
+*/
+func MarkTopicReadEP(w http.ResponseWriter, r *http.Request, db database.DBHUB) {
+
+	alog := logger.StartLogger()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	group, topicID, ok := authorizeTopic(w, db, r)
+	if !ok {
+		return
+	}
+
+	if topicID.IsZero() {
+		alog.ErrorLog("no topic")
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.BAD_FIELD, models.ErrTopicNotFound))
+		return
+	}
+
+	user, _ := primitive.ObjectIDFromHex(r.URL.Query().Get("ai"))
+	readAt := time.Now()
+
+	err := db.MarkTopicReadDB(group.GroupID, topicID, user, readAt)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		tools.WriteJSON(w, http.StatusBadRequest, tools.FormatErrResponse(server.DB_ERROR, err))
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, tools.FormatSuccessResponse(models.TopicRead{
+		GroupID: group.GroupID,
+		TopicID: topicID,
+		UserID:  user,
+		ReadAt:  readAt,
+	}, server.OK, "ok"))
+}
+
+/*
+authorizeTopic
+gets the group of the member (ai) and the topic (tp) on it, writes the error
+response and returns false when the topic does not exist on the group
+*/
+func authorizeTopic(w http.ResponseWriter, db database.DBHUB, r *http.Request) (*models.Group, primitive.ObjectID, bool) {
+
+	alog := logger.StartLogger()
+
+	// every member reads the topics
+	group, ok := authorizeGroup(w, db, r.URL.Query().Get("gi"), r.URL.Query().Get("ai"), models.PERMISSION_SEND_MESSAGES)
+	if !ok {
+		return nil, primitive.NilObjectID, false
+	}
+
+	tp := r.URL.Query().Get("tp")
+	if tp == "" {
+		return group, primitive.NilObjectID, true
+	}
+
+	topicID, err := primitive.ObjectIDFromHex(tp)
+	if err == nil {
+		_, err = group.Topic(topicID)
+	}
+	if err != nil {
+		alog.ErrorLog(fmt.Sprintf("topic %s not found on group %s", tp, group.GroupID))
+		tools.WriteJSON(w, http.StatusNotFound, tools.FormatErrResponse(server.BAD_REQUEST, models.ErrTopicNotFound))
+		return nil, primitive.NilObjectID, false
+	}
+
+	return group, topicID, true
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+	"wechat-back/internals/server"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// topicTestGroup group with a topic for the topic handlers
+func topicTestGroup(member primitive.ObjectID, topic models.GroupTopic) *models.Group {
+	group := inviteTestGroup(member)
+	group.Topics = []models.GroupTopic{topic}
+	return group
+}
+
+// TestCreateGroupTopicEP tests the handler CreateGroupTopicEP
+func TestCreateGroupTopicEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	releases := models.FormatGroupTopic("releases", MockObjectID)
+
+	mt.Run("CreateGroupTopicEP - Success", func(mt *mtest.T) {
+
+		var stored models.GroupTopic
+		var audit models.AuditEntry
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+			AddGroupTopicDBMockFunc: func(oi primitive.ObjectID, gt models.GroupTopic) error {
+				stored = gt
+				return nil
+			},
+			InsertAuditEntryDBMockFunc: func(e models.AuditEntry) (string, error) {
+				audit = e
+				return "", nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CreateGroupTopicEP, db, http.MethodPost, "/gtp?gi=123456789&ai="+MockObjectID.Hex(), models.TopicSettings{Name: "  support  "})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "support", stored.Name)
+		assert.Equal(t, MockObjectID, stored.CreatedBy)
+		assert.Equal(t, stored.ID.Hex(), res.DATA.(map[string]any)["_id"])
+		assert.Equal(t, models.AUDIT_TOPIC_CREATED, audit.Action)
+	})
+
+	mt.Run("CreateGroupTopicEP - Error duplicated name", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CreateGroupTopicEP, db, http.MethodPost, "/gtp?gi=123456789&ai="+MockObjectID.Hex(), models.TopicSettings{Name: "Releases"})
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("CreateGroupTopicEP - Error topics limit", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+			AddGroupTopicDBMockFunc: func(oi primitive.ObjectID, gt models.GroupTopic) error {
+				return mongo.ErrNoDocuments
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CreateGroupTopicEP, db, http.MethodPost, "/gtp?gi=123456789&ai="+MockObjectID.Hex(), models.TopicSettings{Name: "support"})
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+
+	mt.Run("CreateGroupTopicEP - Error empty name", func(mt *mtest.T) {
+
+		db := &DBMock{Client: mt.Client, DatabaseName: MockDBName}
+
+		rr, res := serveGroupRequest(t, CreateGroupTopicEP, db, http.MethodPost, "/gtp?gi=123456789&ai="+MockObjectID.Hex(), models.TopicSettings{Name: " "})
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+
+	mt.Run("CreateGroupTopicEP - Error member denied", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return inviteTestGroup(member), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, CreateGroupTopicEP, db, http.MethodPost, "/gtp?gi=123456789&ai="+member.Hex(), models.TopicSettings{Name: "support"})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+}
+
+// TestUpdateGroupTopicEP tests the handler UpdateGroupTopicEP
+func TestUpdateGroupTopicEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	releases := models.FormatGroupTopic("releases", MockObjectID)
+
+	mt.Run("UpdateGroupTopicEP - Success archive", func(mt *mtest.T) {
+
+		var stored models.GroupTopic
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+			UpdateGroupTopicDBMockFunc: func(oi primitive.ObjectID, gt models.GroupTopic) error {
+				stored = gt
+				return nil
+			},
+		}
+
+		url := fmt.Sprintf("/gtp?gi=123456789&ai=%s&tp=%s", MockObjectID.Hex(), releases.ID.Hex())
+		rr, _ := serveGroupRequest(t, UpdateGroupTopicEP, db, http.MethodPut, url, models.TopicSettings{Name: "old releases", Archived: true})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, releases.ID, stored.ID)
+		assert.Equal(t, "old releases", stored.Name)
+		assert.True(t, stored.Archived)
+	})
+
+	mt.Run("UpdateGroupTopicEP - Error topic not found", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+		}
+
+		url := fmt.Sprintf("/gtp?gi=123456789&ai=%s&tp=%s", MockObjectID.Hex(), primitive.NewObjectID().Hex())
+		rr, res := serveGroupRequest(t, UpdateGroupTopicEP, db, http.MethodPut, url, models.TopicSettings{Name: "support"})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.BAD_REQUEST, res.Code)
+	})
+}
+
+// TestGetGroupTopicsEP tests the handler GetGroupTopicsEP
+func TestGetGroupTopicsEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	releases := models.FormatGroupTopic("releases", MockObjectID)
+
+	mt.Run("GetGroupTopicsEP - Success", func(mt *mtest.T) {
+
+		readAt := time.Now().Add(-time.Hour)
+		var since map[primitive.ObjectID]time.Time
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+			GetTopicReadsDBMockFunc: func(s string, u primitive.ObjectID) (map[primitive.ObjectID]time.Time, error) {
+				return map[primitive.ObjectID]time.Time{releases.ID: readAt}, nil
+			},
+			CountTopicUnreadDBMockFunc: func(oi, u primitive.ObjectID, reads map[primitive.ObjectID]time.Time) (map[primitive.ObjectID]int, error) {
+				since = reads
+				return map[primitive.ObjectID]int{releases.ID: 3}, nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupTopicsEP, db, http.MethodGet, "/gtp?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, readAt, since[releases.ID])
+
+		topics := res.DATA.([]any)
+		assert.Len(t, topics, 1)
+		assert.Equal(t, "releases", topics[0].(map[string]any)["name"])
+		assert.Equal(t, float64(3), topics[0].(map[string]any)["unread"])
+	})
+
+	mt.Run("GetGroupTopicsEP - Error outsider denied", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, GetGroupTopicsEP, db, http.MethodGet, "/gtp?gi=123456789&ai="+primitive.NewObjectID().Hex(), nil)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, server.NOT_ALLOWED, res.Code)
+	})
+}
+
+// TestGetTopicHistoryEP tests the handler GetTopicHistoryEP
+func TestGetTopicHistoryEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	releases := models.FormatGroupTopic("releases", MockObjectID)
+
+	mt.Run("GetTopicHistoryEP - Success", func(mt *mtest.T) {
+
+		var page int
+		var topic primitive.ObjectID
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+			GetTopicChatLogsDBMockFunc: func(pg int, oi, tp primitive.ObjectID) ([]*models.GroupChatContentLog, error) {
+				page, topic = pg, tp
+				return []*models.GroupChatContentLog{{Body: "v1.2 is out", TopicID: tp}}, nil
+			},
+		}
+
+		url := fmt.Sprintf("/gth?gi=123456789&ai=%s&tp=%s&pg=2", member.Hex(), releases.ID.Hex())
+		rr, res := serveGroupRequest(t, GetTopicHistoryEP, db, http.MethodGet, url, nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 2, page)
+		assert.Equal(t, releases.ID, topic)
+		assert.Equal(t, "v1.2 is out", res.DATA.([]any)[0].(map[string]any)["body"])
+	})
+
+	mt.Run("GetTopicHistoryEP - Success main conversation", func(mt *mtest.T) {
+
+		topic := releases.ID
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+			GetTopicChatLogsDBMockFunc: func(pg int, oi, tp primitive.ObjectID) ([]*models.GroupChatContentLog, error) {
+				topic = tp
+				return []*models.GroupChatContentLog{}, nil
+			},
+		}
+
+		rr, _ := serveGroupRequest(t, GetTopicHistoryEP, db, http.MethodGet, "/gth?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.True(t, topic.IsZero())
+	})
+
+	mt.Run("GetTopicHistoryEP - Error topic of another group", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+		}
+
+		url := fmt.Sprintf("/gth?gi=123456789&ai=%s&tp=%s", member.Hex(), primitive.NewObjectID().Hex())
+		rr, res := serveGroupRequest(t, GetTopicHistoryEP, db, http.MethodGet, url, nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, server.BAD_REQUEST, res.Code)
+	})
+}
+
+// TestMarkTopicReadEP tests the handler MarkTopicReadEP
+func TestMarkTopicReadEP(t *testing.T) {
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	member := primitive.NewObjectID()
+	releases := models.FormatGroupTopic("releases", MockObjectID)
+
+	mt.Run("MarkTopicReadEP - Success", func(mt *mtest.T) {
+
+		var topic, user primitive.ObjectID
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+			MarkTopicReadDBMockFunc: func(gi string, tp, u primitive.ObjectID, at time.Time) error {
+				topic, user = tp, u
+				return nil
+			},
+		}
+
+		url := fmt.Sprintf("/rtp?gi=123456789&ai=%s&tp=%s", member.Hex(), releases.ID.Hex())
+		rr, _ := serveGroupRequest(t, MarkTopicReadEP, db, http.MethodPut, url, nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, releases.ID, topic)
+		assert.Equal(t, member, user)
+	})
+
+	mt.Run("MarkTopicReadEP - Error no topic", func(mt *mtest.T) {
+
+		db := &DBMock{
+			Client:       mt.Client,
+			DatabaseName: MockDBName,
+			GetGroupDBMockFunc: func(s string) (*models.Group, error) {
+				return topicTestGroup(member, releases), nil
+			},
+		}
+
+		rr, res := serveGroupRequest(t, MarkTopicReadEP, db, http.MethodPut, "/rtp?gi=123456789&ai="+member.Hex(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, server.BAD_FIELD, res.Code)
+	})
+}
@@ -45,7 +45,7 @@ func HandleP2PConnectionEP(w http.ResponseWriter, r *http.Request, db database.D
 		return
 	}
 
-	payload := server.P2PConnectionCredentials{
+	payload := &server.P2PConnectionCredentials{
 		Conn:            conn,
 		AuthorID:        u.ID.Hex(),
 		TargetID:        r.URL.Query().Get("tar"),
@@ -53,7 +53,7 @@ func HandleP2PConnectionEP(w http.ResponseWriter, r *http.Request, db database.D
 		TargetPushToken: r.URL.Query().Get("tar_tkn"),
 	}
 
-	server.WebsocketHUB.P2PConnections[payload.AuthorID] = payload
+	server.WebsocketHUB.RegisterP2PConnection(payload)
 	server.WebsocketHUB.DBConn = db
 	server.WebsocketHUB.MediaProvider = provider
 
@@ -97,14 +97,14 @@ func HandleGroupConnectionsEP(w http.ResponseWriter, r *http.Request, db databas
 		tools.WriteWebsocketJSON(conn, tools.FormatErrResponse(server.DB_ERROR, err))
 	}
 
-	payload := server.GroupConnectionCredentials{
+	payload := &server.GroupConnectionCredentials{
 		Conn:       conn,
 		AuthorID:   author.ID.Hex(),
 		TargetID:   group.ID.Hex(),
 		AuthorData: &author,
 		TargetData: group,
 	}
-	server.WebsocketHUB.GroupConnections[author.ID.Hex()] = payload
+	server.WebsocketHUB.RegisterGroupConnection(payload)
 
 	server.WebsocketHUB.MediaProvider = provider
 	server.WebsocketHUB.DBConn = db
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"time"
 	"wechat-back/internals/models"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,23 +14,121 @@ var (
 )
 
 type DBMock struct {
-	Client             *mongo.Client
-	DatabaseName       string
-	FindUserMockFunc   func(string) (models.User, bool, error)
-	InsertUserMockFunc func(models.User) (string, error)
-	UpdateUserMockFunc func(map[string]any, string) error
-	GetUsersMockFunc   func(int, string) ([]*models.User, error)
+	Client                 *mongo.Client
+	DatabaseName           string
+	FindUserMockFunc       func(string) (models.User, bool, error)
+	InsertUserMockFunc     func(models.User) (string, error)
+	UpdateUserMockFunc     func(map[string]any, string) error
+	GetUsersMockFunc       func(int, string) ([]*models.User, error)
+	GetUsersByIDDBMockFunc func([]primitive.ObjectID) ([]*models.User, error)
 
 	// groups
 	GetGroupDBMockFunc    func(string) (*models.Group, error)
 	InsertGroupDBMockFunc func(models.Group) (string, error)
 	UpdateGroupDBMockFunc func(map[string]any, primitive.ObjectID) error
 	DeleteGroupDBMockFunc func(string) error
-	SearchGroupsMockFunc  func(int, string) ([]*models.Group, error)
+	SearchGroupsMockFunc  func(int, models.GroupDirectoryFilter) ([]*models.PublicGroup, error)
+
+	AddGroupParticipantDBMockFunc    func(primitive.ObjectID, primitive.ObjectID) error
+	UpdateGroupAvatarDBMockFunc      func(primitive.ObjectID, models.GroupAvatar) error
+	UpdateGroupMuteDBMockFunc        func(primitive.ObjectID, primitive.ObjectID, time.Time) error
+	ClaimGroupPostDBMockFunc         func(primitive.ObjectID, primitive.ObjectID, time.Time, time.Duration) (time.Time, error)
+	LeaveGroupDBMockFunc             func(primitive.ObjectID, models.GroupLeave) error
+	TransferGroupOwnershipDBMockFunc func(primitive.ObjectID, primitive.ObjectID, primitive.ObjectID) error
+	UpdateNotificationMuteDBMockFunc func(primitive.ObjectID, primitive.ObjectID, time.Time) error
+	GetUserGroupIDsDBMockFunc        func(primitive.ObjectID) ([]primitive.ObjectID, error)
+
+	// Group deletions
+	ScheduleGroupDeletionDBMockFunc func(primitive.ObjectID, time.Time) error
+	CancelGroupDeletionDBMockFunc   func(primitive.ObjectID) error
+	ClaimGroupDeletionDBMockFunc    func(time.Time, time.Duration) (*models.Group, error)
+	GetGroupMediaDBMockFunc         func(primitive.ObjectID) ([]string, error)
+	GetGroupVideosDBMockFunc        func(primitive.ObjectID) ([]string, error)
+	DeleteGroupCascadeDBMockFunc    func(primitive.ObjectID, string) error
+
+	// Invites
+	InsertGroupInviteDBMockFunc     func(models.GroupInvite) (string, error)
+	GetGroupInviteDBMockFunc        func(string) (*models.GroupInvite, error)
+	GetGroupInvitesDBMockFunc       func(string) ([]*models.GroupInvite, error)
+	RevokeGroupInviteDBMockFunc     func(string) error
+	UseGroupInviteDBMockFunc        func(string) error
+	InsertJoinRequestDBMockFunc     func(models.JoinRequest) (string, error)
+	GetPendingJoinRequestDBMockFunc func(string, primitive.ObjectID) (*models.JoinRequest, error)
+	GetJoinRequestDBMockFunc        func(primitive.ObjectID) (*models.JoinRequest, error)
+	GetJoinRequestsDBMockFunc       func(int, string) ([]*models.JoinRequest, error)
+	ReviewJoinRequestDBMockFunc     func(primitive.ObjectID, string, primitive.ObjectID) error
+
+	// Topics
+	AddGroupTopicDBMockFunc    func(primitive.ObjectID, models.GroupTopic) error
+	UpdateGroupTopicDBMockFunc func(primitive.ObjectID, models.GroupTopic) error
+	MarkTopicReadDBMockFunc    func(string, primitive.ObjectID, primitive.ObjectID, time.Time) error
+	GetTopicReadsDBMockFunc    func(string, primitive.ObjectID) (map[primitive.ObjectID]time.Time, error)
+	CountTopicUnreadDBMockFunc func(primitive.ObjectID, primitive.ObjectID, map[primitive.ObjectID]time.Time) (map[primitive.ObjectID]int, error)
+	GetTopicChatLogsDBMockFunc func(int, primitive.ObjectID, primitive.ObjectID) ([]*models.GroupChatContentLog, error)
+
+	// Reactions
+	GetGroupMessageDBMockFunc       func(primitive.ObjectID) (*models.GroupChatContentLog, error)
+	GetP2PMessageDBMockFunc         func(primitive.ObjectID) (*models.P2PContentChatLog, error)
+	AddMessageReactionDBMockFunc    func(models.MessageReaction) (map[string]int, error)
+	RemoveMessageReactionDBMockFunc func(models.MessageReaction) (map[string]int, error)
+	GetMessageReactionsDBMockFunc   func(int, primitive.ObjectID) ([]*models.MessageReaction, error)
+
+	// Replies
+	AddThreadReplyDBMockFunc    func(primitive.ObjectID) error
+	GetThreadChatLogsDBMockFunc func(int, primitive.ObjectID, primitive.ObjectID) ([]*models.GroupChatContentLog, error)
+
+	// Mentions
+	InsertMentionsDBMockFunc    func([]models.Mention) error
+	GetUnreadMentionsDBMockFunc func(int, primitive.ObjectID, string) ([]*models.Mention, error)
+	MarkMentionsReadDBMockFunc  func(primitive.ObjectID, string, time.Time) error
+
+	// Search
+	SearchMessagesDBMockFunc func(int, primitive.ObjectID, models.MessageSearchFilter) ([]*models.MessageSearchResult, error)
+
+	// Galleries
+	GetGroupMediaGalleryDBMockFunc func(primitive.ObjectID, []int, models.GalleryCursor) ([]*models.MediaGalleryItem, error)
+	GetP2PMediaGalleryDBMockFunc   func(primitive.ObjectID, primitive.ObjectID, []int, models.GalleryCursor) ([]*models.MediaGalleryItem, error)
+
+	// Pins
+	PinMessageDBMockFunc         func(models.MessagePin) error
+	UnpinMessageDBMockFunc       func(string, primitive.ObjectID) error
+	GetPinnedMessagesDBMockFunc  func(string, bool) ([]*models.MessagePin, error)
+	StarMessageDBMockFunc        func(models.MessageStar) error
+	UnstarMessageDBMockFunc      func(primitive.ObjectID, primitive.ObjectID) error
+	GetStarredMessagesDBMockFunc func(int, primitive.ObjectID) ([]*models.MessageStar, error)
+
+	// Forwards
+	InsertForwardedMessagesDBMockFunc func([]models.GroupChatContentLog, []models.P2PContentChatLog) error
+
+	// Scheduled messages
+	InsertScheduledMessageDBMockFunc   func(models.ScheduledMessage) (string, error)
+	GetScheduledMessagesDBMockFunc     func(int, primitive.ObjectID) ([]*models.ScheduledMessage, error)
+	UpdateScheduledMessageDBMockFunc   func(primitive.ObjectID, primitive.ObjectID, string, time.Time, time.Time) (*models.ScheduledMessage, error)
+	CancelScheduledMessageDBMockFunc   func(primitive.ObjectID, primitive.ObjectID, time.Time) error
+	ClaimScheduledMessageDBMockFunc    func(time.Time, time.Duration) (*models.ScheduledMessage, error)
+	CompleteScheduledMessageDBMockFunc func(primitive.ObjectID) error
+	FailScheduledMessageDBMockFunc     func(primitive.ObjectID, string) error
+
+	// Message timers
+	SetP2PMessageTimerDBMockFunc        func(models.ConversationTimer) error
+	GetP2PMessageTimerDBMockFunc        func(string) (int, error)
+	ClaimExpiredGroupMessageDBMockFunc  func(time.Time, time.Duration) (*models.GroupChatContentLog, error)
+	ClaimExpiredP2PMessageDBMockFunc    func(time.Time, time.Duration) (*models.P2PContentChatLog, error)
+	GetSharedMediaDBMockFunc            func(primitive.ObjectID, []string) ([]string, error)
+	DeleteExpiredGroupMessageDBMockFunc func(primitive.ObjectID, primitive.ObjectID) error
+	DeleteExpiredP2PMessageDBMockFunc   func(primitive.ObjectID) error
+
+	// Audit
+	InsertAuditEntryDBMockFunc func(models.AuditEntry) (string, error)
+	GetAuditEntriesDBMockFunc  func(int, string) ([]*models.AuditEntry, error)
 
 	// Chat
 	InsertP2PMessageDBMockFunc  func(any) (string, error)
 	InsertGroupMessageDBMockFun func(any) (string, error)
+
+	// Calls
+	InsertCallLogDBMockFunc  func(models.CallLog) (string, error)
+	GetCallHistoryDBMockFunc func(int, primitive.ObjectID) ([]*models.CallLog, error)
 }
 
 /*USER MOCK FUNCTIONS*/
@@ -60,6 +159,12 @@ func (db *DBMock) GetUsers(pg int, query string) ([]*models.User, error) {
 	}
 	return []*models.User{}, nil
 }
+func (db *DBMock) GetUsersByIDDB(ids []primitive.ObjectID) ([]*models.User, error) {
+	if db.GetUsersByIDDBMockFunc != nil {
+		return db.GetUsersByIDDBMockFunc(ids)
+	}
+	return []*models.User{}, nil
+}
 
 /*GROUP MOCK FUNCTIONS*/
 func (db *DBMock) GetGroupDB(s string) (*models.Group, error) {
@@ -90,11 +195,183 @@ func (db *DBMock) DeleteGroupDB(i string) error {
 	return nil
 }
 
-func (db *DBMock) SearchGroups(pg int, query string) ([]*models.Group, error) {
+func (db *DBMock) SearchGroups(pg int, filter models.GroupDirectoryFilter) ([]*models.PublicGroup, error) {
 	if db.SearchGroupsMockFunc != nil {
-		return db.SearchGroupsMockFunc(pg, query)
+		return db.SearchGroupsMockFunc(pg, filter)
+	}
+	return []*models.PublicGroup{}, nil
+}
+
+func (db *DBMock) AddGroupParticipantDB(i primitive.ObjectID, u primitive.ObjectID) error {
+	if db.AddGroupParticipantDBMockFunc != nil {
+		return db.AddGroupParticipantDBMockFunc(i, u)
+	}
+	return nil
+}
+
+func (db *DBMock) UpdateGroupAvatarDB(i primitive.ObjectID, a models.GroupAvatar) error {
+	if db.UpdateGroupAvatarDBMockFunc != nil {
+		return db.UpdateGroupAvatarDBMockFunc(i, a)
+	}
+	return nil
+}
+
+func (db *DBMock) UpdateGroupMuteDB(i, u primitive.ObjectID, t time.Time) error {
+	if db.UpdateGroupMuteDBMockFunc != nil {
+		return db.UpdateGroupMuteDBMockFunc(i, u, t)
+	}
+	return nil
+}
+
+func (db *DBMock) ClaimGroupPostDB(i, u primitive.ObjectID, t time.Time, w time.Duration) (time.Time, error) {
+	if db.ClaimGroupPostDBMockFunc != nil {
+		return db.ClaimGroupPostDBMockFunc(i, u, t, w)
+	}
+	return time.Time{}, nil
+}
+
+func (db *DBMock) LeaveGroupDB(i primitive.ObjectID, l models.GroupLeave) error {
+	if db.LeaveGroupDBMockFunc != nil {
+		return db.LeaveGroupDBMockFunc(i, l)
+	}
+	return nil
+}
+
+func (db *DBMock) TransferGroupOwnershipDB(i, o, t primitive.ObjectID) error {
+	if db.TransferGroupOwnershipDBMockFunc != nil {
+		return db.TransferGroupOwnershipDBMockFunc(i, o, t)
+	}
+	return nil
+}
+
+func (db *DBMock) UpdateNotificationMuteDB(i, u primitive.ObjectID, t time.Time) error {
+	if db.UpdateNotificationMuteDBMockFunc != nil {
+		return db.UpdateNotificationMuteDBMockFunc(i, u, t)
+	}
+	return nil
+}
+
+func (db *DBMock) GetUserGroupIDsDB(u primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if db.GetUserGroupIDsDBMockFunc != nil {
+		return db.GetUserGroupIDsDBMockFunc(u)
+	}
+	return []primitive.ObjectID{}, nil
+}
+
+// GROUP DELETION METHODS
+
+func (db *DBMock) ScheduleGroupDeletionDB(i primitive.ObjectID, t time.Time) error {
+	if db.ScheduleGroupDeletionDBMockFunc != nil {
+		return db.ScheduleGroupDeletionDBMockFunc(i, t)
+	}
+	return nil
+}
+
+func (db *DBMock) CancelGroupDeletionDB(i primitive.ObjectID) error {
+	if db.CancelGroupDeletionDBMockFunc != nil {
+		return db.CancelGroupDeletionDBMockFunc(i)
+	}
+	return nil
+}
+
+func (db *DBMock) ClaimGroupDeletionDB(t time.Time, l time.Duration) (*models.Group, error) {
+	if db.ClaimGroupDeletionDBMockFunc != nil {
+		return db.ClaimGroupDeletionDBMockFunc(t, l)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (db *DBMock) GetGroupMediaDB(i primitive.ObjectID) ([]string, error) {
+	if db.GetGroupMediaDBMockFunc != nil {
+		return db.GetGroupMediaDBMockFunc(i)
+	}
+	return []string{}, nil
+}
+
+func (db *DBMock) GetGroupVideosDB(i primitive.ObjectID) ([]string, error) {
+	if db.GetGroupVideosDBMockFunc != nil {
+		return db.GetGroupVideosDBMockFunc(i)
+	}
+	return []string{}, nil
+}
+
+func (db *DBMock) DeleteGroupCascadeDB(i primitive.ObjectID, g string) error {
+	if db.DeleteGroupCascadeDBMockFunc != nil {
+		return db.DeleteGroupCascadeDBMockFunc(i, g)
+	}
+	return nil
+}
+
+// INVITE METHODS
+
+func (db *DBMock) InsertGroupInviteDB(i models.GroupInvite) (string, error) {
+	if db.InsertGroupInviteDBMockFunc != nil {
+		return db.InsertGroupInviteDBMockFunc(i)
+	}
+	return "", nil
+}
+
+func (db *DBMock) GetGroupInviteDB(code string) (*models.GroupInvite, error) {
+	if db.GetGroupInviteDBMockFunc != nil {
+		return db.GetGroupInviteDBMockFunc(code)
+	}
+	return &models.GroupInvite{}, nil
+}
+
+func (db *DBMock) GetGroupInvitesDB(g string) ([]*models.GroupInvite, error) {
+	if db.GetGroupInvitesDBMockFunc != nil {
+		return db.GetGroupInvitesDBMockFunc(g)
+	}
+	return []*models.GroupInvite{}, nil
+}
+
+func (db *DBMock) RevokeGroupInviteDB(code string) error {
+	if db.RevokeGroupInviteDBMockFunc != nil {
+		return db.RevokeGroupInviteDBMockFunc(code)
+	}
+	return nil
+}
+
+func (db *DBMock) UseGroupInviteDB(code string) error {
+	if db.UseGroupInviteDBMockFunc != nil {
+		return db.UseGroupInviteDBMockFunc(code)
+	}
+	return nil
+}
+
+func (db *DBMock) InsertJoinRequestDB(j models.JoinRequest) (string, error) {
+	if db.InsertJoinRequestDBMockFunc != nil {
+		return db.InsertJoinRequestDBMockFunc(j)
+	}
+	return j.ID.Hex(), nil
+}
+
+func (db *DBMock) GetPendingJoinRequestDB(g string, u primitive.ObjectID) (*models.JoinRequest, error) {
+	if db.GetPendingJoinRequestDBMockFunc != nil {
+		return db.GetPendingJoinRequestDBMockFunc(g, u)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (db *DBMock) GetJoinRequestDB(i primitive.ObjectID) (*models.JoinRequest, error) {
+	if db.GetJoinRequestDBMockFunc != nil {
+		return db.GetJoinRequestDBMockFunc(i)
+	}
+	return &models.JoinRequest{}, nil
+}
+
+func (db *DBMock) GetJoinRequestsDB(pg int, g string) ([]*models.JoinRequest, error) {
+	if db.GetJoinRequestsDBMockFunc != nil {
+		return db.GetJoinRequestsDBMockFunc(pg, g)
 	}
-	return []*models.Group{}, nil
+	return []*models.JoinRequest{}, nil
+}
+
+func (db *DBMock) ReviewJoinRequestDB(i primitive.ObjectID, state string, reviewer primitive.ObjectID) error {
+	if db.ReviewJoinRequestDBMockFunc != nil {
+		return db.ReviewJoinRequestDBMockFunc(i, state, reviewer)
+	}
+	return nil
 }
 
 // CHAT METHODS
@@ -112,3 +389,335 @@ func (db *DBMock) InsertGroupMessageDB(m any) (string, error) {
 	}
 	return "", nil
 }
+
+// TOPIC METHODS
+
+func (db *DBMock) AddGroupTopicDB(id primitive.ObjectID, topic models.GroupTopic) error {
+	if db.AddGroupTopicDBMockFunc != nil {
+		return db.AddGroupTopicDBMockFunc(id, topic)
+	}
+	return nil
+}
+
+func (db *DBMock) UpdateGroupTopicDB(id primitive.ObjectID, topic models.GroupTopic) error {
+	if db.UpdateGroupTopicDBMockFunc != nil {
+		return db.UpdateGroupTopicDBMockFunc(id, topic)
+	}
+	return nil
+}
+
+func (db *DBMock) MarkTopicReadDB(groupID string, topic, user primitive.ObjectID, at time.Time) error {
+	if db.MarkTopicReadDBMockFunc != nil {
+		return db.MarkTopicReadDBMockFunc(groupID, topic, user, at)
+	}
+	return nil
+}
+
+func (db *DBMock) GetTopicReadsDB(groupID string, user primitive.ObjectID) (map[primitive.ObjectID]time.Time, error) {
+	if db.GetTopicReadsDBMockFunc != nil {
+		return db.GetTopicReadsDBMockFunc(groupID, user)
+	}
+	return map[primitive.ObjectID]time.Time{}, nil
+}
+
+func (db *DBMock) CountTopicUnreadDB(id, user primitive.ObjectID, reads map[primitive.ObjectID]time.Time) (map[primitive.ObjectID]int, error) {
+	if db.CountTopicUnreadDBMockFunc != nil {
+		return db.CountTopicUnreadDBMockFunc(id, user, reads)
+	}
+	return map[primitive.ObjectID]int{}, nil
+}
+
+func (db *DBMock) GetTopicChatLogsDB(pg int, id, topic primitive.ObjectID) ([]*models.GroupChatContentLog, error) {
+	if db.GetTopicChatLogsDBMockFunc != nil {
+		return db.GetTopicChatLogsDBMockFunc(pg, id, topic)
+	}
+	return []*models.GroupChatContentLog{}, nil
+}
+
+/*REACTIONS MOCK FUNCTIONS*/
+
+func (db *DBMock) GetGroupMessageDB(id primitive.ObjectID) (*models.GroupChatContentLog, error) {
+	if db.GetGroupMessageDBMockFunc != nil {
+		return db.GetGroupMessageDBMockFunc(id)
+	}
+	return &models.GroupChatContentLog{}, nil
+}
+
+func (db *DBMock) GetP2PMessageDB(id primitive.ObjectID) (*models.P2PContentChatLog, error) {
+	if db.GetP2PMessageDBMockFunc != nil {
+		return db.GetP2PMessageDBMockFunc(id)
+	}
+	return &models.P2PContentChatLog{}, nil
+}
+
+func (db *DBMock) AddMessageReactionDB(r models.MessageReaction) (map[string]int, error) {
+	if db.AddMessageReactionDBMockFunc != nil {
+		return db.AddMessageReactionDBMockFunc(r)
+	}
+	return map[string]int{}, nil
+}
+
+func (db *DBMock) RemoveMessageReactionDB(r models.MessageReaction) (map[string]int, error) {
+	if db.RemoveMessageReactionDBMockFunc != nil {
+		return db.RemoveMessageReactionDBMockFunc(r)
+	}
+	return map[string]int{}, nil
+}
+
+func (db *DBMock) GetMessageReactionsDB(pg int, message primitive.ObjectID) ([]*models.MessageReaction, error) {
+	if db.GetMessageReactionsDBMockFunc != nil {
+		return db.GetMessageReactionsDBMockFunc(pg, message)
+	}
+	return []*models.MessageReaction{}, nil
+}
+
+/*REPLIES MOCK FUNCTIONS*/
+
+func (db *DBMock) AddThreadReplyDB(root primitive.ObjectID) error {
+	if db.AddThreadReplyDBMockFunc != nil {
+		return db.AddThreadReplyDBMockFunc(root)
+	}
+	return nil
+}
+
+func (db *DBMock) GetThreadChatLogsDB(pg int, id, root primitive.ObjectID) ([]*models.GroupChatContentLog, error) {
+	if db.GetThreadChatLogsDBMockFunc != nil {
+		return db.GetThreadChatLogsDBMockFunc(pg, id, root)
+	}
+	return []*models.GroupChatContentLog{}, nil
+}
+
+/*MENTIONS MOCK FUNCTIONS*/
+
+func (db *DBMock) InsertMentionsDB(mentions []models.Mention) error {
+	if db.InsertMentionsDBMockFunc != nil {
+		return db.InsertMentionsDBMockFunc(mentions)
+	}
+	return nil
+}
+
+func (db *DBMock) GetUnreadMentionsDB(pg int, user primitive.ObjectID, groupID string) ([]*models.Mention, error) {
+	if db.GetUnreadMentionsDBMockFunc != nil {
+		return db.GetUnreadMentionsDBMockFunc(pg, user, groupID)
+	}
+	return []*models.Mention{}, nil
+}
+
+func (db *DBMock) MarkMentionsReadDB(user primitive.ObjectID, groupID string, at time.Time) error {
+	if db.MarkMentionsReadDBMockFunc != nil {
+		return db.MarkMentionsReadDBMockFunc(user, groupID, at)
+	}
+	return nil
+}
+
+/*SEARCH MOCK FUNCTIONS*/
+
+func (db *DBMock) SearchMessagesDB(pg int, user primitive.ObjectID, f models.MessageSearchFilter) ([]*models.MessageSearchResult, error) {
+	if db.SearchMessagesDBMockFunc != nil {
+		return db.SearchMessagesDBMockFunc(pg, user, f)
+	}
+	return []*models.MessageSearchResult{}, nil
+}
+
+/*GALLERIES MOCK FUNCTIONS*/
+
+func (db *DBMock) GetGroupMediaGalleryDB(id primitive.ObjectID, types []int, cursor models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+	if db.GetGroupMediaGalleryDBMockFunc != nil {
+		return db.GetGroupMediaGalleryDBMockFunc(id, types, cursor)
+	}
+	return []*models.MediaGalleryItem{}, nil
+}
+
+func (db *DBMock) GetP2PMediaGalleryDB(user, peer primitive.ObjectID, types []int, cursor models.GalleryCursor) ([]*models.MediaGalleryItem, error) {
+	if db.GetP2PMediaGalleryDBMockFunc != nil {
+		return db.GetP2PMediaGalleryDBMockFunc(user, peer, types, cursor)
+	}
+	return []*models.MediaGalleryItem{}, nil
+}
+
+/*PINS MOCK FUNCTIONS*/
+
+func (db *DBMock) PinMessageDB(pin models.MessagePin) error {
+	if db.PinMessageDBMockFunc != nil {
+		return db.PinMessageDBMockFunc(pin)
+	}
+	return nil
+}
+
+func (db *DBMock) UnpinMessageDB(conversationID string, message primitive.ObjectID) error {
+	if db.UnpinMessageDBMockFunc != nil {
+		return db.UnpinMessageDBMockFunc(conversationID, message)
+	}
+	return nil
+}
+
+func (db *DBMock) GetPinnedMessagesDB(conversationID string, group bool) ([]*models.MessagePin, error) {
+	if db.GetPinnedMessagesDBMockFunc != nil {
+		return db.GetPinnedMessagesDBMockFunc(conversationID, group)
+	}
+	return []*models.MessagePin{}, nil
+}
+
+func (db *DBMock) StarMessageDB(star models.MessageStar) error {
+	if db.StarMessageDBMockFunc != nil {
+		return db.StarMessageDBMockFunc(star)
+	}
+	return nil
+}
+
+func (db *DBMock) UnstarMessageDB(user, message primitive.ObjectID) error {
+	if db.UnstarMessageDBMockFunc != nil {
+		return db.UnstarMessageDBMockFunc(user, message)
+	}
+	return nil
+}
+
+func (db *DBMock) GetStarredMessagesDB(pg int, user primitive.ObjectID) ([]*models.MessageStar, error) {
+	if db.GetStarredMessagesDBMockFunc != nil {
+		return db.GetStarredMessagesDBMockFunc(pg, user)
+	}
+	return []*models.MessageStar{}, nil
+}
+
+/*FORWARDS MOCK FUNCTIONS*/
+
+func (db *DBMock) InsertForwardedMessagesDB(groups []models.GroupChatContentLog, users []models.P2PContentChatLog) error {
+	if db.InsertForwardedMessagesDBMockFunc != nil {
+		return db.InsertForwardedMessagesDBMockFunc(groups, users)
+	}
+	return nil
+}
+
+/*SCHEDULED MESSAGES MOCK FUNCTIONS*/
+
+func (db *DBMock) InsertScheduledMessageDB(m models.ScheduledMessage) (string, error) {
+	if db.InsertScheduledMessageDBMockFunc != nil {
+		return db.InsertScheduledMessageDBMockFunc(m)
+	}
+	return m.ID.Hex(), nil
+}
+
+func (db *DBMock) GetScheduledMessagesDB(pg int, author primitive.ObjectID) ([]*models.ScheduledMessage, error) {
+	if db.GetScheduledMessagesDBMockFunc != nil {
+		return db.GetScheduledMessagesDBMockFunc(pg, author)
+	}
+	return []*models.ScheduledMessage{}, nil
+}
+
+func (db *DBMock) UpdateScheduledMessageDB(id, author primitive.ObjectID, body string, sendAt, now time.Time) (*models.ScheduledMessage, error) {
+	if db.UpdateScheduledMessageDBMockFunc != nil {
+		return db.UpdateScheduledMessageDBMockFunc(id, author, body, sendAt, now)
+	}
+	return &models.ScheduledMessage{ID: id, AuthorID: author, Body: body, SendAt: sendAt}, nil
+}
+
+func (db *DBMock) CancelScheduledMessageDB(id, author primitive.ObjectID, now time.Time) error {
+	if db.CancelScheduledMessageDBMockFunc != nil {
+		return db.CancelScheduledMessageDBMockFunc(id, author, now)
+	}
+	return nil
+}
+
+func (db *DBMock) ClaimScheduledMessageDB(now time.Time, lease time.Duration) (*models.ScheduledMessage, error) {
+	if db.ClaimScheduledMessageDBMockFunc != nil {
+		return db.ClaimScheduledMessageDBMockFunc(now, lease)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (db *DBMock) CompleteScheduledMessageDB(id primitive.ObjectID) error {
+	if db.CompleteScheduledMessageDBMockFunc != nil {
+		return db.CompleteScheduledMessageDBMockFunc(id)
+	}
+	return nil
+}
+
+func (db *DBMock) FailScheduledMessageDB(id primitive.ObjectID, reason string) error {
+	if db.FailScheduledMessageDBMockFunc != nil {
+		return db.FailScheduledMessageDBMockFunc(id, reason)
+	}
+	return nil
+}
+
+/*MESSAGE TIMERS MOCK FUNCTIONS*/
+
+func (db *DBMock) SetP2PMessageTimerDB(timer models.ConversationTimer) error {
+	if db.SetP2PMessageTimerDBMockFunc != nil {
+		return db.SetP2PMessageTimerDBMockFunc(timer)
+	}
+	return nil
+}
+
+func (db *DBMock) GetP2PMessageTimerDB(conversationID string) (int, error) {
+	if db.GetP2PMessageTimerDBMockFunc != nil {
+		return db.GetP2PMessageTimerDBMockFunc(conversationID)
+	}
+	return 0, nil
+}
+
+func (db *DBMock) ClaimExpiredGroupMessageDB(now time.Time, lease time.Duration) (*models.GroupChatContentLog, error) {
+	if db.ClaimExpiredGroupMessageDBMockFunc != nil {
+		return db.ClaimExpiredGroupMessageDBMockFunc(now, lease)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (db *DBMock) ClaimExpiredP2PMessageDB(now time.Time, lease time.Duration) (*models.P2PContentChatLog, error) {
+	if db.ClaimExpiredP2PMessageDBMockFunc != nil {
+		return db.ClaimExpiredP2PMessageDBMockFunc(now, lease)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (db *DBMock) GetSharedMediaDB(id primitive.ObjectID, media []string) ([]string, error) {
+	if db.GetSharedMediaDBMockFunc != nil {
+		return db.GetSharedMediaDBMockFunc(id, media)
+	}
+	return []string{}, nil
+}
+
+func (db *DBMock) DeleteExpiredGroupMessageDB(id, thread primitive.ObjectID) error {
+	if db.DeleteExpiredGroupMessageDBMockFunc != nil {
+		return db.DeleteExpiredGroupMessageDBMockFunc(id, thread)
+	}
+	return nil
+}
+
+func (db *DBMock) DeleteExpiredP2PMessageDB(id primitive.ObjectID) error {
+	if db.DeleteExpiredP2PMessageDBMockFunc != nil {
+		return db.DeleteExpiredP2PMessageDBMockFunc(id)
+	}
+	return nil
+}
+
+// AUDIT METHODS
+
+func (db *DBMock) InsertAuditEntryDB(e models.AuditEntry) (string, error) {
+	if db.InsertAuditEntryDBMockFunc != nil {
+		return db.InsertAuditEntryDBMockFunc(e)
+	}
+	return "", nil
+}
+
+func (db *DBMock) GetAuditEntriesDB(pg int, groupID string) ([]*models.AuditEntry, error) {
+	if db.GetAuditEntriesDBMockFunc != nil {
+		return db.GetAuditEntriesDBMockFunc(pg, groupID)
+	}
+	return []*models.AuditEntry{}, nil
+}
+
+// CALL METHODS
+
+func (db *DBMock) InsertCallLogDB(c models.CallLog) (string, error) {
+	if db.InsertCallLogDBMockFunc != nil {
+		return db.InsertCallLogDBMockFunc(c)
+	}
+	return "", nil
+}
+
+func (db *DBMock) GetCallHistoryDB(pg int, user primitive.ObjectID) ([]*models.CallLog, error) {
+	if db.GetCallHistoryDBMockFunc != nil {
+		return db.GetCallHistoryDBMockFunc(pg, user)
+	}
+	return []*models.CallLog{}, nil
+}
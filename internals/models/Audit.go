@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// AUDIT_INFO_EDITED the name or the description of the group changed
+	AUDIT_INFO_EDITED = "info_edited"
+	// AUDIT_AVATAR_CHANGED the avatar of the group changed
+	AUDIT_AVATAR_CHANGED = "avatar_changed"
+	// AUDIT_MEMBERS_ADDED participants were added by an admin
+	AUDIT_MEMBERS_ADDED = "members_added"
+	// AUDIT_MEMBERS_REMOVED participants were removed by an admin
+	AUDIT_MEMBERS_REMOVED = "members_removed"
+	// AUDIT_ADMINS_ADDED participants were promoted to admin
+	AUDIT_ADMINS_ADDED = "admins_added"
+	// AUDIT_ADMINS_REMOVED admins were demoted to participant
+	AUDIT_ADMINS_REMOVED = "admins_removed"
+	// AUDIT_OWNERSHIP_TRANSFERRED the group was handed to another participant
+	AUDIT_OWNERSHIP_TRANSFERRED = "ownership_transferred"
+	// AUDIT_PERMISSIONS_CHANGED the permissions of the group changed
+	AUDIT_PERMISSIONS_CHANGED = "permissions_changed"
+	// AUDIT_APPROVAL_CHANGED the approval of the join requests was turned on or off
+	AUDIT_APPROVAL_CHANGED = "approval_changed"
+	// AUDIT_DIRECTORY_CHANGED the visibility, category or tags of the group changed
+	AUDIT_DIRECTORY_CHANGED = "directory_changed"
+	// AUDIT_MODERATION_CHANGED the announcement only mode or the slow mode changed
+	AUDIT_MODERATION_CHANGED = "moderation_changed"
+	// AUDIT_MEMBER_MUTED a member was muted or unmuted
+	AUDIT_MEMBER_MUTED = "member_muted"
+	// AUDIT_MESSAGE_TIMER_CHANGED the timer of the disappearing messages changed
+	AUDIT_MESSAGE_TIMER_CHANGED = "message_timer_changed"
+	// AUDIT_INVITE_CREATED an invite link was created
+	AUDIT_INVITE_CREATED = "invite_created"
+	// AUDIT_INVITE_REVOKED an invite link was revoked
+	AUDIT_INVITE_REVOKED = "invite_revoked"
+	// AUDIT_JOIN_REQUEST_REVIEWED a join request was approved or rejected
+	AUDIT_JOIN_REQUEST_REVIEWED = "join_request_reviewed"
+	// AUDIT_TOPIC_CREATED a topic was added to the group
+	AUDIT_TOPIC_CREATED = "topic_created"
+	// AUDIT_TOPIC_UPDATED a topic was renamed, archived or restored
+	AUDIT_TOPIC_UPDATED = "topic_updated"
+	// AUDIT_MESSAGE_DELETED a message of the group was deleted by an admin
+	AUDIT_MESSAGE_DELETED = "message_deleted"
+	// AUDIT_MESSAGE_PINNED a message of the group was pinned or unpinned
+	AUDIT_MESSAGE_PINNED = "message_pinned"
+	// AUDIT_DELETION_SCHEDULED the deletion of the group was scheduled
+	AUDIT_DELETION_SCHEDULED = "deletion_scheduled"
+	// AUDIT_DELETION_CANCELLED the scheduled deletion of the group was undone
+	AUDIT_DELETION_CANCELLED = "deletion_cancelled"
+)
+
+/*
+AuditEntry
+administrative action done on a group. Before and after hold the changed
+values only, flat so they read the same from the database and the API
+*/
+type AuditEntry struct {
+	ID        primitive.ObjectID   `json:"_id" bson:"_id"`
+	GroupID   string               `json:"group_id" bson:"group_id"`
+	ActorID   primitive.ObjectID   `json:"actor_id" bson:"actor_id"`
+	Targets   []primitive.ObjectID `json:"targets,omitempty" bson:"targets,omitempty"`
+	Action    string               `json:"action" bson:"action"`
+	Before    map[string]any       `json:"before,omitempty" bson:"before,omitempty"`
+	After     map[string]any       `json:"after,omitempty" bson:"after,omitempty"`
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+}
+
+// FormatAuditEntry creates the entry of the action done by the actor on the group
+func FormatAuditEntry(groupID string, actor primitive.ObjectID, action string, before, after map[string]any, targets ...primitive.ObjectID) AuditEntry {
+	return AuditEntry{
+		ID:        primitive.NewObjectID(),
+		GroupID:   groupID,
+		ActorID:   actor,
+		Targets:   targets,
+		Action:    action,
+		Before:    before,
+		After:     after,
+		CreatedAt: time.Now(),
+	}
+}
+
+// AuditValues flattens the permissions of the group for the audit entries
+func (p GroupPermissions) AuditValues() map[string]any {
+	return map[string]any{
+		PERMISSION_EDIT_INFO:    p.EditInfo,
+		PERMISSION_ADD_MEMBERS:  p.AddMembers,
+		PERMISSION_SEND_MEDIA:   p.SendMedia,
+		PERMISSION_PIN_MESSAGES: p.PinMessages,
+	}
+}
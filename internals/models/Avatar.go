@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MAX_AVATAR_HISTORY avatar changes kept on the group
+const MAX_AVATAR_HISTORY = 10
+
+// GroupAvatar change of the avatar of a group
+type GroupAvatar struct {
+	URL       string             `json:"url" bson:"url"`
+	Version   int64              `json:"version" bson:"version"`
+	AuthorID  primitive.ObjectID `json:"author_id" bson:"author_id"`
+	ChangedAt time.Time          `json:"changed_at" bson:"changed_at"`
+}
+
+/*
+FormatGroupAvatar
+creates the avatar change of the group, the version is part of the object name
+so every avatar has its own url and the clients never show a cached one
+*/
+func FormatGroupAvatar(author primitive.ObjectID) GroupAvatar {
+	now := time.Now()
+	return GroupAvatar{
+		Version:   now.UnixMilli(),
+		AuthorID:  author,
+		ChangedAt: now,
+	}
+}
+
+// Filename name of the avatar object on the storage
+func (a GroupAvatar) Filename(groupID string) string {
+	return fmt.Sprintf("%s_%d.jpg", groupID, a.Version)
+}
+
+// AvatarFilename returns the name of the object behind the avatar url, empty if the group has no avatar
+func AvatarFilename(url string) string {
+	if url == "" {
+		return ""
+	}
+	url, _, _ = strings.Cut(url, "?")
+	return path.Base(url)
+}
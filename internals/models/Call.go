@@ -0,0 +1,182 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// CALL_TYPE_AUDIO voice only call
+	CALL_TYPE_AUDIO = "audio"
+	// CALL_TYPE_VIDEO voice and video call
+	CALL_TYPE_VIDEO = "video"
+
+	// CALL_STATE_RINGING the callee has been invited and has not answered
+	CALL_STATE_RINGING = "ringing"
+	// CALL_STATE_ACCEPTED the callee answered the call
+	CALL_STATE_ACCEPTED = "accepted"
+	// CALL_STATE_REJECTED the callee rejected the call
+	CALL_STATE_REJECTED = "rejected"
+	// CALL_STATE_MISSED the callee did not answer before the ring timeout
+	CALL_STATE_MISSED = "missed"
+	// CALL_STATE_ENDED the call was answered and then finished
+	CALL_STATE_ENDED = "ended"
+
+	// EVENT_CALL_PREFIX prefix shared by every call signaling event
+	EVENT_CALL_PREFIX = "call."
+	// EVENT_CALL_OFFER starts a call, carries the SDP offer of the caller
+	EVENT_CALL_OFFER = "call.offer"
+	// EVENT_CALL_ANSWER accepts a call, carries the SDP answer of the callee
+	EVENT_CALL_ANSWER = "call.answer"
+	// EVENT_CALL_CANDIDATE relays an ICE candidate to the other peer
+	EVENT_CALL_CANDIDATE = "call.candidate"
+	// EVENT_CALL_REJECT rejects a ringing call
+	EVENT_CALL_REJECT = "call.reject"
+	// EVENT_CALL_HANGUP finishes or cancels a call
+	EVENT_CALL_HANGUP = "call.hangup"
+	// EVENT_CALL_STATE sent by the server when the state of a call changes
+	EVENT_CALL_STATE = "call.state"
+	// EVENT_CALL_START starts a group call and rings every participant of the group
+	EVENT_CALL_START = "call.start"
+	// EVENT_CALL_JOIN joins the active call of the group
+	EVENT_CALL_JOIN = "call.join"
+	// EVENT_CALL_LEAVE leaves the group call
+	EVENT_CALL_LEAVE = "call.leave"
+	// EVENT_CALL_MUTE changes the mute state of the user in the group call
+	EVENT_CALL_MUTE = "call.mute"
+	// EVENT_CALL_PARTICIPANTS sent by the server when the participants of a group call change
+	EVENT_CALL_PARTICIPANTS = "call.participants"
+	// EVENT_CALL_MISSED notification sent to the users that missed a call
+	EVENT_CALL_MISSED = "call.missed"
+)
+
+// InboundEvent used to know the event of an inbound websocket message before decoding it
+type InboundEvent struct {
+	Event string `json:"event"`
+}
+
+// ICECandidate network candidate of a peer
+type ICECandidate struct {
+	Candidate     string `json:"candidate"`
+	SDPMid        string `json:"sdpMid"`
+	SDPMLineIndex int    `json:"sdpMLineIndex"`
+}
+
+// InboundCallSignal base structure of the call signaling messages sent by the clients
+type InboundCallSignal struct {
+	Event     string        `json:"event"`
+	CallID    string        `json:"call_id"`
+	TargetID  string        `json:"target_id"`
+	CallType  string        `json:"call_type"`
+	SDP       string        `json:"sdp"`
+	Candidate *ICECandidate `json:"candidate,omitempty"`
+	Muted     bool          `json:"muted"`
+
+	// PushTokens tokens of the group participants rung by a group call
+	PushTokens map[string]string `json:"push_tokens"`
+
+	// GroupID and Name are filled by the server from the connection
+	GroupID string `json:"group_id"`
+	Name    string `json:"name"`
+}
+
+// CallParticipant user connected to a group call
+type CallParticipant struct {
+	UserID   string    `json:"user_id"`
+	Name     string    `json:"name"`
+	Muted    bool      `json:"muted"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OutboundCallSignal base structure of the call signaling messages sent to the clients
+type OutboundCallSignal struct {
+	CallID    string        `json:"call_id"`
+	FromID    string        `json:"from_id"`
+	FromName  string        `json:"from_name"`
+	CallType  string        `json:"call_type"`
+	State     string        `json:"state"`
+	SDP       string        `json:"sdp,omitempty"`
+	Candidate *ICECandidate `json:"candidate,omitempty"`
+
+	GroupID      string            `json:"group_id,omitempty"`
+	Muted        bool              `json:"muted"`
+	Participants []CallParticipant `json:"participants,omitempty"`
+}
+
+// CallRecord summary of a finished call
+type CallRecord struct {
+	CallID     string    `json:"call_id" bson:"call_id"`
+	CallType   string    `json:"call_type" bson:"call_type"`
+	State      string    `json:"state" bson:"state"`
+	StartedAt  time.Time `json:"started_at" bson:"started_at"`
+	AnsweredAt time.Time `json:"answered_at" bson:"answered_at"`
+	EndedAt    time.Time `json:"ended_at" bson:"ended_at"`
+	Duration   int64     `json:"duration" bson:"duration"`
+}
+
+// P2PCallChatLog call log entry stored in the private conversation
+type P2PCallChatLog struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	TargetID   primitive.ObjectID `json:"target_id" bson:"target_id"`
+	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
+	ContentID  string             `json:"content_id" bson:"content_id"`
+	AuthorName string             `json:"author_name" bson:"author_name"`
+	BodyType   int                `json:"body_type" bson:"body_type"`
+	Body       string             `json:"body" bson:"body"`
+	Call       CallRecord         `json:"call" bson:"call"`
+	Created_at time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// FormatCallLog fills the call log entry with the record of the call
+func (p *P2PCallChatLog) FormatCallLog(targetID, author primitive.ObjectID, authorName string, record CallRecord) {
+	p.ID = primitive.NewObjectID()
+	p.TargetID = targetID
+	p.AuthorID = author
+	p.ContentID = record.CallID
+	p.AuthorName = authorName
+	p.BodyType = MESSAGE_TYPE_CALL
+	p.Body = fmt.Sprintf("%s %s call", record.State, record.CallType)
+	p.Call = record
+	p.Created_at = time.Now()
+}
+
+// CallLog entry of the call history, p2p calls have a callee and group calls a group
+type CallLog struct {
+	ID       primitive.ObjectID `json:"_id" bson:"_id"`
+	CallerID primitive.ObjectID `json:"caller_id" bson:"caller_id"`
+	CalleeID primitive.ObjectID `json:"callee_id,omitempty" bson:"callee_id,omitempty"`
+	GroupID  primitive.ObjectID `json:"group_id,omitempty" bson:"group_id,omitempty"`
+
+	// Participants users that see the call on their history
+	Participants []primitive.ObjectID `json:"participants" bson:"participants"`
+
+	// Joined users that answered the call
+	Joined []primitive.ObjectID `json:"joined" bson:"joined"`
+
+	CallRecord `bson:",inline"`
+}
+
+// FormatP2PCallLog fills the call log of a p2p call
+func (c *CallLog) FormatP2PCallLog(caller, callee primitive.ObjectID, record CallRecord) {
+	c.ID = primitive.NewObjectID()
+	c.CallerID = caller
+	c.CalleeID = callee
+	c.Participants = []primitive.ObjectID{caller, callee}
+	c.Joined = []primitive.ObjectID{caller}
+	if !record.AnsweredAt.IsZero() {
+		c.Joined = append(c.Joined, callee)
+	}
+	c.CallRecord = record
+}
+
+// FormatGroupCallLog fills the call log of a group call
+func (c *CallLog) FormatGroupCallLog(caller, group primitive.ObjectID, participants, joined []primitive.ObjectID, record CallRecord) {
+	c.ID = primitive.NewObjectID()
+	c.CallerID = caller
+	c.GroupID = group
+	c.Participants = participants
+	c.Joined = joined
+	c.CallRecord = record
+}
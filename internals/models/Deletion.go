@@ -0,0 +1,56 @@
+package models
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	// DEFAULT_GROUP_DELETION_GRACE time the owner has to undo the deletion of a group
+	DEFAULT_GROUP_DELETION_GRACE = 24 * time.Hour
+
+	// EVENT_GROUP_DELETION_SCHEDULED sent to the participants when the group is going to be deleted
+	EVENT_GROUP_DELETION_SCHEDULED = "group.deletion_scheduled"
+	// EVENT_GROUP_DELETION_CANCELLED sent to the participants when the deletion is undone
+	EVENT_GROUP_DELETION_CANCELLED = "group.deletion_cancelled"
+	// EVENT_GROUP_DELETED sent to the participants right before their group connections are closed
+	EVENT_GROUP_DELETED = "group.deleted"
+)
+
+// GroupDeletionEvent data of the deletion events
+type GroupDeletionEvent struct {
+	GroupID  string     `json:"group_id"`
+	DeleteAt *time.Time `json:"delete_at,omitempty"`
+}
+
+/*
+GroupDeletionGrace
+returns the grace period of the deletions, GROUP_DELETION_GRACE overrides the
+default with a duration like "30m", "0s" deletes the groups right away
+*/
+func GroupDeletionGrace() time.Duration {
+	grace, err := time.ParseDuration(os.Getenv("GROUP_DELETION_GRACE"))
+	if err != nil || grace < 0 {
+		return DEFAULT_GROUP_DELETION_GRACE
+	}
+	return grace
+}
+
+// PendingDeletion reports whether the group is scheduled to be deleted
+func (g *Group) PendingDeletion() bool {
+	return g.DeleteAt != nil
+}
+
+// Media returns the objects of the group itself that live on the storage
+func (g *Group) Media() []string {
+	media := []string{}
+	if g.ProfileImage != "" {
+		media = append(media, g.ProfileImage)
+	}
+	for _, avatar := range g.AvatarHistory {
+		if avatar.URL != "" && avatar.URL != g.ProfileImage {
+			media = append(media, avatar.URL)
+		}
+	}
+	return media
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// GROUP_VISIBILITY_PUBLIC listed on the directory and on the search results
+	GROUP_VISIBILITY_PUBLIC = "public"
+	// GROUP_VISIBILITY_PRIVATE not listed, its profile can be seen by the users that have its id, ej. through an invite link
+	GROUP_VISIBILITY_PRIVATE = "private"
+	// GROUP_VISIBILITY_HIDDEN not listed and only the participants can see its profile
+	GROUP_VISIBILITY_HIDDEN = "hidden"
+
+	// MAX_GROUP_TAGS tags a group can have on the directory
+	MAX_GROUP_TAGS = 10
+	// MAX_GROUP_TAG_LENGTH length of a single tag
+	MAX_GROUP_TAG_LENGTH = 32
+)
+
+// PublicGroup projection of the group shown to the users that are not participants
+type PublicGroup struct {
+	GroupID      string   `json:"group_id" bson:"group_id"`
+	Name         string   `json:"name" bson:"name"`
+	Description  string   `json:"description" bson:"description"`
+	ProfileImage string   `json:"profile_image" bson:"profile_image"`
+	Category     string   `json:"category" bson:"category"`
+	Tags         []string `json:"tags" bson:"tags"`
+	MemberCount  int      `json:"member_count" bson:"member_count"`
+}
+
+// GroupDirectoryFilter filters of the group directory
+type GroupDirectoryFilter struct {
+	Query    string
+	Category string
+	Tags     []string
+}
+
+// GroupDirectorySettings settings of the group on the directory
+type GroupDirectorySettings struct {
+	Visibility string   `json:"visibility"`
+	Category   string   `json:"category"`
+	Tags       []string `json:"tags"`
+}
+
+// ValidVisibility reports whether the visibility is known
+func ValidVisibility(v string) bool {
+	return v == GROUP_VISIBILITY_PUBLIC || v == GROUP_VISIBILITY_PRIVATE || v == GROUP_VISIBILITY_HIDDEN
+}
+
+/*
+NormalizeTags
+lower cases and trims the tags, removes the empty and repeated ones and fails
+when there are too many or one is too long
+*/
+func NormalizeTags(tags []string) ([]string, error) {
+
+	res := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(res, tag) {
+			continue
+		}
+		if len(tag) > MAX_GROUP_TAG_LENGTH {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MAX_GROUP_TAG_LENGTH)
+		}
+		res = append(res, tag)
+	}
+
+	if len(res) > MAX_GROUP_TAGS {
+		return nil, fmt.Errorf("a group can not have more than %d tags", MAX_GROUP_TAGS)
+	}
+
+	return res, nil
+}
+
+// Public returns the projection of the group shown to the users that are not participants
+func (g *Group) Public() PublicGroup {
+	return PublicGroup{
+		GroupID:      g.GroupID,
+		Name:         g.Name,
+		Description:  g.Description,
+		ProfileImage: g.ProfileImage,
+		Category:     g.Category,
+		Tags:         g.Tags,
+		MemberCount:  len(g.Participants),
+	}
+}
+
+// Discoverable reports whether the group is listed on the directory
+func (g *Group) Discoverable() bool {
+	return g.Visibility == GROUP_VISIBILITY_PUBLIC
+}
@@ -0,0 +1,30 @@
+package models
+
+const (
+	// EVENT_MESSAGE_UPDATED event sent when a stored message changes
+	EVENT_MESSAGE_UPDATED = "message.updated"
+
+	// EVENT_MESSAGE_NEW event of the push notifications of the new messages
+	EVENT_MESSAGE_NEW = "message.new"
+)
+
+// WebsocketEvent base structure for server events sent through the websocket
+type WebsocketEvent struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// PushNotification notification sent to the device of an offline user
+type PushNotification struct {
+	Token string `json:"token"`
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// FormatPushNotification the push of the payload, the payloads that are not events are new messages
+func FormatPushNotification(token string, payload any) PushNotification {
+	if event, ok := payload.(WebsocketEvent); ok {
+		return PushNotification{Token: token, Event: event.Event, Data: event.Data}
+	}
+	return PushNotification{Token: token, Event: EVENT_MESSAGE_NEW, Data: payload}
+}
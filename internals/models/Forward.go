@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_FORWARD_DESTINATIONS most conversations a message is forwarded to at once
+	MAX_FORWARD_DESTINATIONS = 10
+)
+
+// ERRORS
+var (
+	ErrForwardDestinations = fmt.Errorf("a message is forwarded to between 1 and %d conversations", MAX_FORWARD_DESTINATIONS)
+	ErrForwardType         = errors.New("only text, file, image and video messages can be forwarded")
+	ErrForwardToSelf       = errors.New("a message can not be forwarded to the private conversation with yourself")
+)
+
+// forwardableTypes message types holding content that can be forwarded
+var forwardableTypes = []int{MESSAGE_TYPE_TEXT, MESSAGE_TYPE_FILE, MESSAGE_TYPE_MEDIA_VIDEOS, MESSAGE_TYPE_MEDIA_IMAGES}
+
+/*
+MessageForward
+marks a message as forwarded, it names the first message of the chain and its
+author so forwarding a forwarded message still credits the original, count is
+the number of times the content was forwarded up to this copy
+*/
+type MessageForward struct {
+	MessageID  primitive.ObjectID `json:"message_id" bson:"message_id"`
+	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
+	AuthorName string             `json:"author_name" bson:"author_name"`
+	Count      int                `json:"count" bson:"count"`
+}
+
+/*
+ForwardRequest
+conversations the message is forwarded to, the groups by group_id and the
+private conversations by the id of the other user
+*/
+type ForwardRequest struct {
+	Groups []string             `json:"groups"`
+	Users  []primitive.ObjectID `json:"users"`
+}
+
+// Validate drops the repeated destinations and checks their number is within the limits
+func (f *ForwardRequest) Validate(author primitive.ObjectID) error {
+
+	slices.Sort(f.Groups)
+	f.Groups = slices.Compact(f.Groups)
+
+	slices.SortFunc(f.Users, func(a, b primitive.ObjectID) int { return slices.Compare(a[:], b[:]) })
+	f.Users = slices.Compact(f.Users)
+
+	if slices.Contains(f.Users, author) {
+		return ErrForwardToSelf
+	}
+
+	n := len(f.Groups) + len(f.Users)
+	if n == 0 || n > MAX_FORWARD_DESTINATIONS {
+		return ErrForwardDestinations
+	}
+
+	return nil
+}
+
+/*
+ForwardedContent
+content of the message being forwarded, copied as is on every destination so
+the media is not uploaded again
+*/
+type ForwardedContent struct {
+	BodyType     int
+	Body         string
+	ContentID    string
+	Media        []string
+	Placeholders []string
+	Forward      *MessageForward
+}
+
+// formatForwardedContent fills the content, the marker continues the chain of the message when it was already forwarded
+func formatForwardedContent(id, author primitive.ObjectID, authorName string, bodyType int, body, contentID string, media, placeholders []string, previous *MessageForward) (ForwardedContent, error) {
+
+	if !slices.Contains(forwardableTypes, bodyType) {
+		return ForwardedContent{}, ErrForwardType
+	}
+
+	forward := &MessageForward{
+		MessageID:  id,
+		AuthorID:   author,
+		AuthorName: authorName,
+		Count:      1,
+	}
+	if previous != nil {
+		forward = &MessageForward{
+			MessageID:  previous.MessageID,
+			AuthorID:   previous.AuthorID,
+			AuthorName: previous.AuthorName,
+			Count:      previous.Count + 1,
+		}
+	}
+
+	return ForwardedContent{
+		BodyType:     bodyType,
+		Body:         body,
+		ContentID:    contentID,
+		Media:        media,
+		Placeholders: placeholders,
+		Forward:      forward,
+	}, nil
+}
+
+// ForwardedContent content of the group message to forward
+func (m *GroupChatContentLog) ForwardedContent() (ForwardedContent, error) {
+	return formatForwardedContent(m.ID, m.AuthorID, m.AuthorName, m.BodyType, m.Body, m.ContentID, m.Media, m.Placeholders, m.Forward)
+}
+
+// ForwardedContent content of the private message to forward
+func (m *P2PContentChatLog) ForwardedContent() (ForwardedContent, error) {
+	return formatForwardedContent(m.ID, m.AuthorID, m.AuthorName, m.BodyType, m.Body, m.ContentID, m.Media, m.Placeholders, m.Forward)
+}
+
+// GroupLog copy of the content sent by the author to the group
+func (c ForwardedContent) GroupLog(groupID primitive.ObjectID, author User, now time.Time) GroupChatContentLog {
+
+	var log GroupChatContentLog
+	log.FormatContentChatLog(groupID, author.ID, author.Name, c.Body, c.ContentID, c.Media, c.Placeholders, c.BodyType)
+	log.Forward = c.Forward
+	log.Created_at = now
+
+	return log
+}
+
+// P2PLog copy of the content sent by the author to the private conversation with the target
+func (c ForwardedContent) P2PLog(target primitive.ObjectID, author User, now time.Time) P2PContentChatLog {
+
+	var log P2PContentChatLog
+	log.FormatContentChatLog(target, author.ID, author.Name, c.Body, c.ContentID, c.Media, c.Placeholders, c.BodyType)
+	log.Forward = c.Forward
+	log.Created_at = now
+
+	return log
+}
+
+/*
+ForwardedMessage
+copy of the forwarded message on one destination, conversation tells whether
+target_id is a group or the other user of a private conversation
+*/
+type ForwardedMessage struct {
+	Conversation string             `json:"conversation"`
+	TargetID     primitive.ObjectID `json:"target_id"`
+	GroupID      string             `json:"group_id,omitempty"`
+	MessageID    primitive.ObjectID `json:"message_id"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MEDIA_GALLERY_PAGE items returned on every page of the gallery
+	MEDIA_GALLERY_PAGE = 30
+)
+
+// ERRORS
+var (
+	ErrInvalidGalleryType = errors.New("the gallery only holds file, image and video messages")
+)
+
+// galleryTypes message types shown on the gallery of a conversation
+var galleryTypes = []int{MESSAGE_TYPE_MEDIA_IMAGES, MESSAGE_TYPE_MEDIA_VIDEOS, MESSAGE_TYPE_FILE}
+
+// GalleryTypes message types of the gallery filter, zero lists every type of the gallery
+func GalleryTypes(bodyType int) ([]int, error) {
+	if bodyType == 0 {
+		return galleryTypes, nil
+	}
+	if !slices.Contains(galleryTypes, bodyType) {
+		return nil, ErrInvalidGalleryType
+	}
+	return []int{bodyType}, nil
+}
+
+/*
+MediaGalleryItem
+content message of a conversation as shown on its gallery, media holds the urls
+of the files and placeholders their thumbnails
+*/
+type MediaGalleryItem struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	AuthorID     primitive.ObjectID `json:"author_id" bson:"author_id"`
+	AuthorName   string             `json:"author_name" bson:"author_name"`
+	BodyType     int                `json:"body_type" bson:"body_type"`
+	Body         string             `json:"body" bson:"body"`
+	ContentID    string             `json:"content_id" bson:"content_id"`
+	Media        []string           `json:"media" bson:"media"`
+	Placeholders []string           `json:"placeholders" bson:"placeholders"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+/*
+GalleryCursor
+position of a page of the gallery, the page holds the items sent before the
+date and, for the items sent on the same date, the ones with a lower id. A zero
+id pages only by the date
+*/
+type GalleryCursor struct {
+	Before time.Time          `json:"before"`
+	ID     primitive.ObjectID `json:"id"`
+}
+
+/*
+MediaGallery
+page of the gallery, newest first. Next is the cursor to ask the following page
+with, nil on the last page
+*/
+type MediaGallery struct {
+	Items []*MediaGalleryItem `json:"items"`
+	Next  *GalleryCursor      `json:"next,omitempty"`
+}
+
+// FormatMediaGallery pages the items, a full page points to its oldest item
+func FormatMediaGallery(items []*MediaGalleryItem) MediaGallery {
+	gallery := MediaGallery{Items: items}
+	if len(items) == MEDIA_GALLERY_PAGE {
+		last := items[len(items)-1]
+		gallery.Next = &GalleryCursor{Before: last.CreatedAt, ID: last.ID}
+	}
+	return gallery
+}
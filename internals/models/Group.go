@@ -1,22 +1,128 @@
 package models
 
 import (
+	"fmt"
+	"slices"
 	"time"
 	"wechat-back/internals/generators"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	// GROUP_ROLE_OWNER creator of the group, holds every permission
+	GROUP_ROLE_OWNER = "owner"
+	// GROUP_ROLE_ADMIN user on the admins of the group
+	GROUP_ROLE_ADMIN = "admin"
+	// GROUP_ROLE_MEMBER user on the participants of the group
+	GROUP_ROLE_MEMBER = "member"
+
+	// PERMISSION_EDIT_INFO change the name and description of the group
+	PERMISSION_EDIT_INFO = "edit_info"
+	// PERMISSION_ADD_MEMBERS add participants to the group
+	PERMISSION_ADD_MEMBERS = "add_members"
+	// PERMISSION_SEND_MEDIA send videos, images and files to the group
+	PERMISSION_SEND_MEDIA = "send_media"
+	// PERMISSION_PIN_MESSAGES pin messages on the group
+	PERMISSION_PIN_MESSAGES = "pin_messages"
+	// PERMISSION_SEND_MESSAGES send text messages to the group, always granted to members
+	PERMISSION_SEND_MESSAGES = "send_messages"
+	// PERMISSION_REMOVE_MEMBERS remove participants from the group, always granted to admins
+	PERMISSION_REMOVE_MEMBERS = "remove_members"
+	// PERMISSION_MANAGE_ADMINS promote and demote admins, always granted to the owner
+	PERMISSION_MANAGE_ADMINS = "manage_admins"
+	// PERMISSION_EDIT_PERMISSIONS change the permissions of the group, always granted to the owner
+	PERMISSION_EDIT_PERMISSIONS = "edit_permissions"
+	// PERMISSION_DELETE_GROUP delete the group, always granted to the owner
+	PERMISSION_DELETE_GROUP = "delete_group"
+	// PERMISSION_TRANSFER_OWNERSHIP hand the group to another participant, always granted to the owner
+	PERMISSION_TRANSFER_OWNERSHIP = "transfer_ownership"
+	// PERMISSION_MODERATE change the moderation settings and mute members, always granted to admins
+	PERMISSION_MODERATE = "moderate"
+	// PERMISSION_VIEW_AUDIT read the audit log of the group, always granted to admins
+	PERMISSION_VIEW_AUDIT = "view_audit"
+	// PERMISSION_MANAGE_TOPICS create, rename and archive the topics of the group, always granted to admins
+	PERMISSION_MANAGE_TOPICS = "manage_topics"
+)
+
+// roleRanks orders the roles, a role holds the permissions of the roles below it
+var roleRanks = map[string]int{
+	GROUP_ROLE_MEMBER: 1,
+	GROUP_ROLE_ADMIN:  2,
+	GROUP_ROLE_OWNER:  3,
+}
+
+// GroupPermissions minimum role required for the configurable actions of the group
+type GroupPermissions struct {
+	EditInfo    string `json:"edit_info" bson:"edit_info"`
+	AddMembers  string `json:"add_members" bson:"add_members"`
+	SendMedia   string `json:"send_media" bson:"send_media"`
+	PinMessages string `json:"pin_messages" bson:"pin_messages"`
+}
+
+// DefaultGroupPermissions permissions of a new group
+func DefaultGroupPermissions() GroupPermissions {
+	return GroupPermissions{
+		EditInfo:    GROUP_ROLE_ADMIN,
+		AddMembers:  GROUP_ROLE_ADMIN,
+		SendMedia:   GROUP_ROLE_MEMBER,
+		PinMessages: GROUP_ROLE_ADMIN,
+	}
+}
+
+// WithDefaults fills the empty permissions with the defaults
+func (p GroupPermissions) WithDefaults() GroupPermissions {
+	def := DefaultGroupPermissions()
+	if p.EditInfo == "" {
+		p.EditInfo = def.EditInfo
+	}
+	if p.AddMembers == "" {
+		p.AddMembers = def.AddMembers
+	}
+	if p.SendMedia == "" {
+		p.SendMedia = def.SendMedia
+	}
+	if p.PinMessages == "" {
+		p.PinMessages = def.PinMessages
+	}
+	return p
+}
+
+// Validate checks that every permission holds a known role
+func (p GroupPermissions) Validate() error {
+	for _, role := range []string{p.EditInfo, p.AddMembers, p.SendMedia, p.PinMessages} {
+		if _, ok := roleRanks[role]; !ok {
+			return fmt.Errorf("invalid role %q", role)
+		}
+	}
+	return nil
+}
+
 // Group basic group structure
 type Group struct {
-	ID           primitive.ObjectID   `json:"_id" bson:"_id"`
-	GroupID      string               `json:"group_id" bson:"group_id"`
-	Name         string               `json:"name" bson:"name"`
-	Description  string               `json:"description" bson:"description"`
-	Participants []primitive.ObjectID `json:"participants" bson:"participants"`
-	Admins       []primitive.ObjectID `json:"admins" bson:"admins"`
-	ProfileImage string               `json:"profile_image" bson:"profile_image"`
-	CreatedAt    time.Time            `json:"created_at" bson:"created_at"`
+	ID                primitive.ObjectID   `json:"_id" bson:"_id"`
+	GroupID           string               `json:"group_id" bson:"group_id"`
+	Name              string               `json:"name" bson:"name"`
+	Description       string               `json:"description" bson:"description"`
+	OwnerID           primitive.ObjectID   `json:"owner_id" bson:"owner_id"`
+	Participants      []primitive.ObjectID `json:"participants" bson:"participants"`
+	Admins            []primitive.ObjectID `json:"admins" bson:"admins"`
+	Permissions       GroupPermissions     `json:"permissions" bson:"permissions"`
+	ApprovalRequired  bool                 `json:"approval_required" bson:"approval_required"`
+	AnnouncementOnly  bool                 `json:"announcement_only" bson:"announcement_only"`
+	SlowMode          int                  `json:"slow_mode" bson:"slow_mode"`
+	MessageTimer      int                  `json:"message_timer" bson:"message_timer"`
+	Mutes             map[string]time.Time `json:"mutes,omitempty" bson:"mutes,omitempty"`
+	NotificationMutes map[string]time.Time `json:"notification_mutes,omitempty" bson:"notification_mutes,omitempty"`
+	LastPosts         map[string]time.Time `json:"-" bson:"last_posts,omitempty"`
+	Visibility        string               `json:"visibility" bson:"visibility"`
+	Category          string               `json:"category" bson:"category"`
+	Tags              []string             `json:"tags" bson:"tags"`
+	ProfileImage      string               `json:"profile_image" bson:"profile_image"`
+	AvatarHistory     []GroupAvatar        `json:"avatar_history" bson:"avatar_history"`
+	Topics            []GroupTopic         `json:"topics" bson:"topics"`
+	DeleteAt          *time.Time           `json:"delete_at,omitempty" bson:"delete_at,omitempty"`
+	CreatedAt         time.Time            `json:"created_at" bson:"created_at"`
 }
 
 // FormatGroup adds the necessary information to the structure
@@ -24,5 +130,135 @@ func FormatGroup(g *Group) *Group {
 	g.ID = primitive.NewObjectID()
 	g.GroupID = generators.GenerateUniqueID(g.ID.Hex(), g.Name)
 	g.CreatedAt = time.Now()
+
+	// the first admin owns the group when no owner is given
+	if g.OwnerID.IsZero() && len(g.Admins) > 0 {
+		g.OwnerID = g.Admins[0]
+	}
+	if !g.OwnerID.IsZero() {
+		if !slices.Contains(g.Admins, g.OwnerID) {
+			g.Admins = append(g.Admins, g.OwnerID)
+		}
+		if !slices.Contains(g.Participants, g.OwnerID) {
+			g.Participants = append(g.Participants, g.OwnerID)
+		}
+	}
+
+	g.Permissions = g.Permissions.WithDefaults()
+
+	// groups are not listed on the directory unless the owner asks for it
+	if g.Visibility == "" {
+		g.Visibility = GROUP_VISIBILITY_PRIVATE
+	}
 	return g
 }
+
+/*
+RoleOf
+returns the role of the user on the group, empty if the user does not belong to it
+*/
+func (g *Group) RoleOf(user primitive.ObjectID) string {
+	switch {
+	case !g.OwnerID.IsZero() && g.OwnerID == user:
+		return GROUP_ROLE_OWNER
+	case slices.Contains(g.Admins, user):
+		return GROUP_ROLE_ADMIN
+	case slices.Contains(g.Participants, user):
+		return GROUP_ROLE_MEMBER
+	}
+	return ""
+}
+
+// requiredRole returns the minimum role of the permission
+func (g *Group) requiredRole(permission string) string {
+
+	perms := g.Permissions.WithDefaults()
+
+	switch permission {
+	case PERMISSION_EDIT_INFO:
+		return perms.EditInfo
+	case PERMISSION_ADD_MEMBERS:
+		return perms.AddMembers
+	case PERMISSION_SEND_MEDIA:
+		return perms.SendMedia
+	case PERMISSION_PIN_MESSAGES:
+		return perms.PinMessages
+	case PERMISSION_SEND_MESSAGES:
+		return GROUP_ROLE_MEMBER
+	case PERMISSION_REMOVE_MEMBERS, PERMISSION_MODERATE, PERMISSION_VIEW_AUDIT, PERMISSION_MANAGE_TOPICS:
+		return GROUP_ROLE_ADMIN
+	case PERMISSION_MANAGE_ADMINS, PERMISSION_EDIT_PERMISSIONS, PERMISSION_DELETE_GROUP, PERMISSION_TRANSFER_OWNERSHIP:
+		// groups created before the owner existed are managed by their admins
+		if g.OwnerID.IsZero() {
+			return GROUP_ROLE_ADMIN
+		}
+		return GROUP_ROLE_OWNER
+	}
+
+	return GROUP_ROLE_OWNER
+}
+
+/*
+Can
+reports whether the user holds the permission on the group
+*/
+func (g *Group) Can(user primitive.ObjectID, permission string) bool {
+
+	role := g.RoleOf(user)
+	if role == "" {
+		return false
+	}
+
+	return roleRanks[role] >= roleRanks[g.requiredRole(permission)]
+}
+
+/*
+GroupLeave
+the change on the group when a participant leaves, the promoted participant
+and the new owner are zero when nobody takes the role
+*/
+type GroupLeave struct {
+	UserID   primitive.ObjectID
+	Promoted primitive.ObjectID
+	OwnerID  primitive.ObjectID
+	Last     bool
+}
+
+/*
+Leave
+removes the user from the group, when the last admin leaves the longest-standing
+participant is promoted and when the owner leaves the longest-standing admin
+takes the ownership, returns the change so it can be stored
+*/
+func (g *Group) Leave(user primitive.ObjectID) GroupLeave {
+
+	leave := GroupLeave{UserID: user}
+	wasOwner := !g.OwnerID.IsZero() && g.OwnerID == user
+
+	g.Participants = slices.DeleteFunc(slices.Clone(g.Participants), func(p primitive.ObjectID) bool { return p == user })
+	g.Admins = slices.DeleteFunc(slices.Clone(g.Admins), func(p primitive.ObjectID) bool { return p == user })
+
+	if len(g.Participants) == 0 {
+		g.OwnerID = primitive.NilObjectID
+		leave.Last = true
+		return leave
+	}
+
+	// the participants are appended as they join, the first one has been the longest on the group
+	if len(g.Admins) == 0 {
+		leave.Promoted = g.Participants[0]
+		g.Admins = []primitive.ObjectID{leave.Promoted}
+	}
+
+	if wasOwner {
+		for _, p := range g.Participants {
+			if slices.Contains(g.Admins, p) {
+				g.OwnerID = p
+				leave.OwnerID = p
+				break
+			}
+		}
+	}
+
+	return leave
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"time"
+	"wechat-back/internals/generators"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// INVITE_CODE_LENGTH length of the code of the invite links
+	INVITE_CODE_LENGTH = 12
+
+	// JOIN_REQUEST_PENDING request waiting for the review of an admin
+	JOIN_REQUEST_PENDING = "pending"
+	// JOIN_REQUEST_APPROVED request approved, the user is a participant
+	JOIN_REQUEST_APPROVED = "approved"
+	// JOIN_REQUEST_REJECTED request rejected by an admin
+	JOIN_REQUEST_REJECTED = "rejected"
+
+	// EVENT_GROUP_MEMBER_JOINED sent to the group when a user joins it
+	EVENT_GROUP_MEMBER_JOINED = "group.member_joined"
+	// EVENT_GROUP_JOIN_REQUESTED sent to the admins when a join request is queued
+	EVENT_GROUP_JOIN_REQUESTED = "group.join_requested"
+)
+
+// ERRORS
+var (
+	ErrInviteRevoked   = errors.New("invite link revoked")
+	ErrInviteExpired   = errors.New("invite link expired")
+	ErrInviteExhausted = errors.New("invite link reached its max uses")
+)
+
+// GroupInvite invite link of a group
+type GroupInvite struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	Code      string             `json:"code" bson:"code"`
+	GroupID   string             `json:"group_id" bson:"group_id"`
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	MaxUses   int                `json:"max_uses" bson:"max_uses"`
+	Uses      int                `json:"uses" bson:"uses"`
+	Revoked   bool               `json:"revoked" bson:"revoked"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// InviteOptions options sent by the admin when the invite link is created
+type InviteOptions struct {
+	// TTL seconds the link lives, 0 never expires
+	TTL int `json:"ttl"`
+	// MaxUses times the link can be used, 0 unlimited
+	MaxUses int `json:"max_uses"`
+}
+
+/*
+FormatGroupInvite
+creates the invite link of the group, the link never expires when the ttl is 0
+and it has no use limit when maxUses is 0
+*/
+func FormatGroupInvite(groupID string, author primitive.ObjectID, opts InviteOptions) (*GroupInvite, error) {
+
+	if opts.TTL < 0 || opts.MaxUses < 0 {
+		return nil, errors.New("ttl and max uses can not be negative")
+	}
+
+	code, err := generators.GenerateAlphaNumericCode(INVITE_CODE_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &GroupInvite{
+		ID:        primitive.NewObjectID(),
+		Code:      code,
+		GroupID:   groupID,
+		CreatedBy: author,
+		MaxUses:   opts.MaxUses,
+		CreatedAt: time.Now(),
+	}
+
+	if opts.TTL > 0 {
+		invite.ExpiresAt = invite.CreatedAt.Add(time.Duration(opts.TTL) * time.Second)
+	}
+
+	return invite, nil
+}
+
+// Check returns the reason the invite can not be used, nil if it is valid
+func (i *GroupInvite) Check(now time.Time) error {
+	switch {
+	case i.Revoked:
+		return ErrInviteRevoked
+	case !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt):
+		return ErrInviteExpired
+	case i.MaxUses > 0 && i.Uses >= i.MaxUses:
+		return ErrInviteExhausted
+	}
+	return nil
+}
+
+// JoinRequest request of a user to join a group that requires approval
+type JoinRequest struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	GroupID    string             `json:"group_id" bson:"group_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name       string             `json:"name" bson:"name"`
+	InviteCode string             `json:"invite_code" bson:"invite_code"`
+	State      string             `json:"state" bson:"state"`
+	ReviewedBy primitive.ObjectID `json:"reviewed_by" bson:"reviewed_by,omitempty"`
+	ReviewedAt time.Time          `json:"reviewed_at" bson:"reviewed_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// FormatJoinRequest creates the pending request of the user
+func FormatJoinRequest(groupID string, user User, code string) JoinRequest {
+	return JoinRequest{
+		ID:         primitive.NewObjectID(),
+		GroupID:    groupID,
+		UserID:     user.ID,
+		Name:       user.Name,
+		InviteCode: code,
+		State:      JOIN_REQUEST_PENDING,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// GroupMemberEvent data of the group membership events
+type GroupMemberEvent struct {
+	GroupID   string             `json:"group_id"`
+	UserID    primitive.ObjectID `json:"user_id"`
+	Name      string             `json:"name"`
+	RequestID primitive.ObjectID `json:"request_id"`
+}
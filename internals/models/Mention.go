@@ -0,0 +1,147 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MENTION_ALL entity that mentions every participant of the group, reserved to admins
+	MENTION_ALL = "all"
+	// MAX_MENTION_BODY runes of the body of the message kept on the mention
+	MAX_MENTION_BODY = 200
+	// MAX_NOTIFICATION_MUTE longest a member can mute the notifications of a group, in seconds
+	MAX_NOTIFICATION_MUTE = 365 * 24 * 60 * 60
+
+	// EVENT_GROUP_MENTION push sent to the mentioned participants that are offline
+	EVENT_GROUP_MENTION = "group.mention"
+)
+
+// ERRORS
+var (
+	ErrMentionNotParticipant = errors.New("only participants of the group can be mentioned")
+	ErrMentionAllNotAllowed  = errors.New("only admins can mention everyone")
+)
+
+/*
+Mention
+mention of a participant on a message of the group, kept apart from the message
+so the unread mentions of a user are read without scanning the chat logs
+*/
+type Mention struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	GroupID    string             `json:"group_id" bson:"group_id"`
+	MessageID  primitive.ObjectID `json:"message_id" bson:"message_id"`
+	TopicID    primitive.ObjectID `json:"topic_id,omitempty" bson:"topic_id,omitempty"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
+	AuthorName string             `json:"author_name" bson:"author_name"`
+	Body       string             `json:"body" bson:"body"`
+	All        bool               `json:"all" bson:"all"`
+	Read       bool               `json:"read" bson:"read"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// MentionsRead mentions of the user on the group marked as read up to read_at
+type MentionsRead struct {
+	GroupID string             `json:"group_id"`
+	UserID  primitive.ObjectID `json:"user_id"`
+	ReadAt  time.Time          `json:"read_at"`
+}
+
+// NotificationMute notifications of the group muted by the user until the given time, zero when unmuted
+type NotificationMute struct {
+	GroupID string             `json:"group_id"`
+	UserID  primitive.ObjectID `json:"user_id"`
+	Until   time.Time          `json:"until"`
+}
+
+/*
+CheckMentions
+validates the mention entities declared by the author, every entity is the id
+of a participant or MENTION_ALL, which only admins can use. Returns the
+mentioned participants without duplicates
+*/
+func (g *Group) CheckMentions(author primitive.ObjectID, entities []string) ([]primitive.ObjectID, bool, error) {
+
+	var users []primitive.ObjectID
+	all := false
+
+	for _, entity := range entities {
+
+		if entity == MENTION_ALL {
+			if roleRanks[g.RoleOf(author)] < roleRanks[GROUP_ROLE_ADMIN] {
+				return nil, false, ErrMentionAllNotAllowed
+			}
+			all = true
+			continue
+		}
+
+		user, err := primitive.ObjectIDFromHex(entity)
+		if err != nil || !slices.Contains(g.Participants, user) {
+			return nil, false, fmt.Errorf("%w: %q", ErrMentionNotParticipant, entity)
+		}
+
+		if !slices.Contains(users, user) {
+			users = append(users, user)
+		}
+	}
+
+	return users, all, nil
+}
+
+// MentionsUser reports whether the message mentions the user, directly or with MENTION_ALL
+func (m GroupChatTextLog) MentionsUser(user primitive.ObjectID) bool {
+	return user != m.AuthorID && (m.MentionsAll || slices.Contains(m.Mentions, user))
+}
+
+// FormatMentions creates the mentions of the message, one per mentioned participant but the author
+func (m GroupChatTextLog) FormatMentions(group *Group) []Mention {
+
+	body := m.Body
+	if utf8.RuneCountInString(body) > MAX_MENTION_BODY {
+		body = string([]rune(body)[:MAX_MENTION_BODY]) + "…"
+	}
+
+	users := m.Mentions
+	if m.MentionsAll {
+		users = group.Participants
+	}
+
+	res := []Mention{}
+	for _, user := range users {
+
+		if user == m.AuthorID {
+			continue
+		}
+
+		res = append(res, Mention{
+			ID:         primitive.NewObjectID(),
+			GroupID:    group.GroupID,
+			MessageID:  m.ID,
+			TopicID:    m.TopicID,
+			UserID:     user,
+			AuthorID:   m.AuthorID,
+			AuthorName: m.AuthorName,
+			Body:       body,
+			All:        m.MentionsAll,
+			CreatedAt:  m.Created_At,
+		})
+	}
+
+	return res
+}
+
+// NotificationsMutedUntil returns the end of the notification mute the user set on the group, zero when they are not muted
+func (g *Group) NotificationsMutedUntil(user primitive.ObjectID, now time.Time) time.Time {
+	until, ok := g.NotificationMutes[user.Hex()]
+	if !ok || !until.After(now) {
+		return time.Time{}
+	}
+	return until
+}
@@ -19,29 +19,47 @@ const (
 
 // GroupChatTextLog Represent a message structure for groups
 type GroupChatTextLog struct {
-	ID         primitive.ObjectID `json:"_id" bson:"_id"`
-	TargetID   primitive.ObjectID `json:"target_id" bson:"target_id"`
-	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
-	ContentID  string             `json:"content_id" bson:"content_id"`
-	AuthorName string             `json:"author_name" bson:"author_name"`
-	BodyType   int                `json:"body_type" bson:"body_type"`
-	Body       string             `json:"body" bson:"body"`
-	Alt        string             `json:"alt" bson:"alt"`
-	Created_At time.Time          `json:"created_at" bson:"created_at"`
+	ID          primitive.ObjectID   `json:"_id" bson:"_id"`
+	TargetID    primitive.ObjectID   `json:"target_id" bson:"target_id"`
+	AuthorID    primitive.ObjectID   `json:"author_id" bson:"author_id"`
+	ContentID   string               `json:"content_id" bson:"content_id"`
+	AuthorName  string               `json:"author_name" bson:"author_name"`
+	BodyType    int                  `json:"body_type" bson:"body_type"`
+	Body        string               `json:"body" bson:"body"`
+	Alt         string               `json:"alt" bson:"alt"`
+	TopicID     primitive.ObjectID   `json:"topic_id,omitempty" bson:"topic_id,omitempty"`
+	Quote       *MessageQuote        `json:"quote,omitempty" bson:"quote,omitempty"`
+	Forward     *MessageForward      `json:"forward,omitempty" bson:"forward,omitempty"`
+	ThreadID    primitive.ObjectID   `json:"thread_id,omitempty" bson:"thread_id,omitempty"`
+	ReplyCount  int                  `json:"reply_count,omitempty" bson:"reply_count,omitempty"`
+	Reactions   map[string]int       `json:"reactions,omitempty" bson:"reactions,omitempty"`
+	Mentions    []primitive.ObjectID `json:"mentions,omitempty" bson:"mentions,omitempty"`
+	MentionsAll bool                 `json:"mentions_all,omitempty" bson:"mentions_all,omitempty"`
+	ExpireAt    *time.Time           `json:"expire_at,omitempty" bson:"expire_at,omitempty"`
+	Created_At  time.Time            `json:"created_at" bson:"created_at"`
 }
 
 // GroupChatContentLog content message structure for groups
 type GroupChatContentLog struct {
-	ID           primitive.ObjectID `json:"_id" bson:"_id"`
-	TargetID     primitive.ObjectID `json:"target_id" bson:"target_id"`
-	AuthorID     primitive.ObjectID `json:"author_id" bson:"author_id"`
-	ContentID    string             `json:"content_id" bson:"content_id"`
-	AuthorName   string             `json:"author_name" bson:"author_name"`
-	BodyType     int                `json:"body_type" bson:"body_type"`
-	Body         string             `json:"body" bson:"body"`
-	Media        []string           `json:"media" bson:"media"`
-	Placeholders []string           `json:"placeholders" bson:"placeholders"`
-	Created_at   time.Time          `json:"created_at" bson:"created_at"`
+	ID           primitive.ObjectID   `json:"_id" bson:"_id"`
+	TargetID     primitive.ObjectID   `json:"target_id" bson:"target_id"`
+	AuthorID     primitive.ObjectID   `json:"author_id" bson:"author_id"`
+	ContentID    string               `json:"content_id" bson:"content_id"`
+	AuthorName   string               `json:"author_name" bson:"author_name"`
+	BodyType     int                  `json:"body_type" bson:"body_type"`
+	Body         string               `json:"body" bson:"body"`
+	Media        []string             `json:"media" bson:"media"`
+	Placeholders []string             `json:"placeholders" bson:"placeholders"`
+	TopicID      primitive.ObjectID   `json:"topic_id,omitempty" bson:"topic_id,omitempty"`
+	Quote        *MessageQuote        `json:"quote,omitempty" bson:"quote,omitempty"`
+	Forward      *MessageForward      `json:"forward,omitempty" bson:"forward,omitempty"`
+	ThreadID     primitive.ObjectID   `json:"thread_id,omitempty" bson:"thread_id,omitempty"`
+	ReplyCount   int                  `json:"reply_count,omitempty" bson:"reply_count,omitempty"`
+	Reactions    map[string]int       `json:"reactions,omitempty" bson:"reactions,omitempty"`
+	Mentions     []primitive.ObjectID `json:"mentions,omitempty" bson:"mentions,omitempty"`
+	MentionsAll  bool                 `json:"mentions_all,omitempty" bson:"mentions_all,omitempty"`
+	ExpireAt     *time.Time           `json:"expire_at,omitempty" bson:"expire_at,omitempty"`
+	Created_at   time.Time            `json:"created_at" bson:"created_at"`
 }
 
 func (g *GroupChatTextLog) FormatTextChatLog(groupID, author_id primitive.ObjectID, authorname, body string) {
@@ -81,6 +99,10 @@ type P2PTextChatLog struct {
 	AuthorName string             `json:"author_name" bson:"author_name"`
 	BodyType   int                `json:"body_type" bson:"body_type"`
 	Body       string             `json:"body" bson:"body"`
+	Quote      *MessageQuote      `json:"quote,omitempty" bson:"quote,omitempty"`
+	Forward    *MessageForward    `json:"forward,omitempty" bson:"forward,omitempty"`
+	Reactions  map[string]int     `json:"reactions,omitempty" bson:"reactions,omitempty"`
+	ExpireAt   *time.Time         `json:"expire_at,omitempty" bson:"expire_at,omitempty"`
 	Created_at time.Time          `json:"created_at" bson:"created_at"`
 }
 
@@ -94,6 +116,10 @@ type P2PContentChatLog struct {
 	Body         string             `json:"body" bson:"body"`
 	Media        []string           `json:"media" bson:"media"`
 	Placeholders []string           `json:"placeholders" bson:"placeholders"`
+	Quote        *MessageQuote      `json:"quote,omitempty" bson:"quote,omitempty"`
+	Forward      *MessageForward    `json:"forward,omitempty" bson:"forward,omitempty"`
+	Reactions    map[string]int     `json:"reactions,omitempty" bson:"reactions,omitempty"`
+	ExpireAt     *time.Time         `json:"expire_at,omitempty" bson:"expire_at,omitempty"`
 	Created_at   time.Time          `json:"created_at" bson:"created_at"`
 }
 
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_SLOW_MODE longest wait between the messages of a member, in seconds
+	MAX_SLOW_MODE = 6 * 60 * 60
+	// MAX_MUTE_DURATION longest mute an admin can give, in seconds
+	MAX_MUTE_DURATION = 30 * 24 * 60 * 60
+
+	// EVENT_GROUP_MEMBER_MUTED sent to the member when an admin mutes or unmutes it
+	EVENT_GROUP_MEMBER_MUTED = "group.member_muted"
+)
+
+// ERRORS
+var (
+	ErrAnnouncementOnly = errors.New("only admins can post on this group")
+	ErrMemberMuted      = errors.New("you are muted on this group")
+	ErrSlowMode         = errors.New("slow mode is enabled on this group")
+)
+
+// GroupModeration moderation settings of the group
+type GroupModeration struct {
+	AnnouncementOnly bool `json:"announcement_only"`
+	SlowMode         int  `json:"slow_mode"`
+}
+
+// Validate checks the slow mode is within its limits
+func (m GroupModeration) Validate() error {
+	if m.SlowMode < 0 || m.SlowMode > MAX_SLOW_MODE {
+		return fmt.Errorf("slow mode must be between 0 and %d seconds", MAX_SLOW_MODE)
+	}
+	return nil
+}
+
+// GroupMuteEvent data of the mute events, a zero until means the member was unmuted
+type GroupMuteEvent struct {
+	GroupID string    `json:"group_id"`
+	UserID  string    `json:"user_id"`
+	Until   time.Time `json:"until"`
+}
+
+// MutedUntil returns the end of the mute of the user, zero when the user is not muted
+func (g *Group) MutedUntil(user primitive.ObjectID, now time.Time) time.Time {
+	until, ok := g.Mutes[user.Hex()]
+	if !ok || !until.After(now) {
+		return time.Time{}
+	}
+	return until
+}
+
+/*
+CheckPost
+checks the moderation settings of the group before the user posts, the slow mode
+depends on the last post of the user, see SlowModeOf. Admins are never moderated
+*/
+func (g *Group) CheckPost(user primitive.ObjectID, now time.Time) error {
+
+	if roleRanks[g.RoleOf(user)] >= roleRanks[GROUP_ROLE_ADMIN] {
+		return nil
+	}
+
+	if g.AnnouncementOnly {
+		return ErrAnnouncementOnly
+	}
+
+	if until := g.MutedUntil(user, now); !until.IsZero() {
+		return fmt.Errorf("%w until %s", ErrMemberMuted, until.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// SlowModeOf returns the wait between the messages of the user, zero for admins and groups without slow mode
+func (g *Group) SlowModeOf(user primitive.ObjectID) time.Duration {
+	if g.SlowMode <= 0 || roleRanks[g.RoleOf(user)] >= roleRanks[GROUP_ROLE_ADMIN] {
+		return 0
+	}
+	return time.Duration(g.SlowMode) * time.Second
+}
+
+// SlowModeWait returns the slow mode error of a user whose last post was at lastPost, nil once the wait is over
+func SlowModeWait(lastPost time.Time, wait time.Duration, now time.Time) error {
+	remaining := lastPost.Add(wait).Sub(now)
+	if remaining <= 0 {
+		return nil
+	}
+	return fmt.Errorf("%w, wait %d seconds", ErrSlowMode, int(math.Ceil(remaining.Seconds())))
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_PINNED_MESSAGES most messages a conversation holds pinned at once
+	MAX_PINNED_MESSAGES = 5
+
+	// EVENT_MESSAGE_PINNED sent to the conversation when a message is pinned or unpinned
+	EVENT_MESSAGE_PINNED = "message.pinned"
+)
+
+// ERRORS
+var (
+	ErrPinLimit = fmt.Errorf("a conversation holds at most %d pinned messages", MAX_PINNED_MESSAGES)
+)
+
+// P2PConversationID id of the private conversation of the users, the same whatever user comes first
+func P2PConversationID(a, b primitive.ObjectID) string {
+	if a.Hex() > b.Hex() {
+		a, b = b, a
+	}
+	return a.Hex() + ":" + b.Hex()
+}
+
+/*
+MessagePin
+message pinned to the top of a conversation, the conversation is the group_id of
+the group or the P2PConversationID of the private conversation. Message holds
+the pinned message when the pins are listed
+*/
+type MessagePin struct {
+	ID             primitive.ObjectID `json:"_id" bson:"_id"`
+	ConversationID string             `json:"conversation_id" bson:"conversation_id"`
+	MessageID      primitive.ObjectID `json:"message_id" bson:"message_id"`
+	PinnedBy       primitive.ObjectID `json:"pinned_by" bson:"pinned_by"`
+	PinnedAt       time.Time          `json:"pinned_at" bson:"pinned_at"`
+	Message        any                `json:"message,omitempty" bson:"-"`
+}
+
+// FormatMessagePin creates the pin of the message by the user
+func FormatMessagePin(conversationID string, message, user primitive.ObjectID) MessagePin {
+	return MessagePin{
+		ID:             primitive.NewObjectID(),
+		ConversationID: conversationID,
+		MessageID:      message,
+		PinnedBy:       user,
+		PinnedAt:       time.Now(),
+	}
+}
+
+// MessagePinEvent data of the pin events
+type MessagePinEvent struct {
+	MessageID primitive.ObjectID `json:"message_id"`
+	GroupID   string             `json:"group_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id"`
+	Unpinned  bool               `json:"unpinned"`
+}
+
+/*
+MessageStar
+message saved by a user for later, only the user sees it. The stars of group
+messages carry the group_id and the stars of p2p messages leave it empty.
+Message holds the starred message when the stars are listed
+*/
+type MessageStar struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	MessageID primitive.ObjectID `json:"message_id" bson:"message_id"`
+	GroupID   string             `json:"group_id,omitempty" bson:"group_id,omitempty"`
+	StarredAt time.Time          `json:"starred_at" bson:"starred_at"`
+	Message   any                `json:"message,omitempty" bson:"-"`
+}
+
+// FormatMessageStar creates the star of the message by the user
+func FormatMessageStar(message primitive.ObjectID, groupID string, user primitive.ObjectID) MessageStar {
+	return MessageStar{
+		ID:        primitive.NewObjectID(),
+		UserID:    user,
+		MessageID: message,
+		GroupID:   groupID,
+		StarredAt: time.Now(),
+	}
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_REACTION_RUNES longest emoji accepted as reaction, skin tones and joined emojis take several runes
+	MAX_REACTION_RUNES = 10
+
+	// EVENT_MESSAGE_REACTION sent to the conversation when a user adds or removes a reaction
+	EVENT_MESSAGE_REACTION = "message.reaction"
+)
+
+// ERRORS
+var (
+	ErrInvalidReaction = errors.New("the reaction must be a single emoji")
+)
+
+/*
+MessageReaction
+reaction of a user to a message, the reactions to group messages carry the
+group_id and the reactions to p2p messages leave it empty
+*/
+type MessageReaction struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	MessageID primitive.ObjectID `json:"message_id" bson:"message_id"`
+	GroupID   string             `json:"group_id,omitempty" bson:"group_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name      string             `json:"name" bson:"name"`
+	Emoji     string             `json:"emoji" bson:"emoji"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// FormatMessageReaction creates the reaction of the user to the message
+func FormatMessageReaction(message primitive.ObjectID, groupID string, user User, emoji string) MessageReaction {
+	return MessageReaction{
+		ID:        primitive.NewObjectID(),
+		MessageID: message,
+		GroupID:   groupID,
+		UserID:    user.ID,
+		Name:      user.Name,
+		Emoji:     emoji,
+		CreatedAt: time.Now(),
+	}
+}
+
+/*
+NormalizeReaction
+trims the emoji and checks it is a short run of non ascii symbols, the emoji is
+used as key of the counts stored on the message so dots and dollars are refused
+*/
+func NormalizeReaction(emoji string) (string, error) {
+
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > MAX_REACTION_RUNES {
+		return "", ErrInvalidReaction
+	}
+
+	for _, r := range emoji {
+		if r <= unicode.MaxASCII || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return "", ErrInvalidReaction
+		}
+	}
+
+	return emoji, nil
+}
+
+// MessageReactionEvent data of the reaction events, reactions holds the new counts of the message
+type MessageReactionEvent struct {
+	MessageID primitive.ObjectID `json:"message_id"`
+	GroupID   string             `json:"group_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id"`
+	Emoji     string             `json:"emoji"`
+	Removed   bool               `json:"removed"`
+	Reactions map[string]int     `json:"reactions"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_QUOTE_BODY runes of the quoted body kept on the reply
+	MAX_QUOTE_BODY = 200
+)
+
+// ERRORS
+var (
+	ErrReplyNotFound = errors.New("the message replied to does not exist on this conversation")
+)
+
+/*
+MessageQuote
+snapshot of the message a reply refers to, taken when the reply is sent so the
+reply keeps reading the same after the quoted message is edited or deleted
+*/
+type MessageQuote struct {
+	MessageID  primitive.ObjectID `json:"message_id" bson:"message_id"`
+	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
+	AuthorName string             `json:"author_name" bson:"author_name"`
+	BodyType   int                `json:"body_type" bson:"body_type"`
+	Body       string             `json:"body" bson:"body"`
+	Thumbnail  string             `json:"thumbnail,omitempty" bson:"thumbnail,omitempty"`
+}
+
+// formatMessageQuote fills the quote, the body is cut to MAX_QUOTE_BODY runes
+func formatMessageQuote(id, author primitive.ObjectID, authorName string, bodyType int, body string, placeholders []string) *MessageQuote {
+
+	if utf8.RuneCountInString(body) > MAX_QUOTE_BODY {
+		body = string([]rune(body)[:MAX_QUOTE_BODY]) + "…"
+	}
+
+	quote := &MessageQuote{
+		MessageID:  id,
+		AuthorID:   author,
+		AuthorName: authorName,
+		BodyType:   bodyType,
+		Body:       body,
+	}
+
+	if len(placeholders) > 0 {
+		quote.Thumbnail = placeholders[0]
+	}
+
+	return quote
+}
+
+// Snapshot quote of the group message
+func (m *GroupChatContentLog) Snapshot() *MessageQuote {
+	return formatMessageQuote(m.ID, m.AuthorID, m.AuthorName, m.BodyType, m.Body, m.Placeholders)
+}
+
+// Snapshot quote of the private message
+func (m *P2PContentChatLog) Snapshot() *MessageQuote {
+	return formatMessageQuote(m.ID, m.AuthorID, m.AuthorName, m.BodyType, m.Body, m.Placeholders)
+}
+
+/*
+ThreadRoot
+message the thread of the reply hangs from, replies to a reply of a thread stay
+on the same thread
+*/
+func (m *GroupChatContentLog) ThreadRoot() primitive.ObjectID {
+	if !m.ThreadID.IsZero() {
+		return m.ThreadID
+	}
+	return m.ID
+}
+
+// MessageThread root message of a thread and a page of its replies
+type MessageThread struct {
+	Root    *GroupChatContentLog   `json:"root"`
+	Replies []*GroupChatContentLog `json:"replies"`
+}
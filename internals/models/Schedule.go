@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_SCHEDULE_AHEAD furthest in the future a message can be scheduled
+	MAX_SCHEDULE_AHEAD = 365 * 24 * time.Hour
+
+	// SCHEDULE_PENDING the message waits for its send time
+	SCHEDULE_PENDING = "pending"
+	// SCHEDULE_FAILED the message could not be sent at its send time, the reason is kept on it
+	SCHEDULE_FAILED = "failed"
+
+	// EVENT_SCHEDULED_MESSAGE_FAILED sent to the author when a scheduled message can not be sent
+	EVENT_SCHEDULED_MESSAGE_FAILED = "message.schedule_failed"
+)
+
+// ERRORS
+var (
+	ErrScheduleBody        = errors.New("a scheduled message needs a body")
+	ErrScheduleTime        = errors.New("a message is scheduled to a time in the future within a year")
+	ErrScheduleDestination = errors.New("a message is scheduled either to a group or to a user")
+	ErrScheduleNotAllowed  = errors.New("the author can no longer send messages to the conversation")
+)
+
+/*
+ScheduledMessage
+text message kept until its send time, target_id is the group or the other user
+of the private conversation. The scheduler claims the due messages for a lease so
+a single node sends each one and another node retries it if that node dies. The
+message is sent with the id of the scheduled message, a retry can not send it twice
+*/
+type ScheduledMessage struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	AuthorID     primitive.ObjectID `json:"author_id" bson:"author_id"`
+	AuthorName   string             `json:"author_name" bson:"author_name"`
+	Conversation string             `json:"conversation" bson:"conversation"`
+	TargetID     primitive.ObjectID `json:"target_id" bson:"target_id"`
+	GroupID      string             `json:"group_id,omitempty" bson:"group_id,omitempty"`
+	TopicID      primitive.ObjectID `json:"topic_id,omitempty" bson:"topic_id,omitempty"`
+	Body         string             `json:"body" bson:"body"`
+	Status       string             `json:"status" bson:"status"`
+	Error        string             `json:"error,omitempty" bson:"error,omitempty"`
+	SendAt       time.Time          `json:"send_at" bson:"send_at"`
+	LeaseUntil   time.Time          `json:"-" bson:"lease_until"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+/*
+ScheduleRequest
+message to schedule or the changes to a scheduled one, a new message names
+either the group (group_id) or the user (target_id) it is sent to
+*/
+type ScheduleRequest struct {
+	GroupID  string             `json:"group_id"`
+	TargetID primitive.ObjectID `json:"target_id"`
+	TopicID  string             `json:"topic_id"`
+	Body     string             `json:"body"`
+	SendAt   time.Time          `json:"send_at"`
+}
+
+// Validate trims the body and checks the send time is in the future
+func (r *ScheduleRequest) Validate(now time.Time) error {
+
+	r.Body = strings.TrimSpace(r.Body)
+	if r.Body == "" {
+		return ErrScheduleBody
+	}
+
+	if !r.SendAt.After(now) || r.SendAt.After(now.Add(MAX_SCHEDULE_AHEAD)) {
+		return ErrScheduleTime
+	}
+
+	return nil
+}
+
+// ValidateDestination checks the request names a single destination, the author can not schedule to itself
+func (r *ScheduleRequest) ValidateDestination(author primitive.ObjectID) error {
+	if (r.GroupID == "") == r.TargetID.IsZero() || r.TargetID == author {
+		return ErrScheduleDestination
+	}
+	return nil
+}
+
+// FormatScheduledMessage creates the scheduled message of the author
+func FormatScheduledMessage(author User, r ScheduleRequest, now time.Time) ScheduledMessage {
+
+	conversation := CONVERSATION_P2P
+	if r.GroupID != "" {
+		conversation = CONVERSATION_GROUP
+	}
+
+	return ScheduledMessage{
+		ID:           primitive.NewObjectID(),
+		AuthorID:     author.ID,
+		AuthorName:   author.Name,
+		Conversation: conversation,
+		TargetID:     r.TargetID,
+		GroupID:      r.GroupID,
+		Body:         r.Body,
+		Status:       SCHEDULE_PENDING,
+		SendAt:       r.SendAt.UTC(),
+		LeaseUntil:   time.Time{},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// GroupLog message of the group sent at the send time, it keeps the id of the scheduled message
+func (m *ScheduledMessage) GroupLog(now time.Time) GroupChatTextLog {
+
+	var log GroupChatTextLog
+	log.FormatTextChatLog(m.TargetID, m.AuthorID, m.AuthorName, m.Body)
+	log.ID = m.ID
+	log.TopicID = m.TopicID
+	log.Created_At = now
+
+	return log
+}
+
+// P2PLog private message sent at the send time, it keeps the id of the scheduled message
+func (m *ScheduledMessage) P2PLog(now time.Time) P2PTextChatLog {
+
+	var log P2PTextChatLog
+	log.FormatTextLog(m.TargetID, m.AuthorID, m.AuthorName, m.Body)
+	log.ID = m.ID
+	log.Created_at = now
+
+	return log
+}
+
+// ScheduleFailedEvent data of the event sent to the author when a scheduled message can not be sent
+type ScheduleFailedEvent struct {
+	MessageID primitive.ObjectID `json:"message_id"`
+	Reason    string             `json:"reason"`
+}
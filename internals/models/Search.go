@@ -0,0 +1,201 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_SEARCH_QUERY longest search query, in characters
+	MAX_SEARCH_QUERY = 256
+	// MAX_SEARCH_PAGE deepest page of search results, every page ranks the results of the pages before it
+	MAX_SEARCH_PAGE = 10
+	// SEARCH_SNIPPET_RUNES runes of the body kept around the first match on the snippet
+	SEARCH_SNIPPET_RUNES = 120
+
+	// CONVERSATION_GROUP results found on the group chat logs
+	CONVERSATION_GROUP = "group"
+	// CONVERSATION_P2P results found on the private chat logs
+	CONVERSATION_P2P = "p2p"
+)
+
+// ERRORS
+var (
+	ErrInvalidSearchQuery = fmt.Errorf("the search query must have between 1 and %d characters", MAX_SEARCH_QUERY)
+	ErrInvalidSearchType  = errors.New("only text, file, image and video messages can be searched")
+	ErrInvalidSearchRange = errors.New("the start of the date range must be before its end")
+)
+
+// searchableTypes message types holding a body that can be searched
+var searchableTypes = []int{MESSAGE_TYPE_TEXT, MESSAGE_TYPE_FILE, MESSAGE_TYPE_MEDIA_VIDEOS, MESSAGE_TYPE_MEDIA_IMAGES}
+
+/*
+MessageSearchFilter
+scope and filters of a message search, the groups and the private conversations
+searched are set by the server to the ones the user belongs to
+*/
+type MessageSearchFilter struct {
+	Query    string
+	Groups   []primitive.ObjectID
+	Private  bool
+	Peer     primitive.ObjectID
+	Author   primitive.ObjectID
+	From     time.Time
+	To       time.Time
+	BodyType int
+}
+
+// Validate trims the query and checks the filters are within their limits
+func (f *MessageSearchFilter) Validate() error {
+
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Query == "" || utf8.RuneCountInString(f.Query) > MAX_SEARCH_QUERY {
+		return ErrInvalidSearchQuery
+	}
+
+	if f.BodyType != 0 && !slices.Contains(searchableTypes, f.BodyType) {
+		return ErrInvalidSearchType
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return ErrInvalidSearchRange
+	}
+
+	return nil
+}
+
+// SearchHighlight match of the query on the snippet, rune offsets with the end excluded
+type SearchHighlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+/*
+MessageSearchResult
+message found by a search, conversation tells whether target_id is a group or
+the other user of a private conversation
+*/
+type MessageSearchResult struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	Conversation string             `json:"conversation" bson:"-"`
+	TargetID     primitive.ObjectID `json:"target_id" bson:"target_id"`
+	AuthorID     primitive.ObjectID `json:"author_id" bson:"author_id"`
+	AuthorName   string             `json:"author_name" bson:"author_name"`
+	BodyType     int                `json:"body_type" bson:"body_type"`
+	Body         string             `json:"-" bson:"body"`
+	TopicID      primitive.ObjectID `json:"topic_id,omitempty" bson:"topic_id,omitempty"`
+	ThreadID     primitive.ObjectID `json:"thread_id,omitempty" bson:"thread_id,omitempty"`
+	Score        float64            `json:"score" bson:"score"`
+	Snippet      string             `json:"snippet" bson:"-"`
+	Highlights   []SearchHighlight  `json:"highlights" bson:"-"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Highlight fills the snippet of the result and the matches of the query on it
+func (r *MessageSearchResult) Highlight(query string) {
+	r.Snippet, r.Highlights = HighlightSnippet(r.Body, query)
+}
+
+/*
+HighlightSnippet
+cuts the body around the first word that starts with a term of the query and
+returns the matches on the cut, the negated terms of the query are not matched
+*/
+func HighlightSnippet(body, query string) (string, []SearchHighlight) {
+
+	text := []rune(body)
+	lower := []rune(strings.Map(unicode.ToLower, body))
+
+	var matches []SearchHighlight
+	for _, term := range searchTerms(query) {
+		for i := 0; i+len(term) <= len(lower); i++ {
+			if i > 0 && isWordRune(lower[i-1]) {
+				continue
+			}
+			if slices.Equal(lower[i:i+len(term)], term) {
+				matches = append(matches, SearchHighlight{Start: i, End: i + len(term)})
+			}
+		}
+	}
+
+	matches = mergeHighlights(matches)
+
+	start, end := 0, len(text)
+	if len(text) > SEARCH_SNIPPET_RUNES {
+		if len(matches) > 0 {
+			start = max(0, matches[0].Start-SEARCH_SNIPPET_RUNES/3)
+		}
+		end = min(len(text), start+SEARCH_SNIPPET_RUNES)
+		start = max(0, end-SEARCH_SNIPPET_RUNES)
+	}
+
+	prefix := ""
+	if start > 0 {
+		prefix = "…"
+	}
+	suffix := ""
+	if end < len(text) {
+		suffix = "…"
+	}
+
+	offset := utf8.RuneCountInString(prefix) - start
+
+	highlights := []SearchHighlight{}
+	for _, m := range matches {
+		if m.End <= start || m.Start >= end {
+			continue
+		}
+		highlights = append(highlights, SearchHighlight{
+			Start: max(m.Start, start) + offset,
+			End:   min(m.End, end) + offset,
+		})
+	}
+
+	return prefix + string(text[start:end]) + suffix, highlights
+}
+
+// searchTerms lower case words of the query, quotes are dropped and negated words left out
+func searchTerms(query string) [][]rune {
+
+	var terms [][]rune
+	for _, word := range strings.Fields(strings.Map(unicode.ToLower, query)) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		word = strings.Trim(word, `"`)
+		if word != "" {
+			terms = append(terms, []rune(word))
+		}
+	}
+
+	return terms
+}
+
+// mergeHighlights sorts the matches and joins the ones that overlap
+func mergeHighlights(matches []SearchHighlight) []SearchHighlight {
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+
+	var res []SearchHighlight
+	for _, m := range matches {
+		if n := len(res); n > 0 && m.Start <= res[n-1].End {
+			res[n-1].End = max(res[n-1].End, m.End)
+			continue
+		}
+		res = append(res, m)
+	}
+
+	return res
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// SYSTEM_EVENT_MEMBER_LEFT a participant left the group
+	SYSTEM_EVENT_MEMBER_LEFT = "member_left"
+	// SYSTEM_EVENT_ADMIN_PROMOTED the longest-standing participant became admin after the last admin left
+	SYSTEM_EVENT_ADMIN_PROMOTED = "admin_promoted"
+	// SYSTEM_EVENT_OWNERSHIP_TRANSFERRED the group has a new owner
+	SYSTEM_EVENT_OWNERSHIP_TRANSFERRED = "ownership_transferred"
+	// SYSTEM_EVENT_AVATAR_CHANGED the avatar of the group was replaced
+	SYSTEM_EVENT_AVATAR_CHANGED = "avatar_changed"
+	// SYSTEM_EVENT_MESSAGE_TIMER_CHANGED the timer of the disappearing messages changed
+	SYSTEM_EVENT_MESSAGE_TIMER_CHANGED = "message_timer_changed"
+)
+
+/*
+GroupSystemLog
+message written by the server on the group chat log when the membership of the group changes
+*/
+type GroupSystemLog struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	TargetID   primitive.ObjectID `json:"target_id" bson:"target_id"`
+	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
+	ContentID  string             `json:"content_id" bson:"content_id"`
+	AuthorName string             `json:"author_name" bson:"author_name"`
+	BodyType   int                `json:"body_type" bson:"body_type"`
+	Body       string             `json:"body" bson:"body"`
+	Event      string             `json:"event" bson:"event"`
+	SubjectID  primitive.ObjectID `json:"subject_id" bson:"subject_id"`
+	Created_At time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// FormatSystemLog fills the system message, the subject is the user the event is about
+func (g *GroupSystemLog) FormatSystemLog(groupID, author, subject primitive.ObjectID, authorName, event, body string) {
+	g.ID = primitive.NewObjectID()
+	g.TargetID = groupID
+	g.AuthorID = author
+	g.ContentID = "N/A"
+	g.AuthorName = authorName
+	g.BodyType = MESSAGE_TYPE_SYSTEM
+	g.Body = body
+	g.Event = event
+	g.SubjectID = subject
+	g.Created_At = time.Now()
+}
+
+/*
+P2PSystemLog
+message written by the server on the private chat log when the settings of the
+conversation change
+*/
+type P2PSystemLog struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	TargetID   primitive.ObjectID `json:"target_id" bson:"target_id"`
+	AuthorID   primitive.ObjectID `json:"author_id" bson:"author_id"`
+	ContentID  string             `json:"content_id" bson:"content_id"`
+	AuthorName string             `json:"author_name" bson:"author_name"`
+	BodyType   int                `json:"body_type" bson:"body_type"`
+	Body       string             `json:"body" bson:"body"`
+	Event      string             `json:"event" bson:"event"`
+	Created_at time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// FormatSystemLog fills the system message of the conversation of the author with the target
+func (p *P2PSystemLog) FormatSystemLog(target, author primitive.ObjectID, authorName, event, body string) {
+	p.ID = primitive.NewObjectID()
+	p.TargetID = target
+	p.AuthorID = author
+	p.ContentID = "N/A"
+	p.AuthorName = authorName
+	p.BodyType = MESSAGE_TYPE_SYSTEM
+	p.Body = body
+	p.Event = event
+	p.Created_at = time.Now()
+}
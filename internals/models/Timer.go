@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MIN_MESSAGE_TIMER shortest life of the messages of a conversation with disappearing messages, in seconds
+	MIN_MESSAGE_TIMER = 30
+	// MAX_MESSAGE_TIMER longest life of the messages of a conversation with disappearing messages, in seconds
+	MAX_MESSAGE_TIMER = 90 * 24 * 60 * 60
+
+	// EVENT_MESSAGE_EXPIRED sent to the conversation when an expired message is removed
+	EVENT_MESSAGE_EXPIRED = "message.expired"
+)
+
+// ERRORS
+var (
+	ErrMessageTimer = fmt.Errorf("the timer must be 0 or between %d and %d seconds", MIN_MESSAGE_TIMER, MAX_MESSAGE_TIMER)
+)
+
+// MessageTimer timer of the disappearing messages of a conversation, in seconds, zero turns them off
+type MessageTimer struct {
+	Duration int `json:"duration"`
+}
+
+// Validate checks the timer is off or within its limits
+func (t MessageTimer) Validate() error {
+	if t.Duration != 0 && (t.Duration < MIN_MESSAGE_TIMER || t.Duration > MAX_MESSAGE_TIMER) {
+		return ErrMessageTimer
+	}
+	return nil
+}
+
+// SystemBody text of the system message written when the timer changes
+func (t MessageTimer) SystemBody() string {
+	if t.Duration == 0 {
+		return "disappearing messages were turned off"
+	}
+	return fmt.Sprintf("disappearing messages were set to %s", time.Duration(t.Duration)*time.Second)
+}
+
+// MessageExpiry returns the time a message sent at the given time expires, nil when the timer is off
+func MessageExpiry(timer int, sentAt time.Time) *time.Time {
+	if timer <= 0 {
+		return nil
+	}
+	at := sentAt.Add(time.Duration(timer) * time.Second)
+	return &at
+}
+
+/*
+ConversationTimer
+timer of the disappearing messages of a private conversation, the id is the
+P2PConversationID so both users share it. The timers of the groups are kept on
+the group itself
+*/
+type ConversationTimer struct {
+	ConversationID string             `json:"conversation_id" bson:"_id"`
+	Duration       int                `json:"duration" bson:"duration"`
+	UpdatedBy      primitive.ObjectID `json:"updated_by" bson:"updated_by"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+/*
+MessageExpiredEvent
+data of the expiry events, target_id is the group of group messages and the
+target of private ones
+*/
+type MessageExpiredEvent struct {
+	MessageID primitive.ObjectID `json:"message_id"`
+	AuthorID  primitive.ObjectID `json:"author_id"`
+	TargetID  primitive.ObjectID `json:"target_id"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MAX_GROUP_TOPICS most topics a group can hold, archived ones included
+	MAX_GROUP_TOPICS = 50
+	// MAX_TOPIC_NAME longest name of a topic, in characters
+	MAX_TOPIC_NAME = 64
+)
+
+// ERRORS
+var (
+	ErrTopicNotFound = errors.New("topic not found on this group")
+	ErrTopicArchived = errors.New("the topic is archived")
+)
+
+/*
+GroupTopic
+child conversation of a group, the messages of the topic live on the group chat
+log with its id and follow the permissions and moderation of the group
+*/
+type GroupTopic struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	Name      string             `json:"name" bson:"name"`
+	Archived  bool               `json:"archived" bson:"archived"`
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// TopicSettings fields of the topic the admins can change
+type TopicSettings struct {
+	Name     string `json:"name"`
+	Archived bool   `json:"archived"`
+}
+
+// Validate trims the name and checks it is within its limits
+func (s *TopicSettings) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" || utf8.RuneCountInString(s.Name) > MAX_TOPIC_NAME {
+		return fmt.Errorf("the topic name must have between 1 and %d characters", MAX_TOPIC_NAME)
+	}
+	return nil
+}
+
+// HasTopicNamed reports whether another topic of the group already uses the name
+func (g *Group) HasTopicNamed(name string, except primitive.ObjectID) bool {
+	for _, t := range g.Topics {
+		if t.ID != except && strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatGroupTopic creates the topic named by the author
+func FormatGroupTopic(name string, author primitive.ObjectID) GroupTopic {
+	return GroupTopic{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		CreatedBy: author,
+		CreatedAt: time.Now(),
+	}
+}
+
+/*
+Topic
+returns the topic of the group, a zero id is the main conversation of the group
+and returns nil without error
+*/
+func (g *Group) Topic(id primitive.ObjectID) (*GroupTopic, error) {
+
+	if id.IsZero() {
+		return nil, nil
+	}
+
+	for i := range g.Topics {
+		if g.Topics[i].ID == id {
+			return &g.Topics[i], nil
+		}
+	}
+
+	return nil, ErrTopicNotFound
+}
+
+/*
+CheckTopic
+returns the topic the message is posted to, archived topics do not take new messages
+*/
+func (g *Group) CheckTopic(topicID string) (primitive.ObjectID, error) {
+
+	if topicID == "" {
+		return primitive.NilObjectID, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(topicID)
+	if err != nil {
+		return primitive.NilObjectID, ErrTopicNotFound
+	}
+
+	topic, err := g.Topic(id)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	if topic.Archived {
+		return primitive.NilObjectID, ErrTopicArchived
+	}
+
+	return id, nil
+}
+
+// TopicRead last time the user read the topic
+type TopicRead struct {
+	ID      primitive.ObjectID `json:"_id" bson:"_id"`
+	GroupID string             `json:"group_id" bson:"group_id"`
+	TopicID primitive.ObjectID `json:"topic_id" bson:"topic_id"`
+	UserID  primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ReadAt  time.Time          `json:"read_at" bson:"read_at"`
+}
+
+// TopicState topic of the group as seen by the user
+type TopicState struct {
+	GroupTopic
+	ReadAt time.Time `json:"read_at"`
+	Unread int       `json:"unread"`
+}
@@ -9,6 +9,10 @@ const (
 	MESSAGE_TYPE_MEDIA_VIDEOS = 59
 	// MESSAGE_TYPE_MEDIA_IMAGES Type of message that is an array of image content
 	MESSAGE_TYPE_MEDIA_IMAGES = 65
+	// MESSAGE_TYPE_CALL Type of message that logs a call
+	MESSAGE_TYPE_CALL = 71
+	// MESSAGE_TYPE_SYSTEM Type of message written by the server, ej. a member left the group
+	MESSAGE_TYPE_SYSTEM = 77
 
 	// WEBSOCKET_BINARY_SEPARATOR acts as a separator from binary file data and message data
 	WEBSOCKET_BINARY_SEPARATOR = "^~~^"
@@ -23,6 +27,7 @@ type InboundP2PTextMessage struct {
 	AuthorID        string `json:"author_id"`
 	TargetID        string `json:"target_id"`
 	TargetPushToken string `json:"target_pushtoken"`
+	ReplyTo         string `json:"reply_to"`
 	Body            string `json:"body"`
 }
 
@@ -33,6 +38,7 @@ type InboundP2PContentMessage struct {
 	TargetID        string   `json:"target_id"`
 	TargetPushToken string   `json:"target_pushtoken"`
 	Filename        []string `json:"filename"`
+	ReplyTo         string   `json:"reply_to"`
 	Body            string   `json:"body"`
 }
 
@@ -41,6 +47,10 @@ type InboundGroupTextMessage struct {
 	MessageID  string            `json:"message_id"`
 	AuthorID   string            `json:"author_id"`
 	GroupID    string            `json:"group"`
+	TopicID    string            `json:"topic_id"`
+	ReplyTo    string            `json:"reply_to"`
+	Thread     bool              `json:"thread"`
+	Mentions   []string          `json:"mentions"`
 	PushTokens map[string]string `json:"push_tokens"`
 	Body       string            `json:"body"`
 }
@@ -51,6 +61,9 @@ type InboundGroupContentMessage struct {
 	ContentType int               `json:"content_type"`
 	AuthorID    string            `json:"author_id"`
 	GroupID     string            `json:"group_id"`
+	TopicID     string            `json:"topic_id"`
+	ReplyTo     string            `json:"reply_to"`
+	Thread      bool              `json:"thread"`
 	PushTokens  map[string]string `json:"target_pushtoken"`
 	Filename    []string          `json:"filename"`
 	Body        string            `json:"body"`
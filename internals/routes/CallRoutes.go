@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"wechat-back/internals/decorators"
+	"wechat-back/internals/handlers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func CallRoutes(mux *chi.Mux) {
+
+	mux.Get("/turn", decorators.HandlerDecorator(handlers.GetTURNCredentialsEP, nil))
+	mux.Get("/clh", decorators.HandlerDecorator(handlers.GetCallHistoryEP, nil))
+
+}
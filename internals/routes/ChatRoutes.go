@@ -11,5 +11,20 @@ func ChatRoutes(mux *chi.Mux) {
 
 	mux.Handle("/uchat", decorators.HandlerWProvidersDecorator(handlers.HandleP2PConnectionEP, nil, nil))
 	mux.Handle("/gchat", decorators.HandlerWProvidersDecorator(handlers.HandleGroupConnectionsEP, nil, nil))
+	mux.Put("/mrc", decorators.HandlerDecorator(handlers.ReactToMessageEP, nil))
+	mux.Get("/mrc", decorators.HandlerDecorator(handlers.GetMessageReactionsEP, nil))
+	mux.Get("/sms", decorators.HandlerDecorator(handlers.SearchMessagesEP, nil))
+	mux.Get("/pgl", decorators.HandlerDecorator(handlers.GetP2PGalleryEP, nil))
+	mux.Put("/mpn", decorators.HandlerDecorator(handlers.PinMessageEP, nil))
+	mux.Get("/mpn", decorators.HandlerDecorator(handlers.GetPinnedMessagesEP, nil))
+	mux.Put("/mst", decorators.HandlerDecorator(handlers.StarMessageEP, nil))
+	mux.Get("/mst", decorators.HandlerDecorator(handlers.GetStarredMessagesEP, nil))
+	mux.Post("/mfw", decorators.HandlerDecorator(handlers.ForwardMessageEP, nil))
+	mux.Post("/msc", decorators.HandlerDecorator(handlers.ScheduleMessageEP, nil))
+	mux.Get("/msc", decorators.HandlerDecorator(handlers.GetScheduledMessagesEP, nil))
+	mux.Put("/msc", decorators.HandlerDecorator(handlers.UpdateScheduledMessageEP, nil))
+	mux.Delete("/msc", decorators.HandlerDecorator(handlers.CancelScheduledMessageEP, nil))
+	mux.Put("/mtm", decorators.HandlerDecorator(handlers.UpdateP2PMessageTimerEP, nil))
+	mux.Get("/mtm", decorators.HandlerDecorator(handlers.GetP2PMessageTimerEP, nil))
 
 }
@@ -12,10 +12,38 @@ func GroupHandlers(mux *chi.Mux) http.Handler {
 
 	mux.Post("/cg", decorators.HandlerWProvidersDecorator(handlers.CreateNewGroupEP, nil, nil))
 	mux.Put("/ugi", decorators.HandlerDecorator(handlers.UpdateGroupInfoEP, nil))
+	mux.Put("/ugav", decorators.HandlerWProvidersDecorator(handlers.UpdateGroupAvatarEP, nil, nil))
 	mux.Put("/uga", decorators.HandlerDecorator(handlers.UpdateGroupAdminsEP, nil))
 	mux.Put("/igp", decorators.HandlerDecorator(handlers.UpdateGroupParticipantsEP, nil))
+	mux.Put("/ugpm", decorators.HandlerDecorator(handlers.UpdateGroupPermissionsEP, nil))
+	mux.Put("/ugap", decorators.HandlerDecorator(handlers.UpdateGroupApprovalEP, nil))
+	mux.Put("/ugmd", decorators.HandlerDecorator(handlers.UpdateGroupModerationEP, nil))
+	mux.Put("/mgm", decorators.HandlerDecorator(handlers.MuteGroupMemberEP, nil))
+	mux.Put("/ugtm", decorators.HandlerDecorator(handlers.UpdateGroupMessageTimerEP, nil))
+	mux.Post("/gil", decorators.HandlerDecorator(handlers.CreateGroupInviteEP, nil))
+	mux.Get("/gil", decorators.HandlerDecorator(handlers.GetGroupInvitesEP, nil))
+	mux.Delete("/gil", decorators.HandlerDecorator(handlers.RevokeGroupInviteEP, nil))
+	mux.Post("/jgp", decorators.HandlerDecorator(handlers.JoinGroupEP, nil))
+	mux.Get("/gjr", decorators.HandlerDecorator(handlers.GetJoinRequestsEP, nil))
+	mux.Put("/gjr", decorators.HandlerDecorator(handlers.ReviewJoinRequestEP, nil))
+	mux.Put("/lgp", decorators.HandlerDecorator(handlers.LeaveGroupEP, nil))
+	mux.Put("/tgo", decorators.HandlerDecorator(handlers.TransferGroupOwnershipEP, nil))
 	mux.Delete("/dgp", decorators.HandlerDecorator(handlers.DeleteGroupEP, nil))
+	mux.Put("/rgp", decorators.HandlerDecorator(handlers.RestoreGroupEP, nil))
 	mux.Get("/sgp", decorators.HandlerDecorator(handlers.SearchGroupsEP, nil))
+	mux.Put("/ugd", decorators.HandlerDecorator(handlers.UpdateGroupDirectoryEP, nil))
+	mux.Get("/gpf", decorators.HandlerDecorator(handlers.GetGroupProfileEP, nil))
+	mux.Get("/gau", decorators.HandlerDecorator(handlers.GetGroupAuditEP, nil))
+	mux.Post("/gtp", decorators.HandlerDecorator(handlers.CreateGroupTopicEP, nil))
+	mux.Put("/gtp", decorators.HandlerDecorator(handlers.UpdateGroupTopicEP, nil))
+	mux.Get("/gtp", decorators.HandlerDecorator(handlers.GetGroupTopicsEP, nil))
+	mux.Get("/gth", decorators.HandlerDecorator(handlers.GetTopicHistoryEP, nil))
+	mux.Put("/rtp", decorators.HandlerDecorator(handlers.MarkTopicReadEP, nil))
+	mux.Get("/gtr", decorators.HandlerDecorator(handlers.GetThreadHistoryEP, nil))
+	mux.Get("/gmn", decorators.HandlerDecorator(handlers.GetUnreadMentionsEP, nil))
+	mux.Put("/gmn", decorators.HandlerDecorator(handlers.MarkMentionsReadEP, nil))
+	mux.Put("/mgn", decorators.HandlerDecorator(handlers.MuteGroupNotificationsEP, nil))
+	mux.Get("/ggl", decorators.HandlerDecorator(handlers.GetGroupGalleryEP, nil))
 
 	return mux
 }
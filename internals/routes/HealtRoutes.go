@@ -9,5 +9,6 @@ import (
 func HealtRoutes(mux *chi.Mux) {
 
 	mux.Get("/", handlers.ServerHealthCheckEP)
+	mux.Get("/wpm", handlers.WorkerPoolMetricsEP)
 
 }
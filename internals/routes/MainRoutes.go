@@ -29,6 +29,9 @@ func ServerRoutes() http.Handler {
 	// chat routes
 	ChatRoutes(mux)
 
+	// call routes
+	CallRoutes(mux)
+
 	return mux
 }
 
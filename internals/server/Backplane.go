@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+	"wechat-back/internals/backplane"
+	"wechat-back/internals/generators"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// ENVELOPE_P2P envelope that targets a p2p connection
+	ENVELOPE_P2P = "p2p"
+
+	// ENVELOPE_GROUP envelope that targets group connections
+	ENVELOPE_GROUP = "group"
+
+	// PRESENCE_P2P presence kind for p2p connections
+	PRESENCE_P2P = "p2p"
+
+	// PRESENCE_GROUP presence kind for group connections
+	PRESENCE_GROUP = "group"
+)
+
+// Envelope message exchanged between nodes through the backplane
+type Envelope struct {
+	Kind    string          `json:"kind"`
+	Origin  string          `json:"origin"`
+	GroupID string          `json:"group_id"`
+	Targets []string        `json:"targets"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// startBackplane connects the hub to the backplane and starts listening to the node channel
+func (h *WebsocketPanel) startBackplane() {
+
+	alog := logger.StartLogger()
+
+	bp, err := backplane.NewBackplane()
+	if err != nil {
+		alog.ErrorLog(fmt.Sprintf("backplane not available, running as single node: %v", err))
+		bp = backplane.NewLocalBackplane()
+	}
+
+	h.Backplane = bp
+	h.NodeID = os.Getenv("NODE_ID")
+	if h.NodeID == "" {
+		h.NodeID, _ = generators.GenerateAlphaNumericCode(12)
+	}
+
+	sub, err := bp.Subscribe(context.Background(), backplane.NodeChannel(h.NodeID), h.handleEnvelope)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+	h.subscription = sub
+
+	broadcast, err := bp.Subscribe(context.Background(), backplane.BroadcastChannel(), h.handleEnvelope)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+	h.broadcast = broadcast
+
+	go h.refreshPresence()
+}
+
+// stopBackplane removes the presence of this node and closes the backplane
+func (h *WebsocketPanel) stopBackplane() {
+
+	close(h.stop)
+
+	if h.subscription != nil {
+		h.subscription.Unsubscribe()
+	}
+
+	if h.broadcast != nil {
+		h.broadcast.Unsubscribe()
+	}
+
+	for _, key := range h.P2PConnections.Keys() {
+		h.removePresence(PRESENCE_P2P, key)
+	}
+
+	h.GroupConnections.Range(func(key string, conn *GroupConnectionCredentials) bool {
+		h.removePresence(PRESENCE_GROUP, groupPresenceID(conn.TargetID, key))
+		return true
+	})
+
+	h.Backplane.Close()
+}
+
+/*
+refreshPresence
+keeps the presence of the local connections alive, if the node dies the
+entries expire and other nodes stop routing to it
+*/
+func (h *WebsocketPanel) refreshPresence() {
+
+	ticker := time.NewTicker(backplane.DEFAULT_PRESENCE_TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			for _, key := range h.P2PConnections.Keys() {
+				h.setPresence(PRESENCE_P2P, key)
+			}
+
+			h.GroupConnections.Range(func(key string, conn *GroupConnectionCredentials) bool {
+				h.setPresence(PRESENCE_GROUP, groupPresenceID(conn.TargetID, key))
+				return true
+			})
+
+			h.Calls.refresh()
+		}
+	}
+}
+
+func (h *WebsocketPanel) setPresence(kind, userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := h.Backplane.SetPresence(ctx, backplane.PresenceKey(kind, userID), h.NodeID, backplane.DEFAULT_PRESENCE_TTL)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+	}
+}
+
+/*
+claimPresence
+registers this node as the holder of the presence of the user only if no node
+holds it, returns false when the user is already present
+*/
+func (h *WebsocketPanel) claimPresence(kind, userID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return h.Backplane.ClaimPresence(ctx, backplane.PresenceKey(kind, userID), h.NodeID, backplane.DEFAULT_PRESENCE_TTL)
+}
+
+func (h *WebsocketPanel) removePresence(kind, userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := h.Backplane.RemovePresence(ctx, backplane.PresenceKey(kind, userID), h.NodeID)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+	}
+}
+
+/*
+locateUser
+returns the node that holds the connection of the user, false if the user is offline
+*/
+func (h *WebsocketPanel) locateUser(kind, userID string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	nodeID, err := h.Backplane.GetPresence(ctx, backplane.PresenceKey(kind, userID))
+	if err != nil {
+		if err != backplane.ErrNoPresence {
+			logger.StartLogger().ErrorLog(err.Error())
+		}
+		return "", false
+	}
+
+	return nodeID, true
+}
+
+/*
+forwardToNode
+sends the payload to the connections of the targets that live on another node
+*/
+func (h *WebsocketPanel) forwardToNode(nodeID, kind, groupID string, targets []string, payload any) error {
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	env, err := json.Marshal(Envelope{
+		Kind:    kind,
+		Origin:  h.NodeID,
+		GroupID: groupID,
+		Targets: targets,
+		Payload: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return h.Backplane.Publish(ctx, backplane.NodeChannel(nodeID), env)
+}
+
+// publishBroadcast sends the payload to every node
+func (h *WebsocketPanel) publishBroadcast(kind, groupID string, payload json.RawMessage) error {
+
+	env, err := json.Marshal(Envelope{
+		Kind:    kind,
+		Origin:  h.NodeID,
+		GroupID: groupID,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return h.Backplane.Publish(ctx, backplane.BroadcastChannel(), env)
+}
+
+/*
+handleEnvelope
+delivers the envelopes received from other nodes to the local connections
+*/
+func (h *WebsocketPanel) handleEnvelope(data []byte) {
+
+	alog := logger.StartLogger()
+
+	var env Envelope
+	err := json.Unmarshal(data, &env)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+
+	switch env.Kind {
+	case ENVELOPE_P2P:
+		for _, tar := range env.Targets {
+			user, ok := h.P2PConnections.Lookup(tar)
+			if ok {
+				user.WriteMessage(env.Payload)
+			}
+		}
+
+	case ENVELOPE_GROUP:
+		for _, tar := range env.Targets {
+			user, ok := h.GroupConnections.Lookup(tar)
+			if ok && user.TargetID == env.GroupID {
+				user.WriteMessage(env.Payload)
+			}
+		}
+
+	case ENVELOPE_CALL:
+		var signal models.InboundCallSignal
+		err = json.Unmarshal(env.Payload, &signal)
+		if err != nil || len(env.Targets) == 0 {
+			alog.ErrorLog(fmt.Sprintf("invalid call envelope from %s", env.Origin))
+			return
+		}
+
+		err = h.Calls.HandleSignal(env.Targets[0], signal)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+		}
+
+	case ENVELOPE_GROUP_UPDATE:
+		// the origin already refreshed its own connections
+		if env.Origin != h.NodeID {
+			h.applyGroupUpdate(env.Payload)
+		}
+
+	case ENVELOPE_GROUP_CLOSE:
+		if env.Origin != h.NodeID {
+			h.closeGroupConnections(env.GroupID, env.Payload)
+		}
+
+	case ENVELOPE_GROUP_EVENT:
+		if env.Origin != h.NodeID {
+			h.writeGroupConnections(env.GroupID, env.Payload)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"wechat-back/internals/generators"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/workerpool"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// DEFAULT_RING_TIMEOUT time a call rings before it is marked as missed
+	DEFAULT_RING_TIMEOUT = 45 * time.Second
+
+	// PRESENCE_CALL presence kind for users that are in a call
+	PRESENCE_CALL = "call"
+
+	// ENVELOPE_CALL envelope that carries a call signal to the node that owns the call
+	ENVELOPE_CALL = "call"
+)
+
+// ERRORS
+var (
+	ErrCallBusy       = errors.New("user is already in a call")
+	ErrCallNotFound   = errors.New("call not found")
+	ErrCallNotAllowed = errors.New("signal not allowed on this call")
+)
+
+// Call state of a call owned by this node
+type Call struct {
+	ID         string
+	Type       string
+	State      string
+	Caller     *models.User
+	CalleeID   string
+	StartedAt  time.Time
+	AnsweredAt time.Time
+
+	// pushToken token used to notify the callee when it is offline
+	pushToken string
+
+	// ringing fires when the callee does not answer in time
+	ringing *time.Timer
+}
+
+/*
+CallManager
+keeps the calls started on this node, the id of a call starts with the id of
+the node so the signals of a peer connected to another node reach the owner
+*/
+type CallManager struct {
+	hub *WebsocketPanel
+
+	// RingTimeout time a call rings before it is marked as missed
+	RingTimeout time.Duration
+
+	// GroupCallLimit participants a group call holds
+	GroupCallLimit int
+
+	mux   sync.Mutex
+	calls map[string]*Call
+	rooms map[string]*GroupCall
+}
+
+// NewCallManager creates the call manager of the hub
+func NewCallManager(hub *WebsocketPanel) *CallManager {
+	return &CallManager{
+		hub:            hub,
+		RingTimeout:    DEFAULT_RING_TIMEOUT,
+		GroupCallLimit: DEFAULT_GROUP_CALL_LIMIT,
+		calls:          make(map[string]*Call),
+		rooms:          make(map[string]*GroupCall),
+	}
+}
+
+// callOwner returns the node that owns the call
+func callOwner(callID string) string {
+	i := strings.LastIndex(callID, ".")
+	if i < 0 {
+		return ""
+	}
+	return callID[:i]
+}
+
+// peer returns the other side of the call
+func (c *Call) peer(userID string) string {
+	if userID == c.Caller.ID.Hex() {
+		return c.CalleeID
+	}
+	return c.Caller.ID.Hex()
+}
+
+// stopRinging stops the ring timeout of the call
+func (c *Call) stopRinging() {
+	if c.ringing != nil {
+		c.ringing.Stop()
+	}
+}
+
+// signal formats the outbound signal of the call
+func (c *Call) signal(from string) models.OutboundCallSignal {
+	res := models.OutboundCallSignal{
+		CallID:   c.ID,
+		FromID:   from,
+		CallType: c.Type,
+		State:    c.State,
+	}
+	if from == c.Caller.ID.Hex() {
+		res.FromName = c.Caller.Name
+	}
+	return res
+}
+
+/*
+HandleCallSignal
+routes a call signal sent by the user of the connection
+*/
+func (p *P2PConnectionCredentials) HandleCallSignal(data []byte) {
+
+	alog := logger.StartLogger()
+
+	var signal models.InboundCallSignal
+	err := json.Unmarshal(data, &signal)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_REQUEST))
+		return
+	}
+
+	// group calls are only reachable from the group connections
+	signal.GroupID = ""
+
+	if signal.Event == models.EVENT_CALL_OFFER {
+		token := ""
+		if signal.TargetID == p.TargetID {
+			token = p.TargetPushToken
+		}
+		err = p.hub.Calls.Offer(p.AuthorData, token, signal)
+	} else {
+		err = p.hub.Calls.HandleSignal(p.AuthorID, signal)
+	}
+
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, callErrorCode(err)))
+	}
+}
+
+// callErrorCode returns the server code of a call error
+func callErrorCode(err error) int {
+	switch err {
+	case ErrCallBusy:
+		return CALL_BUSY
+	case ErrCallNotFound:
+		return CALL_NOT_FOUND
+	case ErrCallNotAllowed:
+		return NOT_ALLOWED
+	case ErrCallFull:
+		return CALL_FULL
+	}
+	return BAD_FIELD
+}
+
+/*
+Offer
+starts a call from the author to the target of the signal, the callee receives the
+offer wherever it is connected and the call rings until answered or timed out
+*/
+func (m *CallManager) Offer(caller *models.User, pushToken string, signal models.InboundCallSignal) error {
+
+	if _, err := primitive.ObjectIDFromHex(signal.TargetID); err != nil {
+		return err
+	}
+
+	if signal.CallType != models.CALL_TYPE_AUDIO && signal.CallType != models.CALL_TYPE_VIDEO {
+		return errors.New("invalid call type")
+	}
+
+	callerID := caller.ID.Hex()
+	if callerID == signal.TargetID {
+		return ErrCallNotAllowed
+	}
+
+	code, err := generators.GenerateAlphaNumericCode(16)
+	if err != nil {
+		return err
+	}
+
+	call := &Call{
+		ID:        m.hub.NodeID + "." + code,
+		Type:      signal.CallType,
+		State:     models.CALL_STATE_RINGING,
+		Caller:    caller,
+		CalleeID:  signal.TargetID,
+		StartedAt: time.Now(),
+		pushToken: pushToken,
+	}
+
+	// the busy check and the mark are a single claim so two offers never book the same user
+	claimed, err := m.hub.claimPresence(PRESENCE_CALL, callerID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrCallBusy
+	}
+
+	claimed, err = m.hub.claimPresence(PRESENCE_CALL, call.CalleeID)
+	if err != nil || !claimed {
+		m.hub.removePresence(PRESENCE_CALL, callerID)
+		if err != nil {
+			return err
+		}
+		return ErrCallBusy
+	}
+
+	m.mux.Lock()
+	m.calls[call.ID] = call
+
+	state := call.signal(callerID)
+	offer := call.signal(callerID)
+	offer.SDP = signal.SDP
+	m.mux.Unlock()
+
+	m.hub.deliverToUser(callerID, models.WebsocketEvent{Event: models.EVENT_CALL_STATE, Data: state})
+
+	event := models.WebsocketEvent{Event: models.EVENT_CALL_OFFER, Data: offer}
+	if !m.hub.deliverToUser(call.CalleeID, event) {
+		m.hub.sendPushNotification(pushToken, event)
+	}
+
+	// the ring timeout starts once the offer is out so the missed state never overtakes it
+	m.mux.Lock()
+	if _, ok := m.calls[call.ID]; ok && call.State == models.CALL_STATE_RINGING {
+		call.ringing = time.AfterFunc(m.RingTimeout, func() {
+			m.finish(call.ID, "", models.CALL_STATE_MISSED)
+		})
+	}
+	m.mux.Unlock()
+
+	return nil
+}
+
+/*
+HandleSignal
+applies the signal of the user to the call, signals of calls owned by another
+node are forwarded to it
+*/
+func (m *CallManager) HandleSignal(userID string, signal models.InboundCallSignal) error {
+
+	owner := callOwner(signal.CallID)
+	if owner == "" {
+		return ErrCallNotFound
+	}
+	if owner != m.hub.NodeID {
+		return m.hub.forwardToNode(owner, ENVELOPE_CALL, "", []string{userID}, signal)
+	}
+
+	if signal.GroupID != "" {
+		return m.handleGroupSignal(userID, signal)
+	}
+
+	m.mux.Lock()
+	call, ok := m.calls[signal.CallID]
+	if !ok {
+		m.mux.Unlock()
+		return ErrCallNotFound
+	}
+
+	if userID != call.Caller.ID.Hex() && userID != call.CalleeID {
+		m.mux.Unlock()
+		return ErrCallNotAllowed
+	}
+
+	switch signal.Event {
+
+	case models.EVENT_CALL_ANSWER:
+		if userID != call.CalleeID || call.State != models.CALL_STATE_RINGING {
+			m.mux.Unlock()
+			return ErrCallNotAllowed
+		}
+		call.stopRinging()
+		call.State = models.CALL_STATE_ACCEPTED
+		call.AnsweredAt = time.Now()
+
+		answer := call.signal(userID)
+		answer.SDP = signal.SDP
+		state := call.signal(userID)
+		m.mux.Unlock()
+
+		m.hub.deliverToUser(call.Caller.ID.Hex(), models.WebsocketEvent{Event: models.EVENT_CALL_ANSWER, Data: answer})
+		m.hub.deliverToUser(call.CalleeID, models.WebsocketEvent{Event: models.EVENT_CALL_STATE, Data: state})
+
+	case models.EVENT_CALL_CANDIDATE:
+		candidate := call.signal(userID)
+		candidate.Candidate = signal.Candidate
+		m.mux.Unlock()
+
+		m.hub.deliverToUser(call.peer(userID), models.WebsocketEvent{Event: models.EVENT_CALL_CANDIDATE, Data: candidate})
+
+	case models.EVENT_CALL_REJECT:
+		if userID != call.CalleeID || call.State != models.CALL_STATE_RINGING {
+			m.mux.Unlock()
+			return ErrCallNotAllowed
+		}
+		m.mux.Unlock()
+
+		m.finish(call.ID, userID, models.CALL_STATE_REJECTED)
+
+	case models.EVENT_CALL_HANGUP:
+		m.mux.Unlock()
+		m.hangup(call, userID)
+
+	default:
+		m.mux.Unlock()
+		return errors.New("unknown call event")
+	}
+
+	return nil
+}
+
+/*
+hangup
+finishes the call, a ringing call is missed when the caller cancels it
+and rejected when the callee hangs up
+*/
+func (m *CallManager) hangup(call *Call, userID string) {
+
+	m.mux.Lock()
+	state := call.State
+	m.mux.Unlock()
+
+	switch {
+	case state == models.CALL_STATE_ACCEPTED:
+		m.finish(call.ID, userID, models.CALL_STATE_ENDED)
+	case userID == call.CalleeID:
+		m.finish(call.ID, userID, models.CALL_STATE_REJECTED)
+	default:
+		m.finish(call.ID, userID, models.CALL_STATE_MISSED)
+	}
+}
+
+/*
+Disconnect
+hangs up the calls of the user owned by this node, used when its connection closes
+*/
+func (m *CallManager) Disconnect(userID string) {
+
+	var calls []*Call
+
+	m.mux.Lock()
+	for _, call := range m.calls {
+		if call.Caller.ID.Hex() == userID || call.CalleeID == userID {
+			calls = append(calls, call)
+		}
+	}
+	m.mux.Unlock()
+
+	for _, call := range calls {
+		m.hangup(call, userID)
+	}
+}
+
+/*
+finish
+closes the call with the final state, notifies both peers and stores the call
+log as a message of the p2p conversation
+*/
+func (m *CallManager) finish(callID, from, state string) {
+
+	m.mux.Lock()
+	call, ok := m.calls[callID]
+	if !ok {
+		m.mux.Unlock()
+		return
+	}
+	delete(m.calls, callID)
+	call.stopRinging()
+	call.State = state
+
+	record := callRecord(call.ID, call.Type, state, call.StartedAt, call.AnsweredAt)
+	signal := call.signal(from)
+	m.mux.Unlock()
+
+	callerID := call.Caller.ID.Hex()
+	m.hub.removePresence(PRESENCE_CALL, callerID)
+	m.hub.removePresence(PRESENCE_CALL, call.CalleeID)
+
+	event := models.WebsocketEvent{Event: models.EVENT_CALL_STATE, Data: signal}
+	m.hub.deliverToUser(callerID, event)
+	online := m.hub.deliverToUser(call.CalleeID, event)
+
+	if state == models.CALL_STATE_MISSED && !online {
+		m.hub.sendPushNotification(call.pushToken, models.WebsocketEvent{Event: models.EVENT_CALL_MISSED, Data: signal})
+	}
+
+	m.persistCallLog(call, record)
+}
+
+// callRecord formats the record of a finished call
+func callRecord(callID, callType, state string, startedAt, answeredAt time.Time) models.CallRecord {
+
+	record := models.CallRecord{
+		CallID:     callID,
+		CallType:   callType,
+		State:      state,
+		StartedAt:  startedAt,
+		AnsweredAt: answeredAt,
+		EndedAt:    time.Now(),
+	}
+	if !answeredAt.IsZero() {
+		record.Duration = int64(record.EndedAt.Sub(answeredAt).Seconds())
+	}
+
+	return record
+}
+
+/*
+persistCallLog
+queues the insertion of the call on the call history and on the conversation,
+the conversation entry is delivered to both peers
+*/
+func (m *CallManager) persistCallLog(call *Call, record models.CallRecord) {
+
+	alog := logger.StartLogger()
+
+	calleeID, err := primitive.ObjectIDFromHex(call.CalleeID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+
+	var history models.CallLog
+	history.FormatP2PCallLog(call.Caller.ID, calleeID, record)
+	m.hub.storeCallLog(history)
+
+	var payload models.P2PCallChatLog
+	payload.FormatCallLog(calleeID, call.Caller.ID, call.Caller.Name, record)
+
+	opts := workerpool.PersistenceJob()
+	opts.OnFailure = func(err error) {
+		alog.ErrorLog(err.Error())
+	}
+
+	err = m.hub.WorkerPool.Submit(func(ctx context.Context) error {
+		return storedOnce(workerpool.Await(ctx, func() error {
+			_, err := m.hub.DBConn.InsertP2PMessageDB(payload)
+			return err
+		}))
+	}, opts)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+
+	// with change streams the log reaches the peers once stored
+	if m.hub.Streams == nil {
+		m.hub.deliverToUser(call.Caller.ID.Hex(), payload)
+		m.hub.deliverToUser(call.CalleeID, payload)
+	}
+}
+
+// refresh keeps the call presence of the users in a call owned by this node
+func (m *CallManager) refresh() {
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, call := range m.calls {
+		m.hub.setPresence(PRESENCE_CALL, call.Caller.ID.Hex())
+		m.hub.setPresence(PRESENCE_CALL, call.CalleeID)
+	}
+
+	for _, call := range m.rooms {
+		m.setActiveGroupCall(call)
+		for id := range call.Participants {
+			m.hub.setPresence(PRESENCE_CALL, id)
+		}
+	}
+}
+
+// Stop drops the calls of this node without notifying the peers
+func (m *CallManager) Stop() {
+
+	var users []string
+	var rooms []*GroupCall
+
+	m.mux.Lock()
+	for id, call := range m.calls {
+		call.stopRinging()
+		users = append(users, call.Caller.ID.Hex(), call.CalleeID)
+		delete(m.calls, id)
+	}
+
+	for id, call := range m.rooms {
+		call.stopRinging()
+		rooms = append(rooms, call)
+		for usr := range call.Participants {
+			users = append(users, usr)
+		}
+		delete(m.rooms, id)
+	}
+	m.mux.Unlock()
+
+	for _, id := range users {
+		m.hub.removePresence(PRESENCE_CALL, id)
+	}
+	for _, call := range rooms {
+		m.removeActiveGroupCall(call)
+	}
+}
+
+/*
+deliverToUser
+writes the payload on the p2p connection of the user wherever node it lives,
+returns false if the user is not connected
+*/
+func (h *WebsocketPanel) deliverToUser(userID string, payload any) bool {
+
+	user, ok := h.P2PConnections.Lookup(userID)
+	if ok {
+		return user.WriteJSON(payload) == nil
+	}
+
+	nodeID, online := h.locateUser(PRESENCE_P2P, userID)
+	if !online || nodeID == h.NodeID {
+		return false
+	}
+
+	err := h.forwardToNode(nodeID, ENVELOPE_P2P, "", []string{userID}, payload)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// dbMock records the messages inserted by the hub and the last posts of the slow mode, every other method is not used
+type dbMock struct {
+	database.DBHUB
+	inserted chan any
+	calls    chan models.CallLog
+	timer    int
+	postsMux sync.Mutex
+	posts    map[string]time.Time
+}
+
+func newDBMock() *dbMock {
+	return &dbMock{inserted: make(chan any, 1), calls: make(chan models.CallLog, 1), posts: map[string]time.Time{}}
+}
+
+func (m *dbMock) ClaimGroupPostDB(id, user primitive.ObjectID, now time.Time, wait time.Duration) (time.Time, error) {
+	m.postsMux.Lock()
+	defer m.postsMux.Unlock()
+
+	key := id.Hex() + user.Hex()
+	if last, ok := m.posts[key]; ok && last.After(now.Add(-wait)) {
+		return last, nil
+	}
+	m.posts[key] = now
+	return time.Time{}, nil
+}
+
+func (m *dbMock) InsertP2PMessageDB(payload any) (string, error) {
+	m.inserted <- payload
+	return "", nil
+}
+
+func (m *dbMock) InsertGroupMessageDB(payload any) (string, error) {
+	m.inserted <- payload
+	return "", nil
+}
+
+func (m *dbMock) GetP2PMessageTimerDB(conversationID string) (int, error) {
+	return m.timer, nil
+}
+
+func (m *dbMock) InsertCallLogDB(log models.CallLog) (string, error) {
+	m.calls <- log
+	return log.ID.Hex(), nil
+}
+
+func readCallLog(t *testing.T, db *dbMock) models.CallLog {
+	select {
+	case log := <-db.calls:
+		return log
+	case <-time.After(2 * time.Second):
+		t.Fatal("call log not stored")
+	}
+	return models.CallLog{}
+}
+
+type callEvent struct {
+	Event string                    `json:"event"`
+	Data  models.OutboundCallSignal `json:"data"`
+}
+
+// connectCallPeer registers a p2p connection of the user and starts listening to it
+func connectCallPeer(t *testing.T, user, target primitive.ObjectID) *websocket.Conn {
+
+	serverSide, client := newMockConnection(t)
+	c := &P2PConnectionCredentials{
+		Conn:       serverSide,
+		AuthorID:   user.Hex(),
+		TargetID:   target.Hex(),
+		AuthorData: &models.User{ID: user, Name: "user-" + user.Hex()[20:]},
+	}
+	WebsocketHUB.RegisterP2PConnection(c)
+	go ListenForP2PActivity(c)
+
+	return client
+}
+
+func readCallEvent(t *testing.T, conn *websocket.Conn) callEvent {
+	var res callEvent
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	err := conn.ReadJSON(&res)
+	assert.Nil(t, err)
+	return res
+}
+
+// TestCallSignaling test the call signaling between two peers
+func TestCallSignaling(t *testing.T) {
+
+	t.Run("CallSignaling - Offer answer candidates and hangup", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newDBMock()
+		WebsocketHUB.DBConn = db
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		caller := connectCallPeer(t, alice, bob)
+		callee := connectCallPeer(t, bob, alice)
+
+		caller.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_OFFER, TargetID: bob.Hex(), CallType: models.CALL_TYPE_VIDEO, SDP: "offer-sdp"})
+
+		ringing := readCallEvent(t, caller)
+		assert.Equal(t, models.EVENT_CALL_STATE, ringing.Event)
+		assert.Equal(t, models.CALL_STATE_RINGING, ringing.Data.State)
+
+		offer := readCallEvent(t, callee)
+		assert.Equal(t, models.EVENT_CALL_OFFER, offer.Event)
+		assert.Equal(t, "offer-sdp", offer.Data.SDP)
+		assert.Equal(t, alice.Hex(), offer.Data.FromID)
+		assert.Equal(t, ringing.Data.CallID, offer.Data.CallID)
+
+		callID := offer.Data.CallID
+
+		callee.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_ANSWER, CallID: callID, SDP: "answer-sdp"})
+
+		answer := readCallEvent(t, caller)
+		assert.Equal(t, models.EVENT_CALL_ANSWER, answer.Event)
+		assert.Equal(t, "answer-sdp", answer.Data.SDP)
+		assert.Equal(t, models.CALL_STATE_ACCEPTED, answer.Data.State)
+		assert.Equal(t, models.CALL_STATE_ACCEPTED, readCallEvent(t, callee).Data.State)
+
+		caller.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_CANDIDATE, CallID: callID, Candidate: &models.ICECandidate{Candidate: "candidate:1", SDPMid: "0"}})
+
+		candidate := readCallEvent(t, callee)
+		assert.Equal(t, models.EVENT_CALL_CANDIDATE, candidate.Event)
+		assert.Equal(t, "candidate:1", candidate.Data.Candidate.Candidate)
+
+		callee.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_HANGUP, CallID: callID})
+
+		assert.Equal(t, models.CALL_STATE_ENDED, readCallEvent(t, caller).Data.State)
+		assert.Equal(t, models.CALL_STATE_ENDED, readCallEvent(t, callee).Data.State)
+
+		select {
+		case payload := <-db.inserted:
+			log, ok := payload.(models.P2PCallChatLog)
+			assert.True(t, ok)
+			assert.Equal(t, models.MESSAGE_TYPE_CALL, log.BodyType)
+			assert.Equal(t, models.CALL_STATE_ENDED, log.Call.State)
+			assert.Equal(t, alice, log.AuthorID)
+			assert.Equal(t, bob, log.TargetID)
+		case <-time.After(2 * time.Second):
+			t.Fatal("call log not persisted")
+		}
+
+		history := readCallLog(t, db)
+		assert.Equal(t, callID, history.CallID)
+		assert.Equal(t, models.CALL_STATE_ENDED, history.State)
+		assert.Equal(t, []primitive.ObjectID{alice, bob}, history.Participants)
+		assert.Equal(t, []primitive.ObjectID{alice, bob}, history.Joined)
+	})
+
+	t.Run("CallSignaling - Busy callee", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		carol := primitive.NewObjectID()
+		caller := connectCallPeer(t, alice, bob)
+		callee := connectCallPeer(t, bob, alice)
+		other := connectCallPeer(t, carol, bob)
+
+		caller.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_OFFER, TargetID: bob.Hex(), CallType: models.CALL_TYPE_AUDIO})
+		readCallEvent(t, caller)
+		readCallEvent(t, callee)
+
+		other.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_OFFER, TargetID: bob.Hex(), CallType: models.CALL_TYPE_AUDIO})
+
+		var res models.WebsocketResponseMessage
+		other.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := other.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, CALL_BUSY, res.Code)
+	})
+
+	t.Run("CallSignaling - Concurrent offers book the callee once", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		bob := primitive.NewObjectID()
+		callers := make([]*models.User, 8)
+		for i := range callers {
+			callers[i] = &models.User{ID: primitive.NewObjectID()}
+		}
+
+		errs := make(chan error, len(callers))
+		for _, caller := range callers {
+			go func(caller *models.User) {
+				errs <- WebsocketHUB.Calls.Offer(caller, "", models.InboundCallSignal{TargetID: bob.Hex(), CallType: models.CALL_TYPE_AUDIO})
+			}(caller)
+		}
+
+		accepted := 0
+		for range callers {
+			err := <-errs
+			if err == nil {
+				accepted++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrCallBusy)
+		}
+		assert.Equal(t, 1, accepted)
+
+		// the callers refused do not stay busy
+		busy := 0
+		for _, caller := range callers {
+			if _, ok := WebsocketHUB.locateUser(PRESENCE_CALL, caller.ID.Hex()); ok {
+				busy++
+			}
+		}
+		assert.Equal(t, 1, busy)
+	})
+
+	t.Run("CallSignaling - Ring timeout marks the call as missed", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newDBMock()
+		WebsocketHUB.DBConn = db
+		WebsocketHUB.Calls.RingTimeout = 50 * time.Millisecond
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		caller := connectCallPeer(t, alice, bob)
+
+		caller.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_OFFER, TargetID: bob.Hex(), CallType: models.CALL_TYPE_AUDIO})
+		readCallEvent(t, caller)
+
+		assert.Equal(t, models.CALL_STATE_MISSED, readCallEvent(t, caller).Data.State)
+
+		payload := <-db.inserted
+		assert.Equal(t, models.CALL_STATE_MISSED, payload.(models.P2PCallChatLog).Call.State)
+
+		history := readCallLog(t, db)
+		assert.Equal(t, models.CALL_STATE_MISSED, history.State)
+		assert.Equal(t, bob, history.CalleeID)
+		assert.Equal(t, []primitive.ObjectID{alice}, history.Joined)
+		assert.Zero(t, history.Duration)
+
+		_, busy := WebsocketHUB.locateUser(PRESENCE_CALL, bob.Hex())
+		assert.False(t, busy)
+	})
+
+	t.Run("CallSignaling - Unknown call", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		alice := primitive.NewObjectID()
+		caller := connectCallPeer(t, alice, primitive.NewObjectID())
+
+		caller.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_HANGUP, CallID: WebsocketHUB.NodeID + ".missing"})
+
+		var res models.WebsocketResponseMessage
+		caller.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := caller.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, CALL_NOT_FOUND, res.Code)
+	})
+}
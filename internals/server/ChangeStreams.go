@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// DELIVERY_DIRECT messages are written to the sockets by the node that receives them
+	DELIVERY_DIRECT = "direct"
+
+	// DELIVERY_CHANGE_STREAMS messages are written to the sockets by every node tailing the chat logs
+	DELIVERY_CHANGE_STREAMS = "changestream"
+)
+
+// ChatlogChange represents a change event of a chat log collection
+type ChatlogChange struct {
+	OperationType string   `bson:"operationType"`
+	FullDocument  bson.Raw `bson:"fullDocument"`
+}
+
+/*
+ChangeStreamListener
+tails the chat logs collections and pushes the changes to the sockets connected to this node
+*/
+type ChangeStreamListener struct {
+	db     database.StreamHUB
+	hub    *WebsocketPanel
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+/*
+StartChangeStreamListener
+starts tailing the user and group chat logs, the resume tokens are saved per node so
+NODE_ID must be stable between restarts to continue where the node left off
+*/
+func StartChangeStreamListener(db database.StreamHUB, hub *WebsocketPanel) *ChangeStreamListener {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := &ChangeStreamListener{
+		db:     db,
+		hub:    hub,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	l.wg.Add(2)
+	go l.tail(database.STREAM_USER_CHATLOGS, hub.deliverP2PChange)
+	go l.tail(database.STREAM_GROUP_CHATLOGS, hub.deliverGroupChange)
+
+	return l
+}
+
+// Stop stops tailing the streams and waits for the tails to finish
+func (l *ChangeStreamListener) Stop() {
+	l.cancel()
+	l.wg.Wait()
+}
+
+/*
+tail
+watches the stream and reopens it from the last resume token when it fails, when
+the token fell off the oplog the stream is reopened from now and the changes in
+between are not delivered
+*/
+func (l *ChangeStreamListener) tail(stream string, deliver func(ChatlogChange) error) {
+
+	defer l.wg.Done()
+
+	alog := logger.StartLogger()
+	name := fmt.Sprintf("%s:%s", l.hub.NodeID, stream)
+
+	for l.ctx.Err() == nil {
+
+		err := l.watch(name, stream, deliver)
+		if database.IsHistoryLost(err) {
+			alog.ErrorLog(fmt.Sprintf("change stream %s lost its history, the changes since its last resume token are not delivered: %v", name, err))
+
+			err = l.db.SaveResumeTokenDB(name, nil)
+			if err == nil {
+				continue
+			}
+		}
+
+		if err != nil && l.ctx.Err() == nil {
+			alog.ErrorLog(fmt.Sprintf("change stream %s stopped: %v", name, err))
+		}
+
+		select {
+		case <-l.ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (l *ChangeStreamListener) watch(name, stream string, deliver func(ChatlogChange) error) error {
+
+	alog := logger.StartLogger()
+
+	token, err := l.db.GetResumeTokenDB(name)
+	if err != nil {
+		return err
+	}
+
+	cs, err := l.db.WatchChatlogsDB(l.ctx, stream, token)
+	if err != nil {
+		return err
+	}
+	defer cs.Close(context.Background())
+
+	for cs.Next(l.ctx) {
+
+		var change ChatlogChange
+
+		err := cs.Decode(&change)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+		} else if err := deliver(change); err != nil {
+			alog.ErrorLog(err.Error())
+		}
+
+		err = l.db.SaveResumeTokenDB(name, cs.ResumeToken())
+		if err != nil {
+			alog.ErrorLog(err.Error())
+		}
+	}
+
+	return cs.Err()
+}
+
+// formatChangePayload formats the payload sent to the sockets for the change
+func formatChangePayload(change ChatlogChange, msg any) any {
+	if change.OperationType == "insert" {
+		return msg
+	}
+	return models.WebsocketEvent{Event: models.EVENT_MESSAGE_UPDATED, Data: msg}
+}
+
+// chatlogHeader fields every chat log is routed with
+type chatlogHeader struct {
+	AuthorID primitive.ObjectID `bson:"author_id"`
+	TargetID primitive.ObjectID `bson:"target_id"`
+	BodyType int                `bson:"body_type"`
+}
+
+/*
+decodeChange
+decodes the stored message of the change into the chat log of its body type,
+the calls and the system messages carry fields the content messages do not
+*/
+func decodeChange(doc bson.Raw, group bool) (chatlogHeader, any, error) {
+
+	var head chatlogHeader
+
+	err := bson.Unmarshal(doc, &head)
+	if err != nil {
+		return head, nil, err
+	}
+
+	var msg any
+	switch {
+	case head.BodyType == models.MESSAGE_TYPE_SYSTEM && group:
+		msg = &models.GroupSystemLog{}
+	case head.BodyType == models.MESSAGE_TYPE_SYSTEM:
+		msg = &models.P2PSystemLog{}
+	case group:
+		msg = &models.GroupChatContentLog{}
+	case head.BodyType == models.MESSAGE_TYPE_CALL:
+		msg = &models.P2PCallChatLog{}
+	default:
+		msg = &models.P2PContentChatLog{}
+	}
+
+	err = bson.Unmarshal(doc, msg)
+
+	return head, msg, err
+}
+
+// deliverP2PChange writes the p2p message to the author and the target if connected to this node
+func (h *WebsocketPanel) deliverP2PChange(change ChatlogChange) error {
+
+	head, msg, err := decodeChange(change.FullDocument, false)
+	if err != nil {
+		return err
+	}
+
+	payload := formatChangePayload(change, msg)
+	author := head.AuthorID.Hex()
+	target := head.TargetID.Hex()
+
+	if conn, ok := h.P2PConnections.Lookup(author); ok && conn.TargetID == target {
+		conn.WriteJSON(payload)
+	}
+
+	if conn, ok := h.P2PConnections.Lookup(target); ok && conn.TargetID == author {
+		conn.WriteJSON(payload)
+	}
+
+	return nil
+}
+
+// deliverGroupChange writes the group message to the participants connected to this node
+func (h *WebsocketPanel) deliverGroupChange(change ChatlogChange) error {
+
+	head, msg, err := decodeChange(change.FullDocument, true)
+	if err != nil {
+		return err
+	}
+
+	payload := formatChangePayload(change, msg)
+	group := head.TargetID.Hex()
+
+	h.GroupConnections.Range(func(key string, conn *GroupConnectionCredentials) bool {
+		if conn.TargetID == group {
+			conn.WriteJSON(payload)
+		}
+		return true
+	})
+
+	return nil
+}
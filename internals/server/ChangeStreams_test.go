@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// streamMock replays the given changes and blocks until the context is done
+type streamMock struct {
+	changes []bson.Raw
+	tokens  []bson.Raw
+	current int
+}
+
+func (s *streamMock) Next(ctx context.Context) bool {
+	if s.current < len(s.changes) {
+		s.current++
+		return true
+	}
+	<-ctx.Done()
+	return false
+}
+
+func (s *streamMock) Decode(v any) error {
+	return bson.Unmarshal(s.changes[s.current-1], v)
+}
+
+func (s *streamMock) ResumeToken() bson.Raw {
+	return s.tokens[s.current-1]
+}
+
+func (s *streamMock) Err() error {
+	return nil
+}
+
+func (s *streamMock) Close(context.Context) error {
+	return nil
+}
+
+type streamHUBMock struct {
+	mux      sync.Mutex
+	streams  map[string]*streamMock
+	resumed  map[string]bson.Raw
+	saved    map[string]bson.Raw
+	savedAll chan struct{}
+	lost     bson.Raw
+}
+
+func (m *streamHUBMock) WatchChatlogsDB(ctx context.Context, stream string, token bson.Raw) (database.ChatlogStream, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.resumed[stream] = token
+	if len(m.lost) > 0 && bytes.Equal(token, m.lost) {
+		return nil, mongo.CommandError{Code: database.CHANGE_STREAM_HISTORY_LOST, Name: "ChangeStreamHistoryLost"}
+	}
+	return m.streams[stream], nil
+}
+
+func (m *streamHUBMock) GetResumeTokenDB(name string) (bson.Raw, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.saved[name], nil
+}
+
+func (m *streamHUBMock) SaveResumeTokenDB(name string, token bson.Raw) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.saved[name] = token
+	m.savedAll <- struct{}{}
+	return nil
+}
+
+func mustMarshal(t *testing.T, v any) bson.Raw {
+	data, err := bson.Marshal(v)
+	assert.Nil(t, err)
+	return data
+}
+
+// TestChangeStreamListener test the delivery of the chat logs change streams
+func TestChangeStreamListener(t *testing.T) {
+
+	t.Run("ChangeStreamListener - Insert delivered and token saved", func(t *testing.T) {
+
+		t.Setenv("NODE_ID", "node-a")
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		author := primitive.NewObjectID()
+		target := primitive.NewObjectID()
+
+		serverSide, client := newMockConnection(t)
+		WebsocketHUB.RegisterP2PConnection(&P2PConnectionCredentials{
+			Conn:     serverSide,
+			AuthorID: target.Hex(),
+			TargetID: author.Hex(),
+		})
+
+		var msg models.P2PTextChatLog
+		msg.FormatTextLog(target, author, "George", "Hey from an import")
+
+		previous := mustMarshal(t, bson.M{"_data": "01"})
+		token := mustMarshal(t, bson.M{"_data": "02"})
+
+		db := &streamHUBMock{
+			streams: map[string]*streamMock{
+				database.STREAM_USER_CHATLOGS: {
+					changes: []bson.Raw{mustMarshal(t, bson.M{"operationType": "insert", "fullDocument": msg})},
+					tokens:  []bson.Raw{token},
+				},
+				database.STREAM_GROUP_CHATLOGS: {},
+			},
+			resumed:  make(map[string]bson.Raw),
+			saved:    map[string]bson.Raw{"node-a:" + database.STREAM_USER_CHATLOGS: previous},
+			savedAll: make(chan struct{}, 1),
+		}
+
+		listener := StartChangeStreamListener(db, WebsocketHUB)
+		defer listener.Stop()
+
+		var res models.P2PTextChatLog
+		client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := client.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, msg.Body, res.Body)
+
+		<-db.savedAll
+
+		db.mux.Lock()
+		defer db.mux.Unlock()
+		assert.Equal(t, previous, db.resumed[database.STREAM_USER_CHATLOGS])
+		assert.Equal(t, token, db.saved["node-a:"+database.STREAM_USER_CHATLOGS])
+	})
+
+	t.Run("ChangeStreamListener - Update delivered as event", func(t *testing.T) {
+
+		t.Setenv("NODE_ID", "node-a")
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		author := primitive.NewObjectID()
+		group := primitive.NewObjectID()
+
+		serverSide, client := newMockConnection(t)
+		WebsocketHUB.RegisterGroupConnection(&GroupConnectionCredentials{
+			Conn:     serverSide,
+			AuthorID: author.Hex(),
+			TargetID: group.Hex(),
+		})
+
+		var msg models.GroupChatTextLog
+		msg.FormatTextChatLog(group, author, "George", "Edited by an admin tool")
+
+		db := &streamHUBMock{
+			streams: map[string]*streamMock{
+				database.STREAM_USER_CHATLOGS: {},
+				database.STREAM_GROUP_CHATLOGS: {
+					changes: []bson.Raw{mustMarshal(t, bson.M{"operationType": "update", "fullDocument": msg})},
+					tokens:  []bson.Raw{mustMarshal(t, bson.M{"_data": "03"})},
+				},
+			},
+			resumed:  make(map[string]bson.Raw),
+			saved:    make(map[string]bson.Raw),
+			savedAll: make(chan struct{}, 1),
+		}
+
+		listener := StartChangeStreamListener(db, WebsocketHUB)
+		defer listener.Stop()
+
+		var res struct {
+			Event string                  `json:"event"`
+			Data  models.GroupChatTextLog `json:"data"`
+		}
+		client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := client.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, models.EVENT_MESSAGE_UPDATED, res.Event)
+		assert.Equal(t, msg.Body, res.Data.Body)
+	})
+	t.Run("ChangeStreamListener - Call and system logs delivered whole", func(t *testing.T) {
+
+		t.Setenv("NODE_ID", "node-a")
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		author := primitive.NewObjectID()
+		target := primitive.NewObjectID()
+		group := primitive.NewObjectID()
+
+		p2pSide, p2pClient := newMockConnection(t)
+		WebsocketHUB.RegisterP2PConnection(&P2PConnectionCredentials{
+			Conn:     p2pSide,
+			AuthorID: target.Hex(),
+			TargetID: author.Hex(),
+		})
+
+		groupSide, groupClient := newMockConnection(t)
+		WebsocketHUB.RegisterGroupConnection(&GroupConnectionCredentials{
+			Conn:     groupSide,
+			AuthorID: target.Hex(),
+			TargetID: group.Hex(),
+		})
+
+		var call models.P2PCallChatLog
+		call.FormatCallLog(target, author, "George", models.CallRecord{CallID: "call-1", CallType: "video", State: "missed"})
+
+		var system models.GroupSystemLog
+		system.FormatSystemLog(group, author, author, "George", models.SYSTEM_EVENT_MEMBER_LEFT, "George left the group")
+
+		db := &streamHUBMock{
+			streams: map[string]*streamMock{
+				database.STREAM_USER_CHATLOGS: {
+					changes: []bson.Raw{mustMarshal(t, bson.M{"operationType": "insert", "fullDocument": call})},
+					tokens:  []bson.Raw{mustMarshal(t, bson.M{"_data": "05"})},
+				},
+				database.STREAM_GROUP_CHATLOGS: {
+					changes: []bson.Raw{mustMarshal(t, bson.M{"operationType": "insert", "fullDocument": system})},
+					tokens:  []bson.Raw{mustMarshal(t, bson.M{"_data": "06"})},
+				},
+			},
+			resumed:  make(map[string]bson.Raw),
+			saved:    make(map[string]bson.Raw),
+			savedAll: make(chan struct{}, 2),
+		}
+
+		listener := StartChangeStreamListener(db, WebsocketHUB)
+		defer listener.Stop()
+
+		var callRes models.P2PCallChatLog
+		p2pClient.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, p2pClient.ReadJSON(&callRes))
+		assert.Equal(t, models.MESSAGE_TYPE_CALL, callRes.BodyType)
+		assert.Equal(t, "call-1", callRes.Call.CallID)
+		assert.Equal(t, "missed", callRes.Call.State)
+
+		var systemRes models.GroupSystemLog
+		groupClient.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, groupClient.ReadJSON(&systemRes))
+		assert.Equal(t, models.SYSTEM_EVENT_MEMBER_LEFT, systemRes.Event)
+		assert.Equal(t, author, systemRes.SubjectID)
+	})
+
+	t.Run("ChangeStreamListener - History lost reopens the stream from now", func(t *testing.T) {
+
+		t.Setenv("NODE_ID", "node-a")
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		author := primitive.NewObjectID()
+		target := primitive.NewObjectID()
+
+		serverSide, client := newMockConnection(t)
+		WebsocketHUB.RegisterP2PConnection(&P2PConnectionCredentials{
+			Conn:     serverSide,
+			AuthorID: target.Hex(),
+			TargetID: author.Hex(),
+		})
+
+		var msg models.P2PTextChatLog
+		msg.FormatTextLog(target, author, "George", "Sent after the gap")
+
+		stale := mustMarshal(t, bson.M{"_data": "00"})
+		token := mustMarshal(t, bson.M{"_data": "04"})
+
+		db := &streamHUBMock{
+			streams: map[string]*streamMock{
+				database.STREAM_USER_CHATLOGS: {
+					changes: []bson.Raw{mustMarshal(t, bson.M{"operationType": "insert", "fullDocument": msg})},
+					tokens:  []bson.Raw{token},
+				},
+				database.STREAM_GROUP_CHATLOGS: {},
+			},
+			resumed:  make(map[string]bson.Raw),
+			saved:    map[string]bson.Raw{"node-a:" + database.STREAM_USER_CHATLOGS: stale},
+			savedAll: make(chan struct{}, 2),
+			lost:     stale,
+		}
+
+		listener := StartChangeStreamListener(db, WebsocketHUB)
+		defer listener.Stop()
+
+		var res models.P2PTextChatLog
+		client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := client.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, msg.Body, res.Body)
+
+		<-db.savedAll
+		<-db.savedAll
+
+		db.mux.Lock()
+		defer db.mux.Unlock()
+		assert.Nil(t, db.resumed[database.STREAM_USER_CHATLOGS])
+		assert.Equal(t, token, db.saved["node-a:"+database.STREAM_USER_CHATLOGS])
+	})
+}
@@ -121,6 +121,14 @@ services.
 */
 const SERVICES_ERROR = 511
 
+/*
+SERVER_BUSY
+
+means that the server could not take the operation because its queues are full.
+The client can retry the operation later.
+*/
+const SERVER_BUSY = 503
+
 /*
 FOREIGN_ACCESS
 
@@ -136,3 +144,69 @@ const FOREIGN_ACCESS = 525
 */
 
 const PROVIDER_ERROR = 658
+
+/*
+CALL_BUSY
+means that the user called is already in another call
+*/
+const CALL_BUSY = 670
+
+/*
+CALL_NOT_FOUND
+means that the call does not exist or already finished
+*/
+const CALL_NOT_FOUND = 671
+
+/*
+CALL_FULL
+means that the group call reached the participants limit
+*/
+const CALL_FULL = 672
+
+/*
+ANNOUNCEMENT_ONLY
+means that the message was refused because only the admins can post on the group
+*/
+const ANNOUNCEMENT_ONLY = 680
+
+/*
+MEMBER_MUTED
+means that the message was refused because an admin muted the author
+*/
+const MEMBER_MUTED = 681
+
+/*
+SLOW_MODE
+means that the message was refused because the author has to wait before posting again
+*/
+const SLOW_MODE = 682
+
+/*
+TOPIC_NOT_FOUND
+means that the message was refused because the topic does not exist on the group
+*/
+const TOPIC_NOT_FOUND = 683
+
+/*
+TOPIC_ARCHIVED
+means that the message was refused because the topic no longer takes messages
+*/
+const TOPIC_ARCHIVED = 684
+
+/*
+REPLY_NOT_FOUND
+means that the message was refused because the message it replies to does not exist on the conversation
+*/
+const REPLY_NOT_FOUND = 685
+
+/*
+MENTION_NOT_ALLOWED
+means that the message was refused because it mentions a user that is not a participant or mentions everyone without being an admin
+*/
+const MENTION_NOT_ALLOWED = 686
+
+/*
+CLOSE_GROUP_DELETED
+websocket close code sent to the group connections when their group is deleted
+*/
+const CLOSE_GROUP_DELETED = 4404
@@ -0,0 +1,565 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+	"wechat-back/internals/backplane"
+	"wechat-back/internals/generators"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// DEFAULT_GROUP_CALL_LIMIT participants a group call holds, calls are a mesh so every peer sends to every other
+	DEFAULT_GROUP_CALL_LIMIT = 8
+
+	// PRESENCE_GROUP_CALL presence kind that holds the active call of a group
+	PRESENCE_GROUP_CALL = "groupcall"
+)
+
+// ErrCallFull the group call reached the participants limit
+var ErrCallFull = errors.New("call is full")
+
+// GroupCall state of a group call owned by this node
+type GroupCall struct {
+	ID         string
+	GroupID    string
+	Type       string
+	State      string
+	StarterID  string
+	StartedAt  time.Time
+	AnsweredAt time.Time
+
+	// Members participants of the group, the only users allowed to join
+	Members []string
+
+	// Participants users connected to the call
+	Participants map[string]*models.CallParticipant
+
+	// Joined users that joined the call at some point
+	Joined []string
+
+	// pushTokens tokens used to notify the members that missed the call
+	pushTokens map[string]string
+
+	// ringing fires when nobody joins the call in time
+	ringing *time.Timer
+}
+
+// stopRinging stops the ring timeout of the call
+func (c *GroupCall) stopRinging() {
+	if c.ringing != nil {
+		c.ringing.Stop()
+	}
+}
+
+// roster returns the participants of the call
+func (c *GroupCall) roster() []models.CallParticipant {
+
+	res := make([]models.CallParticipant, 0, len(c.Participants))
+	for _, p := range c.Participants {
+		res = append(res, *p)
+	}
+
+	slices.SortFunc(res, func(a, b models.CallParticipant) int {
+		return a.JoinedAt.Compare(b.JoinedAt)
+	})
+
+	return res
+}
+
+// signal formats the outbound signal of the call
+func (c *GroupCall) signal(from string) models.OutboundCallSignal {
+	res := models.OutboundCallSignal{
+		CallID:   c.ID,
+		GroupID:  c.GroupID,
+		FromID:   from,
+		CallType: c.Type,
+		State:    c.State,
+	}
+	if p, ok := c.Participants[from]; ok {
+		res.FromName = p.Name
+		res.Muted = p.Muted
+	}
+	return res
+}
+
+// recipients returns the participants except the given user
+func (c *GroupCall) recipients(except string) []string {
+	res := make([]string, 0, len(c.Participants))
+	for id := range c.Participants {
+		if id != except {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+/*
+HandleGroupCallSignal
+routes a call signal sent by the user of the group connection
+*/
+func (g *GroupConnectionCredentials) HandleGroupCallSignal(data []byte) {
+
+	alog := logger.StartLogger()
+
+	var signal models.InboundCallSignal
+	err := json.Unmarshal(data, &signal)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		g.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_REQUEST))
+		return
+	}
+
+	signal.GroupID = g.TargetID
+	signal.Name = g.AuthorData.Name
+
+	if signal.CallID == "" {
+		signal.CallID, _ = g.hub.Calls.activeGroupCall(g.TargetID)
+	}
+
+	if signal.Event == models.EVENT_CALL_START {
+		if signal.CallID == "" {
+			err = g.hub.Calls.StartGroupCall(g.AuthorData.ID.Hex(), g.Group(), signal)
+		} else {
+			// the group already has a call, the user joins it
+			signal.Event = models.EVENT_CALL_JOIN
+			err = g.hub.Calls.HandleSignal(g.AuthorID, signal)
+		}
+	} else {
+		err = g.hub.Calls.HandleSignal(g.AuthorID, signal)
+	}
+
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		g.WriteJSON(models.FormatWebsocketErrResponse(err, callErrorCode(err)))
+	}
+}
+
+// activeGroupCall returns the id of the active call of the group
+func (m *CallManager) activeGroupCall(groupID string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	callID, err := m.hub.Backplane.GetPresence(ctx, backplane.PresenceKey(PRESENCE_GROUP_CALL, groupID))
+	if err != nil {
+		if err != backplane.ErrNoPresence {
+			logger.StartLogger().ErrorLog(err.Error())
+		}
+		return "", false
+	}
+
+	return callID, true
+}
+
+// setActiveGroupCall registers the call as the active call of the group
+func (m *CallManager) setActiveGroupCall(call *GroupCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.hub.Backplane.SetPresence(ctx, backplane.PresenceKey(PRESENCE_GROUP_CALL, call.GroupID), call.ID, backplane.DEFAULT_PRESENCE_TTL)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+	}
+}
+
+// removeActiveGroupCall removes the call as the active call of the group
+func (m *CallManager) removeActiveGroupCall(call *GroupCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.hub.Backplane.RemovePresence(ctx, backplane.PresenceKey(PRESENCE_GROUP_CALL, call.GroupID), call.ID)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+	}
+}
+
+/*
+StartGroupCall
+opens a call on the group with the starter as first participant and rings the
+rest of the participants, the call is missed if nobody joins before the ring timeout
+*/
+func (m *CallManager) StartGroupCall(starterID string, group *models.Group, signal models.InboundCallSignal) error {
+
+	if signal.CallType != models.CALL_TYPE_AUDIO && signal.CallType != models.CALL_TYPE_VIDEO {
+		return errors.New("invalid call type")
+	}
+
+	members := make([]string, 0, len(group.Participants))
+	for _, usr := range group.Participants {
+		members = append(members, usr.Hex())
+	}
+	if !slices.Contains(members, starterID) {
+		return ErrCallNotAllowed
+	}
+
+	code, err := generators.GenerateAlphaNumericCode(16)
+	if err != nil {
+		return err
+	}
+
+	call := &GroupCall{
+		ID:        m.hub.NodeID + "." + code,
+		GroupID:   group.ID.Hex(),
+		Type:      signal.CallType,
+		State:     models.CALL_STATE_RINGING,
+		StarterID: starterID,
+		StartedAt: time.Now(),
+		Members:   members,
+		Participants: map[string]*models.CallParticipant{
+			starterID: {UserID: starterID, Name: signal.Name, JoinedAt: time.Now()},
+		},
+		Joined:     []string{starterID},
+		pushTokens: signal.PushTokens,
+	}
+
+	claimed, err := m.hub.claimPresence(PRESENCE_CALL, starterID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrCallBusy
+	}
+
+	// announced before it is registered so a quick finish never leaves it behind
+	m.setActiveGroupCall(call)
+
+	m.mux.Lock()
+	m.rooms[call.ID] = call
+
+	state := call.signal(starterID)
+	state.Participants = call.roster()
+	offer := call.signal(starterID)
+	m.mux.Unlock()
+
+	m.hub.deliverToGroupMember(call.GroupID, starterID, models.WebsocketEvent{Event: models.EVENT_CALL_STATE, Data: state})
+
+	event := models.WebsocketEvent{Event: models.EVENT_CALL_OFFER, Data: offer}
+	for _, usr := range members {
+		if usr == starterID {
+			continue
+		}
+		if !m.hub.deliverToGroupMember(call.GroupID, usr, event) {
+			if token, ok := signal.PushTokens[usr]; ok {
+				m.hub.sendPushNotification(token, event)
+			}
+		}
+	}
+
+	m.mux.Lock()
+	if _, ok := m.rooms[call.ID]; ok && call.State == models.CALL_STATE_RINGING {
+		call.ringing = time.AfterFunc(m.RingTimeout, func() {
+			m.finishGroupCall(call.ID, models.CALL_STATE_MISSED)
+		})
+	}
+	m.mux.Unlock()
+
+	return nil
+}
+
+/*
+handleGroupSignal
+applies the signal of the user to the group call, offers answers and candidates
+are relayed to the target peer so every pair of participants negotiates its own connection
+*/
+func (m *CallManager) handleGroupSignal(userID string, signal models.InboundCallSignal) error {
+
+	m.mux.Lock()
+	call, ok := m.rooms[signal.CallID]
+	if !ok {
+		m.mux.Unlock()
+		return ErrCallNotFound
+	}
+
+	if !slices.Contains(call.Members, userID) {
+		m.mux.Unlock()
+		return ErrCallNotAllowed
+	}
+
+	_, joined := call.Participants[userID]
+
+	switch signal.Event {
+
+	case models.EVENT_CALL_JOIN:
+		if joined {
+			m.mux.Unlock()
+			return nil
+		}
+		if len(call.Participants) >= m.GroupCallLimit {
+			m.mux.Unlock()
+			return ErrCallFull
+		}
+		m.mux.Unlock()
+
+		return m.joinGroupCall(call, userID, signal.Name)
+
+	case models.EVENT_CALL_OFFER, models.EVENT_CALL_ANSWER, models.EVENT_CALL_CANDIDATE:
+		_, target := call.Participants[signal.TargetID]
+		if !joined || !target || signal.TargetID == userID {
+			m.mux.Unlock()
+			return ErrCallNotAllowed
+		}
+
+		relay := call.signal(userID)
+		relay.SDP = signal.SDP
+		relay.Candidate = signal.Candidate
+		m.mux.Unlock()
+
+		m.hub.deliverToGroupMember(call.GroupID, signal.TargetID, models.WebsocketEvent{Event: signal.Event, Data: relay})
+
+	case models.EVENT_CALL_MUTE:
+		if !joined {
+			m.mux.Unlock()
+			return ErrCallNotAllowed
+		}
+		call.Participants[userID].Muted = signal.Muted
+
+		state := call.signal(userID)
+		recipients := call.recipients(userID)
+		m.mux.Unlock()
+
+		m.broadcastGroupCall(call.GroupID, recipients, models.WebsocketEvent{Event: models.EVENT_CALL_MUTE, Data: state})
+
+	case models.EVENT_CALL_LEAVE, models.EVENT_CALL_HANGUP:
+		if !joined {
+			m.mux.Unlock()
+			return ErrCallNotAllowed
+		}
+		m.mux.Unlock()
+
+		m.leaveGroupCall(call.ID, userID)
+
+	case models.EVENT_CALL_REJECT:
+		// declining the ring of a group call does not change the call
+		m.mux.Unlock()
+
+	default:
+		m.mux.Unlock()
+		return errors.New("unknown call event")
+	}
+
+	return nil
+}
+
+/*
+joinGroupCall
+adds the user to the call once its call presence is claimed, the room is checked
+again as it may change while the backplane is reached
+*/
+func (m *CallManager) joinGroupCall(call *GroupCall, userID, name string) error {
+
+	claimed, err := m.hub.claimPresence(PRESENCE_CALL, userID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrCallBusy
+	}
+
+	m.mux.Lock()
+	_, open := m.rooms[call.ID]
+	if !open || len(call.Participants) >= m.GroupCallLimit {
+		m.mux.Unlock()
+		m.hub.removePresence(PRESENCE_CALL, userID)
+		if !open {
+			return ErrCallNotFound
+		}
+		return ErrCallFull
+	}
+
+	call.Participants[userID] = &models.CallParticipant{UserID: userID, Name: name, JoinedAt: time.Now()}
+	if !slices.Contains(call.Joined, userID) {
+		call.Joined = append(call.Joined, userID)
+	}
+	if call.State == models.CALL_STATE_RINGING {
+		call.stopRinging()
+		call.State = models.CALL_STATE_ACCEPTED
+		call.AnsweredAt = time.Now()
+	}
+
+	state := call.signal(userID)
+	state.Participants = call.roster()
+	recipients := call.recipients("")
+	m.mux.Unlock()
+
+	m.broadcastGroupCall(call.GroupID, recipients, models.WebsocketEvent{Event: models.EVENT_CALL_PARTICIPANTS, Data: state})
+
+	return nil
+}
+
+/*
+leaveGroupCall
+removes the participant, the call finishes when it is left empty or when only one
+participant remains after somebody answered
+*/
+func (m *CallManager) leaveGroupCall(callID, userID string) {
+
+	m.mux.Lock()
+	call, ok := m.rooms[callID]
+	if !ok {
+		m.mux.Unlock()
+		return
+	}
+
+	if _, joined := call.Participants[userID]; !joined {
+		m.mux.Unlock()
+		return
+	}
+
+	delete(call.Participants, userID)
+	m.hub.removePresence(PRESENCE_CALL, userID)
+
+	answered := !call.AnsweredAt.IsZero()
+	if len(call.Participants) == 0 || (answered && len(call.Participants) < 2) {
+		m.mux.Unlock()
+
+		state := models.CALL_STATE_MISSED
+		if answered {
+			state = models.CALL_STATE_ENDED
+		}
+		m.finishGroupCall(callID, state)
+		return
+	}
+
+	state := call.signal(userID)
+	state.Participants = call.roster()
+	recipients := call.recipients(userID)
+	m.mux.Unlock()
+
+	m.broadcastGroupCall(call.GroupID, recipients, models.WebsocketEvent{Event: models.EVENT_CALL_PARTICIPANTS, Data: state})
+}
+
+/*
+LeaveGroupCalls
+removes the user from the active call of the group wherever node owns it, used
+when its group connection closes
+*/
+func (m *CallManager) LeaveGroupCalls(userID, groupID string) {
+
+	callID, ok := m.activeGroupCall(groupID)
+	if !ok {
+		return
+	}
+
+	m.HandleSignal(userID, models.InboundCallSignal{
+		Event:   models.EVENT_CALL_LEAVE,
+		CallID:  callID,
+		GroupID: groupID,
+	})
+}
+
+/*
+finishGroupCall
+closes the group call with the final state and notifies every member of the group
+so the ringing stops on the devices that did not join, the offline members that
+never joined get a missed call notification
+*/
+func (m *CallManager) finishGroupCall(callID, state string) {
+
+	m.mux.Lock()
+	call, ok := m.rooms[callID]
+	if !ok {
+		m.mux.Unlock()
+		return
+	}
+	delete(m.rooms, callID)
+	call.stopRinging()
+	call.State = state
+
+	for id := range call.Participants {
+		m.hub.removePresence(PRESENCE_CALL, id)
+	}
+	m.removeActiveGroupCall(call)
+
+	signal := call.signal("")
+	record := callRecord(call.ID, call.Type, state, call.StartedAt, call.AnsweredAt)
+	m.mux.Unlock()
+
+	event := models.WebsocketEvent{Event: models.EVENT_CALL_STATE, Data: signal}
+	for _, usr := range call.Members {
+		online := m.hub.deliverToGroupMember(call.GroupID, usr, event)
+		if online || slices.Contains(call.Joined, usr) {
+			continue
+		}
+		if token, ok := call.pushTokens[usr]; ok {
+			m.hub.sendPushNotification(token, models.WebsocketEvent{Event: models.EVENT_CALL_MISSED, Data: signal})
+		}
+	}
+
+	m.persistGroupCallLog(call, record)
+}
+
+// persistGroupCallLog queues the insertion of the group call on the call history
+func (m *CallManager) persistGroupCallLog(call *GroupCall, record models.CallRecord) {
+
+	alog := logger.StartLogger()
+
+	starter, err := primitive.ObjectIDFromHex(call.StarterID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+
+	group, err := primitive.ObjectIDFromHex(call.GroupID)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+
+	var history models.CallLog
+	history.FormatGroupCallLog(starter, group, toObjectIDs(call.Members), toObjectIDs(call.Joined), record)
+
+	m.hub.storeCallLog(history)
+}
+
+// toObjectIDs converts the hex ids, invalid ids are skipped
+func toObjectIDs(ids []string) []primitive.ObjectID {
+	res := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			res = append(res, oid)
+		}
+	}
+	return res
+}
+
+// broadcastGroupCall delivers the payload to the given members of the group
+func (m *CallManager) broadcastGroupCall(groupID string, users []string, payload any) {
+	for _, usr := range users {
+		m.hub.deliverToGroupMember(groupID, usr, payload)
+	}
+}
+
+/*
+deliverToGroupMember
+writes the payload on the connection of the user to the group wherever node it lives,
+returns false if the user is not connected to the group
+*/
+func (h *WebsocketPanel) deliverToGroupMember(groupID, userID string, payload any) bool {
+
+	user, ok := h.GroupConnections.Lookup(userID)
+	if ok && user.TargetID == groupID {
+		return user.WriteJSON(payload) == nil
+	}
+
+	nodeID, online := h.locateUser(PRESENCE_GROUP, groupPresenceID(groupID, userID))
+	if !online || nodeID == h.NodeID {
+		return false
+	}
+
+	err := h.forwardToNode(nodeID, ENVELOPE_GROUP, groupID, []string{userID}, payload)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+		return false
+	}
+
+	return true
+}
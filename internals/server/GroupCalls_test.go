@@ -0,0 +1,198 @@
+package server
+
+import (
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// connectGroupPeer registers a group connection of the user and starts listening to it
+func connectGroupPeer(t *testing.T, user primitive.ObjectID, group *models.Group) *websocket.Conn {
+
+	serverSide, client := newMockConnection(t)
+	c := &GroupConnectionCredentials{
+		Conn:       serverSide,
+		AuthorID:   user.Hex(),
+		TargetID:   group.ID.Hex(),
+		AuthorData: &models.User{ID: user, Name: "user-" + user.Hex()[20:]},
+		TargetData: group,
+	}
+	WebsocketHUB.RegisterGroupConnection(c)
+	go ListenForGroupActivity(c)
+
+	return client
+}
+
+func readErrCode(t *testing.T, conn *websocket.Conn) int {
+	var res models.WebsocketResponseMessage
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	err := conn.ReadJSON(&res)
+	assert.Nil(t, err)
+	return res.Code
+}
+
+// TestGroupCalls test the group calls rooms
+func TestGroupCalls(t *testing.T) {
+
+	t.Run("GroupCalls - Start join relay mute and leave", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newDBMock()
+		WebsocketHUB.DBConn = db
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		carol := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{alice, bob, carol}}
+
+		starter := connectGroupPeer(t, alice, group)
+		member := connectGroupPeer(t, bob, group)
+
+		starter.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_START, CallType: models.CALL_TYPE_VIDEO})
+
+		ringing := readCallEvent(t, starter)
+		assert.Equal(t, models.EVENT_CALL_STATE, ringing.Event)
+		assert.Equal(t, models.CALL_STATE_RINGING, ringing.Data.State)
+		assert.Equal(t, group.ID.Hex(), ringing.Data.GroupID)
+		assert.Len(t, ringing.Data.Participants, 1)
+
+		offer := readCallEvent(t, member)
+		assert.Equal(t, models.EVENT_CALL_OFFER, offer.Event)
+		assert.Equal(t, ringing.Data.CallID, offer.Data.CallID)
+
+		member.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_JOIN})
+
+		roster := readCallEvent(t, starter)
+		assert.Equal(t, models.EVENT_CALL_PARTICIPANTS, roster.Event)
+		assert.Equal(t, models.CALL_STATE_ACCEPTED, roster.Data.State)
+		assert.Len(t, roster.Data.Participants, 2)
+		assert.Len(t, readCallEvent(t, member).Data.Participants, 2)
+
+		starter.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_OFFER, TargetID: bob.Hex(), SDP: "mesh-offer"})
+
+		relay := readCallEvent(t, member)
+		assert.Equal(t, models.EVENT_CALL_OFFER, relay.Event)
+		assert.Equal(t, alice.Hex(), relay.Data.FromID)
+		assert.Equal(t, "mesh-offer", relay.Data.SDP)
+
+		member.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_MUTE, Muted: true})
+
+		mute := readCallEvent(t, starter)
+		assert.Equal(t, models.EVENT_CALL_MUTE, mute.Event)
+		assert.Equal(t, bob.Hex(), mute.Data.FromID)
+		assert.True(t, mute.Data.Muted)
+
+		member.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_LEAVE})
+
+		ended := readCallEvent(t, starter)
+		assert.Equal(t, models.EVENT_CALL_STATE, ended.Event)
+		assert.Equal(t, models.CALL_STATE_ENDED, ended.Data.State)
+
+		_, active := WebsocketHUB.Calls.activeGroupCall(group.ID.Hex())
+		assert.False(t, active)
+
+		history := readCallLog(t, db)
+		assert.Equal(t, group.ID, history.GroupID)
+		assert.Equal(t, alice, history.CallerID)
+		assert.Equal(t, models.CALL_STATE_ENDED, history.State)
+		assert.Len(t, history.Participants, 3)
+		assert.Equal(t, []primitive.ObjectID{alice, bob}, history.Joined)
+	})
+
+	t.Run("GroupCalls - Participants limit", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.Calls.GroupCallLimit = 2
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		carol := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{alice, bob, carol}}
+
+		starter := connectGroupPeer(t, alice, group)
+		member := connectGroupPeer(t, bob, group)
+		late := connectGroupPeer(t, carol, group)
+
+		starter.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_START, CallType: models.CALL_TYPE_AUDIO})
+		readCallEvent(t, starter)
+		readCallEvent(t, member)
+		readCallEvent(t, late)
+
+		member.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_JOIN})
+		readCallEvent(t, member)
+
+		late.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_START, CallType: models.CALL_TYPE_AUDIO})
+		assert.Equal(t, CALL_FULL, readErrCode(t, late))
+	})
+
+	t.Run("GroupCalls - Concurrent joins respect the limit", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+		WebsocketHUB.Calls.GroupCallLimit = 2
+
+		alice := primitive.NewObjectID()
+		members := make([]primitive.ObjectID, 6)
+		for i := range members {
+			members[i] = primitive.NewObjectID()
+		}
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: append([]primitive.ObjectID{alice}, members...)}
+
+		err := WebsocketHUB.Calls.StartGroupCall(alice.Hex(), group, models.InboundCallSignal{CallType: models.CALL_TYPE_AUDIO})
+		assert.Nil(t, err)
+
+		callID, ok := WebsocketHUB.Calls.activeGroupCall(group.ID.Hex())
+		assert.True(t, ok)
+
+		errs := make(chan error, len(members))
+		for _, usr := range members {
+			go func(usr primitive.ObjectID) {
+				errs <- WebsocketHUB.Calls.HandleSignal(usr.Hex(), models.InboundCallSignal{Event: models.EVENT_CALL_JOIN, CallID: callID, GroupID: group.ID.Hex()})
+			}(usr)
+		}
+
+		joined := 0
+		for range members {
+			err := <-errs
+			if err == nil {
+				joined++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrCallFull)
+		}
+		assert.Equal(t, 1, joined)
+
+		// the members refused do not stay busy
+		busy := 0
+		for _, usr := range members {
+			if _, ok := WebsocketHUB.locateUser(PRESENCE_CALL, usr.Hex()); ok {
+				busy++
+			}
+		}
+		assert.Equal(t, 1, busy)
+	})
+
+	t.Run("GroupCalls - Error not a participant", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		outsider := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{primitive.NewObjectID()}}
+
+		conn := connectGroupPeer(t, outsider, group)
+
+		conn.WriteJSON(models.InboundCallSignal{Event: models.EVENT_CALL_START, CallType: models.CALL_TYPE_AUDIO})
+		assert.Equal(t, NOT_ALLOWED, readErrCode(t, conn))
+	})
+}
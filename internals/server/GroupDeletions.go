@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/providers/media"
+
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// ENVELOPE_GROUP_CLOSE envelope that asks every node to close the connections to a deleted group
+	ENVELOPE_GROUP_CLOSE = "group_close"
+
+	// DEFAULT_DELETION_SWEEP time between the checks for groups whose grace period is over
+	DEFAULT_DELETION_SWEEP = time.Minute
+
+	// DELETION_LEASE time a node holds a claimed deletion, if the node dies another one retries it after
+	DELETION_LEASE = 10 * time.Minute
+)
+
+/*
+GroupDeletionJanitor
+runs the deletions of the groups once their grace period is over, the chat logs,
+invites and join requests are removed together with the group and the media is
+removed from the storage
+*/
+type GroupDeletionJanitor struct {
+	db       database.DBHUB
+	provider media.MediaHUB
+	hub      *WebsocketPanel
+	interval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// StartGroupDeletionJanitor starts checking for expired deletions every interval
+func StartGroupDeletionJanitor(db database.DBHUB, provider media.MediaHUB, hub *WebsocketPanel, interval time.Duration) *GroupDeletionJanitor {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &GroupDeletionJanitor{
+		db:       db,
+		provider: provider,
+		hub:      hub,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	j.wg.Add(1)
+	go j.run()
+
+	return j
+}
+
+// Stop stops the janitor and waits for the running sweep to finish
+func (j *GroupDeletionJanitor) Stop() {
+	j.cancel()
+	j.wg.Wait()
+}
+
+func (j *GroupDeletionJanitor) run() {
+
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+			j.sweep()
+		}
+	}
+}
+
+// sweep deletes every group whose grace period is over
+func (j *GroupDeletionJanitor) sweep() {
+
+	alog := logger.StartLogger()
+
+	for j.ctx.Err() == nil {
+
+		group, err := j.db.ClaimGroupDeletionDB(time.Now(), DELETION_LEASE)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				alog.ErrorLog(err.Error())
+			}
+			return
+		}
+
+		// the claim stays until the lease expires, the deletion is retried then
+		err = j.deleteGroup(group)
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("group %s not deleted: %v", group.GroupID, err))
+		}
+	}
+}
+
+/*
+deleteGroup
+removes the media of the group before its documents, once the documents are gone
+there is no way to find the media again. The media forwarded outside the group
+is kept for the copies
+*/
+func (j *GroupDeletionJanitor) deleteGroup(group *models.Group) error {
+
+	alog := logger.StartLogger()
+
+	content, err := j.db.GetGroupMediaDB(group.ID)
+	if err != nil {
+		return err
+	}
+
+	videos, err := j.db.GetGroupVideosDB(group.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(content)+len(videos) > 0 {
+		shared, err := j.db.GetSharedMediaDB(group.ID, append(slices.Clone(content), videos...))
+		if err != nil {
+			return err
+		}
+		isShared := func(ref string) bool {
+			return slices.Contains(shared, ref)
+		}
+		content = slices.DeleteFunc(content, isShared)
+		videos = slices.DeleteFunc(videos, isShared)
+	}
+
+	if j.provider != nil {
+		err = j.provider.DeleteContent(append(content, group.Media()...))
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("media of group %s not deleted: %v", group.GroupID, err))
+		}
+
+		if len(videos) > 0 {
+			err = j.provider.DeleteVideos(videos)
+			if err != nil {
+				alog.ErrorLog(fmt.Sprintf("videos of group %s not deleted: %v", group.GroupID, err))
+			}
+		}
+	}
+
+	err = j.db.DeleteGroupCascadeDB(group.ID, group.GroupID)
+	if err != nil && err != database.ErrNoDeleted {
+		return err
+	}
+
+	if j.hub != nil {
+		j.hub.CloseGroup(group)
+	}
+
+	alog.InfoLogger(fmt.Sprintf("group %s deleted", group.GroupID))
+	return nil
+}
+
+/*
+CloseGroup
+tells the members connected to the deleted group and closes their connections,
+wherever node they live
+*/
+func (h *WebsocketPanel) CloseGroup(group *models.Group) {
+
+	alog := logger.StartLogger()
+
+	data, err := json.Marshal(models.WebsocketEvent{
+		Event: models.EVENT_GROUP_DELETED,
+		Data:  models.GroupDeletionEvent{GroupID: group.GroupID},
+	})
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+
+	h.closeGroupConnections(group.ID.Hex(), data)
+
+	err = h.publishBroadcast(ENVELOPE_GROUP_CLOSE, group.ID.Hex(), data)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+}
+
+// closeGroupConnections writes the event on the local connections to the group and closes them
+func (h *WebsocketPanel) closeGroupConnections(groupID string, event []byte) {
+
+	var conns []*GroupConnectionCredentials
+	h.GroupConnections.Range(func(key string, conn *GroupConnectionCredentials) bool {
+		if conn.TargetID == groupID {
+			conns = append(conns, conn)
+		}
+		return true
+	})
+
+	for _, conn := range conns {
+		conn.WriteMessage(event)
+		conn.closeWithCode(CLOSE_GROUP_DELETED, "group deleted")
+	}
+}
+
+// closeWithCode sends the close frame to the client before closing the connection
+func (g *GroupConnectionCredentials) closeWithCode(code int, reason string) {
+
+	g.writeMux.Lock()
+	err := g.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	g.writeMux.Unlock()
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+	}
+
+	g.CloseGroupConnection()
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+	"wechat-back/providers/media"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// deletionDBMock hands out the claimed groups once and records the cascades
+type deletionDBMock struct {
+	database.DBHUB
+	claims   []*models.Group
+	deleted  []string
+	shared   []string
+	cascades chan primitive.ObjectID
+}
+
+func (m *deletionDBMock) ClaimGroupDeletionDB(now time.Time, lease time.Duration) (*models.Group, error) {
+	if len(m.claims) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	group := m.claims[0]
+	m.claims = m.claims[1:]
+	return group, nil
+}
+
+func (m *deletionDBMock) GetGroupMediaDB(id primitive.ObjectID) ([]string, error) {
+	return []string{"https://cdn/content/a.jpg", "https://cdn/thumbs/a.jpg"}, nil
+}
+
+func (m *deletionDBMock) GetGroupVideosDB(id primitive.ObjectID) ([]string, error) {
+	return []string{"101$video-a", "102$video-b"}, nil
+}
+
+func (m *deletionDBMock) GetSharedMediaDB(id primitive.ObjectID, media []string) ([]string, error) {
+	return m.shared, nil
+}
+
+func (m *deletionDBMock) DeleteGroupCascadeDB(id primitive.ObjectID, groupID string) error {
+	m.deleted = append(m.deleted, groupID)
+	return nil
+}
+
+// TestGroupDeletions test the deletion of the groups whose grace period is over
+func TestGroupDeletions(t *testing.T) {
+
+	t.Run("GroupDeletions - Cascade closes the connections", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			GroupID:      "123456789",
+			Participants: []primitive.ObjectID{alice},
+			ProfileImage: "https://cdn/profiles/123456789_1.jpg",
+		}
+		other := &models.Group{ID: primitive.NewObjectID(), GroupID: "987654321"}
+
+		conn := connectGroupPeer(t, alice, group)
+
+		var removed, videos []string
+		provider := &media.MediaMock{
+			DeleteContentMockFunc: func(urls []string) error {
+				removed = append(removed, urls...)
+				return nil
+			},
+			DeleteVideosMockFunc: func(contentIDs []string) error {
+				videos = append(videos, contentIDs...)
+				return nil
+			},
+		}
+
+		db := &deletionDBMock{claims: []*models.Group{group, other}}
+		j := &GroupDeletionJanitor{db: db, provider: provider, hub: WebsocketHUB, ctx: context.Background()}
+		j.sweep()
+
+		assert.Equal(t, []string{"123456789", "987654321"}, db.deleted)
+		assert.Contains(t, removed, "https://cdn/content/a.jpg")
+		assert.Contains(t, removed, "https://cdn/thumbs/a.jpg")
+		assert.Contains(t, removed, group.ProfileImage)
+		assert.Contains(t, videos, "101$video-a")
+
+		var event struct {
+			Event string                    `json:"event"`
+			Data  models.GroupDeletionEvent `json:"data"`
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&event))
+		assert.Equal(t, models.EVENT_GROUP_DELETED, event.Event)
+		assert.Equal(t, "123456789", event.Data.GroupID)
+
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, CLOSE_GROUP_DELETED))
+
+		_, online := WebsocketHUB.GroupConnections.Lookup(alice.Hex())
+		assert.False(t, online)
+	})
+
+	t.Run("GroupDeletions - Media forwarded outside the group is kept", func(t *testing.T) {
+
+		var removed, videos []string
+		provider := &media.MediaMock{
+			DeleteContentMockFunc: func(urls []string) error {
+				removed = append(removed, urls...)
+				return nil
+			},
+			DeleteVideosMockFunc: func(contentIDs []string) error {
+				videos = append(videos, contentIDs...)
+				return nil
+			},
+		}
+
+		group := &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", ProfileImage: "https://cdn/profiles/123456789_1.jpg"}
+
+		db := &deletionDBMock{claims: []*models.Group{group}, shared: []string{"https://cdn/content/a.jpg", "https://cdn/thumbs/a.jpg", "101$video-a"}}
+		j := &GroupDeletionJanitor{db: db, provider: provider, ctx: context.Background()}
+		j.sweep()
+
+		assert.Equal(t, []string{"123456789"}, db.deleted)
+		assert.Equal(t, []string{group.ProfileImage}, removed)
+		assert.Equal(t, []string{"102$video-b"}, videos)
+	})
+}
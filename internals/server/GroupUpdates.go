@@ -0,0 +1,226 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// ENVELOPE_GROUP_UPDATE envelope that carries the new state of a group to every node
+	ENVELOPE_GROUP_UPDATE = "group_update"
+)
+
+// Group returns the group data of the connection
+func (g *GroupConnectionCredentials) Group() *models.Group {
+	g.dataMux.RLock()
+	defer g.dataMux.RUnlock()
+	return g.TargetData
+}
+
+// SetGroup replaces the group data of the connection
+func (g *GroupConnectionCredentials) SetGroup(group *models.Group) {
+	g.dataMux.Lock()
+	defer g.dataMux.Unlock()
+	g.TargetData = group
+}
+
+/*
+authorize
+checks the permission of the author on the group, the author is notified when it is denied
+*/
+func (g *GroupConnectionCredentials) authorize(permission string) bool {
+
+	group := g.Group()
+	if group != nil && group.Can(g.AuthorData.ID, permission) {
+		return true
+	}
+
+	logger.StartLogger().WarningLogger(fmt.Sprintf("user %s has no %s permission on group %s", g.AuthorID, permission, g.TargetID))
+	g.WriteJSON(models.FormatWebsocketErrResponse(fmt.Errorf("forbidden"), NOT_ALLOWED))
+	return false
+}
+
+/*
+moderate
+checks the moderation settings of the group before the author posts, the author
+is notified with the code of the rule that refused the message. The last post of
+the author is kept on the group so the slow mode holds across connections
+*/
+func (g *GroupConnectionCredentials) moderate() bool {
+
+	now := time.Now()
+	group := g.Group()
+
+	err := group.CheckPost(g.AuthorData.ID, now)
+	if err == nil {
+		err = ClaimSlowMode(g.hub.DBConn, group, g.AuthorData.ID, now)
+		if err != nil && !errors.Is(err, models.ErrSlowMode) {
+			logger.StartLogger().ErrorLog(err.Error())
+			g.WriteJSON(models.FormatWebsocketErrResponse(err, DB_ERROR))
+			return false
+		}
+	}
+
+	if err == nil {
+		return true
+	}
+
+	g.WriteJSON(models.FormatWebsocketErrResponse(err, ModerationCode(err)))
+	return false
+}
+
+/*
+ClaimSlowMode
+records the post of the user on the group when its slow mode lets it post, every
+way of posting claims it so none gets past the slow mode. Returns an error
+wrapping models.ErrSlowMode when the user has to wait, any other error comes
+from the database. A post claimed at the same time is the same post retried
+*/
+func ClaimSlowMode(db database.DBHUB, group *models.Group, user primitive.ObjectID, now time.Time) error {
+
+	wait := group.SlowModeOf(user)
+	if wait <= 0 {
+		return nil
+	}
+
+	last, err := db.ClaimGroupPostDB(group.ID, user, now, wait)
+	if err != nil {
+		return err
+	}
+
+	// the database keeps the time in milliseconds
+	if last.Equal(now.Truncate(time.Millisecond)) {
+		return nil
+	}
+
+	return models.SlowModeWait(last, wait, now)
+}
+
+// ModerationCode code of the moderation rule that refused the message, see models.Group.CheckPost
+func ModerationCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrAnnouncementOnly):
+		return ANNOUNCEMENT_ONLY
+	case errors.Is(err, models.ErrMemberMuted):
+		return MEMBER_MUTED
+	}
+	return SLOW_MODE
+}
+
+/*
+topic
+resolves the topic the author posts to, the author is notified when the topic
+does not exist or is archived
+*/
+func (g *GroupConnectionCredentials) topic(topicID string) (primitive.ObjectID, bool) {
+
+	id, err := g.Group().CheckTopic(topicID)
+	if err == nil {
+		return id, true
+	}
+
+	code := TOPIC_NOT_FOUND
+	if errors.Is(err, models.ErrTopicArchived) {
+		code = TOPIC_ARCHIVED
+	}
+
+	g.WriteJSON(models.FormatWebsocketErrResponse(err, code))
+	return primitive.NilObjectID, false
+}
+
+/*
+PublishGroupUpdate
+refreshes the group data of the local connections and shares the update with the other nodes
+*/
+func (h *WebsocketPanel) PublishGroupUpdate(group *models.Group) {
+
+	alog := logger.StartLogger()
+
+	data, err := json.Marshal(group)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+
+	h.applyGroupUpdate(data)
+
+	err = h.publishBroadcast(ENVELOPE_GROUP_UPDATE, group.ID.Hex(), data)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+}
+
+// applyGroupUpdate replaces the group data of the local connections to the group
+func (h *WebsocketPanel) applyGroupUpdate(data []byte) {
+
+	var group models.Group
+	err := json.Unmarshal(data, &group)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+		return
+	}
+
+	h.GroupConnections.Range(func(key string, conn *GroupConnectionCredentials) bool {
+		if conn.TargetID == group.ID.Hex() {
+			// every connection holds its own copy
+			update := group
+			conn.SetGroup(&update)
+		}
+		return true
+	})
+}
+
+/*
+NotifyGroupMembers
+sends the event to the given members that are connected to the group, wherever node they live
+*/
+func (h *WebsocketPanel) NotifyGroupMembers(groupID primitive.ObjectID, members []primitive.ObjectID, payload any) {
+	for _, usr := range members {
+		h.deliverToGroupMember(groupID.Hex(), usr.Hex(), payload)
+	}
+}
+
+/*
+NotifyUsers
+sends the event to the p2p connections of the given users, wherever node they live
+*/
+func (h *WebsocketPanel) NotifyUsers(users []primitive.ObjectID, payload any) {
+	for _, usr := range users {
+		h.deliverToUser(usr.Hex(), payload)
+	}
+}
+
+/*
+DeliverGroupMessage
+writes a message stored by the server on the connections of the members, with
+change streams the message reaches them from the chat log instead
+*/
+func (h *WebsocketPanel) DeliverGroupMessage(groupID primitive.ObjectID, members []primitive.ObjectID, payload any) {
+	if h.Streams != nil {
+		return
+	}
+	h.NotifyGroupMembers(groupID, members, payload)
+}
+
+/*
+DeliverP2PMessage
+writes a private message stored by the server on the connections of the author
+and the target, with change streams the message reaches them from the chat log instead
+*/
+func (h *WebsocketPanel) DeliverP2PMessage(author, target primitive.ObjectID, payload any) {
+	if h.Streams != nil {
+		return
+	}
+	h.deliverToUser(author.Hex(), payload)
+	h.deliverToUser(target.Hex(), payload)
+}
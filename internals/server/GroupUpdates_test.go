@@ -0,0 +1,347 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestGroupPermissions test the permissions enforced on the group connections
+func TestGroupPermissions(t *testing.T) {
+
+	t.Run("GroupPermissions - Outsider can not send messages", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{primitive.NewObjectID()}}
+
+		conn := connectGroupPeer(t, alice, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello"})
+
+		assert.Equal(t, NOT_ALLOWED, readErrCode(t, conn))
+	})
+
+	t.Run("GroupPermissions - Member can not send media", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			Participants: []primitive.ObjectID{alice},
+			Permissions:  models.GroupPermissions{SendMedia: models.GROUP_ROLE_ADMIN},
+		}
+
+		conn := connectGroupPeer(t, alice, group)
+		header, err := json.Marshal(models.InboundGroupContentMessage{ContentType: models.MESSAGE_TYPE_MEDIA_IMAGES, Filename: []string{"a.jpg"}})
+		assert.Nil(t, err)
+
+		msg := strings.Join([]string{string(header), "image"}, models.WEBSOCKET_BINARY_SEPARATOR)
+		conn.WriteMessage(websocket.BinaryMessage, []byte(msg))
+
+		assert.Equal(t, NOT_ALLOWED, readErrCode(t, conn))
+	})
+
+	t.Run("GroupPermissions - Group updates refresh the connections", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newDBMock()
+		WebsocketHUB.DBConn = db
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{primitive.NewObjectID()}}
+
+		conn := connectGroupPeer(t, alice, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello"})
+		assert.Equal(t, NOT_ALLOWED, readErrCode(t, conn))
+
+		WebsocketHUB.PublishGroupUpdate(&models.Group{ID: group.ID, Participants: []primitive.ObjectID{alice}})
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello"})
+
+		var res models.GroupChatTextLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := conn.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, "hello", res.Body)
+		assert.Equal(t, alice, res.AuthorID)
+
+		select {
+		case <-db.inserted:
+		case <-time.After(2 * time.Second):
+			t.Fatal("message not persisted")
+		}
+	})
+
+	t.Run("GroupPermissions - Members are notified when a user joins", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{alice, bob}}
+
+		conn := connectGroupPeer(t, alice, group)
+
+		WebsocketHUB.NotifyGroupMembers(group.ID, group.Participants, models.WebsocketEvent{
+			Event: models.EVENT_GROUP_MEMBER_JOINED,
+			Data:  models.GroupMemberEvent{UserID: bob, Name: "bob"},
+		})
+
+		var res struct {
+			Event string                  `json:"event"`
+			Data  models.GroupMemberEvent `json:"data"`
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := conn.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, models.EVENT_GROUP_MEMBER_JOINED, res.Event)
+		assert.Equal(t, bob, res.Data.UserID)
+	})
+
+	t.Run("GroupPermissions - Users are notified on their private conversations", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		message := primitive.NewObjectID()
+
+		conn := connectCallPeer(t, alice, bob)
+
+		WebsocketHUB.NotifyUsers([]primitive.ObjectID{alice, bob}, models.WebsocketEvent{
+			Event: models.EVENT_MESSAGE_REACTION,
+			Data:  models.MessageReactionEvent{MessageID: message, UserID: bob, Emoji: "👍", Reactions: map[string]int{"👍": 1}},
+		})
+
+		var res struct {
+			Event string                      `json:"event"`
+			Data  models.MessageReactionEvent `json:"data"`
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := conn.ReadJSON(&res)
+		assert.Nil(t, err)
+		assert.Equal(t, models.EVENT_MESSAGE_REACTION, res.Event)
+		assert.Equal(t, message, res.Data.MessageID)
+		assert.Equal(t, 1, res.Data.Reactions["👍"])
+	})
+}
+
+// TestGroupModeration test the moderation settings enforced on the group connections
+func TestGroupModeration(t *testing.T) {
+
+	readText := func(t *testing.T, conn *websocket.Conn) models.GroupChatTextLog {
+		var res models.GroupChatTextLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err := conn.ReadJSON(&res)
+		assert.Nil(t, err)
+		return res
+	}
+
+	t.Run("GroupModeration - Announcement only groups refuse the members", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		admin := primitive.NewObjectID()
+		member := primitive.NewObjectID()
+		group := &models.Group{
+			ID:               primitive.NewObjectID(),
+			Admins:           []primitive.ObjectID{admin},
+			Participants:     []primitive.ObjectID{admin, member},
+			AnnouncementOnly: true,
+		}
+
+		memberConn := connectGroupPeer(t, member, group)
+		memberConn.WriteJSON(models.InboundGroupTextMessage{Body: "hello"})
+		assert.Equal(t, ANNOUNCEMENT_ONLY, readErrCode(t, memberConn))
+
+		adminConn := connectGroupPeer(t, admin, group)
+		adminConn.WriteJSON(models.InboundGroupTextMessage{Body: "announcement"})
+		assert.Equal(t, "announcement", readText(t, adminConn).Body)
+	})
+
+	t.Run("GroupModeration - Muted members are refused", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		member := primitive.NewObjectID()
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			Participants: []primitive.ObjectID{member},
+			Mutes:        map[string]time.Time{member.Hex(): time.Now().Add(time.Hour)},
+		}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello"})
+		assert.Equal(t, MEMBER_MUTED, readErrCode(t, conn))
+	})
+
+	t.Run("GroupModeration - Slow mode limits the members", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		member := primitive.NewObjectID()
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			Participants: []primitive.ObjectID{member},
+			SlowMode:     60,
+		}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "first"})
+		assert.Equal(t, "first", readText(t, conn).Body)
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "second"})
+		assert.Equal(t, SLOW_MODE, readErrCode(t, conn))
+	})
+
+	t.Run("GroupModeration - Slow mode holds across connections", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		member := primitive.NewObjectID()
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			Participants: []primitive.ObjectID{member},
+			SlowMode:     60,
+		}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "first"})
+		assert.Equal(t, "first", readText(t, conn).Body)
+
+		// the new connection replaces the first one
+		conn = connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "second"})
+		assert.Equal(t, SLOW_MODE, readErrCode(t, conn))
+	})
+}
+
+// TestGroupTopics tests the routing of the group messages to the topics of the group
+func TestGroupTopics(t *testing.T) {
+
+	t.Run("GroupTopics - Messages are stored on their topic", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newDBMock()
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		topic := models.FormatGroupTopic("releases", member)
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			Participants: []primitive.ObjectID{member},
+			Topics:       []models.GroupTopic{topic},
+		}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "v1.2 is out", TopicID: topic.ID.Hex()})
+
+		var res models.GroupChatTextLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&res))
+		assert.Equal(t, topic.ID, res.TopicID)
+
+		select {
+		case stored := <-db.inserted:
+			assert.Equal(t, topic.ID, stored.(models.GroupChatTextLog).TopicID)
+		case <-time.After(2 * time.Second):
+			t.Fatal("message not stored")
+		}
+	})
+
+	t.Run("GroupTopics - Unknown and archived topics are refused", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		member := primitive.NewObjectID()
+		archived := models.FormatGroupTopic("old releases", member)
+		archived.Archived = true
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			Participants: []primitive.ObjectID{member},
+			Topics:       []models.GroupTopic{archived},
+			SlowMode:     60,
+		}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello", TopicID: primitive.NewObjectID().Hex()})
+		assert.Equal(t, TOPIC_NOT_FOUND, readErrCode(t, conn))
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello", TopicID: archived.ID.Hex()})
+		assert.Equal(t, TOPIC_ARCHIVED, readErrCode(t, conn))
+
+		// the refused messages do not count for the slow mode
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello"})
+		var res models.GroupChatTextLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&res))
+		assert.Equal(t, "hello", res.Body)
+		assert.True(t, res.TopicID.IsZero())
+	})
+}
+
+// TestDeliverP2PMessage test the delivery of the private messages stored by the server
+func TestDeliverP2PMessage(t *testing.T) {
+
+	t.Run("DeliverP2PMessage - Author and target connected", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		author := connectCallPeer(t, alice, bob)
+		target := connectCallPeer(t, bob, alice)
+
+		var payload models.P2PContentChatLog
+		payload.FormatContentChatLog(bob, alice, "alice", "hello", "N/A", nil, nil, models.MESSAGE_TYPE_TEXT)
+		payload.Forward = &models.MessageForward{MessageID: primitive.NewObjectID(), Count: 1}
+
+		WebsocketHUB.DeliverP2PMessage(alice, bob, payload)
+
+		for _, conn := range []*websocket.Conn{author, target} {
+			var res models.P2PContentChatLog
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			assert.Nil(t, conn.ReadJSON(&res))
+			assert.Equal(t, payload.ID, res.ID)
+			assert.Equal(t, 1, res.Forward.Count)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/internals/workerpool"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+persistMessage
+queues the insertion of the p2p message, returns false when the job was not
+queued so the message is not delivered. The author is notified if it fails
+*/
+func (p *P2PConnectionCredentials) persistMessage(payload any) bool {
+
+	alog := logger.StartLogger()
+
+	opts := workerpool.PersistenceJob()
+	opts.OnFailure = func(err error) {
+		alog.ErrorLog(err.Error())
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, DB_ERROR))
+	}
+
+	err := p.hub.WorkerPool.Submit(func(ctx context.Context) error {
+		return storedOnce(workerpool.Await(ctx, func() error {
+			_, err := p.hub.DBConn.InsertP2PMessageDB(payload)
+			return err
+		}))
+	}, opts)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, SERVER_BUSY))
+		return false
+	}
+
+	return true
+}
+
+/*
+persistMessage
+queues the insertion of the group message, returns false when the job was not
+queued so the message is not delivered. The follow ups run in the same job once
+the message is stored, the retries skip the steps that already succeeded. The
+author is notified if the message is not stored
+*/
+func (g *GroupConnectionCredentials) persistMessage(payload any, then ...func() error) bool {
+
+	alog := logger.StartLogger()
+
+	stored, next := false, 0
+
+	opts := workerpool.PersistenceJob()
+	opts.OnFailure = func(err error) {
+		alog.ErrorLog(err.Error())
+		if !stored {
+			g.WriteJSON(models.FormatWebsocketErrResponse(err, DB_ERROR))
+		}
+	}
+
+	err := g.hub.WorkerPool.Submit(func(ctx context.Context) error {
+
+		if !stored {
+			err := storedOnce(workerpool.Await(ctx, func() error {
+				_, err := g.hub.DBConn.InsertGroupMessageDB(payload)
+				return err
+			}))
+			if err != nil {
+				return err
+			}
+			stored = true
+		}
+
+		for ; next < len(then); next++ {
+			if err := workerpool.Await(ctx, then[next]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, opts)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		g.WriteJSON(models.FormatWebsocketErrResponse(err, SERVER_BUSY))
+		return false
+	}
+
+	return true
+}
+
+/*
+storedOnce
+the id of the document is set before the insertion, a retry after an attempt
+that timed out but was stored fails with a duplicate key and is a success
+*/
+func storedOnce(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// countThreadReply counts the reply on the root message of its thread after the reply is stored, zero roots are not threads
+func (g *GroupConnectionCredentials) countThreadReply(root primitive.ObjectID) func() error {
+	return func() error {
+		if root.IsZero() {
+			return nil
+		}
+		return g.hub.DBConn.AddThreadReplyDB(root)
+	}
+}
+
+// storeMentions stores the mentions of the message after the message is stored, the unread mentions of the users are read from them
+func (g *GroupConnectionCredentials) storeMentions(mentions []models.Mention) func() error {
+	return func() error {
+		if len(mentions) == 0 {
+			return nil
+		}
+		return g.hub.DBConn.InsertMentionsDB(mentions)
+	}
+}
+
+// storeCallLog queues the insertion of the call on the call history
+func (h *WebsocketPanel) storeCallLog(log models.CallLog) {
+
+	alog := logger.StartLogger()
+
+	opts := workerpool.PersistenceJob()
+	opts.OnFailure = func(err error) {
+		alog.ErrorLog(fmt.Sprintf("call log %s not stored: %v", log.CallID, err))
+	}
+
+	err := h.WorkerPool.Submit(func(ctx context.Context) error {
+		return storedOnce(workerpool.Await(ctx, func() error {
+			_, err := h.DBConn.InsertCallLogDB(log)
+			return err
+		}))
+	}, opts)
+	if err != nil {
+		alog.ErrorLog(fmt.Sprintf("call log %s dropped: %v", log.CallID, err))
+	}
+}
+
+/*
+sendPushNotification
+queues the push notification for an offline user, notifications run after the persistence jobs
+*/
+func (h *WebsocketPanel) sendPushNotification(token string, payload any) {
+
+	alog := logger.StartLogger()
+
+	push := models.FormatPushNotification(token, payload)
+
+	err := h.WorkerPool.Submit(func(ctx context.Context) error {
+		alog.InfoLogger(fmt.Sprintf("about to send %s push notification to user %s", push.Event, token))
+		if h.Pusher == nil {
+			return nil
+		}
+		return workerpool.Await(ctx, func() error {
+			return h.Pusher(push)
+		})
+	}, workerpool.NotificationJob())
+	if err != nil {
+		alog.ErrorLog(fmt.Sprintf("push notification to %s dropped: %v", token, err))
+	}
+}
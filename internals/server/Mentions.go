@@ -0,0 +1,48 @@
+package server
+
+import (
+	"time"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mentioner payloads that can mention the participants of the group
+type mentioner interface {
+	MentionsUser(primitive.ObjectID) bool
+}
+
+/*
+mentions
+validates the mention entities of the message of the author, the author is
+notified when a mentioned user is not a participant or it can not mention everyone
+*/
+func (g *GroupConnectionCredentials) mentions(entities []string) ([]primitive.ObjectID, bool, bool) {
+
+	users, all, err := g.Group().CheckMentions(g.AuthorData.ID, entities)
+	if err != nil {
+		g.WriteJSON(models.FormatWebsocketErrResponse(err, MENTION_NOT_ALLOWED))
+		return nil, false, false
+	}
+
+	return users, all, true
+}
+
+/*
+offlinePush
+push sent to the offline participant for the payload, the mentioned participants
+get a mention push even when they muted the notifications of the group, the
+rest of the participants that muted them get nothing
+*/
+func offlinePush(group *models.Group, user primitive.ObjectID, payload any, now time.Time) (any, bool) {
+
+	if m, ok := payload.(mentioner); ok && m.MentionsUser(user) {
+		return models.WebsocketEvent{Event: models.EVENT_GROUP_MENTION, Data: payload}, true
+	}
+
+	if group != nil && !group.NotificationsMutedUntil(user, now).IsZero() {
+		return nil, false
+	}
+
+	return payload, true
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mentionDBMock records the mentions stored for the messages
+type mentionDBMock struct {
+	*dbMock
+	mentions chan []models.Mention
+}
+
+func newMentionDBMock() *mentionDBMock {
+	return &mentionDBMock{
+		dbMock:   newDBMock(),
+		mentions: make(chan []models.Mention, 1),
+	}
+}
+
+func (m *mentionDBMock) InsertMentionsDB(mentions []models.Mention) error {
+	m.mentions <- mentions
+	return nil
+}
+
+// unstoredMentionDBMock fails the insertion of the messages
+type unstoredMentionDBMock struct {
+	*mentionDBMock
+}
+
+func (m *unstoredMentionDBMock) InsertGroupMessageDB(payload any) (string, error) {
+	return "", errors.New("insert failed")
+}
+
+// TestGroupMentions tests the mentions of the group text messages
+func TestGroupMentions(t *testing.T) {
+
+	t.Run("GroupMentions - Mentioned participants are stored", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newMentionDBMock()
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		friend := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", Participants: []primitive.ObjectID{member, friend}}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hi @friend", Mentions: []string{friend.Hex(), friend.Hex()}})
+
+		res := readGroupText(t, conn)
+		assert.Equal(t, []primitive.ObjectID{friend}, res.Mentions)
+		assert.False(t, res.MentionsAll)
+
+		select {
+		case mentions := <-db.mentions:
+			assert.Len(t, mentions, 1)
+			assert.Equal(t, friend, mentions[0].UserID)
+			assert.Equal(t, res.ID, mentions[0].MessageID)
+			assert.Equal(t, "123456789", mentions[0].GroupID)
+		case <-time.After(2 * time.Second):
+			t.Fatal("mentions not stored")
+		}
+	})
+
+	t.Run("GroupMentions - Mentions of messages not stored are dropped", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := &unstoredMentionDBMock{newMentionDBMock()}
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		friend := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{member, friend}}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hi @friend", Mentions: []string{friend.Hex()}})
+
+		readGroupText(t, conn)
+		assert.Equal(t, DB_ERROR, readErrCode(t, conn))
+
+		select {
+		case <-db.mentions:
+			t.Fatal("mentions stored without the message")
+		default:
+		}
+	})
+
+	t.Run("GroupMentions - Admins mention everyone but themselves", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newMentionDBMock()
+		WebsocketHUB.DBConn = db
+
+		admin := primitive.NewObjectID()
+		members := []primitive.ObjectID{admin, primitive.NewObjectID(), primitive.NewObjectID()}
+		group := &models.Group{ID: primitive.NewObjectID(), OwnerID: admin, Participants: members}
+
+		conn := connectGroupPeer(t, admin, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "meeting at 5", Mentions: []string{models.MENTION_ALL}})
+
+		res := readGroupText(t, conn)
+		assert.True(t, res.MentionsAll)
+
+		select {
+		case mentions := <-db.mentions:
+			assert.Len(t, mentions, 2)
+			for _, m := range mentions {
+				assert.NotEqual(t, admin, m.UserID)
+				assert.True(t, m.All)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("mentions not stored")
+		}
+	})
+
+	t.Run("GroupMentions - Outsiders and everyone by members are refused", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newMentionDBMock()
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{member}}
+
+		conn := connectGroupPeer(t, member, group)
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hi", Mentions: []string{primitive.NewObjectID().Hex()}})
+		assert.Equal(t, MENTION_NOT_ALLOWED, readErrCode(t, conn))
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hi", Mentions: []string{models.MENTION_ALL}})
+		assert.Equal(t, MENTION_NOT_ALLOWED, readErrCode(t, conn))
+
+		select {
+		case <-db.mentions:
+			t.Fatal("refused messages store no mentions")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+// TestMentionPush tests the pushes of the mentions sent to the offline participants
+func TestMentionPush(t *testing.T) {
+
+	t.Run("MentionPush - Muted participants mentioned get the mention push", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newMentionDBMock()
+
+		pushes := make(chan models.PushNotification, 2)
+		WebsocketHUB.Pusher = func(push models.PushNotification) error {
+			pushes <- push
+			return nil
+		}
+
+		member := primitive.NewObjectID()
+		friend := primitive.NewObjectID()
+		group := &models.Group{
+			ID:                primitive.NewObjectID(),
+			GroupID:           "123456789",
+			Participants:      []primitive.ObjectID{member, friend},
+			NotificationMutes: map[string]time.Time{friend.Hex(): time.Now().Add(time.Hour)},
+		}
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hi @friend", Mentions: []string{friend.Hex()}, PushTokens: map[string]string{friend.Hex(): "friend-token"}})
+
+		res := readGroupText(t, conn)
+
+		select {
+		case push := <-pushes:
+			assert.Equal(t, "friend-token", push.Token)
+			assert.Equal(t, models.EVENT_GROUP_MENTION, push.Event)
+			assert.Equal(t, res.ID, push.Data.(models.GroupChatTextLog).ID)
+		case <-time.After(2 * time.Second):
+			t.Fatal("mention push not sent")
+		}
+	})
+}
+
+// TestOfflinePush tests the pushes sent to the offline participants
+func TestOfflinePush(t *testing.T) {
+
+	now := time.Now()
+	author := primitive.NewObjectID()
+	mentioned := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	group := &models.Group{
+		ID:           primitive.NewObjectID(),
+		Participants: []primitive.ObjectID{author, mentioned, other},
+		NotificationMutes: map[string]time.Time{
+			mentioned.Hex(): now.Add(time.Hour),
+			other.Hex():     now.Add(time.Hour),
+		},
+	}
+
+	payload := models.GroupChatTextLog{AuthorID: author, Body: "hi", Mentions: []primitive.ObjectID{mentioned}}
+
+	t.Run("OfflinePush - Mentions bypass the notification mutes", func(t *testing.T) {
+		push, ok := offlinePush(group, mentioned, payload, now)
+		assert.True(t, ok)
+		assert.Equal(t, models.EVENT_GROUP_MENTION, push.(models.WebsocketEvent).Event)
+	})
+
+	t.Run("OfflinePush - Muted participants get nothing", func(t *testing.T) {
+		_, ok := offlinePush(group, other, payload, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("OfflinePush - Expired mutes notify again", func(t *testing.T) {
+		push, ok := offlinePush(group, other, payload, now.Add(2*time.Hour))
+		assert.True(t, ok)
+		assert.Equal(t, payload, push)
+	})
+
+	t.Run("OfflinePush - Everyone mentions reach muted participants", func(t *testing.T) {
+		all := payload
+		all.Mentions = nil
+		all.MentionsAll = true
+
+		push, ok := offlinePush(group, other, all, now)
+		assert.True(t, ok)
+		assert.Equal(t, models.EVENT_GROUP_MENTION, push.(models.WebsocketEvent).Event)
+	})
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// REGISTRY_SHARDS number of shards used by the connection registries of the hub
+const REGISTRY_SHARDS = 64
+
+/*
+ConnectionRegistry
+holds the connections of the hub split in shards, every shard has its own
+RW lock so lookups and registrations on different users do not block each other
+*/
+type ConnectionRegistry[T comparable] struct {
+	shards []*registryShard[T]
+}
+
+type registryShard[T comparable] struct {
+	mux   sync.RWMutex
+	items map[string]T
+}
+
+// NewConnectionRegistry creates a registry with the given number of shards
+func NewConnectionRegistry[T comparable](shards int) *ConnectionRegistry[T] {
+
+	if shards < 1 {
+		shards = 1
+	}
+
+	r := &ConnectionRegistry[T]{
+		shards: make([]*registryShard[T], shards),
+	}
+
+	for i := range r.shards {
+		r.shards[i] = &registryShard[T]{items: make(map[string]T)}
+	}
+
+	return r
+}
+
+// shard returns the shard that holds the key
+func (r *ConnectionRegistry[T]) shard(key string) *registryShard[T] {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return r.shards[h.Sum32()%uint32(len(r.shards))]
+}
+
+/*
+Register
+adds the connection under the key, returns the connection it replaced if any
+*/
+func (r *ConnectionRegistry[T]) Register(key string, conn T) (T, bool) {
+
+	s := r.shard(key)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	previous, replaced := s.items[key]
+	s.items[key] = conn
+
+	return previous, replaced
+}
+
+/*
+Unregister
+removes the connection under the key only if it is still the given connection,
+so a closing connection never removes the one that replaced it
+*/
+func (r *ConnectionRegistry[T]) Unregister(key string, conn T) bool {
+
+	s := r.shard(key)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	current, ok := s.items[key]
+	if !ok || current != conn {
+		return false
+	}
+
+	delete(s.items, key)
+	return true
+}
+
+// Lookup returns the connection registered under the key
+func (r *ConnectionRegistry[T]) Lookup(key string) (T, bool) {
+
+	s := r.shard(key)
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	conn, ok := s.items[key]
+	return conn, ok
+}
+
+/*
+Range
+calls fn for every connection until fn returns false, the shards are copied
+before calling fn so fn can write to the connections without holding any lock
+*/
+func (r *ConnectionRegistry[T]) Range(fn func(key string, conn T) bool) {
+
+	for _, s := range r.shards {
+
+		s.mux.RLock()
+		keys := make([]string, 0, len(s.items))
+		conns := make([]T, 0, len(s.items))
+		for key, conn := range s.items {
+			keys = append(keys, key)
+			conns = append(conns, conn)
+		}
+		s.mux.RUnlock()
+
+		for i := range keys {
+			if !fn(keys[i], conns[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns the connections that satisfy fn
+func (r *ConnectionRegistry[T]) Filter(fn func(key string, conn T) bool) []T {
+
+	var res []T
+
+	r.Range(func(key string, conn T) bool {
+		if fn(key, conn) {
+			res = append(res, conn)
+		}
+		return true
+	})
+
+	return res
+}
+
+// Keys returns the keys of every registered connection
+func (r *ConnectionRegistry[T]) Keys() []string {
+
+	var res []string
+
+	r.Range(func(key string, conn T) bool {
+		res = append(res, key)
+		return true
+	})
+
+	return res
+}
+
+// Len returns the number of registered connections
+func (r *ConnectionRegistry[T]) Len() int {
+
+	total := 0
+
+	for _, s := range r.shards {
+		s.mux.RLock()
+		total += len(s.items)
+		s.mux.RUnlock()
+	}
+
+	return total
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mockConnections creates n simulated connections with unique authors
+func mockConnections(n int) []*P2PConnectionCredentials {
+	conns := make([]*P2PConnectionCredentials, n)
+	for i := range conns {
+		conns[i] = &P2PConnectionCredentials{
+			AuthorID: primitive.NewObjectID().Hex(),
+			TargetID: primitive.NewObjectID().Hex(),
+		}
+	}
+	return conns
+}
+
+// TestConnectionRegistry test the connection registry
+func TestConnectionRegistry(t *testing.T) {
+
+	t.Run("ConnectionRegistry - Register and lookup", func(t *testing.T) {
+
+		registry := NewConnectionRegistry[*P2PConnectionCredentials](REGISTRY_SHARDS)
+		conns := mockConnections(10)
+
+		for _, c := range conns {
+			_, replaced := registry.Register(c.AuthorID, c)
+			assert.False(t, replaced)
+		}
+
+		for _, c := range conns {
+			res, ok := registry.Lookup(c.AuthorID)
+			assert.True(t, ok)
+			assert.Same(t, c, res)
+		}
+
+		_, ok := registry.Lookup(primitive.NewObjectID().Hex())
+		assert.False(t, ok)
+		assert.Equal(t, 10, registry.Len())
+	})
+
+	t.Run("ConnectionRegistry - Replaced connection not unregistered", func(t *testing.T) {
+
+		registry := NewConnectionRegistry[*P2PConnectionCredentials](REGISTRY_SHARDS)
+		author := primitive.NewObjectID().Hex()
+
+		old := &P2PConnectionCredentials{AuthorID: author}
+		current := &P2PConnectionCredentials{AuthorID: author}
+
+		registry.Register(author, old)
+		previous, replaced := registry.Register(author, current)
+		assert.True(t, replaced)
+		assert.Same(t, old, previous)
+
+		assert.False(t, registry.Unregister(author, old))
+
+		res, ok := registry.Lookup(author)
+		assert.True(t, ok)
+		assert.Same(t, current, res)
+
+		assert.True(t, registry.Unregister(author, current))
+		assert.Equal(t, 0, registry.Len())
+	})
+
+	t.Run("ConnectionRegistry - Replaced connection closed by the hub", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+
+		old := connectCallPeer(t, alice, bob)
+		connectCallPeer(t, alice, bob)
+
+		old.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := old.ReadMessage()
+		assert.NotNil(t, err)
+
+		// the listener of the replaced connection does not unregister the new one
+		time.Sleep(50 * time.Millisecond)
+		_, ok := WebsocketHUB.P2PConnections.Lookup(alice.Hex())
+		assert.True(t, ok)
+	})
+
+	t.Run("ConnectionRegistry - Range and filter", func(t *testing.T) {
+
+		registry := NewConnectionRegistry[*P2PConnectionCredentials](8)
+		conns := mockConnections(100)
+		group := conns[0].TargetID
+
+		for i, c := range conns {
+			if i%4 == 0 {
+				c.TargetID = group
+			}
+			registry.Register(c.AuthorID, c)
+		}
+
+		seen := make(map[string]bool)
+		registry.Range(func(key string, conn *P2PConnectionCredentials) bool {
+			seen[key] = true
+			return true
+		})
+		assert.Len(t, seen, 100)
+
+		res := registry.Filter(func(key string, conn *P2PConnectionCredentials) bool {
+			return conn.TargetID == group
+		})
+		assert.Len(t, res, 25)
+
+		count := 0
+		registry.Range(func(key string, conn *P2PConnectionCredentials) bool {
+			count++
+			return count < 10
+		})
+		assert.Equal(t, 10, count)
+	})
+
+	t.Run("ConnectionRegistry - Concurrent access", func(t *testing.T) {
+
+		registry := NewConnectionRegistry[*P2PConnectionCredentials](REGISTRY_SHARDS)
+		conns := mockConnections(5000)
+
+		var wg sync.WaitGroup
+		for w := 0; w < 50; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(conns); i += 50 {
+					c := conns[i]
+					registry.Register(c.AuthorID, c)
+					registry.Lookup(conns[(i+1)%len(conns)].AuthorID)
+					if i%3 == 0 {
+						registry.Unregister(c.AuthorID, c)
+					}
+				}
+			}(w)
+		}
+
+		// broadcasting while connections come and go
+		for r := 0; r < 5; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				registry.Range(func(key string, conn *P2PConnectionCredentials) bool {
+					return conn.AuthorID == key
+				})
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, 5000-1667, registry.Len())
+		for i, c := range conns {
+			_, ok := registry.Lookup(c.AuthorID)
+			assert.Equal(t, i%3 != 0, ok)
+		}
+	})
+}
+
+// BenchmarkRegistryLookup benchmarks concurrent lookups on the registry
+func BenchmarkRegistryLookup(b *testing.B) {
+
+	registry := NewConnectionRegistry[*P2PConnectionCredentials](REGISTRY_SHARDS)
+	conns := mockConnections(10000)
+	for _, c := range conns {
+		registry.Register(c.AuthorID, c)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			registry.Lookup(conns[i%len(conns)].AuthorID)
+			i++
+		}
+	})
+}
+
+// BenchmarkRegistryRegister benchmarks concurrent registrations mixed with lookups
+func BenchmarkRegistryRegister(b *testing.B) {
+
+	for _, shards := range []int{1, REGISTRY_SHARDS} {
+		b.Run(fmt.Sprintf("shards-%d", shards), func(b *testing.B) {
+
+			registry := NewConnectionRegistry[*P2PConnectionCredentials](shards)
+			conns := mockConnections(10000)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c := conns[i%len(conns)]
+					registry.Register(c.AuthorID, c)
+					registry.Lookup(conns[(i+7)%len(conns)].AuthorID)
+					registry.Unregister(c.AuthorID, c)
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkRegistryRange benchmarks iterating the registry to broadcast
+func BenchmarkRegistryRange(b *testing.B) {
+
+	registry := NewConnectionRegistry[*P2PConnectionCredentials](REGISTRY_SHARDS)
+	for _, c := range mockConnections(10000) {
+		registry.Register(c.AuthorID, c)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		registry.Range(func(key string, conn *P2PConnectionCredentials) bool {
+			return true
+		})
+	}
+}
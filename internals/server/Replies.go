@@ -0,0 +1,123 @@
+package server
+
+import (
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// messageRoute where a group message lands: its topic, the message it quotes and its thread
+type messageRoute struct {
+	topic  primitive.ObjectID
+	quote  *models.MessageQuote
+	thread primitive.ObjectID
+}
+
+/*
+route
+resolves the topic, the quote and the thread of the message of the author. The
+replies of a thread stay on the topic of the thread whatever topic they name.
+The author is notified when the topic or the message replied to are not valid
+*/
+func (g *GroupConnectionCredentials) route(topicID, replyTo string, thread bool) (messageRoute, bool) {
+
+	var route messageRoute
+
+	if replyTo != "" {
+
+		parent, err := g.parent(replyTo)
+		if err != nil {
+			g.WriteJSON(models.FormatWebsocketErrResponse(err, replyErrorCode(err)))
+			return route, false
+		}
+
+		route.quote = parent.Snapshot()
+
+		if thread {
+			route.thread = parent.ThreadRoot()
+
+			topicID = ""
+			if !parent.TopicID.IsZero() {
+				topicID = parent.TopicID.Hex()
+			}
+		}
+	}
+
+	topic, ok := g.topic(topicID)
+	if !ok {
+		return route, false
+	}
+
+	route.topic = topic
+
+	return route, true
+}
+
+// parent gets the message of the group the author replies to
+func (g *GroupConnectionCredentials) parent(replyTo string) (*models.GroupChatContentLog, error) {
+
+	id, err := primitive.ObjectIDFromHex(replyTo)
+	if err != nil {
+		return nil, models.ErrReplyNotFound
+	}
+
+	parent, err := g.hub.DBConn.GetGroupMessageDB(id)
+	if err == mongo.ErrNoDocuments {
+		return nil, models.ErrReplyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if parent.TargetID != g.Group().ID {
+		return nil, models.ErrReplyNotFound
+	}
+
+	return parent, nil
+}
+
+/*
+quote
+snapshot of the private message the author replies to, nil when the message is
+not a reply. The author is notified when the message replied to does not belong
+to the conversation
+*/
+func (p *P2PConnectionCredentials) quote(replyTo string) (*models.MessageQuote, bool) {
+
+	if replyTo == "" {
+		return nil, true
+	}
+
+	id, err := primitive.ObjectIDFromHex(replyTo)
+	if err != nil {
+		p.WriteJSON(models.FormatWebsocketErrResponse(models.ErrReplyNotFound, REPLY_NOT_FOUND))
+		return nil, false
+	}
+
+	parent, err := p.hub.DBConn.GetP2PMessageDB(id)
+	if err == mongo.ErrNoDocuments || (err == nil && !p.owns(parent)) {
+		err = models.ErrReplyNotFound
+	}
+	if err != nil {
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, replyErrorCode(err)))
+		return nil, false
+	}
+
+	return parent.Snapshot(), true
+}
+
+// owns checks the message was sent on the conversation of the connection
+func (p *P2PConnectionCredentials) owns(msg *models.P2PContentChatLog) bool {
+	author, target := msg.AuthorID.Hex(), msg.TargetID.Hex()
+	return (author == p.AuthorID && target == p.TargetID) || (author == p.TargetID && target == p.AuthorID)
+}
+
+// replyErrorCode code sent to the author when the message replied to can not be read
+func replyErrorCode(err error) int {
+	if err == models.ErrReplyNotFound {
+		return REPLY_NOT_FOUND
+	}
+	logger.StartLogger().ErrorLog(err.Error())
+	return DB_ERROR
+}
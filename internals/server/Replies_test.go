@@ -0,0 +1,220 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wechat-back/internals/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// replyDBMock serves the messages replied to and records the replies counted on the threads
+type replyDBMock struct {
+	*dbMock
+	group   map[primitive.ObjectID]*models.GroupChatContentLog
+	p2p     map[primitive.ObjectID]*models.P2PContentChatLog
+	threads chan primitive.ObjectID
+}
+
+func newReplyDBMock() *replyDBMock {
+	return &replyDBMock{
+		dbMock:  newDBMock(),
+		group:   map[primitive.ObjectID]*models.GroupChatContentLog{},
+		p2p:     map[primitive.ObjectID]*models.P2PContentChatLog{},
+		threads: make(chan primitive.ObjectID, 1),
+	}
+}
+
+func (m *replyDBMock) GetGroupMessageDB(id primitive.ObjectID) (*models.GroupChatContentLog, error) {
+	if msg, ok := m.group[id]; ok {
+		return msg, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *replyDBMock) GetP2PMessageDB(id primitive.ObjectID) (*models.P2PContentChatLog, error) {
+	if msg, ok := m.p2p[id]; ok {
+		return msg, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *replyDBMock) AddThreadReplyDB(root primitive.ObjectID) error {
+	m.threads <- root
+	return nil
+}
+
+// unstoredReplyDBMock fails the insertion of the messages
+type unstoredReplyDBMock struct {
+	*replyDBMock
+}
+
+func (m *unstoredReplyDBMock) InsertGroupMessageDB(payload any) (string, error) {
+	return "", errors.New("insert failed")
+}
+
+func readGroupText(t *testing.T, conn *websocket.Conn) models.GroupChatTextLog {
+	var res models.GroupChatTextLog
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	assert.Nil(t, conn.ReadJSON(&res))
+	return res
+}
+
+// TestGroupReplies tests the quotes and the threads of the group messages
+func TestGroupReplies(t *testing.T) {
+
+	t.Run("GroupReplies - Replies quote the parent message", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newReplyDBMock()
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{member}}
+
+		parent := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group.ID, AuthorID: member, AuthorName: "alice", BodyType: models.MESSAGE_TYPE_TEXT, Body: "lunch?"}
+		db.group[parent.ID] = parent
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "yes", ReplyTo: parent.ID.Hex()})
+
+		res := readGroupText(t, conn)
+		assert.Equal(t, "yes", res.Body)
+		assert.Equal(t, parent.ID, res.Quote.MessageID)
+		assert.Equal(t, "lunch?", res.Quote.Body)
+		assert.Equal(t, "alice", res.Quote.AuthorName)
+		assert.True(t, res.ThreadID.IsZero())
+
+		select {
+		case <-db.threads:
+			t.Fatal("quotes are not thread replies")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("GroupReplies - Thread replies stay on the thread and topic of the root", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newReplyDBMock()
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		topic := models.FormatGroupTopic("releases", member)
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{member}, Topics: []models.GroupTopic{topic}}
+
+		root := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group.ID, TopicID: topic.ID, Body: "v1.2 is out"}
+		reply := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group.ID, TopicID: topic.ID, ThreadID: root.ID, Body: "nice"}
+		db.group[root.ID] = root
+		db.group[reply.ID] = reply
+
+		conn := connectGroupPeer(t, member, group)
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "changelog?", ReplyTo: reply.ID.Hex(), Thread: true})
+
+		res := readGroupText(t, conn)
+		assert.Equal(t, root.ID, res.ThreadID)
+		assert.Equal(t, topic.ID, res.TopicID)
+		assert.Equal(t, reply.ID, res.Quote.MessageID)
+
+		select {
+		case counted := <-db.threads:
+			assert.Equal(t, root.ID, counted)
+		case <-time.After(2 * time.Second):
+			t.Fatal("reply not counted on the thread")
+		}
+	})
+
+	t.Run("GroupReplies - Replies not stored are not counted", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := &unstoredReplyDBMock{newReplyDBMock()}
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{member}}
+
+		root := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group.ID, Body: "root"}
+		db.group[root.ID] = root
+
+		conn := connectGroupPeer(t, member, group)
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "in thread", ReplyTo: root.ID.Hex(), Thread: true})
+
+		// the reply is delivered before the insertion fails on every retry
+		readGroupText(t, conn)
+		assert.Equal(t, DB_ERROR, readErrCode(t, conn))
+
+		select {
+		case <-db.threads:
+			t.Fatal("reply counted without being stored")
+		default:
+		}
+	})
+
+	t.Run("GroupReplies - Messages of other groups can not be replied", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newReplyDBMock()
+		WebsocketHUB.DBConn = db
+
+		member := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{member}}
+
+		foreign := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: primitive.NewObjectID(), Body: "secret"}
+		db.group[foreign.ID] = foreign
+
+		conn := connectGroupPeer(t, member, group)
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello", ReplyTo: foreign.ID.Hex()})
+		assert.Equal(t, REPLY_NOT_FOUND, readErrCode(t, conn))
+
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello", ReplyTo: primitive.NewObjectID().Hex(), Thread: true})
+		assert.Equal(t, REPLY_NOT_FOUND, readErrCode(t, conn))
+	})
+}
+
+// TestP2PReplies tests the quotes of the private messages
+func TestP2PReplies(t *testing.T) {
+
+	t.Run("P2PReplies - Replies quote messages of the conversation only", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newReplyDBMock()
+		WebsocketHUB.DBConn = db
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+
+		parent := &models.P2PContentChatLog{ID: primitive.NewObjectID(), AuthorID: bob, TargetID: alice, BodyType: models.MESSAGE_TYPE_MEDIA_IMAGES, Body: "look", Placeholders: []string{"thumb.jpg"}}
+		foreign := &models.P2PContentChatLog{ID: primitive.NewObjectID(), AuthorID: bob, TargetID: primitive.NewObjectID(), Body: "secret"}
+		db.p2p[parent.ID] = parent
+		db.p2p[foreign.ID] = foreign
+
+		conn := connectCallPeer(t, alice, bob)
+
+		conn.WriteJSON(models.InboundP2PTextMessage{Body: "hello", ReplyTo: foreign.ID.Hex()})
+		assert.Equal(t, REPLY_NOT_FOUND, readErrCode(t, conn))
+
+		conn.WriteJSON(models.InboundP2PTextMessage{Body: "wow", ReplyTo: parent.ID.Hex()})
+
+		var res models.P2PTextChatLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&res))
+		assert.Equal(t, "wow", res.Body)
+		assert.Equal(t, parent.ID, res.Quote.MessageID)
+		assert.Equal(t, "thumb.jpg", res.Quote.Thumbnail)
+	})
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// DEFAULT_SCHEDULE_SWEEP time between the checks for scheduled messages that are due
+	DEFAULT_SCHEDULE_SWEEP = 10 * time.Second
+
+	// SCHEDULE_LEASE time a node holds a claimed message, if the node dies another one sends it after
+	SCHEDULE_LEASE = time.Minute
+)
+
+// scheduleRefused reason the author can no longer send the scheduled message, it is not retried
+type scheduleRefused struct {
+	error
+}
+
+/*
+MessageScheduler
+sends the scheduled messages once they are due through the chat logs and the
+hub, the messages live on the database so they survive the restarts of the nodes
+*/
+type MessageScheduler struct {
+	db       database.DBHUB
+	hub      *WebsocketPanel
+	interval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// StartMessageScheduler starts checking for due messages every interval
+func StartMessageScheduler(db database.DBHUB, hub *WebsocketPanel, interval time.Duration) *MessageScheduler {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &MessageScheduler{
+		db:       db,
+		hub:      hub,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	s.wg.Add(1)
+	go s.run()
+
+	return s
+}
+
+// Stop stops the scheduler and waits for the running sweep to finish
+func (s *MessageScheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *MessageScheduler) run() {
+
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+// sweep sends every message that is due
+func (s *MessageScheduler) sweep() {
+
+	alog := logger.StartLogger()
+
+	for s.ctx.Err() == nil {
+
+		msg, err := s.db.ClaimScheduledMessageDB(time.Now(), SCHEDULE_LEASE)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				alog.ErrorLog(err.Error())
+			}
+			return
+		}
+
+		// the claim stays until the lease expires, the message is retried then
+		err = s.dispatch(msg)
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("scheduled message %s not sent: %v", msg.ID.Hex(), err))
+		}
+	}
+}
+
+/*
+dispatch
+sends the message to its conversation, a message the author can no longer send
+is kept as failed and the author is told why
+*/
+func (s *MessageScheduler) dispatch(msg *models.ScheduledMessage) error {
+
+	now := time.Now()
+
+	var err error
+	if msg.Conversation == models.CONVERSATION_GROUP {
+		err = s.sendGroup(msg, now)
+	} else {
+		err = s.sendP2P(msg, now)
+	}
+
+	var refused scheduleRefused
+	if errors.As(err, &refused) {
+
+		err = s.db.FailScheduledMessageDB(msg.ID, refused.Error())
+		if err != nil {
+			return err
+		}
+
+		if s.hub != nil {
+			s.hub.NotifyUsers([]primitive.ObjectID{msg.AuthorID}, models.WebsocketEvent{
+				Event: models.EVENT_SCHEDULED_MESSAGE_FAILED,
+				Data:  models.ScheduleFailedEvent{MessageID: msg.ID, Reason: refused.Error()},
+			})
+		}
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.db.CompleteScheduledMessageDB(msg.ID)
+	if err != nil && err != database.ErrNoDeleted {
+		return err
+	}
+
+	return nil
+}
+
+/*
+sendGroup
+checks the author still posts on the group, stores the message and delivers it
+to the participants
+*/
+func (s *MessageScheduler) sendGroup(msg *models.ScheduledMessage, now time.Time) error {
+
+	group, err := s.db.GetGroupDB(msg.GroupID)
+	if err == mongo.ErrNoDocuments {
+		return scheduleRefused{models.ErrScheduleNotAllowed}
+	} else if err != nil {
+		return err
+	}
+
+	if group.PendingDeletion() || !group.Can(msg.AuthorID, models.PERMISSION_SEND_MESSAGES) {
+		return scheduleRefused{models.ErrScheduleNotAllowed}
+	}
+
+	err = group.CheckPost(msg.AuthorID, now)
+	if err != nil {
+		return scheduleRefused{err}
+	}
+
+	if !msg.TopicID.IsZero() {
+		_, err = group.CheckTopic(msg.TopicID.Hex())
+		if err != nil {
+			return scheduleRefused{err}
+		}
+	}
+
+	// claimed at the send time so a retry of the message finds its own claim
+	err = ClaimSlowMode(s.db, group, msg.AuthorID, msg.SendAt)
+	if errors.Is(err, models.ErrSlowMode) {
+		return scheduleRefused{err}
+	} else if err != nil {
+		return err
+	}
+
+	payload := msg.GroupLog(now)
+	payload.ExpireAt = models.MessageExpiry(group.MessageTimer, now)
+
+	// a retried message was already stored by the node that died
+	_, err = s.db.InsertGroupMessageDB(payload)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	if s.hub == nil {
+		return nil
+	}
+
+	s.hub.DeliverGroupMessage(group.ID, group.Participants, payload)
+
+	users, err := s.db.GetUsersByIDDB(group.Participants)
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+		return nil
+	}
+
+	for _, user := range users {
+		if user.ID == msg.AuthorID {
+			continue
+		}
+		if _, online := s.hub.locateUser(PRESENCE_GROUP, groupPresenceID(group.ID.Hex(), user.ID.Hex())); online {
+			continue
+		}
+		if push, ok := offlinePush(group, user.ID, payload, now); ok {
+			s.hub.sendPushNotification(user.Credentials.PushToken, push)
+		}
+	}
+
+	return nil
+}
+
+/*
+sendP2P
+stores the private message and delivers it to the author and the target, the
+target is notified when offline
+*/
+func (s *MessageScheduler) sendP2P(msg *models.ScheduledMessage, now time.Time) error {
+
+	users, err := s.db.GetUsersByIDDB([]primitive.ObjectID{msg.TargetID})
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return scheduleRefused{models.ErrScheduleNotAllowed}
+	}
+
+	timer, err := s.db.GetP2PMessageTimerDB(models.P2PConversationID(msg.AuthorID, msg.TargetID))
+	if err != nil {
+		return err
+	}
+
+	payload := msg.P2PLog(now)
+	payload.ExpireAt = models.MessageExpiry(timer, now)
+
+	// a retried message was already stored by the node that died
+	_, err = s.db.InsertP2PMessageDB(payload)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	if s.hub == nil {
+		return nil
+	}
+
+	s.hub.DeliverP2PMessage(msg.AuthorID, msg.TargetID, payload)
+
+	if _, online := s.hub.locateUser(PRESENCE_P2P, msg.TargetID.Hex()); !online {
+		s.hub.sendPushNotification(users[0].Credentials.PushToken, payload)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// scheduleDBMock hands out the claimed messages once and records what the scheduler does with them
+type scheduleDBMock struct {
+	database.DBHUB
+	claims    []*models.ScheduledMessage
+	group     *models.Group
+	inserted  []any
+	insertErr error
+	completed []primitive.ObjectID
+	failed    map[primitive.ObjectID]string
+	lastPost  time.Time
+}
+
+func (m *scheduleDBMock) ClaimScheduledMessageDB(now time.Time, lease time.Duration) (*models.ScheduledMessage, error) {
+	if len(m.claims) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	msg := m.claims[0]
+	m.claims = m.claims[1:]
+	return msg, nil
+}
+
+func (m *scheduleDBMock) GetGroupDB(groupID string) (*models.Group, error) {
+	if m.group == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return m.group, nil
+}
+
+func (m *scheduleDBMock) ClaimGroupPostDB(id, user primitive.ObjectID, now time.Time, wait time.Duration) (time.Time, error) {
+	if m.lastPost.After(now.Add(-wait)) {
+		return m.lastPost, nil
+	}
+	m.lastPost = now
+	return time.Time{}, nil
+}
+
+func (m *scheduleDBMock) GetUsersByIDDB(ids []primitive.ObjectID) ([]*models.User, error) {
+	res := []*models.User{}
+	for _, id := range ids {
+		res = append(res, &models.User{ID: id})
+	}
+	return res, nil
+}
+
+func (m *scheduleDBMock) GetP2PMessageTimerDB(conversationID string) (int, error) {
+	return 0, nil
+}
+
+func (m *scheduleDBMock) InsertP2PMessageDB(payload any) (string, error) {
+	m.inserted = append(m.inserted, payload)
+	return "", m.insertErr
+}
+
+func (m *scheduleDBMock) InsertGroupMessageDB(payload any) (string, error) {
+	m.inserted = append(m.inserted, payload)
+	return "", m.insertErr
+}
+
+func (m *scheduleDBMock) CompleteScheduledMessageDB(id primitive.ObjectID) error {
+	m.completed = append(m.completed, id)
+	return nil
+}
+
+func (m *scheduleDBMock) FailScheduledMessageDB(id primitive.ObjectID, reason string) error {
+	m.failed[id] = reason
+	return nil
+}
+
+// TestMessageScheduler test the dispatch of the scheduled messages that are due
+func TestMessageScheduler(t *testing.T) {
+
+	t.Run("MessageScheduler - Private message delivered", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+		author := connectCallPeer(t, alice, bob)
+		target := connectCallPeer(t, bob, alice)
+
+		msg := &models.ScheduledMessage{ID: primitive.NewObjectID(), AuthorID: alice, AuthorName: "alice", Conversation: models.CONVERSATION_P2P, TargetID: bob, Body: "happy birthday"}
+		db := &scheduleDBMock{claims: []*models.ScheduledMessage{msg}, failed: map[primitive.ObjectID]string{}}
+
+		s := &MessageScheduler{db: db, hub: WebsocketHUB, ctx: context.Background()}
+		s.sweep()
+
+		assert.Equal(t, []primitive.ObjectID{msg.ID}, db.completed)
+		assert.Len(t, db.inserted, 1)
+
+		for _, conn := range []*websocket.Conn{author, target} {
+			var res models.P2PTextChatLog
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			assert.Nil(t, conn.ReadJSON(&res))
+			assert.Equal(t, msg.ID, res.ID)
+			assert.Equal(t, "happy birthday", res.Body)
+		}
+	})
+
+	t.Run("MessageScheduler - Group message of a muted author fails", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{
+			ID:           primitive.NewObjectID(),
+			GroupID:      "123456789",
+			OwnerID:      primitive.NewObjectID(),
+			Participants: []primitive.ObjectID{alice},
+			Mutes:        map[string]time.Time{alice.Hex(): time.Now().Add(time.Hour)},
+		}
+
+		conn := connectCallPeer(t, alice, primitive.NewObjectID())
+
+		msg := &models.ScheduledMessage{ID: primitive.NewObjectID(), AuthorID: alice, Conversation: models.CONVERSATION_GROUP, TargetID: group.ID, GroupID: group.GroupID, Body: "hello"}
+		db := &scheduleDBMock{claims: []*models.ScheduledMessage{msg}, group: group, failed: map[primitive.ObjectID]string{}}
+
+		s := &MessageScheduler{db: db, hub: WebsocketHUB, ctx: context.Background()}
+		s.sweep()
+
+		assert.Empty(t, db.inserted)
+		assert.Empty(t, db.completed)
+		assert.Contains(t, db.failed[msg.ID], models.ErrMemberMuted.Error())
+
+		var event struct {
+			Event string                     `json:"event"`
+			Data  models.ScheduleFailedEvent `json:"data"`
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&event))
+		assert.Equal(t, models.EVENT_SCHEDULED_MESSAGE_FAILED, event.Event)
+		assert.Equal(t, msg.ID, event.Data.MessageID)
+	})
+
+	t.Run("MessageScheduler - Group message already stored by a dead node", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", OwnerID: alice, Participants: []primitive.ObjectID{alice}}
+
+		msg := &models.ScheduledMessage{ID: primitive.NewObjectID(), AuthorID: alice, Conversation: models.CONVERSATION_GROUP, TargetID: group.ID, GroupID: group.GroupID, Body: "hello"}
+		db := &scheduleDBMock{
+			claims:    []*models.ScheduledMessage{msg},
+			group:     group,
+			insertErr: mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}},
+			failed:    map[primitive.ObjectID]string{},
+		}
+
+		s := &MessageScheduler{db: db, hub: WebsocketHUB, ctx: context.Background()}
+		s.sweep()
+
+		assert.Equal(t, []primitive.ObjectID{msg.ID}, db.completed)
+		assert.Empty(t, db.failed)
+	})
+
+	t.Run("MessageScheduler - Group message in the slow mode of the author fails", func(t *testing.T) {
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", OwnerID: primitive.NewObjectID(), Participants: []primitive.ObjectID{alice}, SlowMode: 60}
+
+		msg := &models.ScheduledMessage{ID: primitive.NewObjectID(), AuthorID: alice, Conversation: models.CONVERSATION_GROUP, TargetID: group.ID, GroupID: group.GroupID, Body: "hello", SendAt: time.Now().Truncate(time.Millisecond)}
+		db := &scheduleDBMock{claims: []*models.ScheduledMessage{msg}, group: group, failed: map[primitive.ObjectID]string{}, lastPost: msg.SendAt.Add(-10 * time.Second)}
+
+		s := &MessageScheduler{db: db, ctx: context.Background()}
+		s.sweep()
+
+		assert.Empty(t, db.inserted)
+		assert.Contains(t, db.failed[msg.ID], models.ErrSlowMode.Error())
+	})
+
+	t.Run("MessageScheduler - Group message retried in the slow mode of its own claim", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", OwnerID: primitive.NewObjectID(), Participants: []primitive.ObjectID{alice}, SlowMode: 60}
+
+		// the dead node claimed the post at the send time before storing the message
+		msg := &models.ScheduledMessage{ID: primitive.NewObjectID(), AuthorID: alice, Conversation: models.CONVERSATION_GROUP, TargetID: group.ID, GroupID: group.GroupID, Body: "hello", SendAt: time.Now().Truncate(time.Millisecond)}
+		db := &scheduleDBMock{claims: []*models.ScheduledMessage{msg}, group: group, failed: map[primitive.ObjectID]string{}, lastPost: msg.SendAt}
+
+		s := &MessageScheduler{db: db, hub: WebsocketHUB, ctx: context.Background()}
+		s.sweep()
+
+		assert.Len(t, db.inserted, 1)
+		assert.Equal(t, []primitive.ObjectID{msg.ID}, db.completed)
+		assert.Empty(t, db.failed)
+	})
+
+	t.Run("MessageScheduler - Deleted group fails", func(t *testing.T) {
+
+		msg := &models.ScheduledMessage{ID: primitive.NewObjectID(), AuthorID: primitive.NewObjectID(), Conversation: models.CONVERSATION_GROUP, GroupID: "123456789", Body: "hello"}
+		db := &scheduleDBMock{claims: []*models.ScheduledMessage{msg}, failed: map[primitive.ObjectID]string{}}
+
+		s := &MessageScheduler{db: db, ctx: context.Background()}
+		s.sweep()
+
+		assert.Equal(t, models.ErrScheduleNotAllowed.Error(), db.failed[msg.ID])
+	})
+}
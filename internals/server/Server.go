@@ -8,7 +8,9 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	"wechat-back/internals/database"
 	"wechat-back/internals/logger"
+	"wechat-back/providers/media"
 )
 
 func StartServer(cfg ServeConfig) error {
@@ -30,6 +32,36 @@ func StartServer(cfg ServeConfig) error {
 
 	StartWebsocketService()
 
+	db := database.StartDatabase()
+
+	err := db.CreateSearchIndexesDB()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+
+	err = db.CreateGalleryIndexesDB()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+
+	err = db.CreateTimerIndexesDB()
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+
+	// without the media service the janitors remove the messages and keep their media
+	var provider media.MediaHUB
+	service, err := media.NewMediaService()
+	if err != nil {
+		alog.ErrorLog(fmt.Sprintf("media service not available, the media of deleted groups and expired messages is not removed: %v", err))
+	} else {
+		provider = service
+	}
+
+	WebsocketHUB.Deletions = StartGroupDeletionJanitor(db, provider, WebsocketHUB, DEFAULT_DELETION_SWEEP)
+	WebsocketHUB.Scheduler = StartMessageScheduler(db, WebsocketHUB, DEFAULT_SCHEDULE_SWEEP)
+	WebsocketHUB.Expiry = StartMessageExpiryJanitor(db, provider, WebsocketHUB, DEFAULT_EXPIRY_SWEEP)
+
 	if cfg.ENV == "PROD" || cfg.ENV == "DIST" {
 
 		go func() {
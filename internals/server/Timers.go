@@ -0,0 +1,281 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/logger"
+	"wechat-back/internals/models"
+	"wechat-back/providers/media"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// ENVELOPE_GROUP_EVENT envelope that carries an event to every connection to a group
+	ENVELOPE_GROUP_EVENT = "group_event"
+
+	// DEFAULT_EXPIRY_SWEEP time between the checks for expired messages
+	DEFAULT_EXPIRY_SWEEP = 10 * time.Second
+
+	// EXPIRY_LEASE time a node holds a claimed message, if the node dies another one removes it after
+	EXPIRY_LEASE = 5 * time.Minute
+)
+
+/*
+MessageExpiryJanitor
+removes the messages of the conversations with disappearing messages once they
+expire, the media is removed from the storage and the conversation is told so
+the clients drop the message
+*/
+type MessageExpiryJanitor struct {
+	db       database.DBHUB
+	provider media.MediaHUB
+	hub      *WebsocketPanel
+	interval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// StartMessageExpiryJanitor starts checking for expired messages every interval
+func StartMessageExpiryJanitor(db database.DBHUB, provider media.MediaHUB, hub *WebsocketPanel, interval time.Duration) *MessageExpiryJanitor {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &MessageExpiryJanitor{
+		db:       db,
+		provider: provider,
+		hub:      hub,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	j.wg.Add(1)
+	go j.run()
+
+	return j
+}
+
+// Stop stops the janitor and waits for the running sweep to finish
+func (j *MessageExpiryJanitor) Stop() {
+	j.cancel()
+	j.wg.Wait()
+}
+
+func (j *MessageExpiryJanitor) run() {
+
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+			j.sweep()
+		}
+	}
+}
+
+// sweep removes every expired group and private message
+func (j *MessageExpiryJanitor) sweep() {
+
+	alog := logger.StartLogger()
+
+	for j.ctx.Err() == nil {
+
+		msg, err := j.db.ClaimExpiredGroupMessageDB(time.Now(), EXPIRY_LEASE)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				alog.ErrorLog(err.Error())
+			}
+			break
+		}
+
+		// the claim stays until the lease expires, the removal is retried then
+		err = j.expireGroupMessage(msg)
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("group message %s not expired: %v", msg.ID.Hex(), err))
+		}
+	}
+
+	for j.ctx.Err() == nil {
+
+		msg, err := j.db.ClaimExpiredP2PMessageDB(time.Now(), EXPIRY_LEASE)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				alog.ErrorLog(err.Error())
+			}
+			return
+		}
+
+		err = j.expireP2PMessage(msg)
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("private message %s not expired: %v", msg.ID.Hex(), err))
+		}
+	}
+}
+
+// expireGroupMessage removes the group message and tells the connections to the group
+func (j *MessageExpiryJanitor) expireGroupMessage(msg *models.GroupChatContentLog) error {
+
+	err := j.removeMedia(msg.ID, msg.BodyType, msg.ContentID, msg.Media, msg.Placeholders)
+	if err != nil {
+		return err
+	}
+
+	err = j.db.DeleteExpiredGroupMessageDB(msg.ID, msg.ThreadID)
+	if err != nil && err != database.ErrNoDeleted {
+		return err
+	}
+
+	if j.hub != nil {
+		j.hub.PublishGroupEvent(msg.TargetID, models.WebsocketEvent{
+			Event: models.EVENT_MESSAGE_EXPIRED,
+			Data:  models.MessageExpiredEvent{MessageID: msg.ID, AuthorID: msg.AuthorID, TargetID: msg.TargetID},
+		})
+	}
+
+	return nil
+}
+
+// expireP2PMessage removes the private message and tells the author and the target
+func (j *MessageExpiryJanitor) expireP2PMessage(msg *models.P2PContentChatLog) error {
+
+	err := j.removeMedia(msg.ID, msg.BodyType, msg.ContentID, msg.Media, msg.Placeholders)
+	if err != nil {
+		return err
+	}
+
+	err = j.db.DeleteExpiredP2PMessageDB(msg.ID)
+	if err != nil && err != database.ErrNoDeleted {
+		return err
+	}
+
+	if j.hub != nil {
+		j.hub.NotifyUsers([]primitive.ObjectID{msg.AuthorID, msg.TargetID}, models.WebsocketEvent{
+			Event: models.EVENT_MESSAGE_EXPIRED,
+			Data:  models.MessageExpiredEvent{MessageID: msg.ID, AuthorID: msg.AuthorID, TargetID: msg.TargetID},
+		})
+	}
+
+	return nil
+}
+
+/*
+removeMedia
+removes the media of the expired message from the storage before its document,
+once the document is gone there is no way to find the media again. Forwarded
+copies share the media, the placeholders and the video of the message, every
+one of them they still use is kept
+*/
+func (j *MessageExpiryJanitor) removeMedia(id primitive.ObjectID, bodyType int, contentID string, content, placeholders []string) error {
+
+	if j.provider == nil {
+		return nil
+	}
+
+	urls := slices.DeleteFunc(slices.Concat(content, placeholders), func(url string) bool {
+		return url == ""
+	})
+
+	video := bodyType == models.MESSAGE_TYPE_MEDIA_VIDEOS && contentID != ""
+
+	refs := slices.Clone(urls)
+	if video {
+		refs = append(refs, contentID)
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	shared, err := j.db.GetSharedMediaDB(id, refs)
+	if err != nil {
+		return err
+	}
+
+	alog := logger.StartLogger()
+
+	urls = slices.DeleteFunc(urls, func(url string) bool {
+		return slices.Contains(shared, url)
+	})
+
+	if len(urls) > 0 {
+		err = j.provider.DeleteContent(urls)
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("media of message %s not deleted: %v", id.Hex(), err))
+		}
+	}
+
+	if video && !slices.Contains(shared, contentID) {
+		err = j.provider.DeleteVideos([]string{contentID})
+		if err != nil {
+			alog.ErrorLog(fmt.Sprintf("video of message %s not deleted: %v", id.Hex(), err))
+		}
+	}
+
+	return nil
+}
+
+/*
+PublishGroupEvent
+writes the event on every connection to the group, wherever node they live
+*/
+func (h *WebsocketPanel) PublishGroupEvent(groupID primitive.ObjectID, event models.WebsocketEvent) {
+
+	alog := logger.StartLogger()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return
+	}
+
+	h.writeGroupConnections(groupID.Hex(), data)
+
+	err = h.publishBroadcast(ENVELOPE_GROUP_EVENT, groupID.Hex(), data)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+	}
+}
+
+// writeGroupConnections writes the event on the local connections to the group
+func (h *WebsocketPanel) writeGroupConnections(groupID string, event []byte) {
+	h.GroupConnections.Range(func(key string, conn *GroupConnectionCredentials) bool {
+		if conn.TargetID == groupID {
+			conn.WriteMessage(event)
+		}
+		return true
+	})
+}
+
+/*
+expiry
+returns when a message the author sends now to the target expires, nil when the
+conversation has no timer. The author is notified when the timer can not be read
+*/
+func (p *P2PConnectionCredentials) expiry(target primitive.ObjectID) (*time.Time, bool) {
+
+	timer, err := p.hub.DBConn.GetP2PMessageTimerDB(models.P2PConversationID(p.AuthorData.ID, target))
+	if err != nil {
+		logger.StartLogger().ErrorLog(err.Error())
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, DB_ERROR))
+		return nil, false
+	}
+
+	return models.MessageExpiry(timer, time.Now()), true
+}
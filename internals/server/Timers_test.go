@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wechat-back/internals/database"
+	"wechat-back/internals/models"
+	"wechat-back/providers/media"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// expiryDBMock hands out the claimed messages once and records the removals
+type expiryDBMock struct {
+	database.DBHUB
+	groupClaims []*models.GroupChatContentLog
+	p2pClaims   []*models.P2PContentChatLog
+	shared      []string
+	deleted     []primitive.ObjectID
+	threads     []primitive.ObjectID
+}
+
+func (m *expiryDBMock) ClaimExpiredGroupMessageDB(now time.Time, lease time.Duration) (*models.GroupChatContentLog, error) {
+	if len(m.groupClaims) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	msg := m.groupClaims[0]
+	m.groupClaims = m.groupClaims[1:]
+	return msg, nil
+}
+
+func (m *expiryDBMock) ClaimExpiredP2PMessageDB(now time.Time, lease time.Duration) (*models.P2PContentChatLog, error) {
+	if len(m.p2pClaims) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	msg := m.p2pClaims[0]
+	m.p2pClaims = m.p2pClaims[1:]
+	return msg, nil
+}
+
+func (m *expiryDBMock) GetSharedMediaDB(id primitive.ObjectID, media []string) ([]string, error) {
+	return m.shared, nil
+}
+
+func (m *expiryDBMock) DeleteExpiredGroupMessageDB(id, thread primitive.ObjectID) error {
+	m.deleted = append(m.deleted, id)
+	m.threads = append(m.threads, thread)
+	return nil
+}
+
+func (m *expiryDBMock) DeleteExpiredP2PMessageDB(id primitive.ObjectID) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+type expiryEvent struct {
+	Event string                     `json:"event"`
+	Data  models.MessageExpiredEvent `json:"data"`
+}
+
+// TestMessageExpiryJanitor test the removal of the expired messages
+func TestMessageExpiryJanitor(t *testing.T) {
+
+	t.Run("MessageExpiryJanitor - Group message removed with its media", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), GroupID: "123456789", Participants: []primitive.ObjectID{alice}}
+
+		conn := connectGroupPeer(t, alice, group)
+
+		msg := &models.GroupChatContentLog{
+			ID:           primitive.NewObjectID(),
+			TargetID:     group.ID,
+			AuthorID:     alice,
+			ThreadID:     primitive.NewObjectID(),
+			Media:        []string{"https://cdn/content/a.jpg", "https://cdn/content/b.jpg"},
+			Placeholders: []string{"https://cdn/thumbs/a.jpg", "https://cdn/thumbs/b.jpg"},
+		}
+
+		var removed []string
+		provider := &media.MediaMock{
+			DeleteContentMockFunc: func(urls []string) error {
+				removed = append(removed, urls...)
+				return nil
+			},
+		}
+
+		db := &expiryDBMock{groupClaims: []*models.GroupChatContentLog{msg}}
+		j := &MessageExpiryJanitor{db: db, provider: provider, hub: WebsocketHUB, ctx: context.Background()}
+		j.sweep()
+
+		assert.Equal(t, []primitive.ObjectID{msg.ID}, db.deleted)
+		assert.Equal(t, []primitive.ObjectID{msg.ThreadID}, db.threads)
+		assert.ElementsMatch(t, append(msg.Media, msg.Placeholders...), removed)
+
+		var event expiryEvent
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&event))
+		assert.Equal(t, models.EVENT_MESSAGE_EXPIRED, event.Event)
+		assert.Equal(t, msg.ID, event.Data.MessageID)
+		assert.Equal(t, group.ID, event.Data.TargetID)
+	})
+
+	t.Run("MessageExpiryJanitor - Private message keeps the media of forwarded copies", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+
+		conn := connectCallPeer(t, bob, alice)
+
+		msg := &models.P2PContentChatLog{
+			ID:           primitive.NewObjectID(),
+			AuthorID:     alice,
+			TargetID:     bob,
+			Media:        []string{"https://cdn/content/a.jpg", "https://cdn/content/b.jpg"},
+			Placeholders: []string{"https://cdn/thumbs/a.jpg", "https://cdn/thumbs/b.jpg"},
+		}
+
+		var removed []string
+		provider := &media.MediaMock{
+			DeleteContentMockFunc: func(urls []string) error {
+				removed = append(removed, urls...)
+				return nil
+			},
+		}
+
+		db := &expiryDBMock{p2pClaims: []*models.P2PContentChatLog{msg}, shared: []string{"https://cdn/content/a.jpg", "https://cdn/thumbs/a.jpg"}}
+		j := &MessageExpiryJanitor{db: db, provider: provider, hub: WebsocketHUB, ctx: context.Background()}
+		j.sweep()
+
+		assert.Equal(t, []primitive.ObjectID{msg.ID}, db.deleted)
+		assert.Equal(t, []string{"https://cdn/content/b.jpg", "https://cdn/thumbs/b.jpg"}, removed)
+
+		var event expiryEvent
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&event))
+		assert.Equal(t, models.EVENT_MESSAGE_EXPIRED, event.Event)
+		assert.Equal(t, msg.ID, event.Data.MessageID)
+		assert.Equal(t, alice, event.Data.AuthorID)
+	})
+
+	t.Run("MessageExpiryJanitor - Videos removed unless forwarded", func(t *testing.T) {
+
+		alice := primitive.NewObjectID()
+		group := primitive.NewObjectID()
+
+		kept := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group, AuthorID: alice, BodyType: models.MESSAGE_TYPE_MEDIA_VIDEOS, ContentID: "101$video-a", Media: []string{"https://stream/101/a.m3u8"}, Placeholders: []string{"https://stream/101/a.jpg"}}
+		removedVideo := &models.GroupChatContentLog{ID: primitive.NewObjectID(), TargetID: group, AuthorID: alice, BodyType: models.MESSAGE_TYPE_MEDIA_VIDEOS, ContentID: "102$video-b", Media: []string{"https://stream/102/b.m3u8"}, Placeholders: []string{"https://stream/102/b.jpg"}}
+
+		var videos []string
+		provider := &media.MediaMock{
+			DeleteVideosMockFunc: func(contentIDs []string) error {
+				videos = append(videos, contentIDs...)
+				return nil
+			},
+		}
+
+		db := &expiryDBMock{groupClaims: []*models.GroupChatContentLog{kept, removedVideo}, shared: []string{"101$video-a", "https://stream/101/a.m3u8", "https://stream/101/a.jpg"}}
+		j := &MessageExpiryJanitor{db: db, provider: provider, ctx: context.Background()}
+		j.sweep()
+
+		assert.Equal(t, []primitive.ObjectID{kept.ID, removedVideo.ID}, db.deleted)
+		assert.Equal(t, []string{"102$video-b"}, videos)
+	})
+}
+
+// TestMessageTimers test the expiry of the messages sent to conversations with a timer
+func TestMessageTimers(t *testing.T) {
+
+	t.Run("MessageTimers - Private messages follow the timer of the conversation", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newDBMock()
+		db.timer = 60
+		WebsocketHUB.DBConn = db
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+
+		conn := connectCallPeer(t, alice, bob)
+
+		sent := time.Now()
+		conn.WriteJSON(models.InboundP2PTextMessage{Body: "burn after reading"})
+
+		var res models.P2PTextChatLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&res))
+		assert.NotNil(t, res.ExpireAt)
+		assert.WithinDuration(t, sent.Add(time.Minute), *res.ExpireAt, 2*time.Second)
+	})
+
+	t.Run("MessageTimers - Group messages follow the timer of the group", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		db := newDBMock()
+		WebsocketHUB.DBConn = db
+
+		alice := primitive.NewObjectID()
+		group := &models.Group{ID: primitive.NewObjectID(), Participants: []primitive.ObjectID{alice}, MessageTimer: 3600}
+
+		conn := connectGroupPeer(t, alice, group)
+
+		sent := time.Now()
+		conn.WriteJSON(models.InboundGroupTextMessage{Body: "hello"})
+
+		var res models.GroupChatTextLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&res))
+		assert.NotNil(t, res.ExpireAt)
+		assert.WithinDuration(t, sent.Add(time.Hour), *res.ExpireAt, 2*time.Second)
+	})
+
+	t.Run("MessageTimers - Messages without timer do not expire", func(t *testing.T) {
+
+		StartWebsocketService()
+		defer StopWebsocketService()
+
+		WebsocketHUB.DBConn = newDBMock()
+
+		alice := primitive.NewObjectID()
+		bob := primitive.NewObjectID()
+
+		conn := connectCallPeer(t, alice, bob)
+
+		conn.WriteJSON(models.InboundP2PTextMessage{Body: "keep me"})
+
+		var res models.P2PTextChatLog
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		assert.Nil(t, conn.ReadJSON(&res))
+		assert.Nil(t, res.ExpireAt)
+	})
+}
@@ -3,8 +3,11 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 	"sync"
 	"time"
+	"wechat-back/internals/backplane"
 	"wechat-back/internals/database"
 	"wechat-back/internals/logger"
 	"wechat-back/internals/models"
@@ -22,10 +25,10 @@ var WebsocketHUB *WebsocketPanel
 // WebsocketPanel central pannel that holds information about the websocket server
 type WebsocketPanel struct {
 	// P2PConnections holds all the p2p connections
-	P2PConnections map[string]P2PConnectionCredentials
+	P2PConnections *ConnectionRegistry[*P2PConnectionCredentials]
 
 	// GroupConnections holds all the group connections
-	GroupConnections map[string]GroupConnectionCredentials
+	GroupConnections *ConnectionRegistry[*GroupConnectionCredentials]
 
 	// Workerpool
 	WorkerPool *workerpool.WorkerPool
@@ -36,8 +39,38 @@ type WebsocketPanel struct {
 	// MediaProvider access for media provider
 	MediaProvider media.MediaHUB
 
-	// mux mutext
-	mux sync.Mutex
+	// Pusher sends the push notifications of the offline users, without it they are only logged
+	Pusher func(models.PushNotification) error
+
+	// Backplane shares messages and presence with the other server nodes
+	Backplane backplane.Backplane
+
+	// NodeID identifies this server node on the backplane
+	NodeID string
+
+	// subscription to the channel of this node
+	subscription backplane.Subscription
+
+	// broadcast subscription to the channel shared by every node
+	broadcast backplane.Subscription
+
+	// Calls signaling of the calls started on this node
+	Calls *CallManager
+
+	// Streams delivers the messages from the chat logs change streams when enabled
+	Streams *ChangeStreamListener
+
+	// Deletions runs the deletions of the groups once their grace period is over
+	Deletions *GroupDeletionJanitor
+
+	// Scheduler sends the scheduled messages once they are due
+	Scheduler *MessageScheduler
+
+	// Expiry removes the messages of the conversations with disappearing messages once they expire
+	Expiry *MessageExpiryJanitor
+
+	// stop signals the background routines of the hub
+	stop chan struct{}
 }
 
 // P2PConnectionCredentials holds necessary information about the user connection to a peer
@@ -48,6 +81,12 @@ type P2PConnectionCredentials struct {
 	TargetID        string
 	AuthorData      *models.User
 	TargetPushToken string
+
+	// hub the connection is registered on
+	hub *WebsocketPanel
+
+	// writeMux serializes the writes on the connection
+	writeMux sync.Mutex
 }
 
 // GroupConnectionCredentials holds necessary information about the user connection to a group
@@ -57,18 +96,91 @@ type GroupConnectionCredentials struct {
 	TargetID   string
 	AuthorData *models.User
 	TargetData *models.Group
+
+	// hub the connection is registered on
+	hub *WebsocketPanel
+
+	// writeMux serializes the writes on the connection
+	writeMux sync.Mutex
+
+	// dataMux guards the group data, it is refreshed when the group changes
+	dataMux sync.RWMutex
 }
 
 // StartWebsocketService starts websocket service
 func StartWebsocketService() {
 	WebsocketHUB = &WebsocketPanel{
-		mux:              sync.Mutex{},
-		P2PConnections:   make(map[string]P2PConnectionCredentials),
-		GroupConnections: make(map[string]GroupConnectionCredentials),
+		P2PConnections:   NewConnectionRegistry[*P2PConnectionCredentials](REGISTRY_SHARDS),
+		GroupConnections: NewConnectionRegistry[*GroupConnectionCredentials](REGISTRY_SHARDS),
 		WorkerPool:       workerpool.StartNewWorkerPool(10, 100),
+		stop:             make(chan struct{}),
+	}
+
+	WebsocketHUB.Calls = NewCallManager(WebsocketHUB)
+
+	WebsocketHUB.WorkerPool.StartPool()
+
+	WebsocketHUB.startBackplane()
+
+	if os.Getenv("WS_DELIVERY") == DELIVERY_CHANGE_STREAMS {
+		WebsocketHUB.Streams = StartChangeStreamListener(database.StartDatabase(), WebsocketHUB)
 	}
 }
 
+/*
+RegisterP2PConnection
+adds the p2p connection to the hub and announces it to the other nodes, the connection
+it replaces is closed so its listener ends without unregistering the new one
+*/
+func (h *WebsocketPanel) RegisterP2PConnection(c *P2PConnectionCredentials) {
+	c.hub = h
+	if previous, replaced := h.P2PConnections.Register(c.AuthorID, c); replaced && previous != c {
+		previous.Conn.Close()
+	}
+	h.setPresence(PRESENCE_P2P, c.AuthorID)
+}
+
+/*
+RegisterGroupConnection
+adds the group connection to the hub and announces it to the other nodes, the connection
+it replaces is closed so its listener ends without unregistering the new one
+*/
+func (h *WebsocketPanel) RegisterGroupConnection(c *GroupConnectionCredentials) {
+	c.hub = h
+	if previous, replaced := h.GroupConnections.Register(c.AuthorID, c); replaced && previous != c {
+		previous.Conn.Close()
+	}
+	h.setPresence(PRESENCE_GROUP, groupPresenceID(c.TargetID, c.AuthorID))
+}
+
+// WriteJSON writes the payload on the connection, only one write runs at a time
+func (p *P2PConnectionCredentials) WriteJSON(payload any) error {
+	p.writeMux.Lock()
+	defer p.writeMux.Unlock()
+	return p.Conn.WriteJSON(payload)
+}
+
+// WriteMessage writes an already encoded message on the connection
+func (p *P2PConnectionCredentials) WriteMessage(data []byte) error {
+	p.writeMux.Lock()
+	defer p.writeMux.Unlock()
+	return p.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// WriteJSON writes the payload on the connection, only one write runs at a time
+func (g *GroupConnectionCredentials) WriteJSON(payload any) error {
+	g.writeMux.Lock()
+	defer g.writeMux.Unlock()
+	return g.Conn.WriteJSON(payload)
+}
+
+// WriteMessage writes an already encoded message on the connection
+func (g *GroupConnectionCredentials) WriteMessage(data []byte) error {
+	g.writeMux.Lock()
+	defer g.writeMux.Unlock()
+	return g.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
 // StopWebsocketService stops the WebSocket server and deletes all connections
 func StopWebsocketService() {
 	alog := logger.StartLogger()
@@ -77,33 +189,58 @@ func StopWebsocketService() {
 		return
 	}
 
+	// the calls and the janitors submit jobs, they stop before the pool drains
+	alog.WarningLogger("Stopping calls")
+	WebsocketHUB.Calls.Stop()
+
+	if WebsocketHUB.Deletions != nil {
+		alog.WarningLogger("Stopping group deletions")
+		WebsocketHUB.Deletions.Stop()
+	}
+
+	if WebsocketHUB.Scheduler != nil {
+		alog.WarningLogger("Stopping scheduled messages")
+		WebsocketHUB.Scheduler.Stop()
+	}
+
+	if WebsocketHUB.Expiry != nil {
+		alog.WarningLogger("Stopping disappearing messages")
+		WebsocketHUB.Expiry.Stop()
+	}
+
 	alog.WarningLogger("Gracefully shutting down worker pool")
 	WebsocketHUB.WorkerPool.ShutdownPool()
 
+	if WebsocketHUB.Streams != nil {
+		alog.WarningLogger("Stopping change streams")
+		WebsocketHUB.Streams.Stop()
+	}
+
 	alog.WarningLogger("Initializing websocket clean up")
-	WebsocketHUB.mux.Lock()
-	defer WebsocketHUB.mux.Unlock()
+	WebsocketHUB.stopBackplane()
 
-	for key, conn := range WebsocketHUB.P2PConnections {
+	WebsocketHUB.P2PConnections.Range(func(key string, conn *P2PConnectionCredentials) bool {
 		err := conn.Conn.Close()
 		if err != nil {
 			alog.ErrorLog(fmt.Sprintf("Error closing P2P connection %s: %v", key, err))
 		}
-		delete(WebsocketHUB.P2PConnections, key)
-	}
+		WebsocketHUB.P2PConnections.Unregister(key, conn)
+		return true
+	})
 
-	for key, conn := range WebsocketHUB.GroupConnections {
+	WebsocketHUB.GroupConnections.Range(func(key string, conn *GroupConnectionCredentials) bool {
 		err := conn.Conn.Close()
 		if err != nil {
 			alog.WarningLogger(fmt.Sprintf("Error closing Group connection %s: %v", key, err))
 		}
-		delete(WebsocketHUB.GroupConnections, key)
-	}
+		WebsocketHUB.GroupConnections.Unregister(key, conn)
+		return true
+	})
 
 	alog.InfoLogger("All WebSocket connections closed and cleaned up.")
 }
 
-func ListenForP2PActivity(c P2PConnectionCredentials) {
+func ListenForP2PActivity(c *P2PConnectionCredentials) {
 
 	alog := logger.StartLogger()
 	defer c.Conn.Close()
@@ -118,12 +255,19 @@ func ListenForP2PActivity(c P2PConnectionCredentials) {
 
 		switch msgType {
 		case websocket.TextMessage:
+			var event models.InboundEvent
+			if json.Unmarshal(data, &event) == nil && strings.HasPrefix(event.Event, models.EVENT_CALL_PREFIX) {
+				c.HandleCallSignal(data)
+				continue
+			}
+
 			var payload models.InboundP2PTextMessage
 
 			err = json.Unmarshal(data, &payload)
 			if err != nil {
 				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(c.Conn, models.FormatWebsocketErrResponse(err, BAD_REQUEST))
+				c.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_REQUEST))
+				continue
 			}
 
 			c.HandleP2PTextContent(payload)
@@ -133,7 +277,8 @@ func ListenForP2PActivity(c P2PConnectionCredentials) {
 			cont, err := tools.ReadBinaryWebsocketMessage(data, &payload)
 			if err != nil {
 				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(c.Conn, models.FormatWebsocketErrResponse(err, BAD_REQUEST))
+				c.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_REQUEST))
+				continue
 			}
 
 			c.HandleP2PMediaContent(payload, cont)
@@ -145,15 +290,16 @@ func ListenForP2PActivity(c P2PConnectionCredentials) {
 }
 
 func (p *P2PConnectionCredentials) CloseP2PConnection() {
-	WebsocketHUB.mux.Lock()
-	defer WebsocketHUB.mux.Unlock()
 	if err := p.Conn.Close(); err != nil {
 		logger.StartLogger().ErrorLog(fmt.Sprintf("Error closing WebSocket connection for AuthorID %s: %v", p.AuthorID, err))
 	}
-	delete(WebsocketHUB.P2PConnections, p.AuthorID)
+	if p.hub.P2PConnections.Unregister(p.AuthorID, p) {
+		p.hub.removePresence(PRESENCE_P2P, p.AuthorID)
+		p.hub.Calls.Disconnect(p.AuthorID)
+	}
 }
 
-func ListenForGroupActivity(c GroupConnectionCredentials) {
+func ListenForGroupActivity(c *GroupConnectionCredentials) {
 
 	alog := logger.StartLogger()
 	defer c.Conn.Close()
@@ -168,12 +314,18 @@ func ListenForGroupActivity(c GroupConnectionCredentials) {
 
 		switch msgType {
 		case websocket.TextMessage:
+			var event models.InboundEvent
+			if json.Unmarshal(data, &event) == nil && strings.HasPrefix(event.Event, models.EVENT_CALL_PREFIX) {
+				c.HandleGroupCallSignal(data)
+				continue
+			}
+
 			var payload models.InboundGroupTextMessage
 
 			err = json.Unmarshal(data, &payload)
 			if err != nil {
 				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(c.Conn, models.FormatWebsocketErrResponse(err, BAD_FIELD))
+				c.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_FIELD))
 				continue
 			}
 
@@ -185,7 +337,7 @@ func ListenForGroupActivity(c GroupConnectionCredentials) {
 			cont, err := tools.ReadBinaryWebsocketMessage(data, &payload)
 			if err != nil {
 				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(c.Conn, models.FormatWebsocketErrResponse(err, BAD_FIELD))
+				c.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_FIELD))
 				continue
 			}
 
@@ -199,12 +351,13 @@ func ListenForGroupActivity(c GroupConnectionCredentials) {
 }
 
 func (g *GroupConnectionCredentials) CloseGroupConnection() {
-	WebsocketHUB.mux.Lock()
-	defer WebsocketHUB.mux.Unlock()
 	if err := g.Conn.Close(); err != nil {
 		logger.StartLogger().ErrorLog(fmt.Sprintf("Error closing WebSocket connection for GroupID %s: %v", g.TargetID, err))
 	}
-	delete(WebsocketHUB.GroupConnections, g.AuthorID)
+	if g.hub.GroupConnections.Unregister(g.AuthorID, g) {
+		g.hub.removePresence(PRESENCE_GROUP, groupPresenceID(g.TargetID, g.AuthorID))
+		g.hub.Calls.LeaveGroupCalls(g.AuthorID, g.TargetID)
+	}
 }
 
 func (p *P2PConnectionCredentials) HandleP2PTextContent(msg models.InboundP2PTextMessage) {
@@ -215,18 +368,27 @@ func (p *P2PConnectionCredentials) HandleP2PTextContent(msg models.InboundP2PTex
 	tarID, err := primitive.ObjectIDFromHex(p.TargetID)
 	if err != nil {
 		alog.ErrorLog(err.Error())
-		tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, BAD_FIELD))
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_FIELD))
+		return
+	}
+
+	quote, ok := p.quote(msg.ReplyTo)
+	if !ok {
+		return
+	}
+
+	expireAt, ok := p.expiry(tarID)
+	if !ok {
+		return
 	}
 
 	payload.FormatTextLog(tarID, p.AuthorData.ID, p.AuthorData.Name, msg.Body)
+	payload.Quote = quote
+	payload.ExpireAt = expireAt
 
-	WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-		_, err = WebsocketHUB.DBConn.InsertP2PMessageDB(payload)
-		if err != nil {
-			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
-		}
-	})
+	if !p.persistMessage(payload) {
+		return
+	}
 
 	p.BroadcastToP2P(payload)
 }
@@ -239,63 +401,58 @@ func (p *P2PConnectionCredentials) HandleP2PMediaContent(msg models.InboundP2PCo
 	tarID, err := primitive.ObjectIDFromHex(p.TargetID)
 	if err != nil {
 		alog.ErrorLog(err.Error())
-		tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, BAD_FIELD))
+		p.WriteJSON(models.FormatWebsocketErrResponse(err, BAD_FIELD))
+		return
+	}
+
+	quote, ok := p.quote(msg.ReplyTo)
+	if !ok {
+		return
+	}
+	payload.Quote = quote
+
+	payload.ExpireAt, ok = p.expiry(tarID)
+	if !ok {
+		return
 	}
 
 	switch msg.ContentType {
 
 	case models.MESSAGE_TYPE_MEDIA_VIDEOS:
 
-		videoPlay, err := WebsocketHUB.MediaProvider.StoreVideo(fmt.Sprintf("%s*%d", p.TargetID, time.Now().Unix()), msg.Filename[0], binaryContent[0])
+		videoPlay, err := p.hub.MediaProvider.StoreVideo(fmt.Sprintf("%s*%d", p.TargetID, time.Now().Unix()), msg.Filename[0], binaryContent[0])
 		if err != nil {
 			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			p.WriteJSON(models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			return
 		}
 
 		payload.FormatContentChatLog(tarID, p.AuthorData.ID, p.AuthorData.Name, msg.Body, videoPlay.GUID, []string{videoPlay.Src}, []string{videoPlay.Thumbnail}, models.MESSAGE_TYPE_MEDIA_VIDEOS)
 
-		WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-			_, err = WebsocketHUB.DBConn.InsertP2PMessageDB(payload)
-			if err != nil {
-				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
-			}
-		})
-
 	case models.MESSAGE_TYPE_MEDIA_IMAGES:
-		ImageInfo, err := WebsocketHUB.MediaProvider.InsetImages(binaryContent, msg.Filename)
+		ImageInfo, err := p.hub.MediaProvider.InsetImages(binaryContent, msg.Filename)
 		if err != nil {
 			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			p.WriteJSON(models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			return
 		}
 
 		payload.FormatContentChatLog(tarID, p.AuthorData.ID, p.AuthorData.Name, msg.Body, ImageInfo.ContentID, ImageInfo.MediaSource, ImageInfo.Thumbnails, models.MESSAGE_TYPE_MEDIA_IMAGES)
 
-		WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-			_, err = WebsocketHUB.DBConn.InsertP2PMessageDB(payload)
-			if err != nil {
-				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
-			}
-		})
-
 	case models.MESSAGE_TYPE_FILE:
 
-		fileURL, err := WebsocketHUB.MediaProvider.InsertFile(binaryContent[0], msg.Filename[0])
+		fileURL, err := p.hub.MediaProvider.InsertFile(binaryContent[0], msg.Filename[0])
 		if err != nil {
 			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			p.WriteJSON(models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			return
 		}
 		payload.FormatContentChatLog(tarID, p.AuthorData.ID, p.AuthorData.Name, msg.Body, "N/A", []string{fileURL}, []string{""}, models.MESSAGE_TYPE_FILE)
 
-		WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-			_, err = WebsocketHUB.DBConn.InsertP2PMessageDB(payload)
-			if err != nil {
-				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(p.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
-			}
-		})
+	}
 
+	if !p.persistMessage(payload) {
+		return
 	}
 
 	p.BroadcastToP2P(payload)
@@ -304,21 +461,37 @@ func (p *P2PConnectionCredentials) HandleP2PMediaContent(msg models.InboundP2PCo
 
 func (g *GroupConnectionCredentials) HandleGroupTextContent(msg models.InboundGroupTextMessage) {
 
-	alog := logger.StartLogger()
+	if !g.authorize(models.PERMISSION_SEND_MESSAGES) {
+		return
+	}
+
+	route, ok := g.route(msg.TopicID, msg.ReplyTo, msg.Thread)
+	if !ok {
+		return
+	}
+
+	mentions, all, ok := g.mentions(msg.Mentions)
+	if !ok || !g.moderate() {
+		return
+	}
+
+	group := g.Group()
 
 	var payload models.GroupChatTextLog
 
-	payload.FormatTextChatLog(g.TargetData.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body)
+	payload.FormatTextChatLog(group.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body)
+	payload.TopicID = route.topic
+	payload.Quote = route.quote
+	payload.ThreadID = route.thread
+	payload.Mentions = mentions
+	payload.MentionsAll = all
+	payload.ExpireAt = models.MessageExpiry(group.MessageTimer, payload.Created_At)
 
-	WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-		_, err := WebsocketHUB.DBConn.InsertGroupMessageDB(payload)
-		if err != nil {
-			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(g.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
-		}
-	})
+	if !g.persistMessage(payload, g.countThreadReply(route.thread), g.storeMentions(payload.FormatMentions(group))) {
+		return
+	}
 
-	g.BroadcastToParticipants(g.TargetData.Participants, msg.PushTokens, payload)
+	g.BroadcastToParticipants(group.Participants, msg.PushTokens, payload)
 
 }
 
@@ -326,104 +499,164 @@ func (g *GroupConnectionCredentials) HandleGroupMediaContent(msg models.InboundG
 
 	alog := logger.StartLogger()
 
+	if !g.authorize(models.PERMISSION_SEND_MEDIA) {
+		return
+	}
+
+	route, ok := g.route(msg.TopicID, msg.ReplyTo, msg.Thread)
+	if !ok || !g.moderate() {
+		return
+	}
+
+	group := g.Group()
+
 	var payload models.GroupChatContentLog
+	payload.TopicID = route.topic
+	payload.Quote = route.quote
+	payload.ThreadID = route.thread
+	payload.ExpireAt = models.MessageExpiry(group.MessageTimer, time.Now())
 
 	switch msg.ContentType {
 
 	case models.MESSAGE_TYPE_MEDIA_VIDEOS:
 
-		videoPlay, err := WebsocketHUB.MediaProvider.StoreVideo(fmt.Sprintf("%s*%d", g.TargetData.GroupID, time.Now().Unix()), msg.Filename[0], binaryContent[0])
+		videoPlay, err := g.hub.MediaProvider.StoreVideo(fmt.Sprintf("%s*%d", group.GroupID, time.Now().Unix()), msg.Filename[0], binaryContent[0])
 		if err != nil {
 			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(g.Conn, models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			g.WriteJSON(models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			return
 		}
 
-		payload.FormatContentChatLog(g.TargetData.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body, videoPlay.GUID, []string{videoPlay.Src}, []string{videoPlay.Thumbnail}, models.MESSAGE_TYPE_MEDIA_VIDEOS)
-
-		WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-			_, err = WebsocketHUB.DBConn.InsertGroupMessageDB(payload)
-			if err != nil {
-				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(g.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
-			}
-		})
+		payload.FormatContentChatLog(group.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body, videoPlay.GUID, []string{videoPlay.Src}, []string{videoPlay.Thumbnail}, models.MESSAGE_TYPE_MEDIA_VIDEOS)
 
 	case models.MESSAGE_TYPE_MEDIA_IMAGES:
-		ImageInfo, err := WebsocketHUB.MediaProvider.InsetImages(binaryContent, msg.Filename)
+		ImageInfo, err := g.hub.MediaProvider.InsetImages(binaryContent, msg.Filename)
 		if err != nil {
 			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(g.Conn, models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			g.WriteJSON(models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			return
 		}
 
-		payload.FormatContentChatLog(g.TargetData.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body, ImageInfo.ContentID, ImageInfo.MediaSource, ImageInfo.Thumbnails, models.MESSAGE_TYPE_MEDIA_IMAGES)
-
-		WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-			_, err = WebsocketHUB.DBConn.InsertGroupMessageDB(payload)
-			if err != nil {
-				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(g.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
-
-			}
-		})
+		payload.FormatContentChatLog(group.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body, ImageInfo.ContentID, ImageInfo.MediaSource, ImageInfo.Thumbnails, models.MESSAGE_TYPE_MEDIA_IMAGES)
 
 	case models.MESSAGE_TYPE_FILE:
 
-		fileURL, err := WebsocketHUB.MediaProvider.InsertFile(binaryContent[0], msg.Filename[0])
+		fileURL, err := g.hub.MediaProvider.InsertFile(binaryContent[0], msg.Filename[0])
 		if err != nil {
 			alog.ErrorLog(err.Error())
-			tools.WriteWebsocketJSON(g.Conn, models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
-
+			g.WriteJSON(models.FormatWebsocketErrResponse(err, PROVIDER_ERROR))
+			return
 		}
 
-		payload.FormatContentChatLog(g.TargetData.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body, "N/A", []string{fileURL}, []string{}, models.MESSAGE_TYPE_FILE)
-
-		WebsocketHUB.WorkerPool.AssignJobToWorker(func() {
-			_, err = WebsocketHUB.DBConn.InsertGroupMessageDB(payload)
-			if err != nil {
-				alog.ErrorLog(err.Error())
-				tools.WriteWebsocketJSON(g.Conn, models.FormatWebsocketErrResponse(err, DB_ERROR))
+		payload.FormatContentChatLog(group.ID, g.AuthorData.ID, g.AuthorData.Name, msg.Body, "N/A", []string{fileURL}, []string{}, models.MESSAGE_TYPE_FILE)
 
-			}
-		})
+	}
 
+	if !g.persistMessage(payload, g.countThreadReply(route.thread)) {
+		return
 	}
 
-	g.BroadcastToParticipants(g.TargetData.Participants, msg.PushTokens, payload)
+	g.BroadcastToParticipants(group.Participants, msg.PushTokens, payload)
 
 }
 
 // BROADCASTING FUNCTIONS
+
+/*
+BroadcastToParticipants
+sends the payload to the participants connected to this node, forwards it to the
+nodes that hold the rest of the connections and notifies the offline participants,
+see offlinePush
+*/
 func (g *GroupConnectionCredentials) BroadcastToParticipants(participants []primitive.ObjectID, tokens map[string]string, payload any) {
 
 	alog := logger.StartLogger()
 
-	for _, usr := range g.TargetData.Participants {
+	// with change streams every node writes the message to its own sockets
+	deliver := g.hub.Streams == nil
 
-		user, ok := WebsocketHUB.GroupConnections[usr.Hex()]
-		if ok {
-			user.Conn.WriteJSON(payload)
-		} else {
-			u, ok := tokens[usr.Hex()]
-			if !ok {
-				alog.ErrorLog(fmt.Sprintf("User %v is not a participant of group %v", usr.Hex(), g.TargetID))
+	group := g.Group()
+	now := time.Now()
+
+	remote := make(map[string][]string)
+	for _, usr := range participants {
+
+		user, ok := g.hub.GroupConnections.Lookup(usr.Hex())
+		if ok && user.TargetID == g.TargetID {
+			if deliver {
+				user.WriteJSON(payload)
+			}
+			continue
+		}
+
+		nodeID, online := g.hub.locateUser(PRESENCE_GROUP, groupPresenceID(g.TargetID, usr.Hex()))
+		if online && nodeID != g.hub.NodeID {
+			if deliver {
+				remote[nodeID] = append(remote[nodeID], usr.Hex())
 			}
-			alog.InfoLogger(fmt.Sprintf("about to send push notification to user %s", u))
+			continue
+		}
+
+		u, ok := tokens[usr.Hex()]
+		if !ok {
+			alog.ErrorLog(fmt.Sprintf("User %v is not a participant of group %v", usr.Hex(), g.TargetID))
 		}
 
+		push, ok := offlinePush(group, usr, payload, now)
+		if ok {
+			g.hub.sendPushNotification(u, push)
+		}
+	}
+
+	for nodeID, targets := range remote {
+		err := g.hub.forwardToNode(nodeID, ENVELOPE_GROUP, g.TargetID, targets, payload)
+		if err != nil {
+			alog.ErrorLog(err.Error())
+		}
 	}
 }
 
+/*
+BroadcastToP2P
+sends the payload to the author and to the target, wherever node the target is connected,
+if the target is offline a push notification is sent
+*/
 func (p *P2PConnectionCredentials) BroadcastToP2P(payload any) {
 
-	WebsocketHUB.mux.Lock()
-	defer WebsocketHUB.mux.Unlock()
+	alog := logger.StartLogger()
+
+	// with change streams every node writes the message to its own sockets
+	deliver := p.hub.Streams == nil
 
-	p.Conn.WriteJSON(payload)
+	if deliver {
+		p.WriteJSON(payload)
+	}
 
-	user, ok := WebsocketHUB.P2PConnections[p.TargetID]
+	user, ok := p.hub.P2PConnections.Lookup(p.TargetID)
 	if ok {
+		if deliver {
+			user.WriteJSON(payload)
+		}
+		return
+	}
 
-		user.Conn.WriteJSON(payload)
+	nodeID, online := p.hub.locateUser(PRESENCE_P2P, p.TargetID)
+	if online && !deliver {
+		return
 	}
-	logger.StartLogger().InfoLogger(fmt.Sprintf("about to send push notification to user %s", p.TargetPushToken))
+
+	if online && nodeID != p.hub.NodeID {
+		err := p.hub.forwardToNode(nodeID, ENVELOPE_P2P, "", []string{p.TargetID}, payload)
+		if err == nil {
+			return
+		}
+		alog.ErrorLog(err.Error())
+	}
+
+	p.hub.sendPushNotification(p.TargetPushToken, payload)
+}
+
+// groupPresenceID formats the presence id of a user connected to a group
+func groupPresenceID(groupID, userID string) string {
+	return groupID + ":" + userID
 }
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMockConnection returns the server side and the client side of a websocket connection
+func newMockConnection(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+
+	serverSide := make(chan *websocket.Conn, 1)
+
+	S := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		assert.Nil(t, err)
+		serverSide <- conn
+	}))
+	t.Cleanup(S.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial(strings.ReplaceAll(S.URL, "http", "ws"), nil)
+	assert.Nil(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return <-serverSide, client
+}
@@ -0,0 +1,108 @@
+package turn
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+CONSTANTS
+*/
+const (
+	// DEFAULT_CREDENTIALS_TTL time the credentials are accepted by the TURN server
+	DEFAULT_CREDENTIALS_TTL = time.Hour
+)
+
+// ERRORS
+var (
+	ErrNoSecret = errors.New("turn secret not configured")
+)
+
+// Credentials time limited credentials of the TURN server
+type Credentials struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	TTL      int64    `json:"ttl"`
+	URIs     []string `json:"uris"`
+}
+
+/*
+GenerateCredentials
+signs the credentials with the shared secret of the TURN server (REST API scheme),
+the username holds the expiration so the TURN server validates them without calling us
+*/
+func GenerateCredentials(secret, userID string, ttl time.Duration, now time.Time) Credentials {
+
+	username := fmt.Sprintf("%d:%s", now.Add(ttl).Unix(), userID)
+
+	return Credentials{
+		Username: username,
+		Password: sign(secret, username),
+		TTL:      int64(ttl.Seconds()),
+	}
+}
+
+/*
+ValidateCredentials
+checks the credentials the same way the TURN server does
+*/
+func ValidateCredentials(secret, username, password string, now time.Time) bool {
+
+	exp, _, ok := strings.Cut(username, ":")
+	if !ok {
+		return false
+	}
+
+	expiry, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > expiry {
+		return false
+	}
+
+	return hmac.Equal([]byte(sign(secret, username)), []byte(password))
+}
+
+/*
+NewCredentials
+issues the credentials of the user with the server configuration, TURN_SECRET is the
+shared secret, TURN_URIS the comma separated uris and TURN_TTL the lifetime in seconds
+*/
+func NewCredentials(userID string) (Credentials, error) {
+
+	secret := os.Getenv("TURN_SECRET")
+	if secret == "" {
+		return Credentials{}, ErrNoSecret
+	}
+
+	ttl := DEFAULT_CREDENTIALS_TTL
+	if v := os.Getenv("TURN_TTL"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return Credentials{}, err
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	creds := GenerateCredentials(secret, userID, ttl, time.Now())
+
+	for _, uri := range strings.Split(os.Getenv("TURN_URIS"), ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			creds.URIs = append(creds.URIs, uri)
+		}
+	}
+
+	return creds, nil
+}
+
+// sign returns the base64 HMAC-SHA1 of the username
+func sign(secret, username string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,62 @@
+package turn
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerateCredentials test the TURN credentials scheme
+func TestGenerateCredentials(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+
+	t.Run("GenerateCredentials - Username holds expiration and user", func(t *testing.T) {
+
+		creds := GenerateCredentials("secret", "user-1", time.Hour, now)
+
+		assert.Equal(t, "1700003600:user-1", creds.Username)
+		assert.Equal(t, int64(3600), creds.TTL)
+
+		mac := hmac.New(sha1.New, []byte("secret"))
+		mac.Write([]byte(creds.Username))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), creds.Password)
+	})
+
+	t.Run("GenerateCredentials - Valid until expiration", func(t *testing.T) {
+
+		creds := GenerateCredentials("secret", "user-1", time.Hour, now)
+
+		assert.True(t, ValidateCredentials("secret", creds.Username, creds.Password, now.Add(59*time.Minute)))
+		assert.False(t, ValidateCredentials("secret", creds.Username, creds.Password, now.Add(61*time.Minute)))
+		assert.False(t, ValidateCredentials("other", creds.Username, creds.Password, now))
+		assert.False(t, ValidateCredentials("secret", "1700003600:user-2", creds.Password, now))
+	})
+
+	t.Run("NewCredentials - Server configuration", func(t *testing.T) {
+
+		t.Setenv("TURN_SECRET", "secret")
+		t.Setenv("TURN_TTL", "600")
+		t.Setenv("TURN_URIS", "turn:turn.example.com:3478?transport=udp, turns:turn.example.com:5349")
+
+		creds, err := NewCredentials("user-1")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(600), creds.TTL)
+		assert.Len(t, creds.URIs, 2)
+		assert.True(t, strings.HasSuffix(creds.Username, ":user-1"))
+		assert.True(t, ValidateCredentials("secret", creds.Username, creds.Password, time.Now()))
+	})
+
+	t.Run("NewCredentials - Error no secret", func(t *testing.T) {
+
+		t.Setenv("TURN_SECRET", "")
+
+		_, err := NewCredentials("user-1")
+		assert.ErrorIs(t, err, ErrNoSecret)
+	})
+}
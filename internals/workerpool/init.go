@@ -1,11 +1,45 @@
 package workerpool
 
-import "sync"
+import (
+	"errors"
+	"sync"
+	"time"
+)
 
+// ERRORS
+var (
+	ErrQueueFull  = errors.New("worker pool queue is full")
+	ErrPoolClosed = errors.New("worker pool is closed")
+)
+
+// StartNewWorkerPool creates a pool with poolSize workers and a queue of queueSize jobs per priority lane
 func StartNewWorkerPool(poolSize, queueSize int) *WorkerPool {
 	return &WorkerPool{
-		jobQueue: make(chan Job, queueSize),
-		poolSize: poolSize,
-		wg:       sync.WaitGroup{},
+		highQueue: make(chan *task, queueSize),
+		lowQueue:  make(chan *task, queueSize),
+		poolSize:  poolSize,
+		wg:        sync.WaitGroup{},
+		stop:      make(chan struct{}),
+		metrics:   &poolMetrics{},
+	}
+}
+
+// PersistenceJob options for jobs that store data, they run before any notification and are retried
+func PersistenceJob() JobOptions {
+	return JobOptions{
+		Priority: PRIORITY_HIGH,
+		Timeout:  30 * time.Second,
+		Retries:  3,
+		Backoff:  200 * time.Millisecond,
+	}
+}
+
+// NotificationJob options for jobs that notify users, they run after the persistence jobs
+func NotificationJob() JobOptions {
+	return JobOptions{
+		Priority: PRIORITY_LOW,
+		Timeout:  15 * time.Second,
+		Retries:  1,
+		Backoff:  500 * time.Millisecond,
 	}
 }
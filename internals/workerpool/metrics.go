@@ -0,0 +1,86 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolMetrics snapshot of the state of the pool
+type PoolMetrics struct {
+	HighQueueDepth int           `json:"high_queue_depth"`
+	LowQueueDepth  int           `json:"low_queue_depth"`
+	Submitted      uint64        `json:"submitted"`
+	Rejected       uint64        `json:"rejected"`
+	Completed      uint64        `json:"completed"`
+	Failed         uint64        `json:"failed"`
+	Retried        uint64        `json:"retried"`
+	AverageWait    time.Duration `json:"average_wait"`
+	MaxWait        time.Duration `json:"max_wait"`
+	AverageRun     time.Duration `json:"average_run"`
+	MaxRun         time.Duration `json:"max_run"`
+}
+
+type poolMetrics struct {
+	submitted atomic.Uint64
+	rejected  atomic.Uint64
+	completed atomic.Uint64
+	failed    atomic.Uint64
+	retried   atomic.Uint64
+
+	mux       sync.Mutex
+	waits     int64
+	totalWait time.Duration
+	maxWait   time.Duration
+	runs      int64
+	totalRun  time.Duration
+	maxRun    time.Duration
+}
+
+func (m *poolMetrics) observeWait(d time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.waits++
+	m.totalWait += d
+	if d > m.maxWait {
+		m.maxWait = d
+	}
+}
+
+func (m *poolMetrics) observeRun(d time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.runs++
+	m.totalRun += d
+	if d > m.maxRun {
+		m.maxRun = d
+	}
+}
+
+// Metrics returns the queue depth and latency of the pool
+func (w *WorkerPool) Metrics() PoolMetrics {
+
+	res := PoolMetrics{
+		HighQueueDepth: len(w.highQueue),
+		LowQueueDepth:  len(w.lowQueue),
+		Submitted:      w.metrics.submitted.Load(),
+		Rejected:       w.metrics.rejected.Load(),
+		Completed:      w.metrics.completed.Load(),
+		Failed:         w.metrics.failed.Load(),
+		Retried:        w.metrics.retried.Load(),
+	}
+
+	w.metrics.mux.Lock()
+	defer w.metrics.mux.Unlock()
+
+	if w.metrics.waits > 0 {
+		res.AverageWait = w.metrics.totalWait / time.Duration(w.metrics.waits)
+	}
+	if w.metrics.runs > 0 {
+		res.AverageRun = w.metrics.totalRun / time.Duration(w.metrics.runs)
+	}
+	res.MaxWait = w.metrics.maxWait
+	res.MaxRun = w.metrics.maxRun
+
+	return res
+}
@@ -1,23 +1,52 @@
 package workerpool
 
-import "sync"
+import (
+	"sync"
+)
 
 type WorkerPool struct {
-	jobQueue chan Job
-	poolSize int
-	wg       sync.WaitGroup
+	highQueue chan *task
+	lowQueue  chan *task
+	poolSize  int
+	wg        sync.WaitGroup
+
+	// mux guards closed so no job is sent to a closed queue
+	mux    sync.RWMutex
+	closed bool
+
+	// stop is closed on shutdown, the jobs waiting to be retried give up
+	stop chan struct{}
+
+	metrics *poolMetrics
 }
 
+// StartPool starts the workers of the pool
 func (w *WorkerPool) StartPool() {
 
-	for i := 0; i < 0; i++ {
+	for i := 0; i < w.poolSize; i++ {
 		w.wg.Add(1)
 		go w.Worker(i)
 	}
 
 }
 
+/*
+ShutdownPool
+stops receiving jobs and waits for the workers to finish the queued ones, the
+jobs waiting for a retry fail without it
+*/
 func (w *WorkerPool) ShutdownPool() {
-	close(w.jobQueue)
+
+	w.mux.Lock()
+	if w.closed {
+		w.mux.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.stop)
+	close(w.highQueue)
+	close(w.lowQueue)
+	w.mux.Unlock()
+
 	w.wg.Wait()
 }
@@ -0,0 +1,267 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStartPool test that the workers of the pool run the submitted jobs
+func TestStartPool(t *testing.T) {
+
+	t.Run("StartPool - Jobs executed", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(4, 100)
+		pool.StartPool()
+
+		var executed atomic.Int64
+		for i := 0; i < 100; i++ {
+			err := pool.Submit(func(ctx context.Context) error {
+				executed.Add(1)
+				return nil
+			}, PersistenceJob())
+			assert.Nil(t, err)
+		}
+
+		pool.ShutdownPool()
+
+		assert.Equal(t, int64(100), executed.Load())
+		assert.Equal(t, uint64(100), pool.Metrics().Completed)
+	})
+
+	t.Run("StartPool - High priority jobs first", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 10)
+
+		var mux sync.Mutex
+		var order []Priority
+		record := func(p Priority) Job {
+			return func(ctx context.Context) error {
+				mux.Lock()
+				defer mux.Unlock()
+				order = append(order, p)
+				return nil
+			}
+		}
+
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, pool.Submit(record(PRIORITY_LOW), NotificationJob()))
+		}
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, pool.Submit(record(PRIORITY_HIGH), PersistenceJob()))
+		}
+
+		pool.StartPool()
+		pool.ShutdownPool()
+
+		assert.Equal(t, []Priority{PRIORITY_HIGH, PRIORITY_HIGH, PRIORITY_HIGH, PRIORITY_LOW, PRIORITY_LOW, PRIORITY_LOW}, order)
+	})
+}
+
+// TestSubmit test the method Submit
+func TestSubmit(t *testing.T) {
+
+	t.Run("Submit - Queue full", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 1)
+
+		err := pool.Submit(func(ctx context.Context) error { return nil }, PersistenceJob())
+		assert.Nil(t, err)
+
+		err = pool.Submit(func(ctx context.Context) error { return nil }, PersistenceJob())
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		// the low priority lane has its own capacity
+		err = pool.Submit(func(ctx context.Context) error { return nil }, NotificationJob())
+		assert.Nil(t, err)
+
+		metrics := pool.Metrics()
+		assert.Equal(t, 1, metrics.HighQueueDepth)
+		assert.Equal(t, 1, metrics.LowQueueDepth)
+		assert.Equal(t, uint64(1), metrics.Rejected)
+	})
+
+	t.Run("Submit - Pool closed", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 1)
+		pool.StartPool()
+		pool.ShutdownPool()
+
+		err := pool.Submit(func(ctx context.Context) error { return nil }, PersistenceJob())
+		assert.ErrorIs(t, err, ErrPoolClosed)
+	})
+
+	t.Run("Submit - Retried with backoff", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 1)
+		pool.StartPool()
+
+		var attempts atomic.Int64
+		opts := JobOptions{Priority: PRIORITY_HIGH, Retries: 3, Backoff: time.Millisecond}
+
+		err := pool.Submit(func(ctx context.Context) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("database not available")
+			}
+			return nil
+		}, opts)
+		assert.Nil(t, err)
+
+		// the shutdown stops the retries, the job is done first
+		assert.Eventually(t, func() bool { return pool.Metrics().Completed == 1 }, time.Second, time.Millisecond)
+		pool.ShutdownPool()
+
+		metrics := pool.Metrics()
+		assert.Equal(t, int64(3), attempts.Load())
+		assert.Equal(t, uint64(2), metrics.Retried)
+		assert.Equal(t, uint64(1), metrics.Completed)
+		assert.Equal(t, uint64(0), metrics.Failed)
+	})
+
+	t.Run("Submit - Failure reported after retries", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 1)
+		pool.StartPool()
+
+		expectedError := errors.New("database not available")
+		var reported error
+
+		opts := JobOptions{
+			Priority:  PRIORITY_HIGH,
+			Retries:   2,
+			Backoff:   time.Millisecond,
+			OnFailure: func(err error) { reported = err },
+		}
+
+		err := pool.Submit(func(ctx context.Context) error { return expectedError }, opts)
+		assert.Nil(t, err)
+
+		assert.Eventually(t, func() bool { return pool.Metrics().Failed == 1 }, time.Second, time.Millisecond)
+		pool.ShutdownPool()
+
+		assert.ErrorIs(t, reported, expectedError)
+		assert.Equal(t, uint64(2), pool.Metrics().Retried)
+	})
+
+	t.Run("Submit - Job timeout", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 1)
+		pool.StartPool()
+
+		var reported error
+		opts := JobOptions{
+			Priority:  PRIORITY_HIGH,
+			Timeout:   10 * time.Millisecond,
+			OnFailure: func(err error) { reported = err },
+		}
+
+		err := pool.Submit(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, opts)
+		assert.Nil(t, err)
+
+		pool.ShutdownPool()
+
+		assert.ErrorIs(t, reported, context.DeadlineExceeded)
+	})
+
+	t.Run("Submit - Panic recovered", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 2)
+		pool.StartPool()
+
+		var reported error
+		opts := JobOptions{Priority: PRIORITY_HIGH, OnFailure: func(err error) { reported = err }}
+
+		assert.Nil(t, pool.Submit(func(ctx context.Context) error { panic("nil user") }, opts))
+		assert.Nil(t, pool.Submit(func(ctx context.Context) error { return nil }, PersistenceJob()))
+
+		pool.ShutdownPool()
+
+		assert.Error(t, reported)
+		assert.Equal(t, uint64(1), pool.Metrics().Completed)
+	})
+
+	t.Run("Submit - Shutdown stops the retries", func(t *testing.T) {
+
+		pool := StartNewWorkerPool(1, 1)
+		pool.StartPool()
+
+		expectedError := errors.New("database not available")
+		var attempts atomic.Int64
+		var reported error
+
+		opts := JobOptions{
+			Priority:  PRIORITY_HIGH,
+			Retries:   3,
+			Backoff:   time.Hour,
+			OnFailure: func(err error) { reported = err },
+		}
+
+		err := pool.Submit(func(ctx context.Context) error {
+			attempts.Add(1)
+			return expectedError
+		}, opts)
+		assert.Nil(t, err)
+
+		assert.Eventually(t, func() bool { return attempts.Load() == 1 }, time.Second, time.Millisecond)
+
+		stopped := make(chan struct{})
+		go func() {
+			pool.ShutdownPool()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(2 * time.Second):
+			t.Fatal("shutdown waited for the backoff")
+		}
+
+		assert.ErrorIs(t, reported, expectedError)
+		assert.Equal(t, uint64(0), pool.Metrics().Retried)
+		assert.Equal(t, uint64(1), pool.Metrics().Failed)
+	})
+}
+
+// TestAwait test that the calls of the jobs give up when the job times out
+func TestAwait(t *testing.T) {
+
+	t.Run("Await - Call finished", func(t *testing.T) {
+
+		expectedError := errors.New("duplicate key")
+
+		err := Await(context.Background(), func() error { return expectedError })
+
+		assert.ErrorIs(t, err, expectedError)
+	})
+
+	t.Run("Await - Job timed out", func(t *testing.T) {
+
+		release := make(chan struct{})
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := Await(ctx, func() error {
+			<-release
+			return nil
+		})
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Await - Panic returned", func(t *testing.T) {
+
+		err := Await(context.Background(), func() error { panic("nil user") })
+
+		assert.Error(t, err)
+	})
+}
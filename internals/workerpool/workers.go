@@ -1,17 +1,207 @@
 package workerpool
 
-type Job func()
+import (
+	"context"
+	"fmt"
+	"time"
+)
 
-// Worker will be in charge to complete an assigned task
+/*
+CONSTANTS
+*/
+const (
+	// PRIORITY_HIGH lane for jobs that must run first, like persisting messages
+	PRIORITY_HIGH Priority = iota
+
+	// PRIORITY_LOW lane for jobs that can wait, like notifications
+	PRIORITY_LOW
+)
+
+// Job unit of work executed by a worker, it must stop when the context is done
+type Job func(ctx context.Context) error
+
+// Priority lane the job is queued on
+type Priority int
+
+// JobOptions controls how a job is executed
+type JobOptions struct {
+	Priority Priority
+
+	// Timeout of every attempt, no timeout if zero
+	Timeout time.Duration
+
+	// Retries number of times the job is retried after failing
+	Retries int
+
+	// Backoff wait before the first retry, it doubles on every retry
+	Backoff time.Duration
+
+	// OnFailure is called when the job fails after all the retries
+	OnFailure func(error)
+}
+
+type task struct {
+	job      Job
+	opts     JobOptions
+	enqueued time.Time
+}
+
+// Worker will be in charge to complete an assigned task, high priority jobs are always taken first
 func (w *WorkerPool) Worker(ID int) {
 	defer w.wg.Done()
 
-	for job := range w.jobQueue {
-		job()
+	high, low := w.highQueue, w.lowQueue
+
+	for high != nil || low != nil {
+
+		select {
+		case t, ok := <-high:
+			if !ok {
+				high = nil
+				continue
+			}
+			w.run(t)
+			continue
+		default:
+		}
+
+		select {
+		case t, ok := <-high:
+			if !ok {
+				high = nil
+				continue
+			}
+			w.run(t)
+		case t, ok := <-low:
+			if !ok {
+				low = nil
+				continue
+			}
+			w.run(t)
+		}
+	}
+}
+
+// run executes the task retrying it with backoff while it fails, the shutdown of the pool stops the retries
+func (w *WorkerPool) run(t *task) {
+
+	w.metrics.observeWait(time.Since(t.enqueued))
+
+	start := time.Now()
+	backoff := t.opts.Backoff
+
+	var err error
+
+retries:
+	for attempt := 0; attempt <= t.opts.Retries; attempt++ {
+
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-w.stop:
+				timer.Stop()
+				break retries
+			}
+			w.metrics.retried.Add(1)
+			backoff *= 2
+		}
+
+		err = w.attempt(t)
+		if err == nil {
+			break
+		}
+	}
+
+	w.metrics.observeRun(time.Since(start))
+
+	if err != nil {
+		w.metrics.failed.Add(1)
+		if t.opts.OnFailure != nil {
+			t.opts.OnFailure(err)
+		}
+		return
+	}
+
+	w.metrics.completed.Add(1)
+}
+
+// attempt runs the job once, a panic on the job is returned as an error
+func (w *WorkerPool) attempt(t *task) (err error) {
+
+	ctx := context.Background()
+	if t.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.opts.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return t.job(ctx)
+}
+
+/*
+Submit
+queues the job without blocking, returns ErrQueueFull if the lane is full
+and ErrPoolClosed if the pool is shutting down
+*/
+func (w *WorkerPool) Submit(job Job, opts JobOptions) error {
+
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	if w.closed {
+		w.metrics.rejected.Add(1)
+		return ErrPoolClosed
+	}
+
+	queue := w.highQueue
+	if opts.Priority == PRIORITY_LOW {
+		queue = w.lowQueue
+	}
+
+	select {
+	case queue <- &task{job: job, opts: opts, enqueued: time.Now()}:
+		w.metrics.submitted.Add(1)
+		return nil
+	default:
+		w.metrics.rejected.Add(1)
+		return ErrQueueFull
+	}
+}
+
+/*
+Await
+runs the call of a job and returns its error, or the error of the context of
+the job when it is done first. The call keeps running on its own then, it must
+not touch the state of the job
+*/
+func Await(ctx context.Context, call func() error) error {
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		done <- call()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// AssignJobToWorker assigns a job to a worker
-func (w *WorkerPool) AssignJobToWorker(job Job) {
-	w.jobQueue <- job
+// AssignJobToWorker assigns a high priority job to a worker without retries
+func (w *WorkerPool) AssignJobToWorker(job Job) error {
+	return w.Submit(job, JobOptions{Priority: PRIORITY_HIGH})
 }
@@ -4,3 +4,8 @@ package media
 func (m *Media) InsertFile(content []byte, filename string) (string, error) {
 	return storeFile(content, filename)
 }
+
+// DeleteContent Deletes the images, files and avatars behind the urls from the provider
+func (m *Media) DeleteContent(urls []string) error {
+	return deleteStoredContent(urls)
+}
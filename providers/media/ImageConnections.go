@@ -12,6 +12,11 @@ func (m *Media) InsertGroupAvatar(content []byte, filename string) (string, erro
 	return storeProfileImages(content, filename)
 }
 
+// DeleteGroupAvatar Deletes a replaced Group avatar from the provider
+func (m *Media) DeleteGroupAvatar(filename string) error {
+	return deleteProfileImage(filename)
+}
+
 func (m *Media) InsetImages(images [][]byte, filenames []string) (ImageResponse, error) {
 	return storeImagesMedia(images, filenames)
 }
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 )
 
 var (
 	ErrNoInserted = errors.New("content not inserted")
+	ErrNoDeleted  = errors.New("content not deleted")
 )
 
 // DIRECT REQUEST TO IMAGES AND FILES
@@ -47,6 +49,79 @@ func storeProfileImages(file []byte, filename string) (string, error) {
 	return fmt.Sprintf("%s/%s", os.Getenv("PROFILES_URL"), filename), nil
 }
 
+// deleteProfileImage creates a request to delete an avatar from storage
+func deleteProfileImage(filename string) error {
+	return deleteObject(os.Getenv("PROFILES_PATH"), os.Getenv("PROFILES_AUTH"), filename)
+}
+
+/*
+deleteStoredContent
+deletes the objects behind the urls, the zone of every object is found by the
+public url it was served from. Videos live on their own libraries, they are
+deleted by their content id, see deleteStoredVideos
+*/
+func deleteStoredContent(urls []string) error {
+
+	zones := []struct {
+		url, path, auth string
+	}{
+		{os.Getenv("CONTENT_URL"), os.Getenv("CONTENT_PATH"), os.Getenv("CONTENT_AUTH")},
+		{os.Getenv("FILES_URL"), os.Getenv("FILES_PATH"), os.Getenv("FILES_AUTH")},
+		{os.Getenv("PROFILES_URL"), os.Getenv("PROFILES_PATH"), os.Getenv("PROFILES_AUTH")},
+	}
+
+	var errs []error
+	for _, url := range urls {
+		for _, zone := range zones {
+			filename, ok := strings.CutPrefix(url, zone.url+"/")
+			if zone.url == "" || !ok {
+				continue
+			}
+
+			filename, _, _ = strings.Cut(filename, "?")
+			err := deleteObject(zone.path, zone.auth, filename)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", url, err))
+			}
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deleteObject creates a request to delete an object from a zone of the storage
+func deleteObject(path, auth, filename string) error {
+
+	alog := StartLogger()
+
+	url := fmt.Sprintf("%s/%s/%s", os.Getenv("BASE_URL"), path, filename)
+
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return err
+	}
+
+	req.Header.Set("AccessKey", auth)
+	req.Header.Set("accept", "application/json")
+
+	client := http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		alog.ErrorLog(err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+
+	// the object is already gone
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return ErrNoDeleted
+	}
+
+	return nil
+}
+
 // storeImagesMedia creates a request to store an image to storage
 func storeImagesMedia(files [][]byte, filename []string) (ImageResponse, error) {
 
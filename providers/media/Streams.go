@@ -1,5 +1,13 @@
 package media
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
 // storeVideo stores a new video to the provider
 func storeVideo(groupID, fileTitle string, videoContent []byte) (VideoPlayback, error) {
 
@@ -39,3 +47,30 @@ func storeVideo(groupID, fileTitle string, videoContent []byte) (VideoPlayback,
 func deleteLibraryData(LibraryID int, API_KEY string) error {
 	return deleteLibrary(LibraryID, API_KEY)
 }
+
+/*
+deleteStoredVideos
+deletes the videos behind the content ids, every video is stored on its own
+library so the library goes with the video, its playlist and its thumbnail
+*/
+func deleteStoredVideos(contentIDs []string) error {
+
+	var errs []error
+	for _, id := range contentIDs {
+
+		// the content id of a video is library$video
+		library, _, ok := strings.Cut(id, "$")
+		libraryID, err := strconv.Atoi(library)
+		if !ok || err != nil {
+			errs = append(errs, fmt.Errorf("%s: not a video", id))
+			continue
+		}
+
+		err = deleteLibrary(libraryID, os.Getenv("MEDIA_PKEY"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
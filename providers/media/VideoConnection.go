@@ -9,3 +9,8 @@ func (m *Media) StoreVideo(groupID, filename string, content []byte) (VideoPlayb
 func (m *Media) DeleteLibraryContent(libraryID int, API_KEY string) error {
 	return deleteLibraryData(libraryID, API_KEY)
 }
+
+// DeleteVideos Deletes the videos behind the content ids from the provider
+func (m *Media) DeleteVideos(contentIDs []string) error {
+	return deleteStoredVideos(contentIDs)
+}
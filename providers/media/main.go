@@ -9,8 +9,11 @@ import (
 type MediaHUB interface {
 	StoreVideo(groupID, filename string, content []byte) (VideoPlayback, error)
 	InsertFile(content []byte, filename string) (string, error)
+	DeleteContent(urls []string) error
+	DeleteVideos(contentIDs []string) error
 	InsertUserAvatar(content []byte, filename string) (string, error)
 	InsertGroupAvatar(content []byte, filename string) (string, error)
+	DeleteGroupAvatar(filename string) error
 	InsetImages(images [][]byte, filenames []string) (ImageResponse, error)
 }
 
@@ -3,8 +3,11 @@ package media
 type MediaMock struct {
 	StoreVideoMockFunc        func(string, string, []byte) (VideoPlayback, error)
 	InsertFileMockFunc        func([]byte, string) (string, error)
+	DeleteContentMockFunc     func([]string) error
+	DeleteVideosMockFunc      func([]string) error
 	InsertUserAvatarMockFunc  func([]byte, string) (string, error)
 	InsertGroupAvatarMockFunc func([]byte, string) (string, error)
+	DeleteGroupAvatarMockFunc func(string) error
 	InsetImagesMockFunc       func([][]byte, []string) (ImageResponse, error)
 }
 
@@ -22,6 +25,20 @@ func (m *MediaMock) InsertFile(content []byte, filename string) (string, error)
 	return "", nil
 }
 
+func (m *MediaMock) DeleteContent(urls []string) error {
+	if m.DeleteContentMockFunc != nil {
+		return m.DeleteContentMockFunc(urls)
+	}
+	return nil
+}
+
+func (m *MediaMock) DeleteVideos(contentIDs []string) error {
+	if m.DeleteVideosMockFunc != nil {
+		return m.DeleteVideosMockFunc(contentIDs)
+	}
+	return nil
+}
+
 func (m *MediaMock) InsertUserAvatar(content []byte, filename string) (string, error) {
 	if m.InsertUserAvatarMockFunc != nil {
 		return m.InsertUserAvatarMockFunc(content, filename)
@@ -31,11 +48,18 @@ func (m *MediaMock) InsertUserAvatar(content []byte, filename string) (string, e
 
 func (m *MediaMock) InsertGroupAvatar(content []byte, filename string) (string, error) {
 	if m.InsertGroupAvatarMockFunc != nil {
-		return m.InsertGroupAvatar(content, filename)
+		return m.InsertGroupAvatarMockFunc(content, filename)
 	}
 	return "", nil
 }
 
+func (m *MediaMock) DeleteGroupAvatar(filename string) error {
+	if m.DeleteGroupAvatarMockFunc != nil {
+		return m.DeleteGroupAvatarMockFunc(filename)
+	}
+	return nil
+}
+
 func (m *MediaMock) InsetImages(images [][]byte, filenames []string) (ImageResponse, error) {
 	if m.InsetImagesMockFunc != nil {
 		return m.InsetImagesMockFunc(images, filenames)